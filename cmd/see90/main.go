@@ -1,90 +1,147 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/jpnock/see90/pkg/c90"
+	"github.com/jpnock/see90/pkg/cpp"
 )
 
-type CommentRemoverReader struct {
-	strippingMultiline  bool
-	strippingRestOfLine bool
-	r                   io.Reader
-	last                byte
+// stringList is a flag which may be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
 }
 
-func (r *CommentRemoverReader) Read(p []byte) (n int, err error) {
-	tmp := make([]byte, len(p)+1)
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
 
-	n, err = r.r.Read(tmp[1:])
-	if err != nil {
-		return n, err
+// splitJoinedFlags rewrites gcc style arguments such as `-Idir` and `-O2`
+// into the `-I dir` form understood by the flag package. A bare `-O` which
+// isn't followed by a level is `-O 1`, as it is for gcc.
+func splitJoinedFlags(args []string) []string {
+	var res []string
+	for i, arg := range args {
+		if arg == "-O" && (i+1 == len(args) || !isNumber(args[i+1])) {
+			res = append(res, "-O", "1")
+			continue
+		}
+		if len(arg) > 2 && strings.HasPrefix(arg, "-O") && isNumber(arg[2:]) {
+			res = append(res, "-O", arg[2:])
+			continue
+		}
+		if len(arg) > 2 && (strings.HasPrefix(arg, "-I") || strings.HasPrefix(arg, "-D") || strings.HasPrefix(arg, "-U")) && arg[2] != '=' {
+			res = append(res, arg[:2], arg[2:])
+			continue
+		}
+		res = append(res, arg)
 	}
+	return res
+}
 
-	if n == 0 {
-		return 0, nil
+// isNumber reports whether s is made up only of decimal digits.
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
 	}
+	return true
+}
 
-	tmp[0] = r.last
+func main() {
+	var includePaths, defines, undefines stringList
+
+	inputPath := flag.String("S", "test/all/main.c", "The input file path")
+	outputPath := flag.String("o", "test/all/main.s", "The output file path")
+	flag.Var(&includePaths, "I", "Add a directory to the #include search path (may be repeated)")
+	flag.Var(&defines, "D", "Define a macro as NAME or NAME=VALUE (may be repeated)")
+	flag.Var(&undefines, "U", "Undefine a macro (may be repeated)")
+	preprocessOnly := flag.Bool("E", false, "Only run the preprocessor, writing its output to the output file")
+	dumpAST := flag.Bool("dump-ast", false, "Print the parsed AST to stderr")
+	emitIR := flag.Bool("emit-ir", false, "Write the IR to the output file instead of assembly")
+	optimization := flag.Int("O", 0, "The optimisation level: 0, 1 or 2")
+	peephole := flag.Bool("peephole", false, "Run the peephole optimiser over the assembly (the default at -O1 and above)")
+	flag.CommandLine.Parse(splitJoinedFlags(os.Args[1:]))
+	if !isFlagSet("peephole") {
+		*peephole = *optimization > 0
+	}
+
+	pp := cpp.New(includePaths)
+	for _, def := range defines {
+		pp.Define(def)
+	}
+	for _, name := range undefines {
+		pp.Undefine(name)
+	}
 
-	for i := 0; i < n+1; i++ {
-		c := tmp[i]
-		var next byte
-		if i+1 < n {
-			next = tmp[i+1]
+	if *preprocessOnly {
+		source, err := pp.PreprocessFile(*inputPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		writeOutput(*outputPath, source.Bytes())
+		return
+	}
 
-		if r.strippingRestOfLine {
-			if c == '\n' {
-				r.strippingRestOfLine = false
-			} else {
-				tmp[i] = ' '
-			}
-		} else if r.strippingMultiline {
-			if c == '*' && next == '/' {
-				r.strippingMultiline = false
-				tmp[i+1] = ' '
-			}
-			tmp[i] = ' '
-		} else {
-			if c == '/' && next == '*' {
-				r.strippingMultiline = true
-				tmp[i] = ' '
-				tmp[i+1] = ' '
-			} else if c == '/' && next == '/' {
-				r.strippingRestOfLine = true
-				tmp[i] = ' '
-				tmp[i+1] = ' '
-			}
+	var diagnostics c90.DiagnosticList
+	exitOnErrors := func(err error) {
+		for _, d := range diagnostics {
+			fmt.Fprintln(os.Stderr, d)
+		}
+		diagnostics = nil
+		if err != nil {
+			os.Exit(1)
 		}
 	}
 
-	r.last = tmp[n-1]
+	session := c90.NewSession(pp, &diagnostics)
+	exitOnErrors(session.ParseFile(*inputPath))
 
-	copy(p, tmp[1:])
-	return n, nil
-}
+	if *dumpAST {
+		fmt.Fprintln(os.Stderr, session.TranslationUnit().Describe(0))
+	}
 
-func main() {
-	inputPath := flag.String("S", "test/all/main.c", "The input file path")
-	outputPath := flag.String("o", "test/all/main.s", "The output file path")
-	flag.Parse()
+	exitOnErrors(session.Check())
+	exitOnErrors(session.Optimize(*optimization))
+	session.SetPeephole(*peephole)
 
-	inputFile, err := os.Open(*inputPath)
-	if err != nil {
-		log.Fatal(err)
-	}
-	outputFile, err := os.Create(*outputPath)
-	if err != nil {
-		log.Fatal(err)
+	var output bytes.Buffer
+	if *emitIR {
+		exitOnErrors(session.EmitIR(&output))
+	} else {
+		exitOnErrors(session.Emit(&output))
 	}
 
-	c90.Parse(c90.NewLexer(&CommentRemoverReader{r: inputFile}))
+	writeOutput(*outputPath, output.Bytes())
+}
+
+// isFlagSet reports whether the flag called name was given on the command
+// line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
 
-	fmt.Fprint(os.Stderr, c90.AST.Describe(0))
-	c90.AST.GenerateMIPS(outputFile, c90.NewMIPS())
+func writeOutput(path string, data []byte) {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Fatal(err)
+	}
 }
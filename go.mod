@@ -3,6 +3,5 @@ module github.com/jpnock/see90
 go 1.17
 
 require (
-	github.com/Knetic/govaluate v3.0.0+incompatible // indirect
 	golang.org/x/tools v0.1.8 // indirect
 )
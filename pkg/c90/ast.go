@@ -2,35 +2,29 @@ package c90
 
 import (
 	"fmt"
-	"io"
 	"math"
-	"os"
-	"strconv"
 	"strings"
+
+	"github.com/jpnock/see90/pkg/ir"
 )
 
-type VarType string
+type StorageClass string
 
 const (
-	VarTypeInvalid  VarType = ""
-	VarTypeInteger  VarType = "int"
-	VarTypeLong     VarType = "long"
-	VarTypeShort    VarType = "short"
-	VarTypeFloat    VarType = "float"
-	VarTypeDouble   VarType = "double"
-	VarTypeChar     VarType = "char"
-	VarTypeVoid     VarType = "void"
-	VarTypeSigned   VarType = "signed"
-	VarTypeUnsigned VarType = "unsigned"
-	VarTypeString   VarType = "string"
-	VarTypeTypeName VarType = "typename"
-	VarTypeEnum     VarType = "enum"
-	VarTypeStruct   VarType = "struct"
+	StorageClassNone     StorageClass = ""
+	StorageClassTypedef  StorageClass = "typedef"
+	StorageClassExtern   StorageClass = "extern"
+	StorageClassStatic   StorageClass = "static"
+	StorageClassAuto     StorageClass = "auto"
+	StorageClassRegister StorageClass = "register"
 )
 
+// Node is a node of the AST. Lower builds the IR for the node, returning its
+// value if it's an expression with a scalar value, or its address if it's an
+// expression of array, structure or function type. Statements return nil.
 type Node interface {
 	Describe(indent int) string
-	GenerateMIPS(w io.Writer, m *MIPS)
+	Lower(l *Lowerer) ir.Value
 }
 
 type ASTExpression []*ASTAssignment
@@ -47,10 +41,12 @@ func (t ASTExpression) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTExpression) GenerateMIPS(w io.Writer, m *MIPS) {
+func (t ASTExpression) Lower(l *Lowerer) ir.Value {
+	var v ir.Value
 	for _, assignment := range t {
-		assignment.GenerateMIPS(w, m)
+		v = assignment.Lower(l)
 	}
+	return v
 }
 
 type ASTTranslationUnit []Node
@@ -66,13 +62,17 @@ func (t ASTTranslationUnit) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTTranslationUnit) GenerateMIPS(w io.Writer, m *MIPS) {
+func (t ASTTranslationUnit) Lower(l *Lowerer) ir.Value {
 	for _, node := range t {
-		node.GenerateMIPS(w, m)
+		l.lowerExternal(node)
 	}
+	return nil
 }
 
 type ASTBrackets struct {
+	located
+	typed
+
 	Node
 }
 
@@ -84,8 +84,8 @@ func (t ASTBrackets) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTBrackets) GenerateMIPS(w io.Writer, m *MIPS) {
-	t.Node.GenerateMIPS(w, m)
+func (t ASTBrackets) Lower(l *Lowerer) ir.Value {
+	return t.Node.Lower(l)
 }
 
 type ASTDeclarationStatementLists struct {
@@ -105,13 +105,10 @@ func (t ASTDeclarationStatementLists) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTDeclarationStatementLists) GenerateMIPS(w io.Writer, m *MIPS) {
-	for _, node := range t.decls {
-		node.GenerateMIPS(w, m)
-	}
-	for _, node := range t.stmts {
-		node.GenerateMIPS(w, m)
-	}
+func (t ASTDeclarationStatementLists) Lower(l *Lowerer) ir.Value {
+	t.decls.Lower(l)
+	t.stmts.Lower(l)
+	return nil
 }
 
 type ASTStatementList []Node
@@ -120,7 +117,6 @@ func (t ASTStatementList) Describe(indent int) string {
 	var sb strings.Builder
 	for i, decl := range t {
 		if decl == nil {
-			fmt.Fprintf(os.Stderr, "Found nil decl in statement list\n")
 			continue
 		}
 		if i != 0 {
@@ -131,10 +127,13 @@ func (t ASTStatementList) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTStatementList) GenerateMIPS(w io.Writer, m *MIPS) {
+func (t ASTStatementList) Lower(l *Lowerer) ir.Value {
 	for _, node := range t {
-		node.GenerateMIPS(w, m)
+		if node != nil {
+			node.Lower(l)
+		}
 	}
+	return nil
 }
 
 type ASTDeclaratorList []*ASTDecl
@@ -150,10 +149,11 @@ func (t ASTDeclaratorList) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTDeclaratorList) GenerateMIPS(w io.Writer, m *MIPS) {
+func (t ASTDeclaratorList) Lower(l *Lowerer) ir.Value {
 	for _, decl := range t {
-		decl.GenerateMIPS(w, m)
+		decl.Lower(l)
 	}
+	return nil
 }
 
 type ASTAssignmentOperator string
@@ -173,7 +173,11 @@ const (
 )
 
 type ASTIdentifier struct {
+	located
+	typed
+
 	ident string
+	sym   *Symbol
 }
 
 func (t *ASTIdentifier) Describe(indent int) string {
@@ -183,104 +187,147 @@ func (t *ASTIdentifier) Describe(indent int) string {
 	return fmt.Sprintf("%s%s", genIndent(indent), t.ident)
 }
 
-func (t *ASTIdentifier) GenerateMIPS(w io.Writer, m *MIPS) {
+func (t *ASTIdentifier) Lower(l *Lowerer) ir.Value {
 	if t == nil {
-		return
+		return nil
 	}
 
-	write(w, "%s:", string(m.CreateUniqueLabel("identgen")))
-	currentlyInGlobalScope := len(m.VariableScopes) == 1
-	if currentlyInGlobalScope {
-		return
+	if sym := t.sym; sym.Kind == SymbolEnumConstant {
+		return ir.IntConst(sym.enum.intValue)
 	}
+	return l.load(t.Type(), l.address(t))
+}
 
-	variable := m.VariableScopes.Peek()[t.ident]
-	if variable == nil {
-		panic(fmt.Errorf("identifier `%s` is not in scope", t.ident))
+// irType returns the type of the IR values holding values of type t. Arrays,
+// structures and functions are used through their address.
+func irType(t *Type) ir.Type {
+	switch t.Kind {
+	case TypeVoid:
+		return ir.Void
+	case TypeChar, TypeSignedChar, TypeUnsignedChar:
+		return ir.I8
+	case TypeShort, TypeUnsignedShort:
+		return ir.I16
+	case TypeFloat:
+		return ir.F32
+	case TypeDouble, TypeLongDouble:
+		// long double is the same as double in the o32 ABI.
+		return ir.F64
 	}
+	return ir.I32
+}
+
+// address returns the address of the object designated by the lvalue n. An
+// expression of structure type which isn't an lvalue, such as a call, gives
+// the address of the temporary holding its value.
+func (l *Lowerer) address(n Node) ir.Value {
+	switch t := n.(type) {
+	case *ASTIdentifier:
+		if t.sym.global {
+			return &ir.Addr{Symbol: string(t.sym.GlobalLabel())}
+		}
+		return &ir.Addr{Slot: t.sym.slot}
+
+	case *ASTBrackets:
+		return l.address(t.Node)
+
+	case ASTExpression:
+		for _, assignment := range t[:len(t)-1] {
+			assignment.Lower(l)
+		}
+		return l.address(t[len(t)-1])
+
+	case *ASTAssignment:
+		if t.tmpAssign {
+			return l.address(t.value)
+		}
 
-	var globalLabel Label
-	if variable.isGlobal {
-		globalLabel = variable.GlobalLabel()
+	case *ASTStringLiteral:
+		return l.stringData(t.data)
 
-		if variable.typ.typ == VarTypeString {
-			globalLabel = *variable.label
+	case *ASTIndexedExpression:
+		// Arrays of arrays are indexed one dimension at a time, as indexing
+		// the outer array gives the address of an inner array.
+		base := t.lvalue.Lower(l)
+		index := l.value(t.index, typeInt)
+		return l.pointerArithmetic(ASTExprBinaryTypeAdd, base, index, t.Type().Size())
+
+	case *ASTExprPrefixUnary:
+		if t.typ == ASTExprPrefixUnaryTypeDereference {
+			return t.lvalue.Lower(l)
 		}
-		// Load the address of the global into $v1
-		write(w, "lui $v1, %%hi(%s)", globalLabel)
-		write(w, "addiu $v1, $v1, %%lo(%s)", globalLabel)
+
+	case *ASTStructElement:
+		return l.memberAddress(t)
+	}
+
+	if typeOf(n).IsStruct() {
+		return n.Lower(l)
+	}
+	l.fatalf(n, "internal compiler error: taking the address of a non-lvalue")
+	return nil
+}
+
+// memberAddress returns the address of the member of a structure accessed by
+// t.
+func (l *Lowerer) memberAddress(t *ASTStructElement) ir.Value {
+	var base ir.Value
+	if t.pointer {
+		base = t.structImp.Lower(l)
 	} else {
-		// Put the address of the local into $v1
-		write(w, "addiu $v1, $fp, %d", -variable.fpOffset)
+		base = l.address(t.structImp)
 	}
+	return l.offset(base, t.field.Offset)
+}
 
-	m.SetLastType(variable.typ.typ)
-	if variable.directDecl != nil {
-		m.pointerLevel = variable.directDecl.pointerDepth
-		if variable.IsArray() {
-			m.pointerLevel += 1
-		}
+// offset returns the integer v plus offset, folding the addition into
+// constants.
+func (l *Lowerer) offset(v ir.Value, offset int) ir.Value {
+	switch c := v.(type) {
+	case *ir.Addr:
+		addr := *c
+		addr.Offset += offset
+		return &addr
+	case *ir.Const:
+		return ir.IntConst(c.Int + int64(offset))
 	}
+	if offset == 0 {
+		return v
+	}
+	return l.b.Binary(ir.OpAdd, ir.I32, false, v, ir.IntConst(int64(offset)))
+}
 
-	if variable.IsArray() {
-		if variable.isGlobal {
-			write(w, "lui $v0, %%hi(%s)", globalLabel)
-			write(w, "addiu $v0, $v0, %%lo(%s)", globalLabel)
-			return
-		}
-		write(w, "addiu $v0, $fp, %d", -variable.fpOffset)
+// load returns the value of type typ at addr. Arrays, structures and functions
+// are used through their address, which is returned instead.
+func (l *Lowerer) load(typ *Type, addr ir.Value) ir.Value {
+	if typ.IsArray() || typ.IsStruct() || typ.IsFunction() {
+		return addr
+	}
+	if typ.IsVoid() {
+		return nil
+	}
+	return l.b.Load(irType(typ), addr, typ.IsUnsigned(), typ.IsVolatile())
+}
 
-		if variable.isLocalDataString {
-			// TODO: make this better
-			// array is in .data section, so we need to dereference.
-			write(w, "lw $v0, 0($v0)")
-			write(w, "lw $v1, 0($v1)")
-		}
-		// Arrays have the same value as their address
+// store stores v, a value of type typ, at addr. Structures are copied from
+// the address v.
+func (l *Lowerer) store(typ *Type, addr, v ir.Value) {
+	if typ.IsStruct() {
+		l.b.CopyBlock(addr, v, typ.Size(), typ.Align())
 		return
 	}
+	l.b.Store(irType(typ), addr, v, typ.IsVolatile())
+}
 
-	switch m.LastType() {
-	case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeStruct:
-		if variable.isGlobal {
-			write(w, "lw $v0, 0($v1)")
-		} else {
-			write(w, "lw $v0, %d($fp)", -variable.fpOffset)
-		}
-	case VarTypeEnum:
-		variable.enum.value.GenerateMIPS(w, m)
-		if variable.enum.offset != 0 {
-			write(w, "addiu $v0, $v0, %d", variable.enum.offset)
-		}
-	case VarTypeChar:
-		if variable.isGlobal {
-			write(w, "lb $v0, 0($v1)")
-		} else {
-			write(w, "lb $v0, %d($fp)", -variable.fpOffset)
-		}
-	case VarTypeFloat:
-		if variable.isGlobal {
-			write(w, "lwc1 $f0, 0($v1)")
-		} else {
-			write(w, "lwc1 $f0, %d($fp)", -variable.fpOffset)
-		}
-	case VarTypeDouble:
-		if variable.isGlobal {
-			write(w, "lwc1 $f0, 4($v1)")
-			write(w, "lwc1 $f1, 0($v1)")
-		} else {
-			write(w, "lwc1 $f0, %d($fp)", -variable.fpOffset+4)
-			write(w, "lwc1 $f1, %d($fp)", -variable.fpOffset)
-		}
-	case VarTypeString:
-		write(w, "lui $v0, %%hi(%s)", *variable.label)
-		write(w, "addiu $v0, $v0, %%lo(%s)", *variable.label)
-	default:
-		panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-	}
+// value lowers the expression n, converting its value to type to.
+func (l *Lowerer) value(n Node, to *Type) ir.Value {
+	return l.convert(n.Lower(l), typeOf(n).Decay(), to)
 }
 
 type ASTAssignment struct {
+	located
+	typed
+
 	lval     Node
 	operator ASTAssignmentOperator
 	value    Node
@@ -301,126 +348,71 @@ func (t *ASTAssignment) Describe(indent int) string {
 	return fmt.Sprintf("%s%s %s %s", genIndent(indent), t.lval.Describe(0), t.operator, t.value.Describe(0))
 }
 
-func storeToReturnRegister(w io.Writer, typ VarType) {
-	switch typ {
-	case VarTypeFloat:
-		write(w, "swc1 $f0, 0($v1)")
-	case VarTypeDouble:
-		write(w, "swc1 $f0, 4($v1)")
-		write(w, "swc1 $f1, 0($v1)")
-	case VarTypeChar:
-		write(w, "sb $v0, 0($v1)")
-	default:
-		write(w, "sw $v0, 0($v1)")
-	}
+// assignmentOperators maps the compound assignment operators to the binary
+// operator they carry out.
+var assignmentOperators = map[ASTAssignmentOperator]ASTExprBinaryType{
+	ASTAssignmentOperatorMulEquals:   ASTExprBinaryTypeMul,
+	ASTAssignmentOperatorDivEquals:   ASTExprBinaryTypeDiv,
+	ASTAssignmentOperatorModEquals:   ASTExprBinaryTypeMod,
+	ASTAssignmentOperatorAddEquals:   ASTExprBinaryTypeAdd,
+	ASTAssignmentOperatorSubEquals:   ASTExprBinaryTypeSub,
+	ASTAssignmentOperatorLeftEquals:  ASTExprBinaryTypeLeftShift,
+	ASTAssignmentOperatorRightEquals: ASTExprBinaryTypeRightShift,
+	ASTAssignmentOperatorAndEquals:   ASTExprBinaryTypeBitwiseAnd,
+	ASTAssignmentOperatorXorEquals:   ASTExprBinaryTypeXor,
+	ASTAssignmentOperatorOrEquals:    ASTExprBinaryTypeBitwiseOr,
 }
 
-// TODO: investigate at later date
-func (t *ASTAssignment) GenerateMIPS(w io.Writer, m *MIPS) {
-	// Load value into $v0/$f0
-	t.value.GenerateMIPS(w, m)
-
-	if t.tmpAssign || m.LastType() == VarTypeString {
-		return
+func (t *ASTAssignment) Lower(l *Lowerer) ir.Value {
+	if t.tmpAssign {
+		return t.value.Lower(l)
 	}
 
-	rhsType := m.LastType()
-
-	// TODO: switch on type
-	switch rhsType {
-	case VarTypeFloat:
-		stackPushFP(w, "$f0")
-		t.lval.GenerateMIPS(w, m)
-		stackPopFP(w, "$f0")
-	case VarTypeDouble:
-		stackPushFP(w, "$f0", "$f1")
-		t.lval.GenerateMIPS(w, m)
-		stackPopFP(w, "$f0", "$f1")
-	default:
-		stackPush(w, "$v0", 4)
-		t.lval.GenerateMIPS(w, m)
-		stackPop(w, "$v0", 4)
+	typ := t.Type()
+	lvalType := typeOf(t.lval)
+	if typ.IsStruct() {
+		// Copy the structure over the lvalue, which is the result of the
+		// assignment.
+		src := t.value.Lower(l)
+		dst := l.address(t.lval)
+		l.store(lvalType, dst, src)
+		return dst
 	}
 
 	if t.operator == ASTAssignmentOperatorEquals {
-		// Special case as this does not require a load
-		storeToReturnRegister(w, m.LastType())
-		return
+		// Special case as this does not require the current value
+		v := l.value(t.value, typ)
+		l.store(lvalType, l.address(t.lval), v)
+		return v
 	}
 
-	switch m.LastType() {
-	case VarTypeFloat:
-		write(w, "lwc1 $f2, 0($v1)")
-	case VarTypeDouble:
-		write(w, "lwc1 $f2, 4($v1)")
-		write(w, "lwc1 $f3, 0($v1)")
-	default:
-		write(w, "lw $t0, 0($v1)")
-	}
-
-	switch t.operator {
-	case ASTAssignmentOperatorMulEquals:
-		switch rhsType {
-		case VarTypeFloat:
-			write(w, "mul.s $f0, $f2, $f0")
-		case VarTypeDouble:
-			write(w, "mul.d $f0, $f2, $f0")
-		case VarTypeUnsigned:
-			write(w, "multu $t0, $v0")
-			write(w, "mflo $v0")
-		default:
-			write(w, "mult $t0, $v0")
-			write(w, "mflo $v0")
-		}
-	case ASTAssignmentOperatorDivEquals:
-		switch rhsType {
-		case VarTypeFloat:
-			write(w, "div.s $f0, $f2, $f0")
-		case VarTypeDouble:
-			write(w, "div.d $f0, $f2, $f0")
-		case VarTypeUnsigned:
-			write(w, "divu $t0, $v0")
-			write(w, "mflo $v0")
-		default:
-			write(w, "div $t0, $v0")
-			write(w, "mflo $v0")
-		}
-	case ASTAssignmentOperatorAddEquals:
-		switch rhsType {
-		case VarTypeFloat:
-			write(w, "add.s $f0, $f2, $f0")
-		case VarTypeDouble:
-			write(w, "add.d $f0, $f2, $f0")
-		default:
-			write(w, "addu $v0, $t0, $v0")
-		}
-	case ASTAssignmentOperatorSubEquals:
-		switch rhsType {
-		case VarTypeFloat:
-			write(w, "sub.s $f0, $f2, $f0")
-		case VarTypeDouble:
-			write(w, "sub.d $f0, $f2, $f0")
-		default:
-			write(w, "subu $v0, $t0, $v0")
-		}
-	case ASTAssignmentOperatorModEquals:
-		write(w, "div $t0, $v0")
-		write(w, "mfhi $v0")
-	case ASTAssignmentOperatorLeftEquals:
-		write(w, "sllv $v0, $t0, $v0")
-	case ASTAssignmentOperatorRightEquals:
-		write(w, "srlv $v0, $t0, $v0")
-	case ASTAssignmentOperatorAndEquals:
-		write(w, "and $v0, $t0, $v0")
-	case ASTAssignmentOperatorXorEquals:
-		write(w, "xor $v0, $t0, $v0")
-	case ASTAssignmentOperatorOrEquals:
-		write(w, "or $v0, $t0, $v0")
-	default:
+	op, ok := assignmentOperators[t.operator]
+	if !ok {
 		panic("unhanlded ASTAssignmentOperator")
 	}
-
-	storeToReturnRegister(w, m.LastType())
+	rhsType := typeOf(t.value).Decay()
+	rhs := t.value.Lower(l)
+	addr := l.address(t.lval)
+	current := l.load(lvalType, addr)
+
+	// The operation is carried out in the type the operands are converted to
+	// by the binary operator, and the result is converted back.
+	var v ir.Value
+	switch {
+	case typ.IsPointer():
+		rhs = l.convert(rhs, rhsType, typeInt)
+		v = l.pointerArithmetic(op, current, rhs, typ.Elem().Size())
+	case op == ASTExprBinaryTypeLeftShift || op == ASTExprBinaryTypeRightShift:
+		opType := promote(typ)
+		rhs = l.convert(rhs, rhsType, promote(rhsType))
+		v = l.convert(l.arithmetic(op, opType, l.convert(current, typ, opType), rhs), opType, typ)
+	default:
+		opType := usualArithmeticConversion(typ, rhsType)
+		rhs = l.convert(rhs, rhsType, opType)
+		v = l.convert(l.arithmetic(op, opType, l.convert(current, typ, opType), rhs), opType, typ)
+	}
+	l.store(lvalType, addr, v)
+	return v
 }
 
 type ASTArgumentExpressionList []*ASTAssignment
@@ -436,10 +428,11 @@ func (t ASTArgumentExpressionList) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTArgumentExpressionList) GenerateMIPS(w io.Writer, m *MIPS) {
+func (t ASTArgumentExpressionList) Lower(l *Lowerer) ir.Value {
 	for _, decl := range t {
-		decl.GenerateMIPS(w, m)
+		decl.Lower(l)
 	}
+	return nil
 }
 
 type ASTInitializerList []Node
@@ -457,16 +450,21 @@ func (t ASTInitializerList) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTInitializerList) GenerateMIPS(w io.Writer, m *MIPS) {
-	// for _, decl := range t {
-	// 	decl.GenerateMIPS(w, m)
-	// }
+// Lower does nothing, as initializers are lowered by the declaration they
+// belong to.
+func (t ASTInitializerList) Lower(l *Lowerer) ir.Value {
+	return nil
 }
 
 type ASTDecl struct {
+	located
+
 	decl    *ASTDirectDeclarator
 	typ     *ASTType
 	initVal Node
+	storage StorageClass
+
+	sym *Symbol
 }
 
 func (t *ASTDecl) Describe(indent int) string {
@@ -474,10 +472,14 @@ func (t *ASTDecl) Describe(indent int) string {
 		return ""
 	}
 
-	if t.decl == nil && t.typ != nil && (t.typ.typ == VarTypeEnum || t.typ.typ == VarTypeStruct) {
+	if t.decl == nil && t.typ != nil && (t.typ.enum != nil || t.typ.structure != nil) {
 		return fmt.Sprintf("%s;", t.typ.Describe(indent))
 	}
 
+	if t.storage == StorageClassTypedef {
+		return fmt.Sprintf("%stypedef %s : %s", genIndent(indent), t.decl.Describe(0), t.typ.Describe(0))
+	}
+
 	pointers := ""
 	if t.decl != nil && t.decl.pointerDepth > 0 {
 		pointers = strings.Repeat("*", t.decl.pointerDepth)
@@ -486,338 +488,250 @@ func (t *ASTDecl) Describe(indent int) string {
 	if t.initVal == nil {
 		return fmt.Sprintf("%s%s : %s%s", genIndent(indent), t.decl.Describe(0), pointers, t.typ.Describe(0))
 	} else {
-		if t.typ != nil && t.typ.typ == VarTypeStruct {
+		if t.typ != nil && t.typ.structure != nil {
 			return fmt.Sprintf("%s%s = { %s } : struct %s%s", genIndent(indent), t.decl.Describe(0), t.initVal.Describe(0), pointers, t.typ.Describe(0))
 		}
 		return fmt.Sprintf("%s%s = %s : %s%s", genIndent(indent), t.decl.Describe(0), t.initVal.Describe(0), pointers, t.typ.Describe(0))
 	}
 }
 
-func (t *ASTDecl) isPointer() bool {
-	return t.decl.pointerDepth > 0
-}
-
-func (t *ASTDecl) isArray() bool {
-	return t.decl.array != nil
-}
-
-func (t *ASTDecl) getArrayInfo(m *MIPS) (dimensions []int, totalElements int, sizeOf int) {
-	if t.decl == nil {
-		return nil, 0, 0
-	}
-
-	// Work out how many bytes to reserve
-	dims := t.decl.ArrayDimensions()
-
-	totalElements = 1
-	if len(dims) == 0 {
-		totalElements = 0
-	}
-	for _, dim := range dims {
-		totalElements *= dim
-	}
+// initializeLocal stores the value of init into the local at addr, which has
+// type typ.
+func (l *Lowerer) initializeLocal(typ *Type, addr *ir.Addr, init Node) {
+	list, isList := init.(ASTInitializerList)
 
-	sizeOfElement := m.sizeOfType(t.typ.typ, t.isPointer())
-	reserveArrayBytes := sizeOfElement * totalElements
-	return dims, totalElements, reserveArrayBytes
-}
+	switch {
+	case isStringInitializer(typ, init):
+		data := unwrapExpr(init).(*ASTStringLiteral).data
+		n := len(data)
+		if n > typ.Size() {
+			n = typ.Size()
+		}
+		for i := 0; i < n; i++ {
+			l.b.Store(ir.I8, l.offset(addr, i), ir.IntConst(int64(data[i])), false)
+		}
+		// Null terminated, and any remaining elements are zeroed.
+		l.zeroLocal(l.offset(addr, n).(*ir.Addr), typ.Size()-n)
 
-func (t *ASTDecl) generateLocalVarMIPSStruct(w io.Writer, m *MIPS, ident *ASTIdentifier, declVar *Variable) {
-	structType := *m.StructScopes[len(m.StructScopes)-1][t.typ.typName]
-	m.Context.GetNewLocalOffsetWithMinSize(structType.totalOffsetSize)
+	case isList && typ.IsArray():
+		elem := typ.Elem()
+		for i := 0; i < typ.Len && i < len(list); i++ {
+			l.initializeLocal(elem, l.offset(addr, i*elem.Size()).(*ir.Addr), list[i])
+		}
+		if len(list) < typ.Len {
+			l.zeroLocal(l.offset(addr, len(list)*elem.Size()).(*ir.Addr), (typ.Len-len(list))*elem.Size())
+		}
 
-	// TODO: is this fine if we're here from an initializer list containing a struct?
-	declVar.structure = &structType
+	case isList && typ.IsStruct():
+		end := 0
+		for i, field := range typ.Struct.InitializedFields() {
+			if i >= len(list) {
+				break
+			}
+			l.zeroLocal(l.offset(addr, end).(*ir.Addr), field.Offset-end)
+			l.initializeLocal(field.Type, l.offset(addr, field.Offset).(*ir.Addr), list[i])
+			end = field.Offset + field.Type.Size()
+		}
+		l.zeroLocal(l.offset(addr, end).(*ir.Addr), typ.Size()-end)
 
-	var numOfInitilizers int
-	if t.initVal != nil {
-		numOfInitilizers = len(t.initVal.(ASTInitializerList))
-		for i, element := range t.initVal.(ASTInitializerList) {
-			// TODO: handle nested init list
+	case isList:
+		l.initializeLocal(typ, addr, list[0])
 
-			element.GenerateMIPS(w, m)
+	case typ.IsStruct():
+		l.store(typ, addr, init.Lower(l))
 
-			typ := structType.types[i].typ
-			if structType.FlatStructEntries[numOfInitilizers+i].decl.isPointer() {
-				typ = VarTypeUnsigned
-			}
+	case typ.IsArray():
+		l.fatalf(init, "initialising an array from an expression is not supported")
 
-			switch typ {
-			case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-				write(w, "sw $v0, %d($fp)", -declVar.fpOffset+structType.offsets[i])
-			case VarTypeChar:
-				write(w, "sb $v0, %d($fp)", -declVar.fpOffset+structType.offsets[i])
-			case VarTypeFloat:
-				write(w, "swc1 $f0, %d($fp)", -declVar.fpOffset+structType.offsets[i])
-			case VarTypeDouble:
-				write(w, "swc1 $f0, %d($fp)", -declVar.fpOffset+structType.offsets[i]+4)
-				write(w, "swc1 $f1, %d($fp)", -declVar.fpOffset+structType.offsets[i])
-			default:
-				panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-			}
-		}
-	} else {
-		numOfInitilizers = 0
+	default:
+		l.store(typ, addr, l.value(init, typ))
 	}
+}
 
-	numOfElements := len(structType.elementIdents)
-	if numOfElements > numOfInitilizers {
-		for i := 0; i < (numOfElements - numOfInitilizers); i++ {
-			typ := structType.types[numOfInitilizers+i].typ
-			if structType.FlatStructEntries[numOfInitilizers+i].decl.isPointer() {
-				typ = VarTypeUnsigned
-			}
-
-			switch typ {
-			case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-				write(w, "addiu $v0, $v0, 0")
-				write(w, "sw $v0, %d($fp)", -declVar.fpOffset+structType.offsets[numOfInitilizers+i])
-			case VarTypeChar:
-				write(w, "addiu $v0, $v0, 0")
-				write(w, "sb $v0, %d($fp)", -declVar.fpOffset+structType.offsets[numOfInitilizers+i])
-			case VarTypeFloat:
-				write(w, "li.s $f0, 0")
-				write(w, "swc1 $f0, %d($fp)", -declVar.fpOffset+structType.offsets[numOfInitilizers+i])
-			case VarTypeDouble:
-				write(w, "li.d $f0, 0")
-				write(w, "swc1 $f0, %d($fp)", -declVar.fpOffset+structType.offsets[numOfInitilizers+i]+4)
-				write(w, "swc1 $f1, %d($fp)", -declVar.fpOffset+structType.offsets[numOfInitilizers+i])
-			default:
-				panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-			}
+// zeroLocal clears size bytes of a local, starting at addr. Whole words are
+// cleared at once where the alignment of the local allows it.
+func (l *Lowerer) zeroLocal(addr *ir.Addr, size int) {
+	end := addr.Offset + size
+	for offset := addr.Offset; offset < end; {
+		typ := ir.I8
+		switch {
+		case offset%4 == 0 && end-offset >= 4 && addr.Slot.Align >= 4:
+			typ = ir.I32
+		case offset%2 == 0 && end-offset >= 2 && addr.Slot.Align >= 2:
+			typ = ir.I16
 		}
+		l.b.Store(typ, &ir.Addr{Slot: addr.Slot, Offset: offset}, ir.IntConst(0), false)
+		offset += typ.Size()
 	}
 }
 
-func (t *ASTDecl) generateLocalVarMIPS(w io.Writer, m *MIPS, ident *ASTIdentifier, declVar *Variable) {
-	isArray := t.decl.array != nil
-
-	if t.typ.typ == VarTypeStruct {
-		declVar.structure = m.StructScopes[len(m.StructScopes)-1][t.typ.typName]
-	}
+func (t *ASTDecl) lowerLocal(l *Lowerer) {
+	typ := t.sym.Type
+	t.sym.slot = l.fn.NewSlot(t.sym.Name, typ.Size(), typ.Align())
 
-	if isArray {
-		_, _, reserveArrayBytes := t.getArrayInfo(m)
-		declVar.fpOffset = m.Context.GetNewLocalOffsetWithMinSize(reserveArrayBytes)
-	} else if t.typ.typ == VarTypeStruct && !t.isPointer() {
-		declVar.fpOffset = m.Context.GetNewLocalOffsetWithMinSize(
-			m.StructScopes[len(m.StructScopes)-1][declVar.typ.structure.ident.ident].totalOffsetSize,
-		)
-		t.generateLocalVarMIPSStruct(w, m, ident, declVar)
-		return
-	} else {
-		declVar.fpOffset = m.Context.GetNewLocalOffset()
+	if t.initVal != nil {
+		l.initializeLocal(typ, &ir.Addr{Slot: t.sym.slot}, t.initVal)
 	}
+}
 
-	if t.initVal == nil {
-		return
+// initializeGlobal appends the initial value of a global of type typ, which
+// is initialised to init, to the items of d.
+func (l *Lowerer) initializeGlobal(d *ir.Data, typ *Type, init Node) {
+	list, isList := init.(ASTInitializerList)
+	space := func(size int) {
+		if size > 0 {
+			d.Items = append(d.Items, ir.DataItem{Kind: ir.DataSpace, Int: int64(size)})
+		}
 	}
 
-	elements := []Node{t.initVal}
-	if initializerList, ok := t.initVal.(ASTInitializerList); isArray && ok {
-		// Generate array with initializer list RHS instead
-		elements = nil
+	switch {
+	case isStringInitializer(typ, init):
+		data := unwrapExpr(init).(*ASTStringLiteral).data
+		if len(data) >= typ.Size() {
+			data = data[:typ.Size()]
+		}
+		d.Items = append(d.Items, ir.DataItem{Kind: ir.DataBytes, Bytes: data})
+		space(typ.Size() - len(data))
 
-		_, numElements, _ := t.getArrayInfo(m)
-		for i, entry := range initializerList {
-			if i >= numElements {
+	case isList && typ.IsArray():
+		elem := typ.Elem()
+		for i, entry := range list {
+			if i >= typ.Len {
 				// Not enough space in the array
 				break
 			}
-
-			if _, ok := entry.(ASTInitializerList); ok {
-				// TODO: handle nested entries
-				panic("entry is an init list which is not yet handled")
-			}
-
-			elements = append(elements, entry)
-		}
-	}
-
-	assignment, ok := t.initVal.(*ASTAssignment)
-	if ok {
-		if _, ok := assignment.value.(*ASTStringLiteral); ok {
-			declVar.isLocalDataString = true
-		}
-	}
-
-	for i, element := range elements {
-		// Value is in $v0/f0, so now we just need to store it
-		element.GenerateMIPS(w, m)
-
-		switchType := t.typ.typ
-		if m.LastType() == VarTypeString || (switchType == VarTypeChar && t.isPointer()) {
-			switchType = VarTypeString
+			l.initializeGlobal(d, elem, entry)
 		}
+		space((typ.Len - len(list)) * elem.Size())
 
-		if len(elements) == 1 && t.isArray() && switchType == VarTypeChar {
-			switchType = VarTypeUnsigned
-		}
-
-		switch switchType {
-		case VarTypeChar:
-			write(w, "sb $v0, %d($fp)", -declVar.fpOffset+i)
-		case VarTypeFloat:
-			write(w, "swc1 $f0, %d($fp)", -declVar.fpOffset+(i*4))
-		case VarTypeDouble:
-			write(w, "swc1 $f0, %d($fp)", -declVar.fpOffset+4+(i*8))
-			write(w, "swc1 $f1, %d($fp)", -declVar.fpOffset+(i*8))
-		case VarTypeString:
-			if isArray {
-				strBytes := m.stringMap[m.lastLabel]
-				_, _, reserveArrayBytes := t.getArrayInfo(m)
-				for i := 0; i < reserveArrayBytes; i++ {
-					if i < len(strBytes) {
-						write(w, "li $t0, %d", strBytes[i])
-						write(w, "sb $t0, %d($fp)", -declVar.fpOffset+i)
-					} else {
-						// Null terminated. If no initialiser is provided
-						// then maybe we shouldn't be doing this?
-						write(w, "sb $zero, %d($fp)", -declVar.fpOffset+i)
-					}
-				}
-			} else {
-				// Generate later in the .data section
-				declVar.label = &m.lastLabel
-				declVar.typ = ASTType{typ: VarTypeString, typName: ""}
-				m.VariableScopes[len(m.VariableScopes)-1][ident.ident] = declVar
-				write(w, "sw $v0, %d($fp)", -declVar.fpOffset+(i*4))
+	case isList && typ.IsStruct():
+		end := 0
+		for i, field := range typ.Struct.InitializedFields() {
+			if i >= len(list) {
+				break
 			}
-		default:
-			write(w, "sw $v0, %d($fp)", -declVar.fpOffset+(i*4))
+			space(field.Offset - end)
+			l.initializeGlobal(d, field.Type, list[i])
+			end = field.Offset + field.Type.Size()
 		}
-	}
-}
+		space(typ.Size() - end)
 
-func (t *ASTDecl) generateGlobalVarMIPS(w io.Writer, m *MIPS, ident *ASTIdentifier, declVar *Variable) {
-	write(w, ".data")
-	defer write(w, ".text")
-	write(w, "%s:", declVar.GlobalLabel())
+	case isList:
+		l.initializeGlobal(d, typ, list[0])
 
-	isArray := t.isArray()
+	case typ.IsArray() || typ.IsStruct():
+		l.fatalf(init, "initializer element is not constant")
 
-	if t.initVal == nil {
-		// Reserve space at the label, even if there is no initial value.
-		if isArray {
-			_, _, reserveArrayBytes := t.getArrayInfo(m)
-			for i := 0; i < reserveArrayBytes; i++ {
-				write(w, "    .byte 0")
-			}
-			return
+	default:
+		// Global initializers have to be constants
+		c, err := evaluateConstant(init)
+		if err != nil {
+			l.fatalf(init, "initializer element is not constant: %v", err)
 		}
-		switch t.typ.typ {
-		case VarTypeChar:
-			write(w, "  .byte 0")
-		case VarTypeDouble:
-			write(w, "  .word 0")
-			write(w, "  .word 0")
-		default:
-			write(w, "  .word 0")
+		if !c.typ.IsScalar() {
+			l.fatalf(init, "initializer element is not constant")
 		}
-		return
-	}
-
-	elements := []Node{t.initVal}
-	if initializerList, ok := t.initVal.(ASTInitializerList); isArray && ok {
-		// No longer generate t.initVal
-		elements = nil
-
-		_, totalElements, _ := t.getArrayInfo(m)
-		for i, entry := range initializerList {
-			if i >= totalElements {
-				// Not enough space in the array
-				break
-			}
-
-			if _, ok := entry.(ASTInitializerList); ok {
-				// TODO: handle nested entries
-				panic("entry is an init list which is not yet handled")
+		if c.base != nil {
+			// The address is filled in by the linker.
+			if !typ.IsPointer() {
+				l.fatalf(init, "initializer element is not computable at load time")
 			}
-
-			elements = append(elements, entry)
+			// The offset is signed, as an address can be before its base.
+			offset := int64(int32(c.intValue))
+			d.Items = append(d.Items, ir.DataItem{Kind: ir.DataAddress, Symbol: l.constantSymbol(c.base), Int: offset})
+			return
 		}
-	}
-
-	// Global initializers have to be constants
-	for _, element := range elements {
-		fmt.Fprintf(os.Stderr, "Got type %T\n", element)
-		assignmentExpr := element.(*ASTAssignment)
-		if _, ok := assignmentExpr.value.(*ASTStringLiteral); ok {
-			// TODO: handle this better (for char * array as there will be
-			// multiple strings to set labels for)
-			declVar.label = &m.lastLabel
-			declVar.typ = ASTType{typ: VarTypeString, typName: ""}
-			element.GenerateMIPS(w, m)
-			continue
+		val := c.convert(typ)
+		word := func(v int64) {
+			d.Items = append(d.Items, ir.DataItem{Kind: ir.DataWord, Int: v})
 		}
-
-		val := EvaluateConstExpr(element)
-		switch t.typ.typ {
-		case VarTypeChar:
-			emitGlobalChar(w, uint8(val))
-		case VarTypeDouble:
-			emitGlobalDouble(w, val)
-		case VarTypeFloat:
-			emitGlobalFloat(w, float32(val))
-		case VarTypeUnsigned:
-			emitGlobalUint32(w, uint32(val))
+		switch {
+		case isDouble(typ):
+			bits := math.Float64bits(val.floatValue)
+			word(int64(bits >> 32))
+			word(int64(bits & 0xFFFFFFFF))
+		case isFloat(typ):
+			word(int64(math.Float32bits(float32(val.floatValue))))
+		case typ.Size() == 1:
+			d.Items = append(d.Items, ir.DataItem{Kind: ir.DataBytes, Bytes: []byte{uint8(val.intValue)}})
+		case typ.Size() == 2:
+			d.Items = append(d.Items, ir.DataItem{Kind: ir.DataHalf, Int: int64(int16(val.intValue))})
 		default:
-			emitGlobalInt32(w, int32(val))
+			word(val.intValue)
 		}
 	}
 }
 
-// TODO: investigate at later date
-func (t *ASTDecl) GenerateMIPS(w io.Writer, m *MIPS) {
-	if t.decl == nil && t.typ != nil && (t.typ.typ == VarTypeEnum || t.typ.typ == VarTypeStruct) {
-		t.typ.GenerateMIPS(w, m)
-		return
+// constantSymbol returns the symbol for the base of an address constant.
+func (l *Lowerer) constantSymbol(base Node) string {
+	if str, ok := base.(*ASTStringLiteral); ok {
+		return l.stringData(str.data).Symbol
 	}
+	return string(base.(*ASTIdentifier).sym.GlobalLabel())
+}
 
-	if t.decl == nil {
+func (t *ASTDecl) lowerGlobal(l *Lowerer) {
+	if t.sym.definition != t {
+		// Only one declaration reserves any space.
 		return
 	}
-
-	ident := t.decl.Identifier()
-	if ident == nil {
-		// TODO: handle this case (mostly caused by function prototypes).
-		return
+	if t.storage == StorageClassStatic && !t.sym.internal {
+		// Static locals are given a unique label, as there may be more than
+		// one with the same name.
+		t.sym.label = l.CreateUniqueLabel("static_" + t.sym.Name)
+	}
+
+	typ := t.sym.Type
+	external := !t.sym.internal && t.sym.label == ""
+	d := &ir.Data{
+		Name:   string(t.sym.GlobalLabel()),
+		Global: external,
+		Size:   typ.Size(),
+		Align:  typ.Align(),
+	}
+	switch {
+	case t.initVal == nil && external:
+		// Tentative definitions are common symbols, so they can be merged
+		// with a definition in another translation unit.
+		d.Section = ir.SectionCommon
+	case t.initVal == nil:
+		d.Section = ir.SectionBSS
+	case typ.IsReadOnly():
+		d.Section = ir.SectionRodata
+	default:
+		d.Section = ir.SectionData
 	}
-
-	isGlobal := len(m.VariableScopes) == 1
-	vartype := t.typ
-	if t.typ.typ == VarTypeTypeName {
-		vartype = m.TypeDefScopes[len(m.TypeDefScopes)-1][t.typ.typName]
+	if t.initVal != nil {
+		l.initializeGlobal(d, typ, t.initVal)
 	}
+	l.module.Data = append(l.module.Data, d)
+}
 
-	declVar := &Variable{
-		decl:       t,
-		directDecl: t.decl,
-		typ:        *vartype,
-		label:      nil,
-		isGlobal:   isGlobal,
+func (t *ASTDecl) Lower(l *Lowerer) ir.Value {
+	if t.sym == nil || t.sym.Kind != SymbolVariable {
+		// Typedefs, function prototypes and declarations of structures or
+		// enumerations don't generate anything.
+		return nil
 	}
 
-	m.SetLastType(t.typ.typ)
-	m.VariableScopes[len(m.VariableScopes)-1][ident.ident] = declVar
-
-	extraPointerDepth := 0
-	if t.isArray() {
-		extraPointerDepth = 1
-	}
-	if isGlobal {
-		t.generateGlobalVarMIPS(w, m, ident, declVar)
-		m.pointerLevel = t.decl.pointerDepth + extraPointerDepth
-		return
+	if t.sym.global {
+		t.lowerGlobal(l)
+		return nil
 	}
-
-	m.pointerLevel = t.decl.pointerDepth + extraPointerDepth
-	t.generateLocalVarMIPS(w, m, ident, declVar)
-	m.pointerLevel = t.decl.pointerDepth + extraPointerDepth
+	t.lowerLocal(l)
+	return nil
 }
 
 type ASTConstant struct {
+	located
+	typed
+
 	value string
+
+	// intValue or floatValue is the value of the constant, depending on its
+	// type.
+	intValue   int64
+	floatValue float64
 }
 
 func (t *ASTConstant) Describe(indent int) string {
@@ -831,128 +745,21 @@ func (t *ASTConstant) Describe(indent int) string {
 	return fmt.Sprintf("%s%s", genIndent(indent), t.value)
 }
 
-func emitGlobalFloat(w io.Writer, val float32) {
-	write(w, "  .word %d", math.Float32bits(val))
-}
-
-func emitGlobalDouble(w io.Writer, val float64) {
-	bits := math.Float64bits(val)
-	write(w, "  .word %d", bits>>32)
-	write(w, "  .word %d", bits&0xFFFFFFFF)
-}
-
-func emitGlobalInt32(w io.Writer, val int32) {
-	write(w, "  .word %d", val)
-}
-
-func emitGlobalUint32(w io.Writer, val uint32) {
-	write(w, "  .word %d", val)
-}
-
-func emitGlobalChar(w io.Writer, val uint8) {
-	write(w, ".byte %d", val)
-}
-
-// TODO: investigate at later date
-func (t *ASTConstant) GenerateMIPS(w io.Writer, m *MIPS) {
-	if len(t.value) == 0 {
-		panic("empty ASTConstant")
-	}
-
-	isGlobal := len(m.VariableScopes) == 1
-
-	// TODO: fix this to support other types etc.
-
-	// TODO: currently doesnt detect chars declard with an int not a char literal
-	if t.value[0] == '\'' {
-		if t.value == `'\0'` {
-			// Handle special case as this is different
-			// in Go to C.
-			t.value = `'\000'`
-		}
-		unquotedString, err := strconv.Unquote(t.value)
-		if err != nil {
-			panic(fmt.Errorf("character literal unquote gave error: %v", err))
-		}
-		if isGlobal {
-			emitGlobalChar(w, uint8(unquotedString[0]))
-		} else {
-			write(w, "li $v0, %d", unquotedString[0])
-		}
-		m.SetLastType(VarTypeChar)
-		return
-	}
-
-	lastIdx := len(t.value) - 1
-
-	// Try to parse the constant as a float (or double) and load
-	// it into $f0.
-	if t.value[lastIdx] == 'f' || t.value[lastIdx] == 'F' {
-		// Appendix A, pg. 194 states that all numbers are doubles (or long doubles)
-		// unless suffixed with f or F, which implies they are floats.
-		f32, err := strconv.ParseFloat(t.value[:lastIdx], 32)
-		if err != nil {
-			panic("invalid floating point constant")
-		}
-		if isGlobal {
-			emitGlobalFloat(w, float32(f32))
-		} else {
-			write(w, "li.s $f0, %f", float32(f32))
-		}
-		m.SetLastType(VarTypeFloat)
-		return
-	}
-
-	if t.value[lastIdx] == 'u' || t.value[lastIdx] == 'U' {
-		// Appendix A, pg. 194 states that all numbers are doubles (or long doubles)
-		// unless suffixed with f or F, which implies they are floats.
-		uintValue, err := strconv.ParseUint(t.value[:lastIdx], 0, 32)
-		if err != nil {
-			panic("unable to convert unsinged to int")
-		}
-		if isGlobal {
-			emitGlobalUint32(w, uint32(uintValue))
-		} else {
-			write(w, "li $v0, %d", uintValue)
-		}
-		return
-	}
-
-	emittedGlobalInt := false
-	intValue, err := strconv.ParseInt(t.value, 0, 32)
-	if err == nil {
-		// Could be an integer or double (assume integer as all operations
-		// can be performed on this type; it will also be overwritten by
-		// ASTDecl/ASTIdentifier etc.)
-		if isGlobal {
-			if m.LastType() != VarTypeDouble {
-				emittedGlobalInt = true
-				emitGlobalInt32(w, int32(intValue))
-			}
-		} else {
-			write(w, "li $v0, %d", intValue)
-		}
-		m.SetLastType(VarTypeInteger)
-	} else {
-		// Not an int
-		m.SetLastType(VarTypeDouble)
-	}
-
-	f64, err := strconv.ParseFloat(t.value, 64)
-	if err != nil {
-		panic("ASTConstant expected double")
-	}
-	if isGlobal {
-		if !emittedGlobalInt {
-			emitGlobalDouble(w, f64)
-		}
-		return
+func (t *ASTConstant) Lower(l *Lowerer) ir.Value {
+	if t.Type().IsFloating() {
+		return ir.FloatConst(irType(t.Type()), t.floatValue)
 	}
-	write(w, "li.d $f0, %f", f64)
+	return ir.IntConst(t.intValue)
 }
 
 type ASTStringLiteral struct {
+	located
+	typed
+
 	value string
+
+	// data is the unquoted value of the string, without the terminating null.
+	data []byte
 }
 
 func (t *ASTStringLiteral) Describe(indent int) string {
@@ -962,29 +769,8 @@ func (t *ASTStringLiteral) Describe(indent int) string {
 	return fmt.Sprintf("%s%s", genIndent(indent), t.value)
 }
 
-// TODO: investigate at later date
-func (t *ASTStringLiteral) GenerateMIPS(w io.Writer, m *MIPS) {
-	//Get slice of escaped runes
-	unquotedString, err := strconv.Unquote(t.value)
-	if err != nil {
-		panic(fmt.Errorf("string Literal unquote gave error: %v", err))
-	}
-
-	stringlabel := m.CreateUniqueLabel("string")
-
-	m.lastLabel = stringlabel
-	m.stringMap[stringlabel] = []byte(unquotedString)
-
-	isGlobal := len(m.VariableScopes) == 1
-	if isGlobal {
-		writeGlobalString(w, stringlabel, []byte(unquotedString))
-	} else {
-		write(w, "lui $v0, %%hi(%s_data)", stringlabel)
-		write(w, "addiu $v0, $v0, %%lo(%s_data)", stringlabel)
-	}
-
-	m.SetLastType(VarTypeChar)
-	m.pointerLevel = 1
+func (t *ASTStringLiteral) Lower(l *Lowerer) ir.Value {
+	return l.stringData(t.data)
 }
 
 type ASTPanic struct{}
@@ -994,11 +780,18 @@ func (t ASTPanic) Describe(indent int) string {
 }
 
 // TODO: investigate at later date
-func (t ASTPanic) GenerateMIPS(w io.Writer, m *MIPS) {}
+func (t ASTPanic) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTType struct {
-	typ     VarType
-	typName string
+	located
+	typed
+
+	// specifiers are the keywords naming a basic type, such as `unsigned`
+	// and `long`, which are folded into kind by specifiedType.
+	specifiers []string
+	kind       TypeKind
+	typName    string
+	qualifiers Qualifiers
 
 	enum      *ASTEnum
 	structure *ASTStruct
@@ -1009,38 +802,47 @@ func (t *ASTType) Describe(indent int) string {
 		panic("ASTType is nil")
 	}
 
-	if t.typ == VarTypeEnum {
-		return t.enum.Describe(indent)
+	qualifiers := ""
+	if t.qualifiers != 0 {
+		qualifiers = t.qualifiers.String() + " "
 	}
 
-	if t.typ == VarTypeStruct {
-		return t.structure.Describe(indent)
+	if t.enum != nil {
+		return qualifiers + t.enum.Describe(indent)
 	}
 
-	if t.typ == VarTypeStruct || t.typ == VarTypeTypeName {
-		return string(t.typName)
+	if t.structure != nil {
+		return qualifiers + t.structure.Describe(indent)
 	}
 
-	return string(t.typ)
+	if t.typName != "" {
+		return qualifiers + t.typName
+	}
+
+	return qualifiers + strings.Join(t.specifiers, " ")
 }
 
-// TODO: investigate at later date
-func (t *ASTType) GenerateMIPS(w io.Writer, m *MIPS) {
-	switch t.typ {
-	case VarTypeEnum:
-		m.SetLastType(VarTypeUnsigned)
-		// TODO: we might have some problems with struct parameters?
-		t.enum.GenerateMIPS(w, m)
-	case VarTypeStruct:
-		m.SetLastType(VarTypeStruct)
-		// TODO: not sure what to set last type to
-		// TODO: we might have some problems with struct parameters?
-		t.structure.GenerateMIPS(w, m)
-	default:
-		m.SetLastType(t.typ)
+func (t *ASTType) Lower(l *Lowerer) ir.Value { return nil }
+
+// ASTTypeName is a type written in an expression, such as the operand of
+// sizeof.
+type ASTTypeName struct {
+	located
+
+	typ *ASTType
+	// decl is nil if there isn't an abstract declarator.
+	decl *ASTDirectDeclarator
+}
+
+func (t *ASTTypeName) Describe(indent int) string {
+	if t.decl == nil {
+		return t.typ.Describe(indent)
 	}
+	return t.typ.Describe(indent) + " " + strings.Repeat("*", t.decl.pointerDepth)
 }
 
+func (t *ASTTypeName) Lower(l *Lowerer) ir.Value { return nil }
+
 type ASTParameterList struct {
 	li      []*ASTParameterDeclaration
 	elipsis bool
@@ -1057,14 +859,11 @@ func (t ASTParameterList) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTParameterList) GenerateMIPS(w io.Writer, m *MIPS) {
-	// TODO: this function is incomplete
-	// for _, decl := range t.li {
-	// 	decl.GenerateMIPS(w, m)
-	// }
-}
+func (t ASTParameterList) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTParameterDeclaration struct {
+	located
+
 	specifier  Node
 	declarator Node
 }
@@ -1079,16 +878,18 @@ func (t ASTParameterDeclaration) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTParameterDeclaration) GenerateMIPS(w io.Writer, m *MIPS) {
-	// TODO: this function is incomplete
-	//t.declarator.GenerateMIPS(w, m)
-}
+func (t *ASTParameterDeclaration) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTDirectDeclarator struct {
+	located
+
 	identifier *ASTIdentifier
 	decl       *ASTDirectDeclarator
 
 	pointerDepth int
+	// pointerQualifiers holds the qualifiers of each level of pointer, from
+	// the one nearest the base type outwards, so `*const *p` is {const, 0}.
+	pointerQualifiers []Qualifiers
 
 	// parameters is nil if it's not a function, else
 	// it has zero or more parameters.
@@ -1098,16 +899,6 @@ type ASTDirectDeclarator struct {
 	array *ASTArray
 }
 
-func (t ASTDirectDeclarator) ArrayDimensions() []int {
-	if t.array == nil || t.decl == nil {
-		return nil
-	}
-
-	dimensions := t.decl.ArrayDimensions()
-	dimensions = append(dimensions, t.array.size)
-	return dimensions
-}
-
 func (t ASTDirectDeclarator) Identifier() *ASTIdentifier {
 	root := &t
 	for root != nil {
@@ -1119,15 +910,26 @@ func (t ASTDirectDeclarator) Identifier() *ASTIdentifier {
 	return nil
 }
 
+// pointerDeclarator applies depth levels of pointer to the declarator d. The
+// pointers of the outermost node of a declarator are applied before its array
+// or parameters, so a declarator which already has its own pointers, such as
+// the `(*p)` of `*(*p)`, is wrapped in a new node.
+func pointerDeclarator(loc located, depth int, qualifiers []Qualifiers, d *ASTDirectDeclarator) *ASTDirectDeclarator {
+	if d.pointerDepth == 0 {
+		d.pointerDepth = depth
+		d.pointerQualifiers = qualifiers
+		return d
+	}
+	return &ASTDirectDeclarator{located: loc, decl: d, pointerDepth: depth, pointerQualifiers: qualifiers}
+}
+
 func (t ASTDirectDeclarator) Describe(indent int) string {
 	var sb strings.Builder
 
 	if t.decl != nil {
 		sb.WriteString(t.decl.Describe(0))
 		if t.array != nil {
-			sb.WriteString("[")
-			sb.WriteString(fmt.Sprintf("%d", t.array.size))
-			sb.WriteString("]")
+			sb.WriteString(t.array.Describe(0))
 		}
 		if t.parameters != nil {
 			sb.WriteString("(")
@@ -1135,30 +937,18 @@ func (t ASTDirectDeclarator) Describe(indent int) string {
 			sb.WriteString(")")
 		}
 	} else if t.array != nil {
-		sb.WriteString("[")
-		sb.WriteString(fmt.Sprintf("%d", t.array.size))
-		sb.WriteString("]")
+		sb.WriteString(t.array.Describe(0))
 	} else {
 		sb.WriteString(t.identifier.Describe(0))
 	}
 	return sb.String()
 }
 
-func (t ASTDirectDeclarator) GenerateMIPS(w io.Writer, m *MIPS) {
-	// TODO: fix for other fields
-
-	// TODO: remove the function name from the variable scope
-	// ident, ok :=
-	// if ok {
-	// 	m.VariableScopes[len(m.VariableScopes)-1][t.identifier] =
-	// }
-
-	// if t.parameters != nil {
-	// 	t.parameters.GenerateMIPS(w, m)
-	// }
-}
+func (t ASTDirectDeclarator) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTScope struct {
+	located
+
 	body Node
 }
 
@@ -1169,25 +959,23 @@ func (t *ASTScope) Describe(indent int) string {
 	return t.body.Describe(indent)
 }
 
-func (t *ASTScope) GenerateMIPS(w io.Writer, m *MIPS) {
+func (t *ASTScope) Lower(l *Lowerer) ir.Value {
 	if t.body == nil {
-		return
+		return nil
 	}
-	m.NewVariableScope()
-	m.NewStructScope()
-	m.NewTypeDefScope()
-	t.body.GenerateMIPS(w, m)
-	m.VariableScopes.Pop()
-	m.StructScopes.Pop()
+	return t.body.Lower(l)
 }
 
 type ASTEnum struct {
+	located
+
 	ident   *ASTIdentifier
 	entries ASTEnumEntryList
 }
 
-func NewASTEnum(ident *ASTIdentifier, entries ASTEnumEntryList) *ASTEnum {
+func NewASTEnum(loc located, ident *ASTIdentifier, entries ASTEnumEntryList) *ASTEnum {
 	enum := &ASTEnum{
+		located: loc,
 		ident:   ident,
 		entries: entries,
 	}
@@ -1237,15 +1025,7 @@ func (t *ASTEnum) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTEnum) GenerateMIPS(w io.Writer, m *MIPS) {
-	for _, entry := range t.entries {
-		variableEntry := entry
-		m.VariableScopes[len(m.VariableScopes)-1][entry.ident.ident] = &Variable{
-			typ:  ASTType{typ: VarTypeEnum},
-			enum: variableEntry,
-		}
-	}
-}
+func (t *ASTEnum) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTEnumEntryList []*ASTEnumEntry
 
@@ -1253,14 +1033,17 @@ func (t ASTEnumEntryList) Describe(indent int) string {
 	return ""
 }
 
-func (t ASTEnumEntryList) GenerateMIPS(w io.Writer, m *MIPS) {
-	return
-}
+func (t ASTEnumEntryList) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTEnumEntry struct {
+	located
+
 	ident  *ASTIdentifier
 	value  Node
 	offset int
+
+	// intValue is the value of the constant, worked out by the checker.
+	intValue int64
 }
 
 func (t ASTEnumEntry) Describe(indent int) string {
@@ -1278,125 +1061,53 @@ func (t ASTEnumEntry) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTEnumEntry) GenerateMIPS(w io.Writer, m *MIPS) {}
+func (t ASTEnumEntry) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTStruct struct {
-	ident    *ASTIdentifier
+	located
+
+	// union is true if the members all share the same storage.
+	union bool
+	// ident is nil for an anonymous structure.
+	ident *ASTIdentifier
+	// elements is nil if this only refers to a structure by its tag.
 	elements ASTStructDeclarationList
+}
+
+// Name returns the tag of the structure, or "" if it is anonymous.
+func (t *ASTStruct) Name() string {
+	if t.ident == nil {
+		return ""
+	}
+	return t.ident.ident
+}
 
-	init bool
+// keyword returns "union" or "struct".
+func (t *ASTStruct) keyword() string {
+	if t.union {
+		return "union"
+	}
+	return "struct"
 }
 
 func (t *ASTStruct) Describe(indent int) string {
 	var sb strings.Builder
 	sindent := genIndent(indent)
-	sb.WriteString(fmt.Sprintf("%sstruct %s {\n", sindent, t.ident.ident))
+	if t.ident == nil {
+		sb.WriteString(fmt.Sprintf("%s%s {\n", sindent, t.keyword()))
+	} else {
+		sb.WriteString(fmt.Sprintf("%s%s %s {\n", sindent, t.keyword(), t.ident.ident))
+	}
 	sb.WriteString(t.elements.Describe(indent))
 	sb.WriteString(fmt.Sprintf("%s}", sindent))
 	return sb.String()
 }
 
-type StructEntry struct {
-	prefix string
-	level  int
-	decl   *ASTDecl
-}
-
-func getFlatStructEntries(m *MIPS, entries ASTStructDeclarationList, level int, prefix string) []*StructEntry {
-	flatEntries := []*StructEntry{}
-	for _, outer := range entries {
-		for _, entry := range outer {
-			if entry.decl.typ.typ == VarTypeStruct {
-				levelPrefix := entry.decl.decl.identifier.ident
-				if prefix != "" {
-					levelPrefix = prefix + "." + levelPrefix
-				}
-				innerTypes := getFlatStructEntries(m, m.StructScopes[len(m.StructScopes)-1][entry.decl.typ.structure.ident.ident].astStruct.elements, level+1, levelPrefix)
-				flatEntries = append(flatEntries, innerTypes...)
-			} else {
-				flatEntries = append(flatEntries, &StructEntry{level: level, decl: entry.decl, prefix: prefix})
-			}
-		}
-	}
-	return flatEntries
-}
-
-func (t *ASTStruct) GenerateMIPS(w io.Writer, m *MIPS) {
-	if t.init {
-		return
-	}
-
-	t.init = true
-
-	structEntry := Struct{astStruct: t, ident: t.ident.ident, offsets: make(map[int]int), types: make(map[int]ASTType), elementIdents: make(map[string]int)}
-
-	previousType := VarTypeInvalid
-
-	var structSize = 0
-	var totalOffsetSize = 0
-
-	containsDouble := false
-
-	entries := getFlatStructEntries(m, t.elements, 0, "")
-
-	structEntry.FlatStructEntries = entries
-
-	for i, structElement := range entries {
-		name := structElement.decl.decl.identifier.ident
-		if structElement.prefix != "" {
-			name = structElement.prefix + "." + name
-		}
-
-		element := structElement.decl
-		structEntry.offsets[i] = totalOffsetSize
-		structEntry.types[i] = *element.typ
-		structEntry.elementIdents[name] = i
-		totalOffsetSize += 8
-
-		if previousType == VarTypeChar && element.typ.typ != VarTypeChar {
-			// Add padding
-			padding := structSize % 4
-			if padding != 0 {
-				structSize += 4 - (padding)
-			}
-		}
-
-		switch element.typ.typ {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeFloat:
-			structSize += 4
-			previousType = VarTypeInteger
-		case VarTypeChar:
-			structSize += 1
-			previousType = VarTypeChar
-		case VarTypeDouble:
-			containsDouble = true
-			structSize += 8
-			previousType = VarTypeDouble
-		case VarTypeStruct:
-			structSize += m.StructScopes[len(m.StructScopes)-1][element.typ.structure.ident.ident].structSize
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-	}
-
-	if containsDouble {
-		padding := structSize % 8
-		if padding != 0 {
-			structSize += 8 - (padding)
-		}
-	} else {
-		padding := structSize % 4
-		if padding != 0 {
-			structSize += 4 - (padding)
-		}
-	}
-
-	structEntry.totalOffsetSize = totalOffsetSize
-	structEntry.structSize = structSize
-	m.StructScopes[len(m.StructScopes)-1][t.ident.ident] = &structEntry
-}
+func (t *ASTStruct) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTStructDeclarator struct {
+	located
+
 	decl *ASTDecl
 }
 
@@ -1406,7 +1117,7 @@ func (t ASTStructDeclarator) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTStructDeclarator) GenerateMIPS(w io.Writer, m *MIPS) {}
+func (t ASTStructDeclarator) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTStructDeclaratorList []ASTStructDeclarator
 
@@ -1421,7 +1132,7 @@ func (t ASTStructDeclaratorList) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTStructDeclaratorList) GenerateMIPS(w io.Writer, m *MIPS) {}
+func (t ASTStructDeclaratorList) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTStructDeclarationList []ASTStructDeclaratorList
 
@@ -1438,31 +1149,18 @@ func (t ASTStructDeclarationList) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t ASTStructDeclarationList) GenerateMIPS(w io.Writer, m *MIPS) {}
-
-type ASTStructInitilizerList []*ASTAssignment
-
-func (t ASTStructInitilizerList) Describe(indent int) string {
-	var sb strings.Builder
-
-	for i, assignment := range t {
-		sb.WriteString(assignment.Describe(indent))
-		if i != len(t)-1 {
-			sb.WriteString(", ")
-		}
-
-	}
-
-	return sb.String()
-}
-
-func (t ASTStructInitilizerList) GenerateMIPS(w io.Writer, m *MIPS) {}
+func (t ASTStructDeclarationList) Lower(l *Lowerer) ir.Value { return nil }
 
 type ASTStructElement struct {
+	located
+	typed
+
 	structImp Node
 	ident     string
 
 	pointer bool
+
+	field *StructField
 }
 
 func (t ASTStructElement) Describe(indent int) string {
@@ -1472,91 +1170,10 @@ func (t ASTStructElement) Describe(indent int) string {
 	return fmt.Sprintf("%s.%s", t.structImp.Describe(0), t.ident)
 }
 
-func (t ASTStructElement) GenerateMIPS(w io.Writer, m *MIPS) {
-	elementName := t.ident
-	var topName string
-	if _, ok := t.structImp.(*ASTStructElement); ok {
-		t.structImp.GenerateMIPS(w, m)
-		m.StructElementName = m.StructElementName + "." + t.ident
-		elementName = m.StructElementName
-		topName = m.TopStruct
-	} else {
-		m.TopStruct = t.structImp.(*ASTIdentifier).ident
-		m.StructElementName = t.ident
-		topName = t.structImp.(*ASTIdentifier).ident
-	}
-
-	structVar := *m.VariableScopes[len(m.VariableScopes)-1][topName]
-	elementIndent := structVar.structure.elementIdents[elementName]
-	elementOffset := structVar.structure.offsets[elementIndent]
-
-	if t.pointer {
-		write(w, "addiu $v1, $fp, %d", -structVar.fpOffset)
-		write(w, "lw $v1, 0($v1)")
-		write(w, "addiu $v1, $v1, %d", elementOffset)
-		m.pointerLevel -= 1
-	} else {
-
-		var globalLabel Label
-		if structVar.isGlobal {
-			globalLabel = structVar.GlobalLabel()
-
-			// Load the address of the global into $v1
-			write(w, "lui $v1, %%hi(%s)", globalLabel)
-			write(w, "addiu $v1, $v1, %%lo(%s)", globalLabel)
-		} else {
-			// Put the address of the local into $v1
-			write(w, "addiu $v1, $fp, %d", -structVar.fpOffset+elementOffset)
-		}
-	}
-	switch structVar.structure.types[elementIndent].typ {
-	case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-		write(w, "lw $v0, 0($v1)")
-	case VarTypeChar:
-		write(w, "lb $v0, 0($v1)")
-	case VarTypeFloat:
-		write(w, "lwc1 $f0, 0($v1)")
-	case VarTypeDouble:
-		write(w, "lwc1 $f0, 4($v1)")
-		write(w, "lwc1 $f1, 0($v1)")
-	default:
-		panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-	}
-	m.SetLastType(structVar.structure.types[elementIndent].typ)
-
-}
-
-type ASTTypeDef struct {
-	typeName string
-	typ      *ASTType
-	decl     *ASTDirectDeclarator
-}
-
-func (t ASTTypeDef) Describe(indent int) string {
-	currentIndent := genIndent(indent)
-	return fmt.Sprintf("%stypedef %s : %s", currentIndent, t.typeName, t.typ.typ)
-}
-
-func (t ASTTypeDef) GenerateMIPS(w io.Writer, m *MIPS) {
-	m.TypeDefScopes[len(m.TypeDefScopes)-1][t.typeName] = t.typ
+func (t ASTStructElement) Lower(l *Lowerer) ir.Value {
+	return l.load(t.Type(), l.memberAddress(&t))
 }
 
 func genIndent(indent int) string {
 	return strings.Repeat(" ", indent)
 }
-
-func writeGlobalString(w io.Writer, label Label, value []byte) {
-	var sb strings.Builder
-	sb.WriteString("\"")
-	//for each rune convert them into hex and add \x before hand then add that to the string
-	for _, r := range value {
-		sb.WriteString(
-			fmt.Sprintf("\\x%02x", r),
-		)
-	}
-	sb.WriteString("\\000\"")
-	write(w, "%s_data:", label)
-	write(w, ".asciz %s", sb.String())
-	write(w, "%s:", label)
-	write(w, ".word %s_data", label)
-}
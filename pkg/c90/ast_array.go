@@ -1,36 +1,18 @@
 package c90
 
-import "github.com/Knetic/govaluate"
-
+// ASTArray is the size of an array declarator. The size is worked out by the
+// checker, as it may use enumeration constants and sizeof.
 type ASTArray struct {
 	sizeConstExpr Node
-	size          int
 }
 
 func NewASTArray(sizeConstExpr Node) *ASTArray {
-	if sizeConstExpr == nil {
-		return &ASTArray{size: 0}
-	}
-
-	res := EvaluateConstExpr(sizeConstExpr)
-	size := int(res)
-	if size < 0 {
-		panic("negative array size")
-	}
-
-	return &ASTArray{size: size, sizeConstExpr: sizeConstExpr}
+	return &ASTArray{sizeConstExpr: sizeConstExpr}
 }
 
-func EvaluateConstExpr(constExpr Node) float64 {
-	expr, err := govaluate.NewEvaluableExpression(constExpr.Describe(0))
-	if err != nil {
-		panic(err)
-	}
-
-	res, err := expr.Evaluate(nil)
-	if err != nil {
-		panic(err)
+func (t *ASTArray) Describe(indent int) string {
+	if t.sizeConstExpr == nil {
+		return "[]"
 	}
-
-	return res.(float64)
+	return "[" + t.sizeConstExpr.Describe(0) + "]"
 }
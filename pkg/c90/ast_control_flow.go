@@ -1,39 +1,67 @@
 package c90
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"strings"
-)
 
-func checkFloatOrDoubleCondition(w io.Writer, m *MIPS) {
-	falseLabel := m.CreateUniqueLabel("f0_eq0")
-	finalLabel := m.CreateUniqueLabel("logical_final")
+	"github.com/jpnock/see90/pkg/ir"
+)
 
-	switch m.LastType() {
-	case VarTypeFloat:
-		write(w, "li.s $f10, 0")
-		write(w, "c.eq.s $f10, $f0")
-	case VarTypeDouble:
-		write(w, "li.d $f10, 0")
-		write(w, "c.eq.d $f10, $f0")
-	default:
-		return
+// condition lowers the controlling expression n, branching to ifTrue if it's
+// non-zero and to ifFalse otherwise. The logical operators branch straight to
+// the targets, rather than working out the value of the condition.
+func (l *Lowerer) condition(n Node, ifTrue, ifFalse *ir.Block) {
+	switch t := unwrapExpr(n).(type) {
+	case *ASTExprBinary:
+		switch t.typ {
+		case ASTExprBinaryTypeLogicalAnd:
+			rhs := l.fn.NewBlock("and.rhs")
+			l.condition(t.lhs, rhs, ifFalse)
+			l.b.SetBlock(rhs)
+			l.condition(t.rhs, ifTrue, ifFalse)
+			return
+		case ASTExprBinaryTypeLogicalOr:
+			rhs := l.fn.NewBlock("or.rhs")
+			l.condition(t.lhs, ifTrue, rhs)
+			l.b.SetBlock(rhs)
+			l.condition(t.rhs, ifTrue, ifFalse)
+			return
+		}
+	case *ASTExprPrefixUnary:
+		if t.typ == ASTExprPrefixUnaryTypeInvert {
+			l.condition(t.lvalue, ifFalse, ifTrue)
+			return
+		}
 	}
 
-	write(w, "bc1t %s", falseLabel)
-
-	write(w, "addiu $v0, $zero, 1")
-	write(w, "j %s", finalLabel)
-
-	write(w, "%s:", falseLabel)
-	write(w, "addiu $v0, $zero, 0")
+	typ := typeOf(n).Decay()
+	v := n.Lower(l)
+	if typ.IsFloating() {
+		v = l.b.Binary(ir.OpNe, v.Type(), false, v, zero(typ))
+	}
+	l.b.Branch(v, ifTrue, ifFalse)
+}
 
-	write(w, "%s:", finalLabel)
+// conditionValue returns 1 if the condition n holds, and 0 otherwise.
+func (l *Lowerer) conditionValue(n Node) ir.Value {
+	result := l.fn.NewReg(ir.I32)
+	ifTrue := l.fn.NewBlock("cond.true")
+	ifFalse := l.fn.NewBlock("cond.false")
+	end := l.fn.NewBlock("cond.end")
+
+	l.condition(n, ifTrue, ifFalse)
+	l.b.SetBlock(ifTrue)
+	l.b.CopyTo(result, ir.IntConst(1))
+	l.b.Jump(end)
+	l.b.SetBlock(ifFalse)
+	l.b.CopyTo(result, ir.IntConst(0))
+	l.b.StartBlock(end)
+	return result
 }
 
 type ASTWhileLoop struct {
+	located
+
 	condition Node
 	body      Node
 }
@@ -55,45 +83,30 @@ func (t *ASTWhileLoop) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTWhileLoop) GenerateMIPS(w io.Writer, m *MIPS) {
-	conditionLabel := m.CreateUniqueLabel("while_condition")
-	bottomLabel := m.CreateUniqueLabel("while_bottom")
-
-	// Create a new variable scope
-	m.NewVariableScope()
-	m.NewStructScope()
-	m.NewTypeDefScope()
-	defer m.VariableScopes.Pop()
-	defer m.StructScopes.Pop()
-	defer m.TypeDefScopes.Pop()
-
-	// Create a new label scope
-	m.NewLabelScope(LabelScope{
-		ContinueLabel: &conditionLabel,
-		BreakLabel:    &bottomLabel,
-	})
-	defer m.LabelScopes.Pop()
-	defer m.StructScopes.Pop()
-	defer m.TypeDefScopes.Pop()
-
-	// Condition
-	write(w, "%s:", conditionLabel)
-	t.condition.GenerateMIPS(w, m)
-	checkFloatOrDoubleCondition(w, m)
-	write(w, "beq $zero, $v0, %s", bottomLabel)
-
-	// Body
+func (t *ASTWhileLoop) Lower(l *Lowerer) ir.Value {
+	condition := l.fn.NewBlock("while.cond")
+	body := l.fn.NewBlock("while.body")
+	end := l.fn.NewBlock("while.end")
+
+	l.b.StartBlock(condition)
+	l.condition(t.condition, body, end)
+
+	l.b.SetBlock(body)
 	if t.body != nil {
-		t.body.GenerateMIPS(w, m)
+		l.pushLoop(end, condition)
+		t.body.Lower(l)
+		l.popLoop()
 	}
-
-	write(w, "j %s", conditionLabel)
+	l.b.Jump(condition)
 
 	// Break to here
-	write(w, "%s:", bottomLabel)
+	l.b.SetBlock(end)
+	return nil
 }
 
 type ASTDoWhileLoop struct {
+	located
+
 	condition Node
 	body      Node
 }
@@ -113,46 +126,29 @@ func (t *ASTDoWhileLoop) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTDoWhileLoop) GenerateMIPS(w io.Writer, m *MIPS) {
-	bodyLabel := m.CreateUniqueLabel("do_while_body")
-	conditionLabel := m.CreateUniqueLabel("do_while_condition")
-	bottomLabel := m.CreateUniqueLabel("do_while_bottom")
-
-	// Create a new variable scope
-	m.NewVariableScope()
-	m.NewStructScope()
-	m.NewTypeDefScope()
-	defer m.VariableScopes.Pop()
-	defer m.StructScopes.Pop()
-	defer m.TypeDefScopes.Pop()
-
-	// Create a new label scope
-	m.NewLabelScope(LabelScope{
-		ContinueLabel: &conditionLabel,
-		BreakLabel:    &bottomLabel,
-	})
-	defer m.LabelScopes.Pop()
-
-	// Body
-	write(w, "%s:", bodyLabel)
+func (t *ASTDoWhileLoop) Lower(l *Lowerer) ir.Value {
+	body := l.fn.NewBlock("do.body")
+	condition := l.fn.NewBlock("do.cond")
+	end := l.fn.NewBlock("do.end")
+
+	l.b.StartBlock(body)
 	if t.body != nil {
-		t.body.GenerateMIPS(w, m)
+		l.pushLoop(end, condition)
+		t.body.Lower(l)
+		l.popLoop()
 	}
 
-	// Condition
-	write(w, "%s:", conditionLabel)
-	t.condition.GenerateMIPS(w, m)
-
-	checkFloatOrDoubleCondition(w, m)
-	write(w, "beq $zero, $v0, %s", bottomLabel)
-
-	write(w, "j %s", bodyLabel)
+	l.b.StartBlock(condition)
+	l.condition(t.condition, body, end)
 
 	// Break to here
-	write(w, "%s:", bottomLabel)
+	l.b.SetBlock(end)
+	return nil
 }
 
 type ASTForLoop struct {
+	located
+
 	initialiser       Node
 	condition         Node
 	postIterationExpr Node
@@ -173,10 +169,15 @@ func (t *ASTForLoop) Describe(indent int) string {
 		postIterationExpr = t.postIterationExpr.Describe(0)
 	}
 
+	condition := ""
+	if t.condition != nil {
+		condition = t.condition.Describe(0)
+	}
+
 	if t.initialiser != nil {
-		sb.WriteString(fmt.Sprintf("%sfor(%s; %s; %s) {", indentStr, t.initialiser.Describe(0), t.condition.Describe(0), postIterationExpr))
+		sb.WriteString(fmt.Sprintf("%sfor(%s; %s; %s) {", indentStr, t.initialiser.Describe(0), condition, postIterationExpr))
 	} else {
-		sb.WriteString(fmt.Sprintf("%sfor( ; %s; %s) {", indentStr, t.condition.Describe(0), postIterationExpr))
+		sb.WriteString(fmt.Sprintf("%sfor( ; %s; %s) {", indentStr, condition, postIterationExpr))
 	}
 	if t.body != nil {
 		sb.WriteString("\n")
@@ -189,60 +190,46 @@ func (t *ASTForLoop) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTForLoop) GenerateMIPS(w io.Writer, m *MIPS) {
-	conditionLabel := m.CreateUniqueLabel("for_condition")
-	bodyLabel := m.CreateUniqueLabel("for_body")
-	bottomLabel := m.CreateUniqueLabel("for_bottom")
-	postIterExprLabel := m.CreateUniqueLabel("for_post_iter_expr")
-
-	// Create a new variable scope
-	m.NewVariableScope()
-	m.NewStructScope()
-	m.NewTypeDefScope()
-	defer m.VariableScopes.Pop()
-	defer m.StructScopes.Pop()
-	defer m.TypeDefScopes.Pop()
-
-	// Create a new label scope
-	m.NewLabelScope(LabelScope{
-		ContinueLabel: &postIterExprLabel,
-		BreakLabel:    &bottomLabel,
-	})
-	defer m.LabelScopes.Pop()
-
-	// Init
+func (t *ASTForLoop) Lower(l *Lowerer) ir.Value {
+	condition := l.fn.NewBlock("for.cond")
+	body := l.fn.NewBlock("for.body")
+	post := l.fn.NewBlock("for.post")
+	end := l.fn.NewBlock("for.end")
+
 	if t.initialiser != nil {
-		t.initialiser.GenerateMIPS(w, m)
+		t.initialiser.Lower(l)
 	}
-	write(w, "j %s", conditionLabel)
 
-	/// Post Iter Expression (continue from here)
-	write(w, "%s:", postIterExprLabel)
-	if t.postIterationExpr != nil {
-		t.postIterationExpr.GenerateMIPS(w, m)
+	l.b.StartBlock(condition)
+	if t.condition != nil {
+		l.condition(t.condition, body, end)
 	}
 
-	// Condition
-	write(w, "%s:", conditionLabel)
-	t.condition.GenerateMIPS(w, m)
-	checkFloatOrDoubleCondition(w, m)
-	write(w, "beq $zero, $v0, %s", bottomLabel)
-
-	// Body
-	write(w, "%s:", bodyLabel)
+	l.b.StartBlock(body)
 	if t.body != nil {
-		t.body.GenerateMIPS(w, m)
+		// Continue from the post iteration expression.
+		l.pushLoop(end, post)
+		t.body.Lower(l)
+		l.popLoop()
 	}
 
-	write(w, "j %s", postIterExprLabel)
+	l.b.StartBlock(post)
+	if t.postIterationExpr != nil {
+		t.postIterationExpr.Lower(l)
+	}
+	l.b.Jump(condition)
 
 	// Break to here
-	write(w, "%s:", bottomLabel)
+	l.b.SetBlock(end)
+	return nil
 }
 
-// ASTIfStatement also works for ternary statements, as long as we keep to the
-// convention that the last result is always put into v0.
+// ASTIfStatement also works for ternary statements, whose value is put into
+// the same register by both branches.
 type ASTIfStatement struct {
+	located
+	typed
+
 	condition Node
 	body      Node
 	elseBody  Node
@@ -275,41 +262,55 @@ func (t *ASTIfStatement) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTIfStatement) GenerateMIPS(w io.Writer, m *MIPS) {
-	failureLabel := m.CreateUniqueLabel("condition_fail")
-	finalLabel := m.CreateUniqueLabel("condition_final")
-
-	m.NewVariableScope()
-	m.NewStructScope()
-	m.NewTypeDefScope()
-	defer m.VariableScopes.Pop()
-	defer m.StructScopes.Pop()
-	defer m.TypeDefScopes.Pop()
+func (t *ASTIfStatement) Lower(l *Lowerer) ir.Value {
+	then := l.fn.NewBlock("if.then")
+	end := l.fn.NewBlock("if.end")
+	otherwise := end
+	if t.elseBody != nil {
+		otherwise = l.fn.NewBlock("if.else")
+	}
 
-	// Condition
-	t.condition.GenerateMIPS(w, m)
-	checkFloatOrDoubleCondition(w, m)
+	var result *ir.Reg
+	if t.ternary && !t.Type().IsVoid() {
+		result = l.fn.NewReg(irType(t.Type().Decay()))
+	}
+	branch := func(body Node) {
+		if body == nil {
+			return
+		}
+		if result == nil {
+			body.Lower(l)
+			return
+		}
+		l.b.CopyTo(result, l.value(body, t.Type()))
+	}
 
-	write(w, "beq $zero, $v0, %s", failureLabel)
+	l.condition(t.condition, then, otherwise)
 
 	// After body, jump to end (to ignore the else clause)
-	if t.body != nil {
-		t.body.GenerateMIPS(w, m)
-	}
-	write(w, "j %s", finalLabel)
+	l.b.SetBlock(then)
+	branch(t.body)
+	l.b.Jump(end)
 
-	// Else...
-	write(w, "%s:", failureLabel)
 	if t.elseBody != nil {
-		t.elseBody.GenerateMIPS(w, m)
+		l.b.SetBlock(otherwise)
+		branch(t.elseBody)
+		l.b.Jump(end)
 	}
 
-	write(w, "%s:", finalLabel)
+	l.b.SetBlock(end)
+	if result == nil {
+		return nil
+	}
+	return result
 }
 
 type ASTSwitchCase struct {
-	// caseVal is a constexpr
+	located
+
+	// caseVal is a constexpr, whose value is worked out by the checker.
 	caseVal     Node
+	value       constant
 	body        Node
 	defaultCase bool
 }
@@ -335,22 +336,27 @@ func (t *ASTSwitchCase) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTSwitchCase) GenerateMIPS(w io.Writer, m *MIPS) {
-	// Put label onto label scope stack
-	val := &CaseLabel{
-		switchCase: t,
-		label:      m.CreateUniqueLabel("switch_case"),
+func (t *ASTSwitchCase) Lower(l *Lowerer) ir.Value {
+	targets := l.switches[len(l.switches)-1]
+	if t.defaultCase {
+		targets.defaultCase = l.fn.NewBlock("switch.default")
+		l.b.StartBlock(targets.defaultCase)
+	} else {
+		block := l.fn.NewBlock("switch.case")
+		targets.cases = append(targets.cases, t)
+		targets.blocks = append(targets.blocks, block)
+		l.b.StartBlock(block)
 	}
-	idx := len(m.CaseLabelScopes) - 1
-	m.CaseLabelScopes[idx].SwitchCase = append(m.CaseLabelScopes[idx].SwitchCase, val)
 
-	write(w, "%s:", val.label)
 	if t.body != nil {
-		t.body.GenerateMIPS(w, m)
+		t.body.Lower(l)
 	}
+	return nil
 }
 
 type ASTSwitchStatement struct {
+	located
+
 	switchOn Node
 	body     Node
 }
@@ -374,49 +380,53 @@ func (t *ASTSwitchStatement) Describe(indent int) string {
 	return sb.String()
 }
 
-func (t *ASTSwitchStatement) GenerateMIPS(w io.Writer, m *MIPS) {
-	bottomLabel := m.NewSwitchStatement()
-	defer m.EndSwitchStatement()
-
-	// Store the body for later. We need to execute GenerateMIPS in order to
-	// explore the case labels and push them to the CaseLabelStack.
-	bodyBuf := new(bytes.Buffer)
-	t.body.GenerateMIPS(bodyBuf, m)
-
-	// Put value to switch on into $t2
-	t.switchOn.GenerateMIPS(w, m)
-	write(w, "addu $t2, $zero, $v0")
-
-	var defaultCase *CaseLabel
-	switchCases := m.CaseLabelScopes.Peek().SwitchCase
-	for _, c := range switchCases {
-		if c.switchCase.defaultCase {
-			// Find the default case for later.
-			defaultCase = c
-			continue
-		}
-
-		// Get condition into $v0
-		c.switchCase.caseVal.GenerateMIPS(w, m)
-
-		// Jump to the label if the condition matches
-		write(w, "beq $v0, $t2, %s", c.label)
-	}
-
-	if defaultCase != nil {
+func (t *ASTSwitchStatement) Lower(l *Lowerer) ir.Value {
+	typ := promote(typeOf(t.switchOn).Decay())
+	value := l.value(t.switchOn, typ)
+
+	// The case labels are only known once the body has been lowered, so the
+	// comparisons are put in a block after it.
+	dispatch := l.fn.NewBlock("switch.dispatch")
+	end := l.fn.NewBlock("switch.end")
+	l.b.Jump(dispatch)
+
+	targets := &switchTargets{}
+	l.switches = append(l.switches, targets)
+	l.breakTargets = append(l.breakTargets, end)
+	l.b.SetBlock(l.fn.NewBlock("switch.body"))
+	t.body.Lower(l)
+	l.b.Jump(end)
+	l.breakTargets = l.breakTargets[:len(l.breakTargets)-1]
+	l.switches = l.switches[:len(l.switches)-1]
+
+	l.b.SetBlock(dispatch)
+	for i, c := range targets.cases {
+		// Jump to the case if the value matches
+		next := l.fn.NewBlock("switch.next")
+		matches := l.b.Binary(ir.OpEq, irType(typ), false, value, ir.IntConst(c.value.convert(typ).intValue))
+		l.b.Branch(matches, targets.blocks[i], next)
+		l.b.SetBlock(next)
+	}
+
+	if targets.defaultCase != nil {
 		// Jump to the default case if no condition was matched.
-		write(w, "j %s", defaultCase.label)
+		l.b.Jump(targets.defaultCase)
 	} else {
 		// Jump to the end of the block if no default is present.
-		write(w, "j %s", bottomLabel)
+		l.b.Jump(end)
 	}
 
-	w.Write(bodyBuf.Bytes())
-	write(w, "%s:", bottomLabel)
+	l.b.SetBlock(end)
+	return nil
 }
 
 type ASTReturn struct {
+	located
+
 	returnVal Node
+	// returnType is the type returned by the function, which the value is
+	// converted to.
+	returnType *Type
 }
 
 func (t *ASTReturn) Describe(indent int) string {
@@ -429,12 +439,25 @@ func (t *ASTReturn) Describe(indent int) string {
 	return fmt.Sprintf("%sreturn %s", genIndent(indent), t.returnVal.Describe(0))
 }
 
-func (t *ASTReturn) GenerateMIPS(w io.Writer, m *MIPS) {
-	t.returnVal.GenerateMIPS(w, m)
-	write(w, "j %s", *m.ReturnScopes.Peek())
+func (t *ASTReturn) Lower(l *Lowerer) ir.Value {
+	var v ir.Value
+	if t.returnVal != nil {
+		v = l.value(t.returnVal, t.returnType)
+	}
+	if t.returnType.IsStruct() {
+		// Structures are returned by copying them to the address the caller
+		// passed as the hidden first argument, which is also returned.
+		dst := l.b.Load(ir.I32, &ir.Addr{Slot: l.structReturn}, false, false)
+		l.store(t.returnType, dst, v)
+		v = dst
+	}
+	l.b.Ret(v)
+	return nil
 }
 
-type ASTContinue struct{}
+type ASTContinue struct {
+	located
+}
 
 func (t *ASTContinue) Describe(indent int) string {
 	if t == nil {
@@ -443,12 +466,14 @@ func (t *ASTContinue) Describe(indent int) string {
 	return fmt.Sprintf("%scontinue;", genIndent(indent))
 }
 
-func (t *ASTContinue) GenerateMIPS(w io.Writer, m *MIPS) {
-	curLabelScope := m.LabelScopes.Peek()
-	write(w, "j %s", *curLabelScope.ContinueLabel)
+func (t *ASTContinue) Lower(l *Lowerer) ir.Value {
+	l.b.Jump(l.continueTargets[len(l.continueTargets)-1])
+	return nil
 }
 
-type ASTBreak struct{}
+type ASTBreak struct {
+	located
+}
 
 func (t *ASTBreak) Describe(indent int) string {
 	if t == nil {
@@ -457,12 +482,14 @@ func (t *ASTBreak) Describe(indent int) string {
 	return fmt.Sprintf("%sbreak;", genIndent(indent))
 }
 
-func (t *ASTBreak) GenerateMIPS(w io.Writer, m *MIPS) {
-	curLabelScope := m.LabelScopes.Peek()
-	write(w, "j %s", *curLabelScope.BreakLabel)
+func (t *ASTBreak) Lower(l *Lowerer) ir.Value {
+	l.b.Jump(l.breakTargets[len(l.breakTargets)-1])
+	return nil
 }
 
 type ASTGoto struct {
+	located
+
 	label *ASTIdentifier
 }
 
@@ -473,10 +500,14 @@ func (t *ASTGoto) Describe(indent int) string {
 	return fmt.Sprintf("%sgoto :%s;", genIndent(indent), t.label.Describe(0))
 }
 
-// TODO: investigate at later date
-func (t *ASTGoto) GenerateMIPS(w io.Writer, m *MIPS) {}
+func (t *ASTGoto) Lower(l *Lowerer) ir.Value {
+	l.b.Jump(l.UserLabel(t.label.ident))
+	return nil
+}
 
 type ASTLabeledStatement struct {
+	located
+
 	ident *ASTIdentifier
 	stmt  Node
 }
@@ -493,7 +524,10 @@ func (t *ASTLabeledStatement) Describe(indent int) string {
 	return sb.String()
 }
 
-// TODO: investigate at later date
-func (t *ASTLabeledStatement) GenerateMIPS(w io.Writer, m *MIPS) {
-	t.stmt.GenerateMIPS(w, m)
+func (t *ASTLabeledStatement) Lower(l *Lowerer) ir.Value {
+	l.b.StartBlock(l.UserLabel(t.ident.ident))
+	if t.stmt != nil {
+		t.stmt.Lower(l)
+	}
+	return nil
 }
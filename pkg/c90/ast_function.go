@@ -1,50 +1,46 @@
 package c90
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"strings"
+
+	"github.com/jpnock/see90/pkg/ir"
 )
 
-// TODO: the following is our own ABI. This needs to be changed to support MIPS
-// o32 ABI.
-//
-// new sp
-//
-// new frame pointer <- return addr
-// arg 1 [fp + 4]
-// arg 2 [fp + 8]
-// arg 3 [fp + 0xC]
-// 0xfffe0000 <- sp
-//
-// var2
-// var1
-// old fp
-// 0xffff0000 <- fp (return addr)
-// arg 1 [fp + 4]
-// arg 2 [fp + 8]
-// arg 3 [fp + 0xC]
-//
-// // GenerateMips -> Function
-// // string = body.GenerateMips
-// // inspect the context
-// // fetch the last offset used
-// // subtract last offset + 12 (for ra and old fp, sp) from $sp at start of the function
-//
-// push 3
-// push 2
-// push 1
-// call function
+// ASTFunction is a function definition. Functions use the MIPS o32 calling
+// convention, which is described in package mips. Every parameter is given a
+// slot where the convention puts it in the caller's argument area, so that it
+// has an address, and a function returning a structure is passed the address
+// to copy it to as a hidden first parameter.
 type ASTFunction struct {
-	typ  *ASTType
-	decl *ASTDirectDeclarator
-	body Node
+	located
+
+	typ     *ASTType
+	storage StorageClass
+	decl    *ASTDirectDeclarator
+	body    Node
+
+	sym *Symbol
+	// params holds a symbol for each parameter, in order. Unnamed parameters
+	// have symbols which aren't in scope.
+	params []*Symbol
 }
 
 func (t *ASTFunction) Name() string {
-	declDescribe := t.decl.Describe(0)
-	return declDescribe[:strings.Index(declDescribe, "(")]
+	return t.decl.Identifier().ident
+}
+
+// parameters returns the parameter list of the function declarator. It's the
+// innermost list, nearest the identifier: any lists outside it belong to the
+// type the function returns, such as a pointer to a function.
+func (t *ASTFunction) parameters() *ASTParameterList {
+	var params *ASTParameterList
+	for d := t.decl; d != nil; d = d.decl {
+		if d.parameters != nil {
+			params = d.parameters
+		}
+	}
+	return params
 }
 
 func (t *ASTFunction) Describe(indent int) string {
@@ -63,147 +59,57 @@ func (t *ASTFunction) Describe(indent int) string {
 	return fmt.Sprintf("%sfunction (%s) -> %s {\n%s\n}\n", indentStr, declDescribe, t.typ.Describe(0), t.body.Describe(indent+4))
 }
 
-func (t *ASTFunction) GenerateMIPS(w io.Writer, m *MIPS) {
-	m.NewFunction()
-	defer m.EndFunction()
+func (t *ASTFunction) Lower(l *Lowerer) ir.Value {
+	sig := t.sym.Type.Func
+	fn := ir.NewFunction(t.Name())
+	fn.Global = !t.sym.internal
+	fn.Variadic = sig.Variadic
+	fn.Return = irType(sig.Return)
+	l.module.Functions = append(l.module.Functions, fn)
 
-	defer func() {
-		// print the lables for strings declared in function
-		write(w, ".data")
-		for k, v := range m.stringMap {
-			writeGlobalString(w, k, v)
-		}
-		write(w, ".text")
-	}()
-
-	// Always return at end of function
-	defer write(w, "jr $ra\n")
-
-	returnLabel := m.ReturnScopes.Peek()
-
-	funcName := t.Name()
-	write(w, ".text")
-	write(w, ".globl %s\n", funcName)
-	write(w, "%s:\n", funcName)
-
-	var arguments []*Variable
-	nextStackOffset := 0
-	for _, param := range t.decl.parameters.li {
-		paramType := *param.specifier.(*ASTType)
-
-		// TODO: at the moment, we're assuming all function parameters are
-		// identifiers, however this is clearly not the case when you have array
-		// parameters.
-		directDecl, ok := param.declarator.(*ASTDirectDeclarator)
-		if ok {
-			v := &Variable{
-				fpOffset:   -nextStackOffset,
-				decl:       nil,
-				directDecl: directDecl,
-				typ:        paramType,
-			}
-			if paramType.typ == VarTypeStruct {
-				v.structure = m.StructScopes[len(m.StructScopes)-1][paramType.typName]
-			}
-			m.VariableScopes[len(m.VariableScopes)-1][directDecl.identifier.ident] = v
-			arguments = append(arguments, v)
-		}
+	l.beginFunction(fn, sig.Return)
+	defer l.endFunction()
 
-		allocatedSize := 4
-		if directDecl.array == nil && directDecl.pointerDepth == 0 && paramType.typ == VarTypeDouble {
-			allocatedSize += 4
-		}
-		nextStackOffset += allocatedSize
+	if sig.Return.IsStruct() {
+		l.structReturn = fn.NewParam("result", ir.I32, 4, 4)
 	}
-
-	// TODO: do we need to generate mips
-	// t.decl.GenerateMIPS(w, m)
-
-	// Store $sp
-	write(w, "move $t7, $sp")
-
-	// Store $fp
-	stackPush(w, "$fp", 4)
-	defer stackPop(w, "$fp", 4)
-
-	// Move frame pointer to bottom of frame
-	// TODO: not ABI compliant
-	write(w, "move $fp, $t7")
-
-	bodyBuf := new(bytes.Buffer)
-	t.body.GenerateMIPS(bodyBuf, m)
-
-	reserve := m.Context.CurrentStackFramePointerOffset
-	write(w, "addiu $sp, $sp, %d", -reserve)
-	defer write(w, "addiu $sp, $sp, %d", reserve)
-
-	nextIntReg := 4
-	for i, param := range arguments {
-		paramTyp := param.typ.typ
-		if param.IsPointer() || param.IsArray() {
-			paramTyp = VarTypeUnsigned
-		}
-
-		if nextIntReg > 7 || (paramTyp == VarTypeDouble && nextIntReg == 7) {
-			// We only need to save the first four args max.
-			break
-		}
-
-		firstParamTyp := arguments[0].typ.typ
-		if arguments[0].IsPointer() || arguments[0].IsArray() {
-			firstParamTyp = VarTypeUnsigned
-		}
-
-		if i < 2 && (firstParamTyp == VarTypeFloat || firstParamTyp == VarTypeDouble) {
-			if paramTyp == VarTypeFloat {
-				if i == 0 {
-					write(w, "swc1 $f12, %d($fp)", -param.fpOffset)
-				} else {
-					write(w, "swc1 $f14, %d($fp)", -param.fpOffset)
-				}
-				nextIntReg += 1
-				continue
-			} else if paramTyp == VarTypeDouble {
-				if i == 0 {
-					write(w, "swc1 $f12, %d($fp)", -param.fpOffset+4)
-					write(w, "swc1 $f13, %d($fp)", -param.fpOffset)
-					nextIntReg += 2
-				} else {
-					write(w, "swc1 $f14, %d($fp)", -param.fpOffset+4)
-					write(w, "swc1 $f15, %d($fp)", -param.fpOffset)
-					// As doubles are even register aligned
-					nextIntReg += 3
-				}
-				continue
-			}
-		}
-
-		if paramTyp == VarTypeDouble {
-			if nextIntReg%2 != 0 {
-				// As doubles are even register aligned
-				nextIntReg += 1
-			}
-			write(w, "sw $%d, %d($fp)", nextIntReg, -param.fpOffset)
-			write(w, "sw $%d, %d($fp)", nextIntReg+1, -param.fpOffset)
-			nextIntReg += 2
-		} else if paramTyp == VarTypeChar {
-			write(w, "sb $%d, %d($fp)", nextIntReg, -param.fpOffset)
-			nextIntReg += 1
-		} else {
-			write(w, "sw $%d, %d($fp)", nextIntReg, -param.fpOffset)
-			nextIntReg += 1
+	for _, param := range t.params {
+		typ := irType(param.Type)
+		if param.Type.IsStruct() {
+			typ = ir.Void
 		}
+		param.slot = fn.NewParam(param.Name, typ, param.Type.Size(), param.Type.Align())
 	}
 
-	write(w, "%s", bodyBuf.String())
+	t.body.Lower(l)
+	if l.b.Block.Terminator() == nil {
+		l.b.Ret(nil)
+	}
+	return nil
+}
 
-	write(w, "%s:", *returnLabel)
+// slotOffset returns the offset of an argument of type typ within its slot in
+// the argument area. Arguments smaller than a word are passed in the low bytes
+// of their word, which come last as MIPS is big-endian.
+func slotOffset(typ *Type) int {
+	if size := typ.Size(); size < 4 && !typ.IsStruct() {
+		return 4 - size
+	}
+	return 0
 }
 
 type ASTFunctionCall struct {
+	located
+	typed
+
 	// primary_expresion node
 	function  Node
 	arguments ASTArgumentExpressionList
+
+	// argumentTypes are the types the arguments are converted to before the
+	// call, which come from the prototype or the default argument
+	// promotions.
+	argumentTypes []*Type
 }
 
 func (t *ASTFunctionCall) Describe(indent int) string {
@@ -220,128 +126,129 @@ func (t *ASTFunctionCall) Describe(indent int) string {
 	return fmt.Sprintf("%s%s(%s)", genIndent(indent), t.function.Describe(0), sb.String())
 }
 
-func (t *ASTFunctionCall) GenerateMIPS(w io.Writer, m *MIPS) {
-	// Need to do this as the very start/last thing
-	// to prevent it interfering with the call stack.
-	stackPush(w, "$ra", 4)
-	defer stackPop(w, "$ra", 4)
-
-	// Allow for function calls that contain function calls by preserving the
-	// existing state of the argument registers.
-	stackPush(w, "$4", 4)
-	defer stackPop(w, "$4", 4)
-	stackPush(w, "$5", 4)
-	defer stackPop(w, "$5", 4)
-	stackPush(w, "$6", 4)
-	defer stackPop(w, "$6", 4)
-	stackPush(w, "$7", 4)
-	defer stackPop(w, "$7", 4)
-
-	const regTypeFP = "fp"
-	const regTypeInt = "int"
-	firstRegisterType := regTypeInt
-
-	overflowArgsStackPopAmount := 0
-	lastIntRegisterUsed := 3
-	numBytesUsed := 0
-
-	// TODO: decide when to switch to stack based on 4x4 byte arguments
-	for i, arg := range t.arguments {
-		arg.GenerateMIPS(w, m)
-
-		if numBytesUsed >= 16 || lastIntRegisterUsed >= 7 {
-			// Put variables on stack as we've overflowed the register space
-			// available.
-			switch m.LastType() {
-			case VarTypeFloat:
-				overflowArgsStackPopAmount += 4
-				stackPushFP(w, "$f0")
-			case VarTypeDouble:
-				overflowArgsStackPopAmount += 8
-				stackPushFP(w, "$f0", "$f1")
-			default:
-				// TODO: Sizing is wrong for some types (and probably argument ordering)
-				overflowArgsStackPopAmount += 4
-				write(w, "addiu $sp, $sp, -4")
-				write(w, "sw $v0, 0($sp)")
-			}
-			continue
-		}
+func (t *ASTFunctionCall) Lower(l *Lowerer) ir.Value {
+	fn := typeOf(t.function)
+	if fn.IsPointer() {
+		fn = fn.Elem()
+	}
 
-		if i == 0 && (m.LastType() == VarTypeFloat || m.LastType() == VarTypeDouble) {
-			// Check if we need to handle the edgecase
-			firstRegisterType = regTypeFP
-		}
+	// Functions which aren't called by name are called through their
+	// address.
+	var callee ir.Value
+	if t.directCall() {
+		callee = &ir.Addr{Symbol: string(t.function.(*ASTIdentifier).sym.GlobalLabel())}
+	} else {
+		callee = t.function.Lower(l)
+	}
 
-		nextIntReg := lastIntRegisterUsed + 1
-
-		if firstRegisterType == regTypeFP && i < 2 {
-			if m.LastType() == VarTypeFloat {
-				if nextIntReg == 4 {
-					write(w, "mov.s $f12, $f0")
-				} else {
-					write(w, "mov.s $f14, $f0")
-				}
-
-				lastIntRegisterUsed += 1
-				numBytesUsed += 4
-
-				// Process next arg
-				continue
-			} else if m.LastType() == VarTypeDouble {
-				if nextIntReg == 4 {
-					write(w, "mov.s $f12, $f0")
-					write(w, "mov.s $f13, $f1")
-				} else {
-					write(w, "mov.s $f14, $f0")
-					write(w, "mov.s $f15, $f1")
-				}
-
-				lastIntRegisterUsed += 2
-				numBytesUsed += 8
-
-				// Process next arg
-				continue
-			}
-
-			// We're no longer dealing with an FP arg, so handle this below.
-		}
+	call := &ir.Call{Named: -1}
+	var args []ir.Value
+	var result *ir.Addr
+	if t.Type().IsStruct() {
+		// Make space in our frame for the returned structure.
+		result = &ir.Addr{Slot: l.fn.NewSlot("", t.Type().Size(), t.Type().Align())}
+		args = append(args, result)
+		call.Aggregates = append(call.Aggregates, ir.Aggregate{})
+	}
+	if fn.Func.Variadic {
+		call.Named = len(args) + len(fn.Func.Params)
+	}
 
-		// Everything from herein goes into int registers
-		switch m.LastType() {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar, VarTypeString:
-			write(w, "move $%d, $v0", nextIntReg)
-			numBytesUsed += 4
-		case VarTypeFloat:
-			write(w, "mfc1 $%d, $f0", nextIntReg)
-			numBytesUsed += 4
-		case VarTypeDouble:
-			if nextIntReg%2 != 0 {
-				// Needs to be even aligned for some reason.
-				nextIntReg += 1
-			}
-			write(w, "mfc1 $%d, $f0", nextIntReg+1)
-			write(w, "mfc1 $%d, $f1", nextIntReg)
-
-			// We use two registers, so increment this here.
-			nextIntReg += 1
-			numBytesUsed += 8
-		default:
-			panic("unknown function call arg type")
+	// Structures are passed as their address, and copied into the argument
+	// area by the call.
+	for i, arg := range t.arguments {
+		argTyp := t.argumentTypes[i]
+		args = append(args, l.value(arg, argTyp))
+		var aggregate ir.Aggregate
+		if argTyp.IsStruct() {
+			aggregate = ir.Aggregate{Size: argTyp.Size(), Align: argTyp.Align()}
 		}
-
-		lastIntRegisterUsed = nextIntReg
+		call.Aggregates = append(call.Aggregates, aggregate)
 	}
 
-	write(w, "addiu $sp, $sp, -16")
-	write(w, "jal %s", t.FunctionName())
-	write(w, "addiu $sp, $sp, 16")
-
-	if overflowArgsStackPopAmount > 0 {
-		write(w, "addiu $sp, $sp, %d", overflowArgsStackPopAmount)
+	if result != nil {
+		l.b.Call(ir.Void, callee, args, call)
+		return result
 	}
+	v := l.b.Call(irType(t.Type()), callee, args, call)
+	if v == nil {
+		return nil
+	}
+	return v
+}
+
+// directCall reports whether the function is called by name, rather than
+// through a function pointer.
+func (t *ASTFunctionCall) directCall() bool {
+	ident, ok := t.function.(*ASTIdentifier)
+	return ok && ident.sym != nil && ident.sym.Kind == SymbolFunction
 }
 
 func (t *ASTFunctionCall) FunctionName() string {
+	if ident, ok := t.function.(*ASTIdentifier); ok {
+		return ident.ident
+	}
 	return t.function.Describe(0)
 }
+
+// ASTVaStart is va_start(ap, last), which points ap at the first unnamed
+// argument of a variadic function.
+type ASTVaStart struct {
+	located
+	typed
+
+	ap   Node
+	last *ASTIdentifier
+}
+
+func (t *ASTVaStart) Describe(indent int) string {
+	return fmt.Sprintf("%sva_start(%s, %s)", genIndent(indent), t.ap.Describe(0), t.last.Describe(0))
+}
+
+func (t *ASTVaStart) Lower(l *Lowerer) ir.Value {
+	l.store(typeOf(t.ap), l.address(t.ap), l.b.VaStart())
+	return nil
+}
+
+// ASTVaArg is va_arg(ap, type), which reads the next unnamed argument and
+// moves ap past it.
+type ASTVaArg struct {
+	located
+	typed
+
+	ap       Node
+	typeName *ASTTypeName
+}
+
+func (t *ASTVaArg) Describe(indent int) string {
+	return fmt.Sprintf("%sva_arg(%s, %s)", genIndent(indent), t.ap.Describe(0), t.typeName.Describe(0))
+}
+
+func (t *ASTVaArg) Lower(l *Lowerer) ir.Value {
+	typ := t.Type()
+	apType := typeOf(t.ap)
+
+	ap := l.address(t.ap)
+	arg := l.load(apType, ap)
+	if align := typ.Align(); align > 4 {
+		arg = l.offset(arg, align-1)
+		arg = l.b.Binary(ir.OpAnd, ir.I32, false, arg, ir.IntConst(int64(-align)))
+	}
+	l.store(apType, ap, l.offset(arg, alignTo(typ.Size(), 4)))
+
+	return l.load(typ, l.offset(arg, slotOffset(typ)))
+}
+
+// ASTVaEnd is va_end(ap), which has nothing to clean up.
+type ASTVaEnd struct {
+	located
+	typed
+
+	ap Node
+}
+
+func (t *ASTVaEnd) Describe(indent int) string {
+	return fmt.Sprintf("%sva_end(%s)", genIndent(indent), t.ap.Describe(0))
+}
+
+func (t *ASTVaEnd) Lower(l *Lowerer) ir.Value { return nil }
@@ -2,7 +2,8 @@ package c90
 
 import (
 	"fmt"
-	"io"
+
+	"github.com/jpnock/see90/pkg/ir"
 )
 
 type ASTExprBinaryType string
@@ -29,9 +30,16 @@ const (
 )
 
 type ASTExprBinary struct {
+	located
+	typed
+
 	lhs Node
 	rhs Node
 	typ ASTExprBinaryType
+
+	// operandType is the type both operands are converted to before the
+	// operation is carried out.
+	operandType *Type
 }
 
 func (t *ASTExprBinary) Describe(indent int) string {
@@ -41,450 +49,185 @@ func (t *ASTExprBinary) Describe(indent int) string {
 	return fmt.Sprintf("%s%s %s %s", genIndent(indent), t.lhs.Describe(0), t.typ, t.rhs.Describe(0))
 }
 
-func write(w io.Writer, format string, args ...interface{}) {
-	io.WriteString(
-		w,
-		fmt.Sprintf(format, args...),
-	)
-	io.WriteString(w, "\n")
+func isFloat(t *Type) bool {
+	return t.Kind == TypeFloat
 }
 
-func stackPushFP(w io.Writer, registers ...string) {
-	if len(registers) > 2 {
-		panic("bad stackPushFP")
-	}
-
-	write(w, "")
-	defer write(w, "")
-
-	if len(registers) == 2 {
-		write(w, "addiu $sp, $sp, -8")
-		write(w, "swc1 %s, 4($sp)", registers[0])
-		write(w, "swc1 %s, 0($sp)", registers[1])
-		return
-	}
-
-	write(w, "addiu $sp, $sp, -4")
-	write(w, "swc1 %s, 0($sp)", registers[0])
+// isDouble reports whether t is held in a pair of floating point registers.
+// long double is the same as double in the o32 ABI.
+func isDouble(t *Type) bool {
+	return t.Kind == TypeDouble || t.Kind == TypeLongDouble
 }
 
-func stackPopFP(w io.Writer, registers ...string) {
-	if len(registers) > 2 {
-		panic("bad stackPopFP")
+// convert converts the scalar value v from type from to type to. Pointers are
+// converted to and from integers without any change, and constants are
+// converted straight away.
+func (l *Lowerer) convert(v ir.Value, from, to *Type) ir.Value {
+	if to.IsVoid() {
+		// The value is discarded.
+		return nil
 	}
-
-	write(w, "")
-	defer write(w, "")
-
-	if len(registers) == 2 {
-		write(w, "lwc1 %s, 4($sp)", registers[0])
-		write(w, "lwc1 %s, 0($sp)", registers[1])
-		write(w, "addiu $sp, $sp, 8")
-		return
+	if v == nil || !from.IsScalar() || !to.IsScalar() {
+		return v
 	}
 
-	write(w, "lwc1 %s, 0($sp)", registers[0])
-	write(w, "addiu $sp, $sp, 4")
-}
-
-func stackPush(w io.Writer, reg string, size int) {
-	write(w, "")
-	defer write(w, "")
-
-	write(w, "addiu $sp, $sp, -8")
-	if reg != "" {
-		// TODO: alter sw based on reg type
-		switch size {
-		case 2:
-			write(w, "sb %s, 0($sp)", reg)
-		case 4:
-			write(w, "sw %s, 0($sp)", reg)
+	typ := irType(to)
+	c, constant := v.(*ir.Const)
+	switch {
+	case from.IsFloating() && to.IsFloating():
+		switch {
+		case irType(from) == typ:
+			return v
+		case constant:
+			return ir.FloatConst(typ, c.Float)
+		}
+		return l.b.Convert(ir.OpFConv, typ, false, v)
+
+	case to.IsFloating():
+		unsigned := from.IsUnsigned() && from.Size() == 4
+		switch {
+		case constant && unsigned:
+			return ir.FloatConst(typ, float64(uint32(c.Int)))
+		case constant:
+			return ir.FloatConst(typ, float64(c.Int))
+		}
+		return l.b.Convert(ir.OpIToF, typ, unsigned, v)
+
+	case from.IsFloating():
+		unsigned := to.IsUnsigned() && to.Size() == 4
+		switch {
+		case constant && unsigned:
+			v = ir.IntConst(int64(uint32(c.Float)))
+		case constant:
+			v = ir.IntConst(int64(int32(c.Float)))
 		default:
-			write(w, "un implemented size push")
+			v = l.b.Convert(ir.OpFToI, ir.I32, unsigned, v)
 		}
+		return l.extend(v, to)
+	}
 
+	if to.Kind != from.Kind {
+		return l.extend(v, to)
 	}
+	return v
 }
 
-func stackPop(w io.Writer, reg string, size int) {
-	write(w, "")
-	defer write(w, "")
-
-	if reg != "" {
-		// TODO: alter lw based on reg type
-		switch size {
-		case 2:
-			write(w, "lb %s, 0($sp)", reg)
-		case 4:
-			write(w, "lw %s, 0($sp)", reg)
-		default:
-			write(w, "un implemented size pop")
-		}
+// extend truncates the integer v to the size of typ, then sign or zero extends
+// it back to a full register.
+func (l *Lowerer) extend(v ir.Value, typ *Type) ir.Value {
+	t := irType(typ)
+	if t != ir.I8 && t != ir.I16 {
+		return v
+	}
+	if c, ok := v.(*ir.Const); ok {
+		switch {
+		case t == ir.I8 && typ.IsUnsigned():
+			return ir.IntConst(int64(uint8(c.Int)))
+		case t == ir.I8:
+			return ir.IntConst(int64(int8(c.Int)))
+		case typ.IsUnsigned():
+			return ir.IntConst(int64(uint16(c.Int)))
+		}
+		return ir.IntConst(int64(int16(c.Int)))
 	}
-	write(w, "addiu $sp, $sp, 8")
+	return l.b.Convert(ir.OpExt, t, typ.IsUnsigned(), v)
 }
 
-func branchOnCondition(w io.Writer, m *MIPS) {
-	trueLabel := m.CreateUniqueLabel("condtion_true")
-	finalLabel := m.CreateUniqueLabel("logical_final")
-	write(w, "bc1t %s", trueLabel)
-
-	write(w, "addiu $v0, $zero, 0")
-	write(w, "j %s", finalLabel)
-
-	write(w, "%s:", trueLabel)
-	write(w, "addiu $v0, $zero, 1")
-
-	write(w, "%s:", finalLabel)
+// binaryOps maps the arithmetic operators to the IR operation carrying them
+// out.
+var binaryOps = map[ASTExprBinaryType]ir.Op{
+	ASTExprBinaryTypeMul:         ir.OpMul,
+	ASTExprBinaryTypeDiv:         ir.OpDiv,
+	ASTExprBinaryTypeMod:         ir.OpRem,
+	ASTExprBinaryTypeAdd:         ir.OpAdd,
+	ASTExprBinaryTypeSub:         ir.OpSub,
+	ASTExprBinaryTypeLeftShift:   ir.OpShl,
+	ASTExprBinaryTypeRightShift:  ir.OpShr,
+	ASTExprBinaryTypeLessThan:    ir.OpLt,
+	ASTExprBinaryTypeLessOrEqual: ir.OpLe,
+	ASTExprBinaryTypeEquality:    ir.OpEq,
+	ASTExprBinaryTypeNotEquality: ir.OpNe,
+	ASTExprBinaryTypeBitwiseAnd:  ir.OpAnd,
+	ASTExprBinaryTypeXor:         ir.OpXor,
+	ASTExprBinaryTypeBitwiseOr:   ir.OpOr,
 }
 
-// TODO: implement for types other than int
-func (t *ASTExprBinary) generateLogical(w io.Writer, m *MIPS) {
-	// Generate LHS -> result in $v0
-	t.lhs.GenerateMIPS(w, m)
-	checkFloatOrDoubleCondition(w, m)
-
-	failureLabel := m.CreateUniqueLabel("logical_failure")
-	successLabel := m.CreateUniqueLabel("logical_success")
-	endLabel := m.CreateUniqueLabel("logical_end")
-
-	// Do a comparison to check if true/false and short circuit
-	switch t.typ {
-	case ASTExprBinaryTypeLogicalAnd:
-		// Jump to end (failure) if short circuit (false)
-		write(w, "beq $zero, $v0, %s", failureLabel)
-	case ASTExprBinaryTypeLogicalOr:
-		// Jump to end (success) if short circuit (true)
-		write(w, "bne $zero, $v0, %s", successLabel)
-	default:
-		panic("unknown logical function in ASTExprBinary")
+// arithmetic returns x op y, where both operands have already been converted
+// to opType.
+func (l *Lowerer) arithmetic(op ASTExprBinaryType, opType *Type, x, y ir.Value) ir.Value {
+	// Pointers are compared as unsigned integers.
+	unsigned := opType.IsUnsigned() || opType.IsPointer()
+	typ := irType(opType)
+
+	switch op {
+	case ASTExprBinaryTypeGreaterThan:
+		return l.b.Binary(ir.OpLt, typ, unsigned, y, x)
+	case ASTExprBinaryTypeGreaterOrEqual:
+		return l.b.Binary(ir.OpLe, typ, unsigned, y, x)
 	}
-
-	// Generate RHS -> result in $v0
-	t.rhs.GenerateMIPS(w, m)
-	checkFloatOrDoubleCondition(w, m)
-
-	switch t.typ {
-	case ASTExprBinaryTypeLogicalAnd:
-		// Jump to end (failure) if short circuit (false)
-		write(w, "beq $zero, $v0, %s", failureLabel)
-		// Both LHS and RHS are non-zero, so jump to success
-		write(w, "j %s", successLabel)
-	case ASTExprBinaryTypeLogicalOr:
-		// Jump to end (success) if short circuit (true)
-		write(w, "bne $zero, $v0, %s", successLabel)
-	default:
-		panic("unknown logical function in ASTExprBinary")
+	irOp, ok := binaryOps[op]
+	if !ok {
+		panic("unsupported ASTExprBinaryType")
 	}
+	return l.b.Binary(irOp, typ, unsigned, x, y)
+}
 
-	// Jump to this section if the condition is not met
-	write(w, "%s:", failureLabel)
-	write(w, "addiu $v0, $zero, 0")
-	write(w, "j %s", endLabel)
+// pointerArithmetic adds the integer index to the pointer ptr, or subtracts
+// it, scaling it by size, the size of the elements pointed to.
+func (l *Lowerer) pointerArithmetic(op ASTExprBinaryType, ptr, index ir.Value, size int) ir.Value {
+	if c, ok := index.(*ir.Const); ok {
+		offset := int(c.Int) * size
+		if op == ASTExprBinaryTypeSub {
+			offset = -offset
+		}
+		return l.offset(ptr, offset)
+	}
 
-	write(w, "%s:", successLabel)
-	write(w, "addiu $v0, $zero, 1")
-	write(w, "%s:", endLabel)
-	m.SetLastType(VarTypeInteger)
+	if size != 1 {
+		index = l.b.Binary(ir.OpMul, ir.I32, false, index, ir.IntConst(int64(size)))
+	}
+	if op == ASTExprBinaryTypeSub {
+		return l.b.Binary(ir.OpSub, ir.I32, false, ptr, index)
+	}
+	return l.b.Binary(ir.OpAdd, ir.I32, false, ptr, index)
 }
 
-func (t *ASTExprBinary) GenerateMIPS(w io.Writer, m *MIPS) {
-	// TODO: work out actual type
+func (t *ASTExprBinary) Lower(l *Lowerer) ir.Value {
 	switch t.typ {
 	case ASTExprBinaryTypeLogicalAnd, ASTExprBinaryTypeLogicalOr:
 		// Special case where we need to potentially short circuit, so we cannot
 		// always execute RHS.
-		t.generateLogical(w, m)
-		return
+		return l.conditionValue(t)
 	}
 
-	var lhsType VarType
-	var lhsPointerLevel int
-
-	// Generate LHS -> result in $v0
-	t.lhs.GenerateMIPS(w, m)
-	lhsType = m.lastType
-	lhsPointerLevel = m.pointerLevel
-
-	var varTyp = m.LastType()
-
-	switch varTyp {
-	case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeString:
-		// Store the LHS on the stack
-		stackPush(w, "$v0", 4)
-
-		// Generate RHS -> result in $v0
-		t.rhs.GenerateMIPS(w, m)
-
-		// TODO: improve this so we don't push/pop to get $v0 into $t1
-		stackPush(w, "$v0", 4)
-
-		// Pop the RHS result into $t1
-		stackPop(w, "$t1", 4)
-
-		// Pop the LHS result into $t0
-		stackPop(w, "$t0", 4)
-	case VarTypeFloat:
-		stackPushFP(w, "$f0")
-		t.rhs.GenerateMIPS(w, m)
-		stackPushFP(w, "$f0")
-
-		stackPopFP(w, "$f4")
-		stackPopFP(w, "$f2")
-	case VarTypeDouble:
-		stackPushFP(w, "$f0", "$f1")
-		t.rhs.GenerateMIPS(w, m)
-		stackPushFP(w, "$f0", "$f1")
-
-		stackPopFP(w, "$f4", "$f5")
-		stackPopFP(w, "$f2", "$f3")
-	case VarTypeChar:
-		stackPush(w, "$v0", 2)
-
-		t.rhs.GenerateMIPS(w, m)
-
-		stackPush(w, "$v0", 2)
-
-		stackPop(w, "$t1", 2)
-
-		stackPop(w, "$t0", 2)
-	default:
-		panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-	}
-
-	emitPointerArithmetic := func(offsetRegister string) {
-		offset := 4
-		switch m.lastType {
-		case VarTypeChar, VarTypeString:
-			offset = 1
-		case VarTypeDouble:
-			offset = 8
-		}
-
-		write(w, "move $t3, %s", offsetRegister)
-		for i := 1; i < offset; i++ {
-			write(w, "addu %s, %s, $t3", offsetRegister, offsetRegister)
-		}
-	}
-
-	if lhsPointerLevel > 0 && m.pointerLevel > 0 {
-		// Pointers on both sides, so we need to divide both sides by the
-		// pointer size as subtraction is the only operation allowed here.
-		nonPtrSize := 4
-		switch m.lastType {
-		case VarTypeChar, VarTypeString:
-			nonPtrSize = 1
-		case VarTypeDouble:
-			nonPtrSize = 8
-		}
-
-		if nonPtrSize != 1 {
-			write(w, "addiu $t3, $zero, %d", nonPtrSize)
-			write(w, "divu $t0, $t3")
-			write(w, "mflo $t0")
-			write(w, "divu $t1, $t3")
-			write(w, "mflo $t1")
-		}
-	} else if lhsPointerLevel > 0 {
-		// This allows for pointer arithmetic, as otherwise the RHS might contain a constant
-		// which overwrites the current pointer information within the context.
-		m.lastType = lhsType
-		m.pointerLevel = lhsPointerLevel
-		emitPointerArithmetic("$t1")
-	} else if m.pointerLevel > 0 {
-		// RHS has a pointer instead, with LHS constant
-		emitPointerArithmetic("$t0")
-	}
-
-	switch t.typ {
-	case ASTExprBinaryTypeMul:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeChar:
-			write(w, "mult $t0, $t1")
-			write(w, "mflo $v0")
-		case VarTypeUnsigned:
-			write(w, "multu $t0, $t1")
-			write(w, "mflo $v0")
-		case VarTypeFloat:
-			write(w, "mul.s $f0, $f2, $f4")
-		case VarTypeDouble:
-			write(w, "mul.d $f0, $f2, $f4")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeDiv:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeChar:
-			write(w, "div $t0, $t1")
-			write(w, "mflo $v0")
-		case VarTypeUnsigned:
-			write(w, "divu $t0, $t1")
-			write(w, "mflo $v0")
-		case VarTypeFloat:
-			write(w, "div.s $f0, $f2, $f4")
-		case VarTypeDouble:
-			write(w, "div.d $f0, $f2, $f4")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeMod:
-		// TODO: check operation of modulo for negative values
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeChar:
-			write(w, "div $t0, $t1")
-			write(w, "mfhi $v0")
-		case VarTypeUnsigned:
-			write(w, "divu $t0, $t1")
-			write(w, "mfhi $v0")
-		case VarTypeFloat, VarTypeDouble:
-			panic("not allowed operation on type float")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeAdd:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "addu $v0, $t0, $t1")
-		case VarTypeFloat:
-			write(w, "add.s $f0, $f2, $f4")
-		case VarTypeDouble:
-			write(w, "add.d $f0, $f2, $f4")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeSub:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "subu $v0, $t0, $t1")
-		case VarTypeFloat:
-			write(w, "sub.s $f0, $f2, $f4")
-		case VarTypeDouble:
-			write(w, "sub.d $f0, $f2, $f4")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeLeftShift:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeUnsigned, VarTypeChar, VarTypeShort, VarTypeLong:
-			write(w, "sllv $v0, $t0, $t1")
-		case VarTypeFloat, VarTypeDouble:
-			panic("not allowed operation on type float or double")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeRightShift:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeUnsigned, VarTypeChar, VarTypeLong:
-			write(w, "srlv  $v0, $t0, $t1")
-		case VarTypeFloat, VarTypeDouble:
-			panic("not allowed operation on type float")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeLessThan, ASTExprBinaryTypeGreaterOrEqual:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeChar:
-			write(w, "slt $v0, $t0, $t1")
-		case VarTypeUnsigned:
-			write(w, "sltu $v0, $t0, $t1")
-		case VarTypeFloat:
-			write(w, "c.lt.s $f2, $f4")
-			branchOnCondition(w, m)
-		case VarTypeDouble:
-			write(w, "c.lt.d $f2, $f4")
-			branchOnCondition(w, m)
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-		if t.typ == ASTExprBinaryTypeGreaterOrEqual {
-			// Invert the condition (greater than 0) => not equal
-			write(w, "xori $v0, $v0, 1")
-		}
-		m.SetLastType(VarTypeInteger)
-
-	case ASTExprBinaryTypeGreaterThan, ASTExprBinaryTypeLessOrEqual:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeChar:
-			write(w, "slt $v0, $t1, $t0")
-		case VarTypeUnsigned:
-			write(w, "sltu $v0, $t1, $t0")
-		case VarTypeFloat:
-			write(w, "c.lt.s $f4, $f2")
-			branchOnCondition(w, m)
-		case VarTypeDouble:
-			write(w, "c.lt.d $f4, $f2")
-			branchOnCondition(w, m)
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-		if t.typ == ASTExprBinaryTypeLessOrEqual {
-			// Invert the condition (greater than 0) => not equal
-			write(w, "xori $v0, $v0, 1")
-		}
-		m.SetLastType(VarTypeInteger)
-
-	case ASTExprBinaryTypeEquality, ASTExprBinaryTypeNotEquality:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar, VarTypeString:
-			// XOR left with right -> if equal, the result is 0
-			write(w, "xor $v0, $t0, $t1")
-			// Check (unsigned) whether the integer is less than 1 (i.e. equal to 0)
-			write(w, "sltiu $v0, $v0, 1")
-		case VarTypeFloat:
-			write(w, "c.eq.s $f2, $f4")
-			branchOnCondition(w, m)
-		case VarTypeDouble:
-			write(w, "c.eq.d $f2, $f4")
-			branchOnCondition(w, m)
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-		if t.typ == ASTExprBinaryTypeNotEquality {
-			// Invert the condition (greater than 0) => not equal
-			write(w, "xori $v0, $v0, 1")
-		}
-		m.SetLastType(VarTypeInteger)
-
-	case ASTExprBinaryTypeBitwiseAnd:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "AND $v0, $t0, $t1")
-		case VarTypeFloat, VarTypeDouble:
-			panic("not allowed operation on type float")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeXor:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "XOR $v0, $t0, $t1")
-		case VarTypeFloat, VarTypeDouble:
-			panic("not allowed operation on type float")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprBinaryTypeBitwiseOr:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "OR $v0, $t0, $t1")
-		case VarTypeFloat, VarTypeDouble:
-			panic("not allowed operation on type float")
+	lhsType := typeOf(t.lhs).Decay()
+	rhsType := typeOf(t.rhs).Decay()
+	opType := t.operandType
+
+	additive := t.typ == ASTExprBinaryTypeAdd || t.typ == ASTExprBinaryTypeSub
+	if opType.IsPointer() && additive {
+		elemSize := opType.Elem().Size()
+		lhs := t.lhs.Lower(l)
+		rhs := t.rhs.Lower(l)
+		switch {
+		case lhsType.IsPointer() && rhsType.IsPointer():
+			// The difference is the number of elements between the
+			// pointers, rather than the number of bytes.
+			diff := l.b.Binary(ir.OpSub, ir.I32, false, lhs, rhs)
+			if elemSize == 1 {
+				return diff
+			}
+			return l.b.Binary(ir.OpDiv, ir.I32, false, diff, ir.IntConst(int64(elemSize)))
+		case lhsType.IsPointer():
+			return l.pointerArithmetic(t.typ, lhs, l.convert(rhs, rhsType, typeInt), elemSize)
 		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
+			return l.pointerArithmetic(t.typ, rhs, l.convert(lhs, lhsType, typeInt), elemSize)
 		}
-
-	default:
-		panic("unsupported ASTExprPrefixUnaryType")
 	}
+
+	lhs := l.value(t.lhs, opType)
+	rhs := l.value(t.rhs, opType)
+	return l.arithmetic(t.typ, opType, lhs, rhs)
 }
 
 type ASTExprPrefixUnaryType string
@@ -502,8 +245,14 @@ const (
 )
 
 type ASTExprPrefixUnary struct {
+	located
+	typed
+
 	typ    ASTExprPrefixUnaryType
 	lvalue Node
+
+	// operandType is the type whose size is given by sizeof.
+	operandType *Type
 }
 
 func (t *ASTExprPrefixUnary) Describe(indent int) string {
@@ -516,141 +265,78 @@ func (t *ASTExprPrefixUnary) Describe(indent int) string {
 	return fmt.Sprintf("%s%s%s", genIndent(indent), t.typ, t.lvalue.Describe(0))
 }
 
-func (t *ASTExprPrefixUnary) GenerateMIPS(w io.Writer, m *MIPS) {
-	// TODO: maybe change from li.s to cvt.d.s
-
-	// TODO: work out actual type
-	t.lvalue.GenerateMIPS(w, m)
-
-	var varTyp = m.LastType()
+// increment adds delta to the lvalue of type typ. The value from before the
+// increment is returned if postfix is set, and the new value otherwise.
+func (l *Lowerer) increment(lvalue Node, delta int, postfix bool) ir.Value {
+	typ := typeOf(lvalue)
+	addr := l.address(lvalue)
+	old := l.load(typ, addr)
+
+	var v ir.Value
+	switch {
+	case typ.IsFloating():
+		v = l.b.Binary(ir.OpAdd, irType(typ), false, old, ir.FloatConst(irType(typ), float64(delta)))
+	case typ.IsPointer():
+		v = l.offset(old, delta*typ.Elem().Size())
+	default:
+		v = l.extend(l.offset(old, delta), typ)
+	}
+	l.store(typ, addr, v)
+	if postfix {
+		return old
+	}
+	return v
+}
 
+func (t *ASTExprPrefixUnary) Lower(l *Lowerer) ir.Value {
 	switch t.typ {
+	case ASTExprPrefixUnaryTypeSizeOf:
+		// The operand of sizeof isn't evaluated.
+		return ir.IntConst(int64(t.operandType.Size()))
 	case ASTExprPrefixUnaryTypeIncrement:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-			write(w, "addiu $v0, $v0, 1")
-			write(w, "sw $v0, 0($v1)")
-		case VarTypeChar:
-			write(w, "addiu $v0, $v0, 1")
-			write(w, "sb $v0, 0($v1)")
-		case VarTypeFloat:
-			write(w, "li.s $f10, 1")
-			write(w, "add.s $f0, $f0, $f10")
-			write(w, "swc1 $f0, 0($v1)")
-		case VarTypeDouble:
-			write(w, "li.d $f10, 1")
-			write(w, "add.d $f0, $f0, $f10")
-			write(w, "swc1 $f0, 4($v1)")
-			write(w, "swc1 $f1, 0($v1)")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
+		return l.increment(t.lvalue, 1, false)
 	case ASTExprPrefixUnaryTypeDecrement:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-			write(w, "addiu $v0, $v0, -1")
-			write(w, "sw $v0, 0($v1)")
-		case VarTypeChar:
-			write(w, "addiu $v0, $v0, -1")
-			write(w, "sb $v0, 0($v1)")
-		case VarTypeFloat:
-			write(w, "li.s $f10, -1")
-			write(w, "add.s $f0, $f0, $f10")
-			write(w, "swc1 $f0, 0($v1)")
-		case VarTypeDouble:
-			write(w, "li.d $f10, -1")
-			write(w, "add.d $f0, $f0, $f10")
-			write(w, "swc1 $f0, 4($v1)")
-			write(w, "swc1 $f1, 0($v1)")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
+		return l.increment(t.lvalue, -1, false)
+	case ASTExprPrefixUnaryTypeAddressOf:
+		return l.address(t.lvalue)
+	case ASTExprPrefixUnaryTypeDereference:
+		return l.load(t.Type(), t.lvalue.Lower(l))
 	case ASTExprPrefixUnaryTypeInvert:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "sltu $v0, $v0, 1")
-		case VarTypeFloat:
-			write(w, "li.s $f10, 0")
-			write(w, "c.eq.s $f0, $f10")
-			branchOnCondition(w, m)
-		case VarTypeDouble:
-			write(w, "li.d $f10, 0")
-			write(w, "c.eq.d $f0, $f10")
-			branchOnCondition(w, m)
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-		m.SetLastType(VarTypeInteger)
+		operandType := typeOf(t.lvalue).Decay()
+		v := t.lvalue.Lower(l)
+		return l.b.Binary(ir.OpEq, v.Type(), false, v, zero(operandType))
+	}
 
+	v := l.value(t.lvalue, t.Type())
+	c, constant := v.(*ir.Const)
+	switch t.typ {
 	case ASTExprPrefixUnaryTypeNegative:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "subu $v0, $zero, $v0")
-		case VarTypeFloat:
-			write(w, "li.s $f10, 0")
-			write(w, "sub.s $f0, $f10, $f0")
-		case VarTypeDouble:
-			write(w, "li.d $f10, 0")
-			write(w, "sub.d $f0, $f10, $f0")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
+		switch {
+		case constant && c.Typ.IsFloat():
+			return ir.FloatConst(c.Typ, -c.Float)
+		case constant:
+			return ir.IntConst(-c.Int)
 		}
+		return l.b.Unary(ir.OpNeg, v)
 
 	case ASTExprPrefixUnaryTypeNot:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeChar:
-			write(w, "nor $v0, $zero, $v0")
-		case VarTypeFloat, VarTypeDouble:
-			panic("not allowed operation on type float")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprPrefixUnaryTypeAddressOf:
-		m.pointerLevel += 1
-		write(w, "addu $v0, $zero, $v1")
-
-	case ASTExprPrefixUnaryTypeDereference:
-		if m.pointerLevel == 0 {
-			panic("can't deference non-pointer")
+		if constant {
+			return ir.IntConst(^c.Int)
 		}
+		return l.b.Unary(ir.OpNot, v)
 
-		m.pointerLevel -= 1
-		write(w, "addu $v1, $v0, $zero")
-
-		switch m.LastType() {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-			write(w, "lw $v0, 0($v0)")
-		case VarTypeString, VarTypeChar:
-			write(w, "lb $v0, 0($v0)")
-		case VarTypeFloat:
-			write(w, "l.s $f0, 0($v0)")
-		case VarTypeDouble:
-			write(w, "l.d $f0, 0($v0)")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	case ASTExprPrefixUnaryTypeSizeOf:
-		switch varTyp {
-		case VarTypeStruct:
-			if typ, ok := t.lvalue.(*ASTType); ok {
-				structTyp := m.StructScopes[len(m.StructScopes)-1][typ.structure.ident.ident]
-				write(w, "li $v0, %d", structTyp.structSize)
-			} else {
-				structVar := m.VariableScopes[len(m.VariableScopes)-1][t.lvalue.(*ASTBrackets).Node.(ASTExpression)[0].value.(*ASTIdentifier).ident]
-				write(w, "li $v0, %d", structVar.structure.structSize)
-			}
-		default:
-			write(w, "li $v0, %d", m.sizeOfType(varTyp, false))
-		}
-		m.SetLastType(VarTypeInteger)
 	case ASTExprPrefixUnaryTypePositive:
-	default:
-		panic("unsupported ASTExprPrefixUnaryType")
+		return v
+	}
+	panic("unsupported ASTExprPrefixUnaryType")
+}
+
+// zero returns the zero value of the scalar type typ.
+func zero(typ *Type) ir.Value {
+	if typ.IsFloating() {
+		return ir.FloatConst(irType(typ), 0)
 	}
+	return ir.IntConst(0)
 }
 
 type ASTExprSuffixUnaryType string
@@ -661,6 +347,9 @@ const (
 )
 
 type ASTExprSuffixUnary struct {
+	located
+	typed
+
 	typ    ASTExprSuffixUnaryType
 	lvalue Node
 }
@@ -672,73 +361,21 @@ func (t *ASTExprSuffixUnary) Describe(indent int) string {
 	return fmt.Sprintf("%s%s%s", genIndent(indent), t.lvalue.Describe(0), t.typ)
 }
 
-func (t *ASTExprSuffixUnary) GenerateMIPS(w io.Writer, m *MIPS) {
-	// TODO: work out actual type this probs doesnt work
-
-	t.lvalue.GenerateMIPS(w, m)
-
-	var varTyp = m.LastType()
-	// TODO: handle global variables
-
+func (t *ASTExprSuffixUnary) Lower(l *Lowerer) ir.Value {
+	// The returned value should not be incremented, only the variable.
 	switch t.typ {
 	case ASTExprSuffixUnaryTypeIncrement:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-			// The returned value should not be incremented, only the variable.
-			write(w, "addiu $v0, $v0, 1")
-			write(w, "sw $v0, 0($v1)")
-			write(w, "addiu $v0, $v0, -1")
-		case VarTypeChar:
-			write(w, "addiu $v0, $v0, 1")
-			write(w, "sb $v0, 0($v1)")
-			write(w, "addiu $v0, $v0, -1")
-		case VarTypeFloat:
-			write(w, "li.s $f10, 1")
-			write(w, "add.s $f0, $f0, $f10")
-			write(w, "swc1 $f0, 0($v1)")
-			write(w, "sub.s $f0, $f0, $f10")
-		case VarTypeDouble:
-			write(w, "li.d $f10, 1")
-			write(w, "add.d $f0, $f0, $f10")
-			write(w, "swc1 $f0, 4($v1)")
-			write(w, "swc1 $f1, 0($v1)")
-			write(w, "sub.d $f0, $f0, $f10")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
+		return l.increment(t.lvalue, 1, true)
 	case ASTExprSuffixUnaryTypeDecrement:
-		switch varTyp {
-		case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned:
-			// The returned value should not be decremented, only the variable.
-			write(w, "addiu $v0, $v0, -1")
-			write(w, "sw $v0, 0($v1)")
-			write(w, "addiu $v0, $v0, 1")
-		case VarTypeChar:
-			write(w, "addiu $v0, $v0, -1")
-			write(w, "sb $v0, 0($v1)")
-			write(w, "addiu $v0, $v0, 1")
-		case VarTypeFloat:
-			write(w, "li.s $f10, -1")
-			write(w, "add.s $f0, $f0, $f10")
-			write(w, "swc1 $f0, 0($v1)")
-			write(w, "sub.s $f0, $f0, $f10")
-		case VarTypeDouble:
-			write(w, "li.d $f10, -1")
-			write(w, "add.d $f0, $f0, $f10")
-			write(w, "swc1 $f0, 4($v1)")
-			write(w, "swc1 $f1, 0($v1)")
-			write(w, "sub.d $f0, $f0, $f10")
-		default:
-			panic("not yet implemented code gen on binary expressions for these types: VarTypeTypeName, VarTypeVoid")
-		}
-
-	default:
-		panic("unsupported ASTExprPrefixUnaryType")
+		return l.increment(t.lvalue, -1, true)
 	}
+	panic("unsupported ASTExprPrefixUnaryType")
 }
 
 type ASTIndexedExpression struct {
+	located
+	typed
+
 	lvalue Node
 	index  Node
 }
@@ -750,78 +387,26 @@ func (t *ASTIndexedExpression) Describe(indent int) string {
 	return fmt.Sprintf("%s%s[%s]", genIndent(indent), t.lvalue.Describe(0), t.index.Describe(0))
 }
 
-func (t *ASTIndexedExpression) GenerateMIPS(w io.Writer, m *MIPS) {
-	// Put index into $v0
-	t.index.GenerateMIPS(w, m)
-	if m.indexLevel == 0 {
-		write(w, "move $t7, $v0")
-	} else {
-		write(w, "move $t6, $v0")
-	}
-
-	m.indexLevel += 1
-
-	// Put lvalue into $v0
-	t.lvalue.GenerateMIPS(w, m)
-
-	// TODO: could be a prefix
-	id, ok := t.lvalue.(*ASTIdentifier)
-	if !ok {
-		// We're still indexing the array
-		return
-	}
-
-	variable := m.VariableScopes[len(m.VariableScopes)-1][id.ident]
-	var dims []int
-	if variable.decl != nil {
-		dims, _, _ = variable.decl.getArrayInfo(m)
-	}
-	//fmt.Printf("Got %v %d %v\n", id.ident, m.indexLevel, dims)
-
-	if m.indexLevel == 2 {
-		// inner most in $t6
-		// outer most in $t7
-		write(w, "li $t0, %d", dims[1])
-		write(w, "mult $t0, $t6")
-		write(w, "mflo $t0")
-		write(w, "addu $t0, $t0, $t7")
-	} else {
-		// Index now in $t0
-		write(w, "move $t0, $t7")
-	}
-
-	m.indexLevel = 0
+func (t *ASTIndexedExpression) Lower(l *Lowerer) ir.Value {
+	return l.load(t.Type(), l.address(t))
+}
 
-	if m.pointerLevel > 0 {
-		// Handle pointer indexing
-		m.pointerLevel -= 1
-	}
+// ASTCast is an explicit conversion of expr to the type named by typeName.
+type ASTCast struct {
+	located
+	typed
 
-	// TODO: alter based on type
-	indexMultiplier := 4
-	switch m.LastType() {
-	case VarTypeDouble:
-		indexMultiplier = 8
-	case VarTypeChar, VarTypeString:
-		indexMultiplier = 1
-	}
+	typeName *ASTTypeName
+	expr     Node
+}
 
-	for i := 0; i < indexMultiplier; i++ {
-		write(w, "addu $v0, $v0, $t0")
-		write(w, "addu $v1, $v1, $t0")
+func (t *ASTCast) Describe(indent int) string {
+	if t == nil {
+		return ""
 	}
+	return fmt.Sprintf("%s(%s)%s", genIndent(indent), t.typeName.Describe(0), t.expr.Describe(0))
+}
 
-	// TODO: change based on type
-	switch m.LastType() {
-	case VarTypeString, VarTypeChar:
-		write(w, "lb $v0, 0($v0)")
-		m.SetLastType(VarTypeChar)
-	case VarTypeDouble:
-		write(w, "lwc1 $f0, 4($v0)")
-		write(w, "lwc1 $f1, 0($v0)")
-	case VarTypeFloat:
-		write(w, "lwc1 $f0, 0($sp)")
-	default:
-		write(w, "lw $v0, 0($v0)")
-	}
+func (t *ASTCast) Lower(l *Lowerer) ir.Value {
+	return l.convert(t.expr.Lower(l), typeOf(t.expr).Decay(), t.Type())
 }
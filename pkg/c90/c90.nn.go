@@ -8269,8 +8269,7 @@ OUTER0:
 		case 32:
 			{
 				setParsedString(yylex, &lval.str)
-				lval.str = yylex.Text()
-				return checkIdentOrType(yylex.Text())
+				return IDENTIFIER
 			}
 		case 33:
 			{
@@ -8573,6 +8572,9 @@ OUTER0:
 			}
 		case 91:
 			{
+				if c := strayChar(yylex.Text()); c != 0 {
+					return c
+				}
 			}
 		default:
 			break OUTER0
@@ -8588,11 +8590,16 @@ func setParsedString(yylex *Lexer, lvalStr *string) {
 	return
 }
 
-func checkIdentOrType(text string) int {
-	// Check if a typedef already exists in the current scope. If
-	// it does, return TYPE_NAME instead.
-	if _, ok := typmap[text]; ok {
-		return TYPE_NAME
+// strayChar returns the token for a character which isn't part of the C
+// language, so that the parser reports it. Carriage returns are ignored so
+// that files with Windows line endings can be compiled.
+func strayChar(text string) int {
+	if text == "\r" {
+		return 0
+	}
+	if c := text[0]; c != 0 && c < 0x7f {
+		return int(c)
 	}
-	return IDENTIFIER
+	// Anything else can't be mistaken for another token.
+	return 0x7f
 }
@@ -0,0 +1,1708 @@
+package c90
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+type SymbolKind int
+
+const (
+	SymbolVariable SymbolKind = iota
+	SymbolFunction
+	SymbolEnumConstant
+	SymbolTypedef
+)
+
+// Symbol is an ordinary identifier: a variable, function, enumeration
+// constant or typedef name. Every identifier used in an expression is resolved
+// to its Symbol by the checker.
+type Symbol struct {
+	Kind SymbolKind
+	Name string
+	Type *Type
+
+	// global is set for identifiers with static storage, which are accessed
+	// through a label rather than the frame pointer.
+	global bool
+	// internal is set for functions and file scope variables declared
+	// static, which can't be referred to from other translation units. extern
+	// is set for variables declared extern, which keep any earlier linkage.
+	internal bool
+	extern   bool
+	// label is the label of a static local variable, which is assigned when
+	// it's lowered.
+	label Label
+	// definition is the declaration which reserves the storage for a
+	// variable with static storage: the one with an initializer, or else the
+	// first tentative definition. It is nil if the variable is only declared
+	// extern.
+	definition *ASTDecl
+
+	enum *ASTEnumEntry
+
+	// slot is the stack slot holding a local variable or parameter. It is
+	// assigned when the function is lowered.
+	slot *ir.Slot
+
+	// defined is set once the body of a function has been seen, so that it
+	// isn't defined twice.
+	defined bool
+}
+
+func (s *Symbol) GlobalLabel() Label {
+	switch {
+	case !s.global:
+		panic("variable not global")
+	case s.label != "":
+		return s.label
+	}
+	return Label(s.Name)
+}
+
+// typed is embedded in expression nodes to hold the type worked out by the
+// checker.
+type typed struct {
+	ctype *Type
+}
+
+func (t typed) Type() *Type {
+	if t.ctype == nil {
+		return typeInvalid
+	}
+	return t.ctype
+}
+
+// typeOf returns the type of an expression which has been checked.
+func typeOf(n Node) *Type {
+	switch t := n.(type) {
+	case interface{ Type() *Type }:
+		return t.Type()
+	case ASTExpression:
+		if len(t) > 0 {
+			return typeOf(t[len(t)-1])
+		}
+	}
+	return typeInvalid
+}
+
+// checker resolves the identifiers in a translation unit and works out the
+// type of every expression, reporting any semantic errors it finds.
+type checker struct {
+	diagnostics DiagnosticSink
+	errors      int
+
+	scopes []map[string]*Symbol
+	tags   []map[string]*StructLayout
+
+	// function is the signature of the function being checked, and params
+	// are its parameters.
+	function *Signature
+	params   []*Symbol
+
+	loops int
+	// switches are the labels of the switch statements being checked, the
+	// innermost last.
+	switches []*switchLabels
+
+	// labels are the labeled statements in the function being checked, and
+	// gotos are the goto statements, which are matched up at the end of the
+	// function as labels can be used before they are defined.
+	labels     map[string]*ASTLabeledStatement
+	labelOrder []*ASTLabeledStatement
+	gotos      []*ASTGoto
+}
+
+// switchLabels are the labels found so far in the body of a switch statement.
+type switchLabels struct {
+	// typ is the promoted type of the controlling expression, which the
+	// case labels are converted to, or nil if it isn't an integer.
+	typ         *Type
+	cases       map[int64]*ASTSwitchCase
+	defaultCase *ASTSwitchCase
+}
+
+// builtinTypedefs are the type names which are declared before the start of
+// every translation unit. va_list points to the next unnamed argument in the
+// argument area.
+var builtinTypedefs = map[string]*Type{
+	"va_list":           typeChar.PointerTo(),
+	"__builtin_va_list": typeChar.PointerTo(),
+}
+
+func newChecker(diagnostics DiagnosticSink) *checker {
+	c := &checker{diagnostics: diagnostics}
+	c.pushScope()
+	for name, typ := range builtinTypedefs {
+		c.scopes[0][name] = &Symbol{Kind: SymbolTypedef, Name: name, Type: typ}
+	}
+	return c
+}
+
+func (c *checker) errorf(n Node, format string, args ...interface{}) {
+	c.errors++
+	reportf(c.diagnostics, SeverityError, rangeOf(n), format, args...)
+}
+
+func (c *checker) warningf(n Node, format string, args ...interface{}) {
+	reportf(c.diagnostics, SeverityWarning, rangeOf(n), format, args...)
+}
+
+func (c *checker) notef(n Node, format string, args ...interface{}) {
+	reportf(c.diagnostics, SeverityNote, rangeOf(n), format, args...)
+}
+
+func (c *checker) pushScope() {
+	c.scopes = append(c.scopes, map[string]*Symbol{})
+	c.tags = append(c.tags, map[string]*StructLayout{})
+}
+
+func (c *checker) popScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.tags = c.tags[:len(c.tags)-1]
+}
+
+func (c *checker) atFileScope() bool {
+	return len(c.scopes) == 1
+}
+
+func (c *checker) lookup(name string) *Symbol {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if sym, ok := c.scopes[i][name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+func (c *checker) lookupTag(name string) *StructLayout {
+	for i := len(c.tags) - 1; i >= 0; i-- {
+		if layout, ok := c.tags[i][name]; ok {
+			return layout
+		}
+	}
+	return nil
+}
+
+// declare adds sym to the innermost scope. Functions and variables at file
+// scope may be declared more than once, in which case the symbol from the
+// first declaration is returned.
+func (c *checker) declare(n Node, sym *Symbol) *Symbol {
+	scope := c.scopes[len(c.scopes)-1]
+	prev, ok := scope[sym.Name]
+	if !ok {
+		scope[sym.Name] = sym
+		return sym
+	}
+
+	redeclarable := sym.Kind == SymbolFunction || (sym.Kind == SymbolVariable && c.atFileScope())
+	switch {
+	case prev.Kind != sym.Kind:
+		c.errorf(n, "'%s' redeclared as different kind of symbol", sym.Name)
+	case !redeclarable:
+		c.errorf(n, "redefinition of '%s'", sym.Name)
+	case !compatibleTypes(prev.Type, sym.Type):
+		c.errorf(n, "conflicting types for '%s'", sym.Name)
+	default:
+		switch {
+		case sym.internal && !prev.internal:
+			c.errorf(n, "static declaration of '%s' follows non-static declaration", sym.Name)
+		case prev.internal && !sym.internal && !sym.extern && sym.Kind == SymbolVariable:
+			c.errorf(n, "non-static declaration of '%s' follows static declaration", sym.Name)
+		}
+		if sym.Kind == SymbolFunction && sym.Type.Func.Prototype {
+			// Keep the prototype rather than an earlier `int f();`.
+			prev.Type = sym.Type
+		}
+		return prev
+	}
+	return sym
+}
+
+// compatibleTypes is like Equal, except a function declared without a
+// prototype is compatible with any function returning the same type.
+func compatibleTypes(a, b *Type) bool {
+	if a.IsFunction() && b.IsFunction() && (!a.Func.Prototype || !b.Func.Prototype) {
+		return a.Func.Return.Equal(b.Func.Return)
+	}
+	return a.Equal(b)
+}
+
+func (c *checker) checkTranslationUnit(unit ASTTranslationUnit) {
+	for _, node := range unit {
+		switch t := node.(type) {
+		case *ASTFunction:
+			c.checkFunction(t)
+		case ASTDeclaratorList:
+			c.checkDeclarations(t)
+		default:
+			c.errorf(node, "internal compiler error: unexpected %T at file scope", node)
+		}
+	}
+}
+
+// resolveType returns the type named by declaration specifiers, declaring any
+// structure, union or enumeration they define.
+func (c *checker) resolveType(t *ASTType) *Type {
+	if t.ctype != nil {
+		return t.ctype
+	}
+
+	var typ *Type
+	switch {
+	case t.enum != nil:
+		typ = c.checkEnum(t.enum)
+	case t.structure != nil:
+		layout := c.checkStruct(t.structure)
+		kind := TypeStruct
+		if layout.Union {
+			kind = TypeUnion
+		}
+		typ = &Type{Kind: kind, Struct: layout}
+	case t.typName != "":
+		sym := c.lookup(t.typName)
+		if sym == nil || sym.Kind != SymbolTypedef {
+			c.errorf(t, "unknown type name '%s'", t.typName)
+			typ = typeInvalid
+		} else {
+			typ = sym.Type
+		}
+	default:
+		typ = &Type{Kind: t.kind}
+	}
+
+	typ = typ.Qualified(t.qualifiers)
+	t.ctype = typ
+	return typ
+}
+
+// checkEnum declares the constants of an enumeration and returns its type.
+func (c *checker) checkEnum(enum *ASTEnum) *Type {
+	typ := &Type{Kind: TypeEnum}
+	if enum.ident != nil {
+		typ.Tag = enum.ident.ident
+	}
+	var value int64
+	for _, entry := range enum.entries {
+		if entry.offset == 0 {
+			// Entries without a value share the node of the last explicit
+			// value, which only needs checking once.
+			value = c.enumValue(entry)
+		}
+		entry.intValue = integerConstant(typeInt, value+int64(entry.offset)).intValue
+		c.declare(entry.ident, &Symbol{
+			Kind:   SymbolEnumConstant,
+			Name:   entry.ident.ident,
+			Type:   typeInt,
+			global: c.atFileScope(),
+			enum:   entry,
+		})
+	}
+	return typ
+}
+
+// enumValue returns the value given to an enumeration constant.
+func (c *checker) enumValue(entry *ASTEnumEntry) int64 {
+	typ := c.checkExpr(entry.value)
+	if !typ.IsValid() {
+		return 0
+	}
+	value, err := evaluateConstant(entry.value)
+	if !typ.IsInteger() || err != nil {
+		c.errorf(entry.value, "enumerator value for '%s' is not an integer constant", entry.ident.ident)
+		return 0
+	}
+	return value.intValue
+}
+
+// checkStruct returns the layout of a structure, working it out if this is
+// the definition.
+func (c *checker) checkStruct(s *ASTStruct) *StructLayout {
+	name := s.Name()
+	tags := c.tags[len(c.tags)-1]
+
+	if s.elements == nil {
+		if layout := c.lookupTag(name); layout != nil {
+			if layout.Union != s.union {
+				c.errorf(s, "'%s' defined as wrong kind of tag", name)
+			}
+			return layout
+		}
+		layout := &StructLayout{Name: name, Union: s.union}
+		tags[name] = layout
+		return layout
+	}
+
+	layout := tags[name]
+	switch {
+	case name == "" || layout == nil:
+		layout = &StructLayout{Name: name, Union: s.union}
+		if name != "" {
+			tags[name] = layout
+		}
+	case layout.Union != s.union:
+		c.errorf(s, "'%s' defined as wrong kind of tag", name)
+		layout = &StructLayout{Name: name, Union: s.union}
+	case layout.Complete:
+		c.errorf(s, "redefinition of '%s %s'", s.keyword(), name)
+		layout = &StructLayout{Name: name, Union: s.union}
+	}
+
+	for _, declarators := range s.elements {
+		for _, member := range declarators {
+			decl := member.decl
+			typ := c.declaratorType(c.resolveType(decl.typ), decl.decl)
+			ident := decl.decl.Identifier().ident
+			switch {
+			case !typ.IsValid():
+			case typ.IsFunction():
+				c.errorf(member, "field '%s' declared as a function", ident)
+			case !typ.IsComplete():
+				c.errorf(member, "field '%s' has incomplete type", ident)
+			case layout.Field(ident) != nil:
+				c.errorf(member, "duplicate member '%s'", ident)
+			default:
+				layout.AddField(ident, typ)
+			}
+		}
+	}
+	layout.complete()
+	return layout
+}
+
+// declaratorType applies the pointers, arrays and function parameters of a
+// declarator to the type given by its declaration specifiers.
+func (c *checker) declaratorType(typ *Type, d *ASTDirectDeclarator) *Type {
+	if !typ.IsValid() {
+		return typ
+	}
+	for ; d != nil; d = d.decl {
+		for i := 0; i < d.pointerDepth; i++ {
+			typ = typ.PointerTo()
+			if i < len(d.pointerQualifiers) {
+				typ = typ.Qualified(d.pointerQualifiers[i])
+			}
+		}
+
+		switch {
+		case d.array != nil:
+			if typ.IsFunction() {
+				c.errorf(d, "declaration of array of functions")
+				return typeInvalid
+			}
+			if !typ.IsComplete() {
+				c.errorf(d, "array type has incomplete element type '%s'", typ)
+				return typeInvalid
+			}
+			size, ok := c.arraySize(d.array)
+			if !ok {
+				return typeInvalid
+			}
+			typ = typ.ArrayOf(size)
+		case d.parameters != nil:
+			if typ.IsArray() || typ.IsFunction() {
+				c.errorf(d, "function cannot return '%s'", typ)
+				return typeInvalid
+			}
+			typ = FunctionReturning(c.signature(typ, d.parameters))
+		}
+	}
+	return typ
+}
+
+// arraySize returns the number of elements given in an array declarator, or
+// 0 if it isn't given.
+func (c *checker) arraySize(array *ASTArray) (int, bool) {
+	if array.sizeConstExpr == nil {
+		return 0, true
+	}
+	typ := c.checkExpr(array.sizeConstExpr)
+	switch {
+	case !typ.IsValid():
+		return 0, false
+	case !typ.IsInteger():
+		c.errorf(array.sizeConstExpr, "size of array has non-integer type '%s'", typ)
+		return 0, false
+	}
+	size, err := evaluateConstant(array.sizeConstExpr)
+	switch {
+	case err != nil:
+		c.errorf(array.sizeConstExpr, "size of array is not an integer constant: %v", err)
+		return 0, false
+	case size.intValue < 0:
+		c.errorf(array.sizeConstExpr, "size of array is negative")
+		return 0, false
+	}
+	return int(size.intValue), true
+}
+
+func (c *checker) signature(ret *Type, params *ASTParameterList) *Signature {
+	sig := &Signature{
+		Return:    ret,
+		Prototype: len(params.li) > 0,
+		Variadic:  params.elipsis,
+	}
+	for _, param := range params.li {
+		typ := c.resolveType(param.specifier.(*ASTType))
+		decl, _ := param.declarator.(*ASTDirectDeclarator)
+		if decl == nil && typ.IsVoid() && len(params.li) == 1 {
+			// f(void) takes no arguments.
+			break
+		}
+		typ = c.declaratorType(typ, decl)
+
+		// Parameters declared as arrays or functions are really pointers.
+		switch {
+		case typ.IsArray(), typ.IsFunction():
+			typ = typ.Decay()
+		case typ.IsVoid():
+			c.errorf(param, "parameter has incomplete type 'void'")
+			typ = typeInvalid
+		}
+		sig.Params = append(sig.Params, typ)
+	}
+	return sig
+}
+
+func (c *checker) checkDeclarations(list ASTDeclaratorList) {
+	for _, decl := range list {
+		c.checkDecl(decl)
+	}
+}
+
+func (c *checker) checkDecl(t *ASTDecl) {
+	typ := c.resolveType(t.typ)
+	if t.decl == nil {
+		// Only declares a structure or enumeration.
+		return
+	}
+
+	typ = c.declaratorType(typ, t.decl)
+	name := t.decl.Identifier().ident
+
+	switch {
+	case t.storage == StorageClassTypedef:
+		t.sym = c.declare(t, &Symbol{Kind: SymbolTypedef, Name: name, Type: typ})
+		return
+	case typ.IsFunction():
+		if t.initVal != nil {
+			c.errorf(t, "function '%s' is initialized like a variable", name)
+		}
+		if t.storage == StorageClassStatic && !c.atFileScope() {
+			c.errorf(t, "invalid storage class for function '%s'", name)
+		}
+		t.sym = c.declare(t, &Symbol{
+			Kind:     SymbolFunction,
+			Name:     name,
+			Type:     typ,
+			global:   true,
+			internal: t.storage == StorageClassStatic,
+		})
+		return
+	}
+
+	if c.atFileScope() && (t.storage == StorageClassAuto || t.storage == StorageClassRegister) {
+		c.errorf(t, "file-scope declaration of '%s' specifies '%s'", name, t.storage)
+	}
+
+	extern := t.storage == StorageClassExtern
+	if extern && !c.atFileScope() {
+		if t.initVal != nil {
+			c.errorf(t, "'%s' has both 'extern' and initializer", name)
+		}
+		// Refer to the variable at file scope, if it has been declared.
+		sym := &Symbol{Kind: SymbolVariable, Name: name, Type: typ, global: true, extern: true}
+		if prev := c.scopes[0][name]; prev != nil && prev.Kind == SymbolVariable {
+			if !compatibleTypes(prev.Type, typ) {
+				c.errorf(t, "conflicting types for '%s'", name)
+			}
+			sym = prev
+		}
+		t.sym = c.declare(t, sym)
+		return
+	}
+
+	global := c.atFileScope() || t.storage == StorageClassStatic
+	t.sym = c.declare(t, &Symbol{
+		Kind:     SymbolVariable,
+		Name:     name,
+		Type:     typ,
+		global:   global,
+		internal: c.atFileScope() && t.storage == StorageClassStatic,
+		extern:   extern,
+	})
+	if extern && t.initVal != nil {
+		c.warningf(t, "'%s' initialized and declared 'extern'", name)
+	}
+	if global && (!extern || t.initVal != nil) {
+		switch {
+		case t.initVal != nil && t.sym.definition != nil && t.sym.definition.initVal != nil:
+			c.errorf(t, "redefinition of '%s'", name)
+		case t.initVal != nil || t.sym.definition == nil:
+			t.sym.definition = t
+		}
+	}
+
+	if t.initVal != nil {
+		t.initVal = c.checkInitializer(typ, t.initVal)
+		if typ.IsArray() && typ.Len == 0 {
+			// The size of an array can be given by its initializer.
+			typ = typ.Elem().ArrayOf(initializerLength(t.initVal))
+			t.sym.Type = typ
+		}
+	}
+
+	switch {
+	case !typ.IsValid():
+	case typ.IsVoid():
+		c.errorf(t, "variable '%s' declared void", name)
+	case !typ.IsComplete() && t.storage != StorageClassExtern:
+		c.errorf(t, "storage size of '%s' isn't known", name)
+	}
+}
+
+// checkInitializer checks the initializer of an object of type typ. It
+// returns the initializer with any braces which were left out put back, so
+// an aggregate is initialized by a list with an entry for each of its leading
+// members, and a scalar by an expression.
+func (c *checker) checkInitializer(typ *Type, init Node) Node {
+	list, ok := init.(ASTInitializerList)
+	if !ok {
+		c.checkInitializerValue(typ, init, c.checkExpr(init))
+		return init
+	}
+	if len(list) == 1 && isStringInitializer(typ, list[0]) {
+		// The string initializing an array of characters may be in braces.
+		return c.checkInitializer(typ, list[0])
+	}
+
+	elements := &initializerElements{list: list}
+	var braced Node
+	if typ.IsArray() || typ.IsStruct() {
+		braced = c.checkMembers(typ, elements)
+	} else {
+		braced = c.checkElement(typ, elements)
+	}
+
+	if elements.next < len(list) {
+		what := "scalar"
+		switch {
+		case typ.IsArray():
+			what = "array"
+		case typ.IsStruct():
+			what = typ.Struct.keyword()
+		}
+		c.warningf(list[elements.next], "excess elements in %s initializer", what)
+		for ; elements.next < len(list); elements.advance() {
+			if _, isList := list[elements.next].(ASTInitializerList); !isList {
+				elements.valueType(c)
+			}
+		}
+	}
+	return braced
+}
+
+// initializerElements is an initializer list which is being matched up with
+// the members of the object it initializes.
+type initializerElements struct {
+	list ASTInitializerList
+	next int
+
+	// checked is the type of the next element, once it has been checked.
+	checked *Type
+}
+
+func (e *initializerElements) advance() {
+	e.next++
+	e.checked = nil
+}
+
+// valueType checks the next element, which is an expression, and returns its
+// type.
+func (e *initializerElements) valueType(c *checker) *Type {
+	if e.checked == nil {
+		e.checked = c.checkExpr(e.list[e.next])
+	}
+	return e.checked
+}
+
+// checkMembers takes the initializers of the members of the aggregate typ from
+// elements, until every member has one or there are no elements left.
+func (c *checker) checkMembers(typ *Type, elements *initializerElements) ASTInitializerList {
+	var fields []*StructField
+	if typ.IsStruct() {
+		fields = typ.Struct.InitializedFields()
+	}
+
+	var braced ASTInitializerList
+	for i := 0; elements.next < len(elements.list); i++ {
+		var member *Type
+		if typ.IsArray() {
+			if typ.Len != 0 && i == typ.Len {
+				break
+			}
+			member = typ.Elem()
+		} else {
+			if i == len(fields) {
+				break
+			}
+			member = fields[i].Type
+		}
+		braced = append(braced, c.checkElement(member, elements))
+	}
+	return braced
+}
+
+// checkElement checks the next of elements, which initializes an object of
+// type typ. If typ is an aggregate and the element isn't in braces, the
+// aggregate takes as many elements as it has members instead.
+func (c *checker) checkElement(typ *Type, elements *initializerElements) Node {
+	element := elements.list[elements.next]
+	if _, isList := element.(ASTInitializerList); isList {
+		elements.advance()
+		return c.checkInitializer(typ, element)
+	}
+
+	valueType := elements.valueType(c)
+	if (typ.IsArray() || typ.IsStruct()) && !isStringInitializer(typ, element) && !typ.Equal(valueType) {
+		return c.checkMembers(typ, elements)
+	}
+	elements.advance()
+	c.checkInitializerValue(typ, element, valueType)
+	return element
+}
+
+// checkInitializerValue checks that the expression init, of type valueType,
+// can initialize an object of type typ.
+func (c *checker) checkInitializerValue(typ *Type, init Node, valueType *Type) {
+	if typ.IsArray() || typ.IsStruct() {
+		switch {
+		case isStringInitializer(typ, init):
+			// Only the terminating null can be left out of the array.
+			str := unwrapExpr(init).(*ASTStringLiteral)
+			if typ.Len != 0 && len(str.data) > typ.Len {
+				c.warningf(init, "initializer-string for array of '%s' is too long", typ.Elem())
+			}
+		case !typ.Equal(valueType):
+			c.errorf(init, "invalid initializer")
+		}
+		return
+	}
+	c.checkAssignment(init, typ, valueType, "initialization")
+}
+
+// initializerLength returns the number of elements in an array which is
+// given its size by the braced initializer init.
+func initializerLength(init Node) int {
+	if list, ok := init.(ASTInitializerList); ok {
+		return len(list)
+	}
+	if str, ok := unwrapExpr(init).(*ASTStringLiteral); ok {
+		// The terminating null is included.
+		return len(str.data) + 1
+	}
+	return 0
+}
+
+// isStringInitializer reports whether init is a string literal initializing
+// an array of characters.
+func isStringInitializer(typ *Type, init Node) bool {
+	_, ok := unwrapExpr(init).(*ASTStringLiteral)
+	if !ok || !typ.IsArray() {
+		return false
+	}
+	switch typ.Elem().Kind {
+	case TypeChar, TypeSignedChar, TypeUnsignedChar:
+		return true
+	}
+	return false
+}
+
+func (c *checker) checkFunction(f *ASTFunction) {
+	typ := c.declaratorType(c.resolveType(f.typ), f.decl)
+	name := f.decl.Identifier().ident
+	if !typ.IsValid() {
+		return
+	}
+	if !typ.IsFunction() {
+		c.errorf(f.decl, "expected a function declarator for '%s'", name)
+		return
+	}
+
+	f.sym = c.declare(f, &Symbol{
+		Kind:     SymbolFunction,
+		Name:     name,
+		Type:     typ,
+		global:   true,
+		internal: f.storage == StorageClassStatic,
+	})
+	if f.sym.defined {
+		c.errorf(f.decl, "redefinition of '%s'", name)
+	}
+	f.sym.defined = true
+
+	// The parameters share a scope with the outermost block of the body.
+	c.pushScope()
+	defer c.popScope()
+
+	f.params = nil
+	if params := f.parameters(); params != nil {
+		for i, paramType := range typ.Func.Params {
+			sym := &Symbol{Kind: SymbolVariable, Type: paramType}
+			if decl, ok := params.li[i].declarator.(*ASTDirectDeclarator); ok && decl.Identifier() != nil {
+				sym.Name = decl.Identifier().ident
+				sym = c.declare(params.li[i], sym)
+			}
+			f.params = append(f.params, sym)
+		}
+	}
+
+	c.function, c.params = typ.Func, f.params
+	defer func() { c.function, c.params = nil, nil }()
+
+	c.labels = map[string]*ASTLabeledStatement{}
+	c.labelOrder = nil
+	c.gotos = nil
+
+	if body, ok := f.body.(*ASTScope); ok {
+		c.checkStatement(body.body)
+	} else {
+		c.checkStatement(f.body)
+	}
+
+	used := map[string]bool{}
+	for _, g := range c.gotos {
+		used[g.label.ident] = true
+		if c.labels[g.label.ident] == nil {
+			c.errorf(g.label, "label '%s' used but not defined", g.label.ident)
+		}
+	}
+	for _, label := range c.labelOrder {
+		if name := label.ident.ident; !used[name] {
+			c.warningf(label.ident, "label '%s' defined but not used", name)
+		}
+	}
+}
+
+func (c *checker) checkStatement(n Node) {
+	switch t := n.(type) {
+	case nil:
+	case *ASTScope:
+		c.pushScope()
+		c.checkStatement(t.body)
+		c.popScope()
+	case *ASTDeclarationStatementLists:
+		c.checkDeclarations(t.decls)
+		c.checkStatement(t.stmts)
+	case ASTStatementList:
+		for _, stmt := range t {
+			c.checkStatement(stmt)
+		}
+	case ASTDeclaratorList:
+		c.checkDeclarations(t)
+	case *ASTWhileLoop:
+		c.checkCondition(t.condition)
+		c.checkLoopBody(t.body)
+	case *ASTDoWhileLoop:
+		c.checkLoopBody(t.body)
+		c.checkCondition(t.condition)
+	case *ASTForLoop:
+		if t.initialiser != nil {
+			c.checkExpr(t.initialiser)
+		}
+		if t.condition != nil {
+			c.checkCondition(t.condition)
+		}
+		if t.postIterationExpr != nil {
+			c.checkExpr(t.postIterationExpr)
+		}
+		c.checkLoopBody(t.body)
+	case *ASTIfStatement:
+		if t.ternary {
+			c.checkExpr(t)
+			return
+		}
+		c.checkCondition(t.condition)
+		c.checkStatement(t.body)
+		c.checkStatement(t.elseBody)
+	case *ASTSwitchStatement:
+		labels := &switchLabels{cases: map[int64]*ASTSwitchCase{}}
+		typ := c.checkExpr(t.switchOn)
+		switch {
+		case typ.IsInteger():
+			labels.typ = promote(typ)
+		case typ.IsValid():
+			c.errorf(t.switchOn, "switch quantity not an integer")
+		}
+		c.switches = append(c.switches, labels)
+		c.checkStatement(t.body)
+		c.switches = c.switches[:len(c.switches)-1]
+	case *ASTSwitchCase:
+		if len(c.switches) == 0 {
+			if t.defaultCase {
+				c.errorf(t, "'default' label not within a switch statement")
+			} else {
+				c.errorf(t, "case label not within a switch statement")
+			}
+		}
+		if t.defaultCase {
+			c.checkDefaultLabel(t)
+		} else {
+			c.checkCaseLabel(t)
+		}
+		c.checkStatement(t.body)
+	case *ASTReturn:
+		c.checkReturn(t)
+	case *ASTContinue:
+		if c.loops == 0 {
+			c.errorf(t, "continue statement not within a loop")
+		}
+	case *ASTBreak:
+		if c.loops == 0 && len(c.switches) == 0 {
+			c.errorf(t, "break statement not within loop or switch")
+		}
+	case *ASTGoto:
+		c.gotos = append(c.gotos, t)
+	case *ASTLabeledStatement:
+		// Labels have function scope, so they are visible before they are
+		// defined and from outside the block they are in.
+		if c.labels[t.ident.ident] != nil {
+			c.errorf(t.ident, "duplicate label '%s'", t.ident.ident)
+		} else {
+			c.labels[t.ident.ident] = t
+			c.labelOrder = append(c.labelOrder, t)
+		}
+		c.checkStatement(t.stmt)
+	default:
+		c.checkExpr(n)
+	}
+}
+
+// checkCaseLabel works out the value of the label of a case, and checks that
+// no other case in the switch has the same value.
+func (c *checker) checkCaseLabel(t *ASTSwitchCase) {
+	typ := c.checkExpr(t.caseVal)
+	if !typ.IsValid() {
+		return
+	}
+	value, err := evaluateConstant(t.caseVal)
+	if !typ.IsInteger() || err != nil {
+		c.errorf(t.caseVal, "case label does not reduce to an integer constant")
+		return
+	}
+	t.value = value
+
+	if len(c.switches) == 0 || c.switches[len(c.switches)-1].typ == nil {
+		return
+	}
+	labels := c.switches[len(c.switches)-1]
+	// The labels are compared with the controlling expression once they
+	// have been converted to its type, so -1 and 0xffffffff are the same
+	// case when switching on an unsigned int.
+	key := value.convert(labels.typ).intValue
+	if prev := labels.cases[key]; prev != nil {
+		c.errorf(t.caseVal, "duplicate case value")
+		c.notef(prev.caseVal, "previously used here")
+		return
+	}
+	labels.cases[key] = t
+}
+
+// checkDefaultLabel checks that t is the only default label in its switch.
+func (c *checker) checkDefaultLabel(t *ASTSwitchCase) {
+	if len(c.switches) == 0 {
+		return
+	}
+	labels := c.switches[len(c.switches)-1]
+	if labels.defaultCase != nil {
+		c.errorf(t, "multiple default labels in one switch")
+		c.notef(labels.defaultCase, "this is the first default label")
+		return
+	}
+	labels.defaultCase = t
+}
+
+func (c *checker) checkLoopBody(body Node) {
+	c.loops++
+	c.checkStatement(body)
+	c.loops--
+}
+
+// checkCondition checks an expression which is compared against zero.
+func (c *checker) checkCondition(n Node) {
+	typ := c.checkExpr(n)
+	if typ.IsValid() && !typ.IsScalar() && !typ.IsArray() {
+		c.errorf(n, "used '%s' where a scalar is required", typ)
+	}
+}
+
+func (c *checker) checkReturn(t *ASTReturn) {
+	ret := c.function.Return
+	t.returnType = ret
+	if t.returnVal == nil {
+		if !ret.IsVoid() {
+			c.warningf(t, "'return' with no value, in function returning non-void")
+		}
+		return
+	}
+
+	typ := c.checkExpr(t.returnVal)
+	if ret.IsVoid() {
+		if !typ.IsVoid() {
+			c.warningf(t, "'return' with a value, in function returning void")
+		}
+		return
+	}
+	c.checkAssignment(t.returnVal, ret, typ, "return")
+}
+
+// checkAssignment checks that a value of type from can be assigned to an
+// object of type to. what describes the assignment in diagnostics.
+func (c *checker) checkAssignment(value Node, to, from *Type, what string) {
+	if !to.IsValid() || !from.IsValid() {
+		return
+	}
+	from = from.Decay()
+
+	switch {
+	case from.IsVoid():
+		c.errorf(value, "void value not ignored as it ought to be")
+	case to.IsArithmetic() && from.IsArithmetic():
+	case to.IsPointer() && from.IsPointer():
+		toElem, fromElem := to.Elem(), from.Elem()
+		switch {
+		case !toElem.IsVoid() && !fromElem.IsVoid() && !toElem.Equal(fromElem):
+			c.warningf(value, "%s from incompatible pointer type '%s'", what, from)
+		case fromElem.Qualifiers&^toElem.Qualifiers != 0:
+			discarded := fromElem.Qualifiers &^ toElem.Qualifiers
+			c.warningf(value, "%s discards '%s' qualifier from pointer target type", what, discarded)
+		}
+	case to.IsPointer() && from.IsInteger():
+		if !isNullPointerConstant(value) {
+			c.warningf(value, "%s makes pointer from integer without a cast", what)
+		}
+	case to.IsInteger() && from.IsPointer():
+		c.warningf(value, "%s makes integer from pointer without a cast", what)
+	case to.IsStruct() && to.Equal(from):
+	default:
+		c.errorf(value, "incompatible types in %s: expected '%s' but found '%s'", what, to, from)
+	}
+}
+
+// unwrapExpr removes the brackets and single element expression lists around
+// an expression.
+func unwrapExpr(n Node) Node {
+	for {
+		switch t := n.(type) {
+		case *ASTBrackets:
+			n = t.Node
+		case ASTExpression:
+			if len(t) != 1 {
+				return n
+			}
+			n = t[0]
+		case *ASTAssignment:
+			if !t.tmpAssign {
+				return n
+			}
+			n = t.value
+		default:
+			return n
+		}
+	}
+}
+
+func isNullPointerConstant(n Node) bool {
+	n = unwrapExpr(n)
+	if cast, ok := n.(*ASTCast); ok && cast.Type().IsPointer() && cast.Type().Elem().IsVoid() {
+		// (void *)0 is also a null pointer constant.
+		n = unwrapExpr(cast.expr)
+	}
+	constant, ok := n.(*ASTConstant)
+	return ok && constant.ctype.IsInteger() && constant.intValue == 0
+}
+
+// isLvalue reports whether n designates an object, and so can have its
+// address taken.
+func isLvalue(n Node) bool {
+	switch t := unwrapExpr(n).(type) {
+	case *ASTIdentifier:
+		return t.sym != nil && t.sym.Kind == SymbolVariable
+	case *ASTStringLiteral, *ASTIndexedExpression:
+		return true
+	case *ASTExprPrefixUnary:
+		return t.typ == ASTExprPrefixUnaryTypeDereference
+	case *ASTStructElement:
+		return t.pointer || isLvalue(t.structImp)
+	}
+	return false
+}
+
+// checkModifiable reports an error if n isn't an lvalue which can be
+// assigned to.
+func (c *checker) checkModifiable(n Node, typ *Type, what string) bool {
+	if !typ.IsValid() {
+		return false
+	}
+	if !isLvalue(n) || typ.IsArray() || typ.IsFunction() {
+		c.errorf(n, "lvalue required as %s", what)
+		return false
+	}
+	return true
+}
+
+// checkReadOnly reports an error if the lvalue n, of type typ, is const and
+// so can't be modified by operation.
+func (c *checker) checkReadOnly(n Node, typ *Type, operation string) bool {
+	if !typ.IsConst() {
+		return true
+	}
+	switch t := unwrapExpr(n).(type) {
+	case *ASTIdentifier:
+		c.errorf(n, "%s of read-only variable '%s'", operation, t.ident)
+	case *ASTStructElement:
+		if t.field != nil && t.field.Type.IsConst() {
+			c.errorf(n, "%s of read-only member '%s'", operation, t.ident)
+		} else {
+			c.errorf(n, "%s of member '%s' in read-only object", operation, t.ident)
+		}
+	default:
+		c.errorf(n, "%s of read-only location", operation)
+	}
+	return false
+}
+
+// checkExpr works out the type of an expression, annotating every node within
+// it with its type.
+func (c *checker) checkExpr(n Node) *Type {
+	switch t := n.(type) {
+	case nil:
+		return typeInvalid
+	case ASTExpression:
+		typ := typeInvalid
+		for _, assignment := range t {
+			typ = c.checkExpr(assignment)
+		}
+		return typ
+	case *ASTAssignment:
+		t.ctype = c.checkAssignmentExpr(t)
+		return t.ctype
+	case *ASTBrackets:
+		t.ctype = c.checkExpr(t.Node)
+		return t.ctype
+	case *ASTIdentifier:
+		t.ctype = c.checkIdentifier(t)
+		return t.ctype
+	case *ASTConstant:
+		t.ctype = c.checkConstant(t)
+		return t.ctype
+	case *ASTStringLiteral:
+		value, err := strconv.Unquote(t.value)
+		if err != nil {
+			c.errorf(t, "invalid string literal: %v", err)
+			t.ctype = typeInvalid
+			return t.ctype
+		}
+		t.data = []byte(value)
+		t.ctype = typeChar.ArrayOf(len(t.data) + 1)
+		return t.ctype
+	case *ASTIndexedExpression:
+		t.ctype = c.checkIndex(t)
+		return t.ctype
+	case *ASTFunctionCall:
+		t.ctype = c.checkCall(t)
+		return t.ctype
+	case *ASTStructElement:
+		t.ctype = c.checkMember(t)
+		return t.ctype
+	case *ASTExprSuffixUnary:
+		typ := c.checkExpr(t.lvalue)
+		t.ctype = c.checkIncrement(t.lvalue, typ, string(t.typ))
+		return t.ctype
+	case *ASTExprPrefixUnary:
+		t.ctype = c.checkUnary(t)
+		return t.ctype
+	case *ASTExprBinary:
+		t.ctype = c.checkBinary(t)
+		return t.ctype
+	case *ASTIfStatement:
+		t.ctype = c.checkConditional(t)
+		return t.ctype
+	case *ASTCast:
+		t.ctype = c.checkCast(t)
+		return t.ctype
+	case *ASTVaStart:
+		t.ctype = c.checkVaStart(t)
+		return t.ctype
+	case *ASTVaArg:
+		t.ctype = c.checkVaArg(t)
+		return t.ctype
+	case *ASTVaEnd:
+		c.checkVaList(t.ap, "va_end")
+		t.ctype = typeVoid
+		return t.ctype
+	}
+	c.errorf(n, "internal compiler error: unexpected %T in expression", n)
+	return typeInvalid
+}
+
+func (c *checker) checkIdentifier(t *ASTIdentifier) *Type {
+	sym := c.lookup(t.ident)
+	switch {
+	case sym == nil:
+		c.errorf(t, "'%s' undeclared", t.ident)
+		return typeInvalid
+	case sym.Kind == SymbolTypedef:
+		c.errorf(t, "unexpected type name '%s': expected expression", t.ident)
+		return typeInvalid
+	}
+	t.sym = sym
+	return sym.Type
+}
+
+// checkConstant works out the type and value of a numeric or character
+// constant.
+func (c *checker) checkConstant(t *ASTConstant) *Type {
+	value := t.value
+	if value[0] == '\'' {
+		if value == `'\0'` {
+			// Handle special case as this is different in Go to C.
+			value = `'\000'`
+		}
+		unquoted, err := strconv.Unquote(value)
+		if err != nil || len(unquoted) == 0 {
+			c.errorf(t, "invalid character constant %s", t.value)
+			return typeInvalid
+		}
+		// Character constants have type int in C.
+		t.intValue = int64(int8(unquoted[0]))
+		return typeInt
+	}
+
+	hex := strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X")
+	last := value[len(value)-1]
+	if (last == 'f' || last == 'F') && !hex {
+		// Appendix A, pg. 194 states that all numbers are doubles (or long
+		// doubles) unless suffixed with f or F, which implies they are floats.
+		f32, err := strconv.ParseFloat(value[:len(value)-1], 32)
+		if err != nil {
+			c.errorf(t, "invalid floating point constant %s", t.value)
+			return typeInvalid
+		}
+		t.floatValue = f32
+		return typeFloat
+	}
+
+	unsigned, long := false, false
+	digits := value
+	for len(digits) > 0 {
+		switch digits[len(digits)-1] {
+		case 'u', 'U':
+			unsigned = true
+		case 'l', 'L':
+			long = true
+		default:
+			goto parse
+		}
+		digits = digits[:len(digits)-1]
+	}
+parse:
+	if i, err := strconv.ParseUint(digits, 0, 64); err == nil {
+		if len(digits) > 1 && digits[0] == '0' && digits[1] != 'x' && digits[1] != 'X' {
+			// ParseUint would accept 0o and 0b prefixes, but only the
+			// leading zero of an octal constant is needed.
+			if _, err := strconv.ParseUint(digits[1:], 8, 64); err != nil {
+				c.errorf(t, "invalid integer constant %s", t.value)
+				return typeInvalid
+			}
+		}
+		if i > 0xFFFFFFFF {
+			c.warningf(t, "integer constant is too large for its type")
+		}
+		t.intValue = int64(uint32(i))
+
+		// The type is the first in the list for its form which can hold the
+		// value. int and long are the same size, so this only depends on
+		// whether the value fits in an int.
+		decimal := digits[0] != '0'
+		fits := i <= 0x7FFFFFFF
+		switch {
+		case unsigned && long:
+			return typeUnsignedLong
+		case unsigned:
+			return typeUnsignedInt
+		case long && fits:
+			return typeLong
+		case long, !fits && decimal:
+			// Decimal constants skip unsigned int.
+			return typeUnsignedLong
+		case !fits:
+			return typeUnsignedInt
+		}
+		return typeInt
+	}
+
+	f64, err := strconv.ParseFloat(value, 64)
+	if err != nil || unsigned || long {
+		c.errorf(t, "invalid numeric constant %s", t.value)
+		return typeInvalid
+	}
+	t.floatValue = f64
+	return typeDouble
+}
+
+func (c *checker) checkAssignmentExpr(t *ASTAssignment) *Type {
+	if t.tmpAssign {
+		return c.checkExpr(t.value)
+	}
+
+	lhs := c.checkExpr(t.lval)
+	rhs := c.checkExpr(t.value)
+	if !c.checkModifiable(t.lval, lhs, "left operand of assignment") || !rhs.IsValid() {
+		return typeInvalid
+	}
+	c.checkReadOnly(t.lval, lhs, "assignment")
+
+	switch t.operator {
+	case ASTAssignmentOperatorEquals:
+		c.checkAssignment(t.value, lhs, rhs, "assignment")
+	case ASTAssignmentOperatorAddEquals, ASTAssignmentOperatorSubEquals:
+		if lhs.IsPointer() && rhs.IsInteger() {
+			break
+		}
+		fallthrough
+	case ASTAssignmentOperatorMulEquals, ASTAssignmentOperatorDivEquals:
+		if !lhs.IsArithmetic() || !rhs.IsArithmetic() {
+			c.errorf(t, "invalid operands to %s (have '%s' and '%s')", t.operator, lhs, rhs)
+		}
+	default:
+		if !lhs.IsInteger() || !rhs.IsInteger() {
+			c.errorf(t, "invalid operands to %s (have '%s' and '%s')", t.operator, lhs, rhs)
+		}
+	}
+	return lhs.Unqualified()
+}
+
+func (c *checker) checkIndex(t *ASTIndexedExpression) *Type {
+	base := c.checkExpr(t.lvalue)
+	index := c.checkExpr(t.index)
+	if !base.IsValid() || !index.IsValid() {
+		return typeInvalid
+	}
+
+	if base.IsInteger() && (index.IsPointer() || index.IsArray()) {
+		// a[i] is the same as i[a].
+		t.lvalue, t.index = t.index, t.lvalue
+		base, index = index, base
+	}
+
+	if !base.IsPointer() && !base.IsArray() {
+		c.errorf(t, "subscripted value is neither array nor pointer")
+		return typeInvalid
+	}
+	if !index.IsInteger() {
+		c.errorf(t.index, "array subscript is not an integer")
+		return typeInvalid
+	}
+
+	elem := base.Elem()
+	if !elem.IsComplete() {
+		c.errorf(t, "subscripting a pointer to incomplete type '%s'", elem)
+		return typeInvalid
+	}
+	return elem
+}
+
+func (c *checker) checkCall(t *ASTFunctionCall) *Type {
+	name := t.FunctionName()
+	if ident, ok := t.function.(*ASTIdentifier); ok && c.lookup(ident.ident) == nil {
+		c.warningf(ident, "implicit declaration of function '%s'", ident.ident)
+		c.scopes[0][ident.ident] = &Symbol{
+			Kind:   SymbolFunction,
+			Name:   ident.ident,
+			Type:   FunctionReturning(&Signature{Return: typeInt}),
+			global: true,
+		}
+	}
+
+	typ := c.checkExpr(t.function)
+	args := make([]*Type, len(t.arguments))
+	for i, arg := range t.arguments {
+		args[i] = c.checkExpr(arg)
+	}
+
+	if !typ.IsValid() {
+		return typeInvalid
+	}
+	if typ.IsPointer() && typ.Elem().IsFunction() {
+		typ = typ.Elem()
+	}
+	if !typ.IsFunction() {
+		c.errorf(t.function, "called object '%s' is not a function or function pointer", name)
+		return typeInvalid
+	}
+
+	sig := typ.Func
+	if sig.Prototype {
+		switch {
+		case len(args) < len(sig.Params):
+			c.errorf(t, "too few arguments to function '%s'", name)
+		case len(args) > len(sig.Params) && !sig.Variadic:
+			c.errorf(t, "too many arguments to function '%s'", name)
+		}
+	}
+	t.argumentTypes = make([]*Type, len(t.arguments))
+	for i, arg := range t.arguments {
+		if i < len(sig.Params) {
+			c.checkAssignment(arg, sig.Params[i], args[i], fmt.Sprintf("passing argument %d of '%s'", i+1, name))
+			t.argumentTypes[i] = sig.Params[i].Unqualified()
+			continue
+		}
+		if args[i].IsVoid() {
+			c.errorf(arg, "void value not ignored as it ought to be")
+		}
+		t.argumentTypes[i] = promoteArgument(args[i].Decay())
+	}
+	return sig.Return
+}
+
+// promoteArgument applies the default argument promotions to the type of an
+// argument which doesn't have a parameter type from a prototype.
+func promoteArgument(t *Type) *Type {
+	if t.Kind == TypeFloat {
+		return typeDouble
+	}
+	return promote(t)
+}
+
+func (c *checker) checkMember(t *ASTStructElement) *Type {
+	typ := c.checkExpr(t.structImp)
+	if !typ.IsValid() {
+		return typeInvalid
+	}
+
+	if t.pointer {
+		if !typ.IsPointer() || !typ.Elem().IsStruct() {
+			c.errorf(t, "invalid type argument of '->' (have '%s')", typ)
+			return typeInvalid
+		}
+		typ = typ.Elem()
+	} else if !typ.IsStruct() {
+		c.errorf(t, "request for member '%s' in something not a structure or union", t.ident)
+		return typeInvalid
+	}
+
+	if !typ.Struct.Complete {
+		c.errorf(t, "invalid use of incomplete type '%s'", typ)
+		return typeInvalid
+	}
+	t.field = typ.Struct.Field(t.ident)
+	if t.field == nil {
+		c.errorf(t, "'%s' has no member named '%s'", typ, t.ident)
+		return typeInvalid
+	}
+	// The members of a qualified structure have its qualifiers too.
+	return t.field.Type.Qualified(typ.Qualifiers)
+}
+
+// checkIncrement checks the operand of a ++ or -- operator.
+func (c *checker) checkIncrement(operand Node, typ *Type, operator string) *Type {
+	what := "increment operand"
+	if operator == "--" {
+		what = "decrement operand"
+	}
+	if !c.checkModifiable(operand, typ, what) {
+		return typeInvalid
+	}
+	c.checkReadOnly(operand, typ, what[:len(what)-len(" operand")])
+	if !typ.IsScalar() {
+		c.errorf(operand, "wrong type argument to %s", what[:len(what)-len(" operand")])
+		return typeInvalid
+	}
+	if typ.IsPointer() && !typ.Elem().IsComplete() {
+		c.errorf(operand, "arithmetic on pointer to incomplete type '%s'", typ.Elem())
+		return typeInvalid
+	}
+	return typ.Unqualified()
+}
+
+func (c *checker) checkUnary(t *ASTExprPrefixUnary) *Type {
+	if t.typ == ASTExprPrefixUnaryTypeSizeOf {
+		return c.checkSizeOf(t)
+	}
+
+	typ := c.checkExpr(t.lvalue)
+	if !typ.IsValid() {
+		return typeInvalid
+	}
+
+	switch t.typ {
+	case ASTExprPrefixUnaryTypeIncrement, ASTExprPrefixUnaryTypeDecrement:
+		return c.checkIncrement(t.lvalue, typ, string(t.typ))
+	case ASTExprPrefixUnaryTypeAddressOf:
+		// The address of a function designator can be taken, although it
+		// isn't an lvalue.
+		if !isLvalue(t.lvalue) && !typ.IsFunction() {
+			c.errorf(t, "lvalue required as unary '&' operand")
+			return typeInvalid
+		}
+		return typ.PointerTo()
+	case ASTExprPrefixUnaryTypeDereference:
+		ptr := typ.Decay()
+		if !ptr.IsPointer() {
+			c.errorf(t, "invalid type argument of unary '*' (have '%s')", typ)
+			return typeInvalid
+		}
+		if ptr.Elem().IsVoid() {
+			c.errorf(t, "dereferencing 'void *' pointer")
+			return typeInvalid
+		}
+		return ptr.Elem()
+	case ASTExprPrefixUnaryTypePositive, ASTExprPrefixUnaryTypeNegative:
+		if !typ.IsArithmetic() {
+			c.errorf(t, "wrong type argument to unary %s", map[bool]string{true: "plus", false: "minus"}[t.typ == ASTExprPrefixUnaryTypePositive])
+			return typeInvalid
+		}
+		return promote(typ)
+	case ASTExprPrefixUnaryTypeNot:
+		if !typ.IsInteger() {
+			c.errorf(t, "wrong type argument to bit-complement")
+			return typeInvalid
+		}
+		return promote(typ)
+	case ASTExprPrefixUnaryTypeInvert:
+		if !typ.IsScalar() && !typ.IsArray() {
+			c.errorf(t, "wrong type argument to unary exclamation mark")
+			return typeInvalid
+		}
+		return typeInt
+	}
+	c.errorf(t, "internal compiler error: unknown unary operator %s", t.typ)
+	return typeInvalid
+}
+
+func (c *checker) checkSizeOf(t *ASTExprPrefixUnary) *Type {
+	var typ *Type
+	if name, ok := t.lvalue.(*ASTTypeName); ok {
+		typ = c.declaratorType(c.resolveType(name.typ), name.decl)
+	} else {
+		typ = c.checkExpr(t.lvalue)
+	}
+
+	switch {
+	case !typ.IsValid():
+		return typeInvalid
+	case typ.IsFunction():
+		c.errorf(t, "invalid application of 'sizeof' to a function type")
+		return typeInvalid
+	case !typ.IsComplete():
+		c.errorf(t, "invalid application of 'sizeof' to incomplete type '%s'", typ)
+		return typeInvalid
+	}
+	t.operandType = typ
+	return typeSize
+}
+
+func (c *checker) checkBinary(t *ASTExprBinary) *Type {
+	lhs := c.checkExpr(t.lhs)
+	rhs := c.checkExpr(t.rhs)
+	if !lhs.IsValid() || !rhs.IsValid() {
+		return typeInvalid
+	}
+
+	invalid := func() *Type {
+		c.errorf(t, "invalid operands to binary %s (have '%s' and '%s')", t.typ, lhs, rhs)
+		return typeInvalid
+	}
+
+	lhs, rhs = lhs.Decay(), rhs.Decay()
+
+	switch t.typ {
+	case ASTExprBinaryTypeLogicalAnd, ASTExprBinaryTypeLogicalOr:
+		if !lhs.IsScalar() || !rhs.IsScalar() {
+			return invalid()
+		}
+		return typeInt
+
+	case ASTExprBinaryTypeLessThan, ASTExprBinaryTypeGreaterThan,
+		ASTExprBinaryTypeLessOrEqual, ASTExprBinaryTypeGreaterOrEqual,
+		ASTExprBinaryTypeEquality, ASTExprBinaryTypeNotEquality:
+		switch {
+		case lhs.IsArithmetic() && rhs.IsArithmetic():
+			t.operandType = usualArithmeticConversion(lhs, rhs)
+		case lhs.IsPointer() && rhs.IsPointer():
+			if !lhs.Elem().IsVoid() && !rhs.Elem().IsVoid() && !lhs.Elem().Equal(rhs.Elem()) {
+				c.warningf(t, "comparison of distinct pointer types lacks a cast")
+			}
+			t.operandType = lhs
+		case lhs.IsPointer() && rhs.IsInteger():
+			if !isNullPointerConstant(t.rhs) {
+				c.warningf(t, "comparison between pointer and integer")
+			}
+			t.operandType = lhs
+		case lhs.IsInteger() && rhs.IsPointer():
+			if !isNullPointerConstant(t.lhs) {
+				c.warningf(t, "comparison between pointer and integer")
+			}
+			t.operandType = rhs
+		default:
+			return invalid()
+		}
+		return typeInt
+
+	case ASTExprBinaryTypeAdd, ASTExprBinaryTypeSub:
+		switch {
+		case lhs.IsArithmetic() && rhs.IsArithmetic():
+			t.operandType = usualArithmeticConversion(lhs, rhs)
+			return t.operandType
+		case lhs.IsPointer() && rhs.IsInteger():
+			t.operandType = lhs
+		case lhs.IsInteger() && rhs.IsPointer() && t.typ == ASTExprBinaryTypeAdd:
+			t.operandType = rhs
+		case lhs.IsPointer() && rhs.IsPointer() && t.typ == ASTExprBinaryTypeSub:
+			if !lhs.Elem().Equal(rhs.Elem()) {
+				return invalid()
+			}
+			t.operandType = lhs
+		default:
+			return invalid()
+		}
+		if !t.operandType.Elem().IsComplete() {
+			c.errorf(t, "arithmetic on pointer to incomplete type '%s'", t.operandType.Elem())
+			return typeInvalid
+		}
+		if lhs.IsPointer() && rhs.IsPointer() {
+			// The difference between two pointers is a ptrdiff_t.
+			return typeInt
+		}
+		return t.operandType.Unqualified()
+
+	case ASTExprBinaryTypeMul, ASTExprBinaryTypeDiv:
+		if !lhs.IsArithmetic() || !rhs.IsArithmetic() {
+			return invalid()
+		}
+		t.operandType = usualArithmeticConversion(lhs, rhs)
+		return t.operandType
+
+	case ASTExprBinaryTypeLeftShift, ASTExprBinaryTypeRightShift:
+		if !lhs.IsInteger() || !rhs.IsInteger() {
+			return invalid()
+		}
+		// The result of a shift has the type of its left operand.
+		t.operandType = promote(lhs)
+		return t.operandType
+
+	case ASTExprBinaryTypeMod, ASTExprBinaryTypeBitwiseAnd, ASTExprBinaryTypeXor, ASTExprBinaryTypeBitwiseOr:
+		if !lhs.IsInteger() || !rhs.IsInteger() {
+			return invalid()
+		}
+		t.operandType = usualArithmeticConversion(lhs, rhs)
+		return t.operandType
+	}
+	c.errorf(t, "internal compiler error: unknown binary operator %s", t.typ)
+	return typeInvalid
+}
+
+// checkCast checks an explicit conversion, which can be between any two
+// scalar types except pointers and floating point types.
+func (c *checker) checkCast(t *ASTCast) *Type {
+	to := c.declaratorType(c.resolveType(t.typeName.typ), t.typeName.decl)
+	from := c.checkExpr(t.expr)
+	if !to.IsValid() || !from.IsValid() {
+		return typeInvalid
+	}
+	from = from.Decay()
+
+	switch {
+	case to.IsVoid():
+		// The value is discarded.
+	case !to.IsScalar():
+		c.errorf(t, "conversion to non-scalar type requested")
+		return typeInvalid
+	case !from.IsScalar():
+		c.errorf(t, "used '%s' where a scalar is required", from)
+		return typeInvalid
+	case to.IsPointer() && from.IsFloating():
+		c.errorf(t, "cannot convert to a pointer type")
+		return typeInvalid
+	case to.IsFloating() && from.IsPointer():
+		c.errorf(t, "pointer value used where a floating point value was expected")
+		return typeInvalid
+	}
+	// The result of a cast isn't an lvalue, so its qualifiers don't matter.
+	return to.Unqualified()
+}
+
+// checkConditional checks a ternary expression.
+func (c *checker) checkConditional(t *ASTIfStatement) *Type {
+	c.checkCondition(t.condition)
+	a, b := c.checkExpr(t.body), c.checkExpr(t.elseBody)
+	if !a.IsValid() || !b.IsValid() {
+		return typeInvalid
+	}
+
+	a, b = a.Decay(), b.Decay()
+	switch {
+	case a.IsArithmetic() && b.IsArithmetic():
+		return usualArithmeticConversion(a, b)
+	case a.IsVoid() && b.IsVoid(), a.IsStruct() && a.Equal(b):
+		return a
+	case a.IsPointer() && b.IsPointer():
+		if !a.Elem().IsVoid() && !b.Elem().IsVoid() && !a.Elem().Equal(b.Elem()) {
+			c.warningf(t, "pointer type mismatch in conditional expression")
+		}
+		return a
+	case a.IsPointer() && isNullPointerConstant(t.elseBody):
+		return a
+	case b.IsPointer() && isNullPointerConstant(t.body):
+		return b
+	default:
+		c.errorf(t, "type mismatch in conditional expression")
+	}
+	return typeInvalid
+}
+
+// checkVaList checks that ap, the first argument to the <stdarg.h> macro
+// called name, is a va_list which can be modified.
+func (c *checker) checkVaList(ap Node, name string) {
+	typ := c.checkExpr(ap)
+	if !typ.IsValid() {
+		return
+	}
+	if !typ.Unqualified().Equal(builtinTypedefs["va_list"]) {
+		c.errorf(ap, "first argument to '%s' not of type 'va_list'", name)
+		return
+	}
+	c.checkModifiable(ap, typ, fmt.Sprintf("first argument to '%s'", name))
+}
+
+func (c *checker) checkVaStart(t *ASTVaStart) *Type {
+	c.checkVaList(t.ap, "va_start")
+	c.checkExpr(t.last)
+
+	switch {
+	case c.function == nil || !c.function.Variadic:
+		c.errorf(t, "'va_start' used in function with fixed arguments")
+	case len(c.params) == 0 || t.last.sym != c.params[len(c.params)-1]:
+		c.warningf(t.last, "second parameter of 'va_start' not last named argument")
+	}
+	return typeVoid
+}
+
+func (c *checker) checkVaArg(t *ASTVaArg) *Type {
+	c.checkVaList(t.ap, "va_arg")
+	typ := c.declaratorType(c.resolveType(t.typeName.typ), t.typeName.decl)
+	switch {
+	case !typ.IsValid():
+		return typeInvalid
+	case !typ.IsComplete() || typ.IsFunction() || typ.IsArray():
+		c.errorf(t.typeName, "invalid type '%s' for 'va_arg'", typ)
+		return typeInvalid
+	case typ.IsArithmetic() && promoteArgument(typ).Size() != typ.Size():
+		c.warningf(t.typeName, "'%s' is promoted to '%s' when passed through '...'", typ, promoteArgument(typ))
+	}
+	return typ.Unqualified()
+}
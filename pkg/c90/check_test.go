@@ -0,0 +1,123 @@
+package c90
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jpnock/see90/pkg/cpp"
+)
+
+// expectDiagnostics parses and checks src, and reports an error unless the
+// messages of the diagnostics found are want, in order.
+func expectDiagnostics(t *testing.T, src string, want ...string) {
+	t.Helper()
+	var diagnostics DiagnosticList
+	pp := cpp.New(nil)
+	s := NewSession(pp, &diagnostics)
+	r, err := pp.Preprocess("test.c", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("preprocessing %q: %v", src, err)
+	}
+	if s.Parse(r) == nil {
+		s.Check()
+	}
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, d.Message)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s: got %q, want %q", src, got, want)
+	}
+}
+
+func TestStringInitializerLength(t *testing.T) {
+	const tooLong = "initializer-string for array of 'char' is too long"
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`char s[2] = "abc";`, []string{tooLong}},
+		{`char s[3] = "abc";`, nil},
+		{`char s[4] = "abc";`, nil},
+		{`char s[] = "abc";`, nil},
+		{`char s[1] = {"ab"};`, []string{tooLong}},
+		{`struct { char n[2]; int x; } s = {"abc", 1};`, []string{tooLong}},
+		{`int f() { char s[2] = "abc"; return s[0]; }`, []string{tooLong}},
+		{`unsigned char s[2] = "abc";`, []string{"initializer-string for array of 'unsigned char' is too long"}},
+	}
+	for _, test := range tests {
+		expectDiagnostics(t, test.src, test.want...)
+	}
+}
+
+func TestDuplicateCaseLabels(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{
+			`int f(int x) { switch (x) { case 1: case 2: return 0; } return 1; }`,
+			nil,
+		},
+		{
+			`int f(int x) { switch (x) { case 1: case 2: case 1: return 0; } return 1; }`,
+			[]string{"duplicate case value", "previously used here"},
+		},
+		{
+			`int f(int x) { switch (x) { case 'a': case 97: return 0; } return 1; }`,
+			[]string{"duplicate case value", "previously used here"},
+		},
+		{
+			`int f(unsigned x) { switch (x) { case -1: case 0xffffffff: return 0; } return 1; }`,
+			[]string{"duplicate case value", "previously used here"},
+		},
+		{
+			`int f(int x) { switch (x) { case 1: switch (x) { case 1: return 0; } } return 1; }`,
+			nil,
+		},
+		{
+			`int f(int x) { switch (x) { default: case 1: default: return 0; } return 1; }`,
+			[]string{"multiple default labels in one switch", "this is the first default label"},
+		},
+	}
+	for _, test := range tests {
+		expectDiagnostics(t, test.src, test.want...)
+	}
+}
+
+func TestAddressConstantInitializers(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`int a[4]; int *p = a + 1;`, nil},
+		{`int a[4]; int *p = &a[2];`, nil},
+		{`struct s { int x, y; } v; int *p = &v.y;`, nil},
+		{`char *p = "abc" + 1;`, nil},
+		{`int f() { int x; static int *p = &x; return 0; }`, []string{"initializer element is not constant: address of 'x' is not a constant"}},
+		{`int *q; int *p = q + 1;`, []string{"initializer element is not constant: 'q' is not a constant"}},
+		{`int a[4]; int n = a - a;`, []string{"initializer element is not constant: binary '-' on pointers is not allowed in a constant expression"}},
+	}
+	for _, test := range tests {
+		expectDiagnostics(t, test.src, test.want...)
+	}
+}
+
+func TestSyntaxErrorMessages(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`int x = ;`, []string{"expected expression or '{', found ';' near `= ;`"}},
+		{`int f() { int y z; return 0; }`, []string{"expected ',' or ';', found identifier near `y z`"}},
+		{`int f() { return 1 + @; }`, []string{"expected expression, found stray '@' near `+ @`"}},
+		{`int f(`, []string{"expected declaration specifiers, identifier or ')', found end of input near `(`"}},
+		{
+			`int f() { int a = ; return 0 }`,
+			[]string{"expected expression or '{', found ';' near `= ;`", "expected ',' or ';', found '}' near `0 }`"},
+		},
+	}
+	for _, test := range tests {
+		expectDiagnostics(t, test.src, test.want...)
+	}
+}
@@ -0,0 +1,403 @@
+package c90
+
+import (
+	"fmt"
+	"math"
+)
+
+// constant is the value of a constant expression. Integers and pointers are
+// held in intValue, sign or zero extended from the width of their type so
+// that each value has one representation, and floating values in floatValue,
+// rounded to single precision for float.
+type constant struct {
+	typ        *Type
+	intValue   int64
+	floatValue float64
+
+	// base is set for an address constant, which is the address of base plus
+	// intValue bytes. It is an identifier with static storage, or a string
+	// literal, whose address is only known once the program is linked.
+	base Node
+}
+
+// integerConstant returns the constant of type typ with the value v,
+// wrapped around to the width of typ.
+func integerConstant(typ *Type, v int64) constant {
+	bits := uint(8 * typ.Size())
+	if typ.IsUnsigned() || typ.IsPointer() {
+		v = int64(uint64(v) << (64 - bits) >> (64 - bits))
+	} else {
+		v = v << (64 - bits) >> (64 - bits)
+	}
+	return constant{typ: typ, intValue: v}
+}
+
+// addressConstant returns the constant of type typ which is offset bytes
+// past the start of base.
+func addressConstant(typ *Type, base Node, offset int64) constant {
+	c := integerConstant(typ, offset)
+	c.base = base
+	return c
+}
+
+func floatingConstant(typ *Type, v float64) constant {
+	if isFloat(typ) {
+		v = float64(float32(v))
+	}
+	return constant{typ: typ, floatValue: v}
+}
+
+// isZero reports whether c compares equal to 0.
+func (c constant) isZero() bool {
+	if c.base != nil {
+		// Objects and functions are never at the null address.
+		return false
+	}
+	if c.typ.IsFloating() {
+		return c.floatValue == 0
+	}
+	return c.intValue == 0
+}
+
+// convert returns c converted to the scalar type to, as an assignment or
+// cast would at run time.
+func (c constant) convert(to *Type) constant {
+	to = to.Unqualified()
+	switch {
+	case to.IsFloating() && c.typ.IsFloating():
+		return floatingConstant(to, c.floatValue)
+	case to.IsFloating():
+		return floatingConstant(to, float64(c.intValue))
+	case c.typ.IsFloating():
+		// Converting a value out of range is undefined, so it is only
+		// truncated to fit.
+		return integerConstant(to, int64(math.Trunc(c.floatValue)))
+	}
+	res := integerConstant(to, c.intValue)
+	res.base = c.base
+	return res
+}
+
+// evaluateConstant works out the value of the checked constant expression n,
+// returning an error saying why if it isn't one. Integer arithmetic wraps
+// around at the width of the type it is carried out in, as it does on the
+// target.
+func evaluateConstant(n Node) (constant, error) {
+	n = unwrapExpr(n)
+	typ := typeOf(n)
+	if !typ.IsValid() {
+		return constant{}, fmt.Errorf("expression has an invalid type")
+	}
+
+	switch t := n.(type) {
+	case *ASTConstant:
+		if typ.IsFloating() {
+			return floatingConstant(typ, t.floatValue), nil
+		}
+		return integerConstant(typ, t.intValue), nil
+
+	case *ASTStringLiteral:
+		return addressConstant(typ.Decay(), t, 0), nil
+
+	case *ASTIdentifier:
+		if t.sym != nil && t.sym.Kind == SymbolEnumConstant {
+			return integerConstant(typeInt, t.sym.enum.intValue), nil
+		}
+		if typ.IsArray() || typ.IsFunction() {
+			return evaluateAddress(t, typ.Decay())
+		}
+		return constant{}, fmt.Errorf("'%s' is not a constant", t.ident)
+
+	case *ASTIndexedExpression, *ASTStructElement:
+		// Only an array element or member is a constant, as its value is
+		// its address.
+		if typ.IsArray() {
+			return evaluateAddress(t, typ.Decay())
+		}
+
+	case *ASTExprPrefixUnary:
+		return evaluateUnary(t)
+
+	case *ASTExprBinary:
+		return evaluateBinary(t)
+
+	case *ASTCast:
+		if !typ.IsScalar() {
+			return constant{}, fmt.Errorf("cast to '%s' is not a constant", typ)
+		}
+		c, err := evaluateConstant(t.expr)
+		if err != nil {
+			return c, err
+		}
+		if !c.typ.IsScalar() {
+			return constant{}, fmt.Errorf("'%s' value is not a constant", c.typ)
+		}
+		if c.base != nil && !typ.IsPointer() {
+			return constant{}, fmt.Errorf("cast of an address to '%s' is not a constant", typ)
+		}
+		return c.convert(typ), nil
+
+	case *ASTIfStatement:
+		if !t.ternary || !typ.IsScalar() {
+			break
+		}
+		cond, err := evaluateConstant(t.condition)
+		if err != nil {
+			return cond, err
+		}
+		// Only the operand chosen is evaluated.
+		operand := t.body
+		if cond.isZero() {
+			operand = t.elseBody
+		}
+		c, err := evaluateConstant(operand)
+		if err != nil {
+			return c, err
+		}
+		return c.convert(typ), nil
+
+	case *ASTFunctionCall:
+		return constant{}, fmt.Errorf("function call is not a constant")
+
+	case *ASTAssignment:
+		return constant{}, fmt.Errorf("assignment is not allowed in a constant expression")
+
+	case ASTExpression:
+		return constant{}, fmt.Errorf("comma operator is not allowed in a constant expression")
+	}
+	return constant{}, fmt.Errorf("expression is not a constant")
+}
+
+// evaluateAddress works out the address of the lvalue n as an address
+// constant of type typ.
+func evaluateAddress(n Node, typ *Type) (constant, error) {
+	n = unwrapExpr(n)
+	switch t := n.(type) {
+	case *ASTStringLiteral:
+		return addressConstant(typ, t, 0), nil
+
+	case *ASTIdentifier:
+		if t.sym == nil || !t.sym.global {
+			return constant{}, fmt.Errorf("address of '%s' is not a constant", t.ident)
+		}
+		return addressConstant(typ, t, 0), nil
+
+	case *ASTIndexedExpression:
+		// a[i] is *(a + i), and the checker puts the pointer in lvalue.
+		base, err := evaluateConstant(t.lvalue)
+		if err != nil {
+			return base, err
+		}
+		index, err := evaluateConstant(t.index)
+		if err != nil {
+			return index, err
+		}
+		offset := index.intValue * int64(typeOf(t).Size())
+		return addressConstant(typ, base.base, base.intValue+offset), nil
+
+	case *ASTStructElement:
+		var base constant
+		var err error
+		if t.pointer {
+			base, err = evaluateConstant(t.structImp)
+		} else {
+			base, err = evaluateAddress(t.structImp, typ)
+		}
+		if err != nil {
+			return base, err
+		}
+		return addressConstant(typ, base.base, base.intValue+int64(t.field.Offset)), nil
+
+	case *ASTExprPrefixUnary:
+		if t.typ == ASTExprPrefixUnaryTypeDereference {
+			c, err := evaluateConstant(t.lvalue)
+			if err != nil {
+				return c, err
+			}
+			return addressConstant(typ, c.base, c.intValue), nil
+		}
+	}
+	return constant{}, fmt.Errorf("address is not a constant")
+}
+
+func evaluateUnary(t *ASTExprPrefixUnary) (constant, error) {
+	switch t.typ {
+	case ASTExprPrefixUnaryTypeSizeOf:
+		// The operand of sizeof isn't evaluated, so it needn't be constant.
+		return integerConstant(typeSize, int64(t.operandType.Size())), nil
+	case ASTExprPrefixUnaryTypeAddressOf:
+		return evaluateAddress(t.lvalue, t.Type())
+	case ASTExprPrefixUnaryTypePositive, ASTExprPrefixUnaryTypeNegative,
+		ASTExprPrefixUnaryTypeNot, ASTExprPrefixUnaryTypeInvert:
+	default:
+		return constant{}, fmt.Errorf("unary '%s' is not allowed in a constant expression", t.typ)
+	}
+
+	c, err := evaluateConstant(t.lvalue)
+	if err != nil {
+		return c, err
+	}
+	if t.typ == ASTExprPrefixUnaryTypeInvert {
+		return integerConstant(typeInt, boolInt(c.isZero())), nil
+	}
+	if !c.typ.IsArithmetic() {
+		return constant{}, fmt.Errorf("'%s' value is not a constant", c.typ)
+	}
+
+	c = c.convert(t.Type())
+	switch t.typ {
+	case ASTExprPrefixUnaryTypeNegative:
+		if c.typ.IsFloating() {
+			return floatingConstant(c.typ, -c.floatValue), nil
+		}
+		return integerConstant(c.typ, -c.intValue), nil
+	case ASTExprPrefixUnaryTypeNot:
+		return integerConstant(c.typ, ^c.intValue), nil
+	}
+	return c, nil
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func evaluateBinary(t *ASTExprBinary) (constant, error) {
+	x, err := evaluateConstant(t.lhs)
+	if err != nil {
+		return x, err
+	}
+
+	// The right operand of && and || is only evaluated if it's needed.
+	switch t.typ {
+	case ASTExprBinaryTypeLogicalAnd, ASTExprBinaryTypeLogicalOr:
+		if x.isZero() == (t.typ == ASTExprBinaryTypeLogicalAnd) {
+			return integerConstant(typeInt, boolInt(t.typ == ASTExprBinaryTypeLogicalOr)), nil
+		}
+		y, err := evaluateConstant(t.rhs)
+		if err != nil {
+			return y, err
+		}
+		return integerConstant(typeInt, boolInt(!y.isZero())), nil
+	}
+
+	y, err := evaluateConstant(t.rhs)
+	if err != nil {
+		return y, err
+	}
+	if !t.operandType.IsArithmetic() {
+		return evaluatePointerBinary(t, x, y)
+	}
+
+	if t.typ == ASTExprBinaryTypeLeftShift || t.typ == ASTExprBinaryTypeRightShift {
+		// The shift amount is masked to the width of a word, as it is by
+		// the shift instructions.
+		x = x.convert(t.operandType)
+		shift := uint(y.intValue & 31)
+		if t.typ == ASTExprBinaryTypeLeftShift {
+			return integerConstant(x.typ, x.intValue<<shift), nil
+		}
+		return integerConstant(x.typ, x.intValue>>shift), nil
+	}
+
+	x, y = x.convert(t.operandType), y.convert(t.operandType)
+	if t.operandType.IsFloating() {
+		return evaluateFloatBinary(t, x.floatValue, y.floatValue)
+	}
+	return evaluateIntBinary(t, x.intValue, y.intValue)
+}
+
+// evaluatePointerBinary adds an integer to, or subtracts one from, whichever
+// of x and y is a pointer.
+func evaluatePointerBinary(t *ASTExprBinary, x, y constant) (constant, error) {
+	ptr, n := x, y
+	if !ptr.typ.IsPointer() {
+		ptr, n = y, x
+	}
+	if n.typ.IsPointer() || (t.typ != ASTExprBinaryTypeAdd && t.typ != ASTExprBinaryTypeSub) {
+		return constant{}, fmt.Errorf("binary '%s' on pointers is not allowed in a constant expression", t.typ)
+	}
+	offset := n.intValue * int64(t.operandType.Elem().Size())
+	if t.typ == ASTExprBinaryTypeSub {
+		offset = -offset
+	}
+	return addressConstant(t.Type(), ptr.base, ptr.intValue+offset), nil
+}
+
+// evaluateIntBinary carries out an integer operation on x and y, which have
+// been converted to the operand type of t.
+func evaluateIntBinary(t *ASTExprBinary, x, y int64) (constant, error) {
+	typ := t.operandType
+	compare := func(b bool) (constant, error) {
+		return integerConstant(typeInt, boolInt(b)), nil
+	}
+	switch t.typ {
+	case ASTExprBinaryTypeMul:
+		return integerConstant(typ, x*y), nil
+	case ASTExprBinaryTypeDiv, ASTExprBinaryTypeMod:
+		if y == 0 {
+			return constant{}, fmt.Errorf("division by zero")
+		}
+		if t.typ == ASTExprBinaryTypeDiv {
+			return integerConstant(typ, x/y), nil
+		}
+		return integerConstant(typ, x%y), nil
+	case ASTExprBinaryTypeAdd:
+		return integerConstant(typ, x+y), nil
+	case ASTExprBinaryTypeSub:
+		return integerConstant(typ, x-y), nil
+	case ASTExprBinaryTypeBitwiseAnd:
+		return integerConstant(typ, x&y), nil
+	case ASTExprBinaryTypeXor:
+		return integerConstant(typ, x^y), nil
+	case ASTExprBinaryTypeBitwiseOr:
+		return integerConstant(typ, x|y), nil
+	case ASTExprBinaryTypeLessThan:
+		return compare(x < y)
+	case ASTExprBinaryTypeGreaterThan:
+		return compare(x > y)
+	case ASTExprBinaryTypeLessOrEqual:
+		return compare(x <= y)
+	case ASTExprBinaryTypeGreaterOrEqual:
+		return compare(x >= y)
+	case ASTExprBinaryTypeEquality:
+		return compare(x == y)
+	case ASTExprBinaryTypeNotEquality:
+		return compare(x != y)
+	}
+	return constant{}, fmt.Errorf("binary '%s' is not allowed in a constant expression", t.typ)
+}
+
+// evaluateFloatBinary carries out a floating point operation on x and y,
+// which have been converted to the operand type of t.
+func evaluateFloatBinary(t *ASTExprBinary, x, y float64) (constant, error) {
+	typ := t.operandType
+	compare := func(b bool) (constant, error) {
+		return integerConstant(typeInt, boolInt(b)), nil
+	}
+	switch t.typ {
+	case ASTExprBinaryTypeMul:
+		return floatingConstant(typ, x*y), nil
+	case ASTExprBinaryTypeDiv:
+		return floatingConstant(typ, x/y), nil
+	case ASTExprBinaryTypeAdd:
+		return floatingConstant(typ, x+y), nil
+	case ASTExprBinaryTypeSub:
+		return floatingConstant(typ, x-y), nil
+	case ASTExprBinaryTypeLessThan:
+		return compare(x < y)
+	case ASTExprBinaryTypeGreaterThan:
+		return compare(x > y)
+	case ASTExprBinaryTypeLessOrEqual:
+		return compare(x <= y)
+	case ASTExprBinaryTypeGreaterOrEqual:
+		return compare(x >= y)
+	case ASTExprBinaryTypeEquality:
+		return compare(x == y)
+	case ASTExprBinaryTypeNotEquality:
+		return compare(x != y)
+	}
+	return constant{}, fmt.Errorf("binary '%s' is not allowed in a constant expression", t.typ)
+}
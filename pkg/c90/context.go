@@ -2,233 +2,170 @@ package c90
 
 import (
 	"fmt"
+
+	"github.com/jpnock/see90/pkg/ir"
 )
 
 type Label string
 
-type Variable struct {
-	// fpOffset is the amount of subtract from fp to access the variable.
-	fpOffset          int
-	decl              *ASTDecl
-	directDecl        *ASTDirectDeclarator
-	typ               ASTType
-	label             *Label
-	isGlobal          bool
-	isLocalDataString bool
-	enum              *ASTEnumEntry
-	structure         *Struct
-}
-
-func (v *Variable) IsPointer() bool {
-	if v.directDecl == nil {
-		return false
-	}
-	return v.directDecl.pointerDepth > 0
-}
-
-func (v *Variable) IsArray() bool {
-	if v.directDecl == nil {
-		return false
-	}
-	return v.directDecl.array != nil
-}
-
-func (v *Variable) GlobalLabel() Label {
-	if !v.isGlobal {
-		panic("variable not global")
-	}
-	return Label("__global_var__" + v.directDecl.Identifier().ident)
-}
+// Lowerer builds the IR for a translation unit from its checked AST. Each
+// node lowers itself with its Lower method, using the Lowerer to keep track of
+// the function being built and the targets of break, continue and goto.
+type Lowerer struct {
+	module *ir.Module
 
-type MIPSContext struct {
-	CurrentStackFramePointerOffset int
-}
+	// fn is the function being lowered, and b appends to its current block.
+	fn *ir.Function
+	b  *ir.Builder
 
-func (m *MIPSContext) GetNewLocalOffset() int {
-	// TODO: change this size depending on the type of variable
-	m.CurrentStackFramePointerOffset += 8
-	return m.CurrentStackFramePointerOffset
-}
+	// returnType is the type returned by the current function, and
+	// structReturn is the hidden parameter holding the address to copy a
+	// returned structure to.
+	returnType   *Type
+	structReturn *ir.Slot
 
-func (m *MIPSContext) GetNewLocalOffsetWithMinSize(reserve int) int {
-	// Align to 8 bytes for the ABI
-	mod := reserve % 8
-	if mod != 0 {
-		reserve += 8 - mod
-	}
-	m.CurrentStackFramePointerOffset += reserve
-	return m.CurrentStackFramePointerOffset
-}
+	breakTargets    []*ir.Block
+	continueTargets []*ir.Block
+	switches        []*switchTargets
 
-type MIPS struct {
-	VariableScopes    VariableScopeStack
-	Context           *MIPSContext
-	LabelScopes       LabelScopeStack
-	CaseLabelScopes   CaseLabelScopeStack
-	ReturnScopes      ReturnScopeStack
-	StructScopes      StructScopeStack
-	TypeDefScopes     TypeDefScopeStack
-	StructElementName string
-	TopStruct         string
-	stringMap         map[Label][]byte
-	lastLabel         Label
-
-	lastType     VarType
-	pointerLevel int
-	indexLevel   int
+	// userLabels maps the labels declared in the current function to their
+	// blocks.
+	userLabels map[string]*ir.Block
 
 	uniqueLabelNumber uint
-}
 
-func NewMIPS() *MIPS {
-	return &MIPS{
-		VariableScopes: VariableScopeStack{
-			// Global scope is always the first level
-			VariableScope{},
-		},
-		StructScopes: StructScopeStack{
-			StructScope{},
-		},
-		TypeDefScopes: TypeDefScopeStack{
-			// Global scope is always the first level
-			TypeDefScope{},
-		},
-		Context:           &MIPSContext{},
-		LabelScopes:       nil,
-		stringMap:         make(map[Label][]byte),
-		lastType:          VarTypeInvalid,
-		uniqueLabelNumber: 0,
-	}
+	diagnostics DiagnosticSink
+	// external is the external declaration currently being lowered, which
+	// is used to locate diagnostics that aren't associated with a node.
+	external Node
+	errors   int
 }
 
-func (m *MIPS) LastType() VarType {
-	if m.pointerLevel != 0 {
-		return VarTypeUnsigned
-	}
-	if m.pointerLevel == 1 && m.lastType == VarTypeChar {
-		return VarTypeString
-	}
-	return m.lastType
+// switchTargets collects the case labels of a switch statement, which are
+// only known once its body has been lowered.
+type switchTargets struct {
+	cases       []*ASTSwitchCase
+	blocks      []*ir.Block
+	defaultCase *ir.Block
 }
 
-func (m *MIPS) SetLastType(typ VarType) {
-	//	log.Printf("setting type to %v\n", typ)
-	//debug.PrintStack()
-
-	if typ == VarTypeString {
-		m.lastType = VarTypeChar
-		m.pointerLevel = 1
-		return
+func NewLowerer(diagnostics DiagnosticSink) *Lowerer {
+	return &Lowerer{
+		module:      &ir.Module{},
+		diagnostics: diagnostics,
 	}
-
-	m.lastType = typ
-	m.pointerLevel = 0
 }
 
-// CreateUniqueLabel takes the provided name and returns a unique label, using
-// this name.
-func (m *MIPS) CreateUniqueLabel(name string) Label {
-	label := fmt.Sprintf("__label__%s__%d__", name, m.uniqueLabelNumber)
-	m.uniqueLabelNumber++
-	return Label(label)
+// Module returns the IR lowered so far.
+func (l *Lowerer) Module() *ir.Module {
+	return l.module
 }
 
-// NewScopes adds a new scope to the stack and copies all of the previous
-// variables into it.
-func (m *MIPS) NewVariableScope() {
-	// Create a new scope and copy the last scope into it
-	newScope := make(VariableScope)
-	top := m.VariableScopes.Peek()
-	for k, v := range top {
-		newScope[k] = v
-	}
-	m.VariableScopes.Push(newScope)
-}
-
-func (m *MIPS) NewStructScope() {
-	// Create a new scope and copy the last scope into it
-	newScope := make(StructScope)
-	top := m.StructScopes.Peek()
-	for k, v := range top {
-		newScope[k] = v
-	}
-	m.StructScopes.Push(newScope)
-}
+// bailout is used to abandon lowering the current external declaration once
+// an error has been reported.
+type bailout struct{}
 
-func (m *MIPS) NewTypeDefScope() {
-	// Create a new scope and copy the last scope into it
-	newScope := make(TypeDefScope)
-	top := m.TypeDefScopes.Peek()
-	for k, v := range top {
-		newScope[k] = v
+// errorf reports an error at the location of n.
+func (l *Lowerer) errorf(n Node, format string, args ...interface{}) {
+	rng := rangeOf(n)
+	if !rng.IsValid() {
+		rng = rangeOf(l.external)
 	}
-	m.TypeDefScopes.Push(newScope)
-}
-
-// NewLabelScope adds a new label scope to the stack and copies all of the
-// previous variables into it.
-func (m *MIPS) NewLabelScope(l LabelScope) {
-	// TODO: copy the last scope into it when we have other labels
-	m.LabelScopes.Push(l)
+	l.errors++
+	reportf(l.diagnostics, SeverityError, rng, format, args...)
 }
 
-// NewCaseLabelScope adds a new case label scope to the stack. Unlike other
-// scopes, it does not copy in the previous values on the stack.
-func (m *MIPS) NewSwitchStatement() (bottomLabel Label) {
-	bottomLabel = m.CreateUniqueLabel("switch_bottom")
-	m.CaseLabelScopes.Push(CaseLabelScope{})
-	m.LabelScopes.Push(LabelScope{BreakLabel: &bottomLabel})
-	m.NewVariableScope()
-	m.NewStructScope()
-	m.NewTypeDefScope()
-	return
+// fatalf reports an error at the location of n and stops lowering the
+// current external declaration.
+func (l *Lowerer) fatalf(n Node, format string, args ...interface{}) {
+	l.errorf(n, format, args...)
+	panic(bailout{})
 }
 
-func (m *MIPS) EndSwitchStatement() {
-	m.VariableScopes.Pop()
-	m.LabelScopes.Pop()
-	m.StructScopes.Pop()
-	m.TypeDefScopes.Pop()
-	m.CaseLabelScopes.Pop()
+// Errors returns the number of errors reported while lowering.
+func (l *Lowerer) Errors() int {
+	return l.errors
 }
 
-// NewFunction resets context variables relating to the current function being
-// generated.
-func (m *MIPS) NewFunction() {
-	// TODO: change this if we change our stack frame
-	const fp = 8
-	m.Context.CurrentStackFramePointerOffset = fp
-
-	//clear map of strings declared in last function
-	m.stringMap = map[Label][]byte{}
-
-	m.NewVariableScope()
-	m.NewStructScope()
-	m.NewTypeDefScope()
-	m.ReturnScopes.Push(m.CreateUniqueLabel("function_return"))
+// lowerExternal lowers a single external declaration, turning errors into
+// diagnostics so that the rest of the translation unit can be checked.
+func (l *Lowerer) lowerExternal(node Node) {
+	l.external = node
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if _, ok := r.(bailout); !ok {
+			l.errorf(node, "internal compiler error: %v", r)
+		}
+		l.endFunction()
+	}()
+	node.Lower(l)
 }
 
-func (m *MIPS) EndFunction() {
-	m.VariableScopes.Pop()
-	m.StructScopes.Pop()
-	m.TypeDefScopes.Pop()
-	m.ReturnScopes.Pop()
-	m.stringMap = map[Label][]byte{}
+// CreateUniqueLabel takes the provided name and returns a unique label, using
+// this name. The label starts with .L so that it's local to the object file,
+// and can't clash with the labels of a function's blocks.
+func (l *Lowerer) CreateUniqueLabel(name string) Label {
+	label := fmt.Sprintf(".L.%s.%d", name, l.uniqueLabelNumber)
+	l.uniqueLabelNumber++
+	return Label(label)
 }
 
-func (m *MIPS) sizeOfType(typ VarType, pointer bool) int {
-	if pointer {
-		return 4
-	}
-
-	switch typ {
-	case VarTypeInteger, VarTypeSigned, VarTypeShort, VarTypeLong, VarTypeUnsigned, VarTypeFloat, VarTypeString:
-		return 4
-	case VarTypeChar:
-		return 1
-	case VarTypeDouble:
-		return 8
-	default:
-		panic(fmt.Sprintf("unknown sizeof type: %s", typ))
+// UserLabel returns the block for the C label called name in the current
+// function, creating it the first time it is used so that forward gotos can
+// jump to it.
+func (l *Lowerer) UserLabel(name string) *ir.Block {
+	block, ok := l.userLabels[name]
+	if !ok {
+		block = l.fn.NewBlock("label " + name)
+		l.userLabels[name] = block
 	}
+	return block
+}
+
+// beginFunction starts lowering the function fn.
+func (l *Lowerer) beginFunction(fn *ir.Function, returnType *Type) {
+	l.fn = fn
+	l.b = ir.NewBuilder(fn)
+	l.returnType = returnType
+	l.structReturn = nil
+	l.userLabels = map[string]*ir.Block{}
+}
+
+// endFunction resets the state used while lowering a function.
+func (l *Lowerer) endFunction() {
+	l.fn = nil
+	l.b = nil
+	l.breakTargets = nil
+	l.continueTargets = nil
+	l.switches = nil
+	l.userLabels = nil
+}
+
+// pushLoop makes break and continue jump to the given blocks, until popLoop
+// is called.
+func (l *Lowerer) pushLoop(breakTarget, continueTarget *ir.Block) {
+	l.breakTargets = append(l.breakTargets, breakTarget)
+	l.continueTargets = append(l.continueTargets, continueTarget)
+}
+
+func (l *Lowerer) popLoop() {
+	l.breakTargets = l.breakTargets[:len(l.breakTargets)-1]
+	l.continueTargets = l.continueTargets[:len(l.continueTargets)-1]
+}
+
+// stringData adds a string literal to the module, returning its address.
+func (l *Lowerer) stringData(value []byte) *ir.Addr {
+	label := string(l.CreateUniqueLabel("string"))
+	data := append(append([]byte{}, value...), 0)
+	l.module.Data = append(l.module.Data, &ir.Data{
+		Name:    label,
+		Section: ir.SectionRodata,
+		Size:    len(data),
+		Align:   1,
+		Items:   []ir.DataItem{{Kind: ir.DataBytes, Bytes: data}},
+	})
+	return &ir.Addr{Symbol: label}
 }
@@ -0,0 +1,148 @@
+package c90
+
+import (
+	"fmt"
+
+	"github.com/jpnock/see90/pkg/cpp"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Range is the span of source code between Start and End (exclusive).
+type Range struct {
+	Start cpp.Position
+	End   cpp.Position
+}
+
+func (r Range) String() string {
+	return r.Start.String()
+}
+
+// IsValid returns false if the range does not refer to anything in a source
+// file.
+func (r Range) IsValid() bool {
+	return r.Start.Line > 0
+}
+
+// Diagnostic is a single error, warning or note about the program being
+// compiled.
+type Diagnostic struct {
+	Severity Severity
+	Range    Range
+	Message  string
+}
+
+// String formats the diagnostic in the same way as gcc, e.g.
+// `main.c:3:5: error: 'x' undeclared`.
+func (d Diagnostic) String() string {
+	if !d.Range.IsValid() {
+		return fmt.Sprintf("see90: %s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Range, d.Severity, d.Message)
+}
+
+// DiagnosticSink receives the diagnostics produced while compiling.
+type DiagnosticSink interface {
+	Report(d Diagnostic)
+}
+
+// DiagnosticList is a DiagnosticSink which keeps every diagnostic in the order
+// they were reported.
+type DiagnosticList []Diagnostic
+
+func (l *DiagnosticList) Report(d Diagnostic) {
+	*l = append(*l, d)
+}
+
+// ErrorCount returns the number of diagnostics with SeverityError.
+func (l DiagnosticList) ErrorCount() int {
+	count := 0
+	for _, d := range l {
+		if d.Severity == SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+func reportf(sink DiagnosticSink, severity Severity, rng Range, format string, args ...interface{}) {
+	sink.Report(Diagnostic{
+		Severity: severity,
+		Range:    rng,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// located is embedded in AST nodes to record where they were parsed from.
+type located struct {
+	rng Range
+}
+
+func (l located) Range() Range {
+	return l.rng
+}
+
+// rangeOf returns the source range of a node. Nodes which don't record their
+// own location (such as lists) span their first and last children.
+func rangeOf(n Node) Range {
+	switch t := n.(type) {
+	case nil:
+		return Range{}
+	case interface{ Range() Range }:
+		return t.Range()
+	case ASTExpression:
+		if len(t) > 0 {
+			return spanRange(rangeOf(t[0]), rangeOf(t[len(t)-1]))
+		}
+	case ASTArgumentExpressionList:
+		if len(t) > 0 {
+			return spanRange(rangeOf(t[0]), rangeOf(t[len(t)-1]))
+		}
+	case ASTStatementList:
+		if len(t) > 0 {
+			return spanRange(rangeOf(t[0]), rangeOf(t[len(t)-1]))
+		}
+	case ASTDeclaratorList:
+		if len(t) > 0 {
+			return spanRange(rangeOf(t[0]), rangeOf(t[len(t)-1]))
+		}
+	case ASTInitializerList:
+		if len(t) > 0 {
+			return spanRange(rangeOf(t[0]), rangeOf(t[len(t)-1]))
+		}
+	case ASTTranslationUnit:
+		if len(t) > 0 {
+			return spanRange(rangeOf(t[0]), rangeOf(t[len(t)-1]))
+		}
+	}
+	return Range{}
+}
+
+func spanRange(from, to Range) Range {
+	if !from.IsValid() {
+		return to
+	}
+	if !to.IsValid() {
+		return from
+	}
+	return Range{Start: from.Start, End: to.End}
+}
@@ -1,22 +1,9 @@
 %{
 package c90
 
-import (
-	"fmt"
-	"os"
-)
-
-var AST ASTTranslationUnit
-var typmap map[string]*ASTTypeDef = map[string]*ASTTypeDef{}
-
 func init() {
-	yyDebug = 1
 	yyErrorVerbose = true
 }
-
-func Parse(yylex yyLexer) int {
-	return yyParse(yylex)
-}
 %}
 
 %union {
@@ -26,6 +13,10 @@ func Parse(yylex yyLexer) int {
   assignmentOperator ASTAssignmentOperator
   unaryOperator ASTExprPrefixUnaryType
   pointerDepth int
+  pointerQualifiers []Qualifiers
+  storage StorageClass
+  qualifiers Qualifiers
+  rng Range
 }
 
 %token IDENTIFIER CONSTANT STRING_LITERAL SIZEOF
@@ -37,6 +28,7 @@ func Parse(yylex yyLexer) int {
 %token TYPEDEF EXTERN STATIC AUTO REGISTER
 %token CHAR SHORT INT LONG SIGNED UNSIGNED FLOAT DOUBLE CONST VOLATILE VOID
 %token STRUCT UNION ENUM ELLIPSIS
+%token VA_START VA_ARG VA_END
 
 %token CASE DEFAULT IF ELSE SWITCH WHILE DO FOR GOTO CONTINUE BREAK RETURN
 
@@ -44,10 +36,19 @@ func Parse(yylex yyLexer) int {
 %%
 
 primary_expression
-	: IDENTIFIER { $$.n = &ASTIdentifier{ident: $1.str} }
-	| CONSTANT { $$.n = &ASTConstant{value: $1.str}}
-	| STRING_LITERAL { $$.n = &ASTStringLiteral{value: $1.str} }
-	| '(' expression ')' { $$.n = &ASTBrackets{$2.n} }
+	: IDENTIFIER { $$.n = &ASTIdentifier{located: span($1, $1), ident: $1.str} }
+	| CONSTANT { $$.n = &ASTConstant{located: span($1, $1), value: $1.str}}
+	| STRING_LITERAL { $$.n = &ASTStringLiteral{located: span($1, $1), value: $1.str} }
+	| '(' expression ')' { $$.n = &ASTBrackets{located: span($1, $3), Node: $2.n} }
+	| VA_START '(' assignment_expression ',' IDENTIFIER ')' {
+		$$.n = &ASTVaStart{located: span($1, $6), ap: $3.n, last: &ASTIdentifier{located: span($5, $5), ident: $5.str}}
+	}
+	| VA_ARG '(' assignment_expression ',' type_name ')' {
+		$$.n = &ASTVaArg{located: span($1, $6), ap: $3.n, typeName: $5.n.(*ASTTypeName)}
+	}
+	| VA_END '(' assignment_expression ')' {
+		$$.n = &ASTVaEnd{located: span($1, $4), ap: $3.n}
+	}
 	;
 
 postfix_expression
@@ -55,26 +56,28 @@ postfix_expression
 	| postfix_expression '[' expression ']' {
 		// Array indexing
 		$$.n = &ASTIndexedExpression{
+			located: span($1, $4),
 			lvalue: $1.n,
 			index: $3.n,
 		}
 	}
 	| postfix_expression '(' ')' { 
-		$$.n = &ASTFunctionCall{function: $1.n} 
+		$$.n = &ASTFunctionCall{located: span($1, $3), function: $1.n}
 	}
 	| postfix_expression '(' argument_expression_list ')' { 
 		$$.n = &ASTFunctionCall{
+			located: span($1, $4),
 			function: $1.n,
 			arguments: $3.n.(ASTArgumentExpressionList),
 		}
 	}
-	| postfix_expression '.' IDENTIFIER { $$.n = &ASTStructElement{structImp: $1.n, ident: $3.str} }
-	| postfix_expression PTR_OP IDENTIFIER { $$.n = &ASTStructElement{structImp: $1.n, ident: $3.str, pointer: true} }
+	| postfix_expression '.' IDENTIFIER { $$.n = &ASTStructElement{located: span($1, $3), structImp: $1.n, ident: $3.str} }
+	| postfix_expression PTR_OP IDENTIFIER { $$.n = &ASTStructElement{located: span($1, $3), structImp: $1.n, ident: $3.str, pointer: true} }
 	| postfix_expression INC_OP {
-		$$.n = &ASTExprSuffixUnary{typ: ASTExprSuffixUnaryTypeIncrement, lvalue: $1.n}
+		$$.n = &ASTExprSuffixUnary{located: span($1, $2), typ: ASTExprSuffixUnaryTypeIncrement, lvalue: $1.n}
 	}
 	| postfix_expression DEC_OP {
-		$$.n = &ASTExprSuffixUnary{typ: ASTExprSuffixUnaryTypeDecrement, lvalue: $1.n}
+		$$.n = &ASTExprSuffixUnary{located: span($1, $2), typ: ASTExprSuffixUnaryTypeDecrement, lvalue: $1.n}
 	}
 	;
 
@@ -90,19 +93,19 @@ argument_expression_list
 unary_expression
 	: postfix_expression {$$.n = $1.n}
 	| INC_OP unary_expression { 
-		$$.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeIncrement, lvalue: $2.n} 
+		$$.n = &ASTExprPrefixUnary{located: span($1, $2), typ: ASTExprPrefixUnaryTypeIncrement, lvalue: $2.n}
 	}
 	| DEC_OP unary_expression {
-		$$.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeDecrement, lvalue: $2.n}
+		$$.n = &ASTExprPrefixUnary{located: span($1, $2), typ: ASTExprPrefixUnaryTypeDecrement, lvalue: $2.n}
 	}
 	| unary_operator cast_expression {
-		$$.n = &ASTExprPrefixUnary{typ: $1.unaryOperator, lvalue: $2.n}
+		$$.n = &ASTExprPrefixUnary{located: span($1, $2), typ: $1.unaryOperator, lvalue: $2.n}
 	}
 	| SIZEOF unary_expression {
-		$$.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: $2.n}
+		$$.n = &ASTExprPrefixUnary{located: span($1, $2), typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: $2.n}
 	}
 	| SIZEOF '(' type_name ')'{
-		$$.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: $3.typ}
+		$$.n = &ASTExprPrefixUnary{located: span($1, $4), typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: $3.n}
 	}
 	;
 
@@ -117,71 +120,74 @@ unary_operator
 
 cast_expression
 	: unary_expression {$$.n = $1.n}
-	| '(' type_name ')' cast_expression
+	| '(' type_name ')' cast_expression {
+		$$.n = &ASTCast{located: span($1, $4), typeName: $2.n.(*ASTTypeName), expr: $4.n}
+	}
 	;
 
 multiplicative_expression
 	: cast_expression {$$.n = $1.n}
-	| multiplicative_expression '*' cast_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeMul} }
-	| multiplicative_expression '/' cast_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeDiv} }
-	| multiplicative_expression '%' cast_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeMod} }
+	| multiplicative_expression '*' cast_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeMul} }
+	| multiplicative_expression '/' cast_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeDiv} }
+	| multiplicative_expression '%' cast_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeMod} }
 	;
 
 additive_expression
 	: multiplicative_expression {$$.n = $1.n}
-	| additive_expression '+' multiplicative_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeAdd } }
-	| additive_expression '-' multiplicative_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeSub } }
+	| additive_expression '+' multiplicative_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeAdd } }
+	| additive_expression '-' multiplicative_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeSub } }
 	;
 
 shift_expression
 	: additive_expression {$$.n = $1.n}
-	| shift_expression LEFT_OP additive_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLeftShift} }
-	| shift_expression RIGHT_OP additive_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeRightShift} }
+	| shift_expression LEFT_OP additive_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLeftShift} }
+	| shift_expression RIGHT_OP additive_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeRightShift} }
 	;
 
 relational_expression
 	: shift_expression {$$.n = $1.n}
-	| relational_expression '<' shift_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLessThan} }
-	| relational_expression '>' shift_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeGreaterThan} }
-	| relational_expression LE_OP shift_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLessOrEqual} }
-	| relational_expression GE_OP shift_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeGreaterOrEqual} }
+	| relational_expression '<' shift_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLessThan} }
+	| relational_expression '>' shift_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeGreaterThan} }
+	| relational_expression LE_OP shift_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLessOrEqual} }
+	| relational_expression GE_OP shift_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeGreaterOrEqual} }
 	;
 
 equality_expression
 	: relational_expression {$$.n = $1.n}
-	| equality_expression EQ_OP relational_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeEquality} }
-	| equality_expression NE_OP relational_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeNotEquality} }
+	| equality_expression EQ_OP relational_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeEquality} }
+	| equality_expression NE_OP relational_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeNotEquality} }
 	;
 
 and_expression
 	: equality_expression {$$.n = $1.n}
-	| and_expression '&' equality_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeBitwiseAnd} }
+	| and_expression '&' equality_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeBitwiseAnd} }
 	;
 
 exclusive_or_expression
 	: and_expression {$$.n = $1.n}
-	| exclusive_or_expression '^' and_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeXor} }
+	| exclusive_or_expression '^' and_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeXor} }
 	;
 
 inclusive_or_expression
 	: exclusive_or_expression {$$.n = $1.n}
-	| inclusive_or_expression '|' exclusive_or_expression  { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeBitwiseOr} }
+	| inclusive_or_expression '|' exclusive_or_expression  { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeBitwiseOr} }
 	;
 
 logical_and_expression
 	: inclusive_or_expression {$$.n = $1.n}
-	| logical_and_expression AND_OP inclusive_or_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLogicalAnd} }
+	| logical_and_expression AND_OP inclusive_or_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLogicalAnd} }
 	;
 
 logical_or_expression
 	: logical_and_expression {$$.n = $1.n}
-	| logical_or_expression OR_OP logical_and_expression { $$.n = &ASTExprBinary{lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLogicalOr} }
+	| logical_or_expression OR_OP logical_and_expression { $$.n = &ASTExprBinary{located: span($1, $3), lhs: $1.n, rhs: $3.n, typ: ASTExprBinaryTypeLogicalOr} }
 	;
 
 conditional_expression
 	: logical_or_expression {$$.n = $1.n}
 	| logical_or_expression '?' expression ':' conditional_expression {
 		$$.n = &ASTIfStatement{
+			located: span($1, $5),
 			condition: $1.n,
 			body: $3.n,
 			elseBody: $5.n,
@@ -192,10 +198,10 @@ conditional_expression
 
 assignment_expression
 	: conditional_expression {
-		$$.n = &ASTAssignment{value: $1.n, tmpAssign: true} 
+		$$.n = &ASTAssignment{located: span($1, $1), value: $1.n, tmpAssign: true}
 	}
 	| unary_expression assignment_operator assignment_expression { 
-		$$.n = &ASTAssignment{lval: $1.n, operator: $2.assignmentOperator, value: $3.n} 
+		$$.n = &ASTAssignment{located: span($1, $3), lval: $1.n, operator: $2.assignmentOperator, value: $3.n}
 	}
 	;
 
@@ -230,97 +236,54 @@ constant_expression
 
 declaration
 	: declaration_specifiers ';' {
-		if $1.typ != nil && $1.typ.typ == VarTypeEnum || $1.typ.typ == VarTypeStruct{
+		if $1.typ != nil && ($1.typ.enum != nil || $1.typ.structure != nil) {
 			$$.n = ASTDeclaratorList{
 				&ASTDecl{
+					located: span($1, $2),
 					typ: $1.typ,
 				},
 			}
 		} else {
-			fmt.Fprintf(os.Stderr, "Ignoring declaration specifier without init declaration list\n")
+			parseWarningf(yylex, span($1, $2).rng, "declaration does not declare anything")
 			$$.n = ASTDeclaratorList{}
 		}
 	}
 	| declaration_specifiers init_declarator_list ';' {
-		if $1.typ != nil {
-			vartype := $1.typ
-			addPointerDepth := 0
-			var typeDefDecl *ASTDirectDeclarator
-			if $1.typ.typ == VarTypeTypeName {
-				mapEntry := typmap[$1.typ.typName]
-				vartype = mapEntry.typ
-				addPointerDepth = mapEntry.decl.pointerDepth
-				typeDefDecl = mapEntry.decl
-			}
-			for _, entry := range $2.n.(ASTDeclaratorList) {
-				entry.typ = vartype
-
-				if typeDefDecl != nil {
-					if entry.decl.array != nil {
-						decl := &entry.decl
-						for *decl != nil {
-							decl = &(*decl).decl
-						}
-						*decl = typeDefDecl
-					} else {
-						entry.decl.array = typeDefDecl.array
-						decl := &entry.decl
-						for *decl != nil {
-							decl = &(*decl).decl
-						}
-						*decl = typeDefDecl
-
-					}
-				}
-
-				entry.decl.pointerDepth += addPointerDepth
-			}
-			$$.n = $2.n
-		} else {
-			// Parse typedef line
-			for _, entry := range $2.n.(ASTDeclaratorList) {
-				typeDef := $1.n.(*ASTTypeDef)
-				ident := entry.decl.Identifier()
-
-				typeDef.typeName = ident.ident
-				typeDef.decl = entry.decl
-
-	            if typeDef.typ.typName != "" {
-					typeDef.decl.pointerDepth += typmap[typeDef.typ.typName].decl.pointerDepth
-					decl := &typeDef.decl
-					for *decl != nil {
-						decl = &(*decl).decl
-					}
-					*decl = typmap[typeDef.typ.typName].decl
-				}
-
-				
-				typmap[ident.ident] = typeDef			
+		typ := specifiedType(yylex, $1)
+		for _, entry := range $2.n.(ASTDeclaratorList) {
+			entry.typ = typ
+			entry.storage = $1.storage
+			if $1.storage == StorageClassTypedef {
+				// The name is lexed as a TYPE_NAME from now on.
+				typedefs(yylex)[entry.decl.Identifier().ident] = true
 			}
-			$$.n = $1.n
 		}
-		
+		$$.n = $2.n
+	}
+	| declaration_specifiers error ';' {
+		// Skip to the end of a declaration after a syntax error.
+		$$.n = ASTDeclaratorList{}
 	}
 	;
 
 declaration_specifiers
-	: storage_class_specifier
+	: storage_class_specifier { $$.storage = $1.storage }
 	| storage_class_specifier declaration_specifiers {
-		if $2.typ.typ == VarTypeTypeName {
-			typName := $2.typ.typName
-			typ := &ASTTypeDef{typ: typmap[$2.typ.typName].typ}
-			typ.typ.typName = typName
-			$$.n = typ
-		} else{
-		$$.n = &ASTTypeDef{typ: $2.typ}
-		}
+		$$ = $2
+		$$.storage = $1.storage
 	}
 	| type_specifier {
 		$$.typ = $1.typ
 	}
-	| type_specifier declaration_specifiers
-	| type_qualifier 
-	| type_qualifier declaration_specifiers
+	| type_specifier declaration_specifiers {
+		$$ = $2
+		$$.typ = combineTypes(yylex, span($1, $2), $1.typ, $2.typ)
+	}
+	| type_qualifier { $$.qualifiers = $1.qualifiers }
+	| type_qualifier declaration_specifiers {
+		$$ = $2
+		$$.qualifiers |= $1.qualifiers
+	}
 	;
 
 init_declarator_list
@@ -335,60 +298,61 @@ init_declarator_list
 init_declarator
 	: declarator { 
 		$$.n = &ASTDecl{
+			located: span($1, $1),
 			decl: $1.n.(*ASTDirectDeclarator),
-		} 
+		}
 	}
 	| declarator '=' initializer { 
 		$$.n = &ASTDecl{
+			located: span($1, $3),
 			decl: $1.n.(*ASTDirectDeclarator),
 			initVal: $3.n,
-		} 
+		}
 	}
 	;
 
 storage_class_specifier
-	: TYPEDEF
-	| EXTERN
-	| STATIC
-	| AUTO
-	| REGISTER
+	: TYPEDEF { $$.storage = StorageClassTypedef }
+	| EXTERN { $$.storage = StorageClassExtern }
+	| STATIC { $$.storage = StorageClassStatic }
+	| AUTO { $$.storage = StorageClassAuto }
+	| REGISTER { $$.storage = StorageClassRegister }
 	;
 
 type_specifier
-	: VOID { $$.typ = &ASTType{typ: VarTypeVoid} }
-	| CHAR { $$.typ = &ASTType{typ: VarTypeChar} }
-	| SHORT { 
-		// https://stackoverflow.com/a/697531
-		$$.typ = &ASTType{typ: VarTypeShort}
-	  }
-	| INT { $$.typ = &ASTType{typ: VarTypeInteger} }
-	| LONG { $$.typ = &ASTType{typ: VarTypeLong} }
-	| FLOAT { $$.typ = &ASTType{typ: VarTypeFloat} }
-	| DOUBLE { $$.typ = &ASTType{typ: VarTypeDouble} }
-	| SIGNED { $$.typ = &ASTType{typ: VarTypeSigned} }
-	| UNSIGNED { $$.typ = &ASTType{typ: VarTypeUnsigned} }
-	| struct_or_union_specifier { 
-		$$.typ = &ASTType{typ: VarTypeStruct, typName: $1.n.(*ASTStruct).ident.ident, structure: $1.n.(*ASTStruct)}
+	: VOID { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"void"}} }
+	| CHAR { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"char"}} }
+	| SHORT { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"short"}} }
+	| INT { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"int"}} }
+	| LONG { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"long"}} }
+	| FLOAT { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"float"}} }
+	| DOUBLE { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"double"}} }
+	| SIGNED { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"signed"}} }
+	| UNSIGNED { $$.typ = &ASTType{located: span($1, $1), specifiers: []string{"unsigned"}} }
+	| struct_or_union_specifier {
+		$$.typ = &ASTType{located: span($1, $1), structure: $1.n.(*ASTStruct)}
 	}
-	| enum_specifier { 
-		$$.typ = &ASTType{typ: VarTypeEnum, enum: $1.n.(*ASTEnum)}
+	| enum_specifier {
+		$$.typ = &ASTType{located: span($1, $1), enum: $1.n.(*ASTEnum)}
 	}
-	| TYPE_NAME { $$.typ = &ASTType{typ: VarTypeTypeName, typName: $1.str} }
+	| TYPE_NAME { $$.typ = &ASTType{located: span($1, $1), typName: $1.str} }
 	;
 
 struct_or_union_specifier
 	: struct_or_union IDENTIFIER '{' struct_declaration_list '}' {
-		$$.n = &ASTStruct{ident: &ASTIdentifier{ident: $2.str}, elements: $4.n.(ASTStructDeclarationList)}
+		$$.n = &ASTStruct{located: span($1, $5), union: $1.str == "union", ident: &ASTIdentifier{located: span($2, $2), ident: $2.str}, elements: $4.n.(ASTStructDeclarationList)}
+	}
+	| struct_or_union '{' struct_declaration_list '}' {
+		$$.n = &ASTStruct{located: span($1, $4), union: $1.str == "union", elements: $3.n.(ASTStructDeclarationList)}
 	}
-	| struct_or_union '{' struct_declaration_list '}'
 	| struct_or_union IDENTIFIER {
-		$$.n = &ASTStruct{ident: &ASTIdentifier{ident: $2.str}, init: true}
+		$$.n = &ASTStruct{located: span($1, $2), union: $1.str == "union", ident: &ASTIdentifier{located: span($2, $2), ident: $2.str}}
 	}
 	;
 
 struct_or_union
-	: STRUCT 
-	| UNION
+	: STRUCT { $$.str = "struct" }
+	| UNION { $$.str = "union" }
 	;
 
 struct_declaration_list 
@@ -402,18 +366,25 @@ struct_declaration_list
 
 struct_declaration
 	: specifier_qualifier_list struct_declarator_list ';' {
+		typ := specifiedType(yylex, $1)
 		for _, entry := range $2.n.(ASTStructDeclaratorList) {
-			entry.decl.typ = $1.typ
+			entry.decl.typ = typ
 		}
 		$$.n = $2.n
 	}
 	;
 
 specifier_qualifier_list
-	: type_specifier specifier_qualifier_list
-	| type_specifier {$$.n = $1.typ}
-	| type_qualifier specifier_qualifier_list
-	| type_qualifier
+	: type_specifier specifier_qualifier_list {
+		$$ = $2
+		$$.typ = combineTypes(yylex, span($1, $2), $1.typ, $2.typ)
+	}
+	| type_specifier {$$.typ = $1.typ}
+	| type_qualifier specifier_qualifier_list {
+		$$ = $2
+		$$.qualifiers |= $1.qualifiers
+	}
+	| type_qualifier { $$.qualifiers = $1.qualifiers }
 	;
 
 struct_declarator_list
@@ -426,7 +397,7 @@ struct_declarator_list
 	;
 
 struct_declarator
-	: declarator {$$.n = ASTStructDeclarator{decl: &ASTDecl{decl: $1.n.(*ASTDirectDeclarator)}}}
+	: declarator {$$.n = ASTStructDeclarator{located: span($1, $1), decl: &ASTDecl{located: span($1, $1), decl: $1.n.(*ASTDirectDeclarator)}}}
 	| ':' constant_expression //bit-feild
 	| declarator ':' constant_expression //bit-feild
 	;
@@ -434,20 +405,23 @@ struct_declarator
 enum_specifier
 	: ENUM '{' enumerator_list '}' {
 		$$.n = NewASTEnum(
+			span($1, $4),
 			nil,
 			$3.n.(ASTEnumEntryList),
 		)
 	}
 	| ENUM IDENTIFIER '{' enumerator_list '}' {
 		$$.n = NewASTEnum(
-			&ASTIdentifier{ident: $2.str},
+			span($1, $5),
+			&ASTIdentifier{located: span($2, $2), ident: $2.str},
 			$4.n.(ASTEnumEntryList),
 		)
 	}
 	| ENUM IDENTIFIER {
 		// TODO: still need to parse for typedef
 		$$.n = NewASTEnum(
-			&ASTIdentifier{ident: $2.str},
+			span($1, $2),
+			&ASTIdentifier{located: span($2, $2), ident: $2.str},
 			nil,
 		)
 	}
@@ -467,27 +441,28 @@ enumerator_list
 enumerator
 	: IDENTIFIER {
 		$$.n = &ASTEnumEntry{
-			ident: &ASTIdentifier{ident: $1.str},
+			located: span($1, $1),
+			ident: &ASTIdentifier{located: span($1, $1), ident: $1.str},
 			value: nil,
 		}
 	}
 	| IDENTIFIER '=' constant_expression {
 		$$.n = &ASTEnumEntry{
-			ident: &ASTIdentifier{ident: $1.str},
+			located: span($1, $3),
+			ident: &ASTIdentifier{located: span($1, $1), ident: $1.str},
 			value: $3.n,
 		}
 	}
 	;
 
 type_qualifier
-	: CONST
-	| VOLATILE
+	: CONST { $$.qualifiers = QualifierConst }
+	| VOLATILE { $$.qualifiers = QualifierVolatile }
 	;
 
 declarator
 	: pointer direct_declarator {
-		$2.n.(*ASTDirectDeclarator).pointerDepth = $1.pointerDepth
-		$$.n = $2.n
+		$$.n = pointerDeclarator(span($1, $2), $1.pointerDepth, $1.pointerQualifiers, $2.n.(*ASTDirectDeclarator))
 	}
 	| direct_declarator { $$.n = $1.n }
 	;
@@ -495,20 +470,24 @@ declarator
 direct_declarator
 	: IDENTIFIER	{
 		$$.n = &ASTDirectDeclarator{
+			located: span($1, $1),
 			identifier: &ASTIdentifier{
+				located: span($1, $1),
 				ident: $1.str,
 			},
 		}
 	}
-	| '(' declarator ')'
+	| '(' declarator ')' { $$.n = $2.n }
 	| direct_declarator '[' constant_expression ']' {
 		$$.n = &ASTDirectDeclarator{
+			located: span($1, $4),
 			decl: $1.n.(*ASTDirectDeclarator),
 			array: NewASTArray($3.n),
 		}
 	}
 	| direct_declarator '[' ']' {
 		$$.n = &ASTDirectDeclarator{
+			located: span($1, $3),
 			decl: $1.n.(*ASTDirectDeclarator),
 			array: NewASTArray(nil),
 		}
@@ -516,16 +495,24 @@ direct_declarator
 	| direct_declarator '(' parameter_type_list ')' {
 		// Function declaration with arguments
 		$$.n = &ASTDirectDeclarator{
+			located: span($1, $4),
 			decl: $1.n.(*ASTDirectDeclarator),
 			parameters: $3.n.(*ASTParameterList),
 		}
 	}
 	| direct_declarator '(' identifier_list ')' {
 		// Function declaration for old K&R style funcs
+		parseErrorf(yylex, span($1, $4).rng, "old-style (K&R) function declarations are not supported")
+		$$.n = &ASTDirectDeclarator{
+			located: span($1, $4),
+			decl: $1.n.(*ASTDirectDeclarator),
+			parameters: &ASTParameterList{},
+		}
 	}
 	| direct_declarator '(' ')' {
 		// Function declaration with no arguments
 		$$.n = &ASTDirectDeclarator{
+			located: span($1, $3),
 			decl: $1.n.(*ASTDirectDeclarator),
 			parameters: &ASTParameterList{},
 		}
@@ -533,15 +520,27 @@ direct_declarator
 	;
 
 pointer
-	: '*' {$$.pointerDepth = 1}
-	| '*' type_qualifier_list
-	| '*' pointer {$$.pointerDepth = 1 + $2.pointerDepth}
-	| '*' type_qualifier_list pointer
+	: '*' {
+		$$.pointerDepth = 1
+		$$.pointerQualifiers = []Qualifiers{0}
+	}
+	| '*' type_qualifier_list {
+		$$.pointerDepth = 1
+		$$.pointerQualifiers = []Qualifiers{$2.qualifiers}
+	}
+	| '*' pointer {
+		$$.pointerDepth = 1 + $2.pointerDepth
+		$$.pointerQualifiers = append([]Qualifiers{0}, $2.pointerQualifiers...)
+	}
+	| '*' type_qualifier_list pointer {
+		$$.pointerDepth = 1 + $3.pointerDepth
+		$$.pointerQualifiers = append([]Qualifiers{$2.qualifiers}, $3.pointerQualifiers...)
+	}
 	;
 
 type_qualifier_list
-	: type_qualifier
-	| type_qualifier_list type_qualifier
+	: type_qualifier { $$.qualifiers = $1.qualifiers }
+	| type_qualifier_list type_qualifier { $$.qualifiers = $1.qualifiers | $2.qualifiers }
 	;
 
 parameter_type_list
@@ -572,23 +571,23 @@ parameter_list
 
 parameter_declaration
 	: declaration_specifiers declarator {
-		vartype := $1.typ
-		if $1.typ.typ == VarTypeTypeName {
-			vartype = typmap[$1.typ.typName].typ
+		$$.n = &ASTParameterDeclaration{
+			located: span($1, $2),
+			specifier: specifiedType(yylex, $1),
+			declarator: $2.n,
 		}
+	}
+	| declaration_specifiers abstract_declarator {
 		$$.n = &ASTParameterDeclaration{
-			specifier: vartype,
+			located: span($1, $2),
+			specifier: specifiedType(yylex, $1),
 			declarator: $2.n,
 		}
 	}
-	| declaration_specifiers abstract_declarator
 	| declaration_specifiers {
-		vartype := $1.typ
-		if $1.typ.typ == VarTypeTypeName {
-			vartype = typmap[$1.typ.typName].typ
-		}
 		$$.n = &ASTParameterDeclaration{
-			specifier: vartype,
+			located: span($1, $1),
+			specifier: specifiedType(yylex, $1),
 		}
 	}
 	;
@@ -600,26 +599,64 @@ identifier_list
 	;
 
 type_name
-	: specifier_qualifier_list {$$.n = $1.n}
-	| specifier_qualifier_list abstract_declarator
+	: specifier_qualifier_list {
+		$$.n = &ASTTypeName{located: span($1, $1), typ: specifiedType(yylex, $1)}
+	}
+	| specifier_qualifier_list abstract_declarator {
+		$$.n = &ASTTypeName{located: span($1, $2), typ: specifiedType(yylex, $1), decl: $2.n.(*ASTDirectDeclarator)}
+	}
 	;
 
 abstract_declarator
-	: pointer
-	| direct_abstract_declarator
-	| pointer direct_abstract_declarator
+	: pointer { $$.n = &ASTDirectDeclarator{located: span($1, $1), pointerDepth: $1.pointerDepth, pointerQualifiers: $1.pointerQualifiers} }
+	| direct_abstract_declarator { $$.n = $1.n }
+	| pointer direct_abstract_declarator {
+		$$.n = pointerDeclarator(span($1, $2), $1.pointerDepth, $1.pointerQualifiers, $2.n.(*ASTDirectDeclarator))
+	}
 	;
 
 direct_abstract_declarator
-	: '(' abstract_declarator ')'
-	| '[' ']'
-	| '[' constant_expression ']'
-	| direct_abstract_declarator '[' ']'
-	| direct_abstract_declarator '[' constant_expression ']'
-	| '(' ')'
-	| '(' parameter_type_list ')'
-	| direct_abstract_declarator '(' ')'
-	| direct_abstract_declarator '(' parameter_type_list ')'
+	: '(' abstract_declarator ')' { $$.n = $2.n }
+	| '[' ']' {
+		$$.n = &ASTDirectDeclarator{located: span($1, $2), array: NewASTArray(nil)}
+	}
+	| '[' constant_expression ']' {
+		$$.n = &ASTDirectDeclarator{located: span($1, $3), array: NewASTArray($2.n)}
+	}
+	| direct_abstract_declarator '[' ']' {
+		$$.n = &ASTDirectDeclarator{
+			located: span($1, $3),
+			decl: $1.n.(*ASTDirectDeclarator),
+			array: NewASTArray(nil),
+		}
+	}
+	| direct_abstract_declarator '[' constant_expression ']' {
+		$$.n = &ASTDirectDeclarator{
+			located: span($1, $4),
+			decl: $1.n.(*ASTDirectDeclarator),
+			array: NewASTArray($3.n),
+		}
+	}
+	| '(' ')' {
+		$$.n = &ASTDirectDeclarator{located: span($1, $2), parameters: &ASTParameterList{}}
+	}
+	| '(' parameter_type_list ')' {
+		$$.n = &ASTDirectDeclarator{located: span($1, $3), parameters: $2.n.(*ASTParameterList)}
+	}
+	| direct_abstract_declarator '(' ')' {
+		$$.n = &ASTDirectDeclarator{
+			located: span($1, $3),
+			decl: $1.n.(*ASTDirectDeclarator),
+			parameters: &ASTParameterList{},
+		}
+	}
+	| direct_abstract_declarator '(' parameter_type_list ')' {
+		$$.n = &ASTDirectDeclarator{
+			located: span($1, $4),
+			decl: $1.n.(*ASTDirectDeclarator),
+			parameters: $3.n.(*ASTParameterList),
+		}
+	}
 	;
 
 initializer
@@ -644,17 +681,23 @@ statement
 	| selection_statement { $$.n = $1.n }
 	| iteration_statement { $$.n = $1.n }
 	| jump_statement { $$.n = $1.n }
+	| error ';' {
+		// Skip to the end of a statement after a syntax error.
+		$$.n = nil
+	}
 	;
 
 labeled_statement
 	: IDENTIFIER ':' statement {
 		$$.n = &ASTLabeledStatement{
-			ident: &ASTIdentifier{ident: $1.str},
+			located: span($1, $3),
+			ident: &ASTIdentifier{located: span($1, $1), ident: $1.str},
 			stmt: $3.n,
 		}
 	}
 	| CASE constant_expression ':' statement {
 		$$.n = &ASTSwitchCase{
+			located: span($1, $4),
 			caseVal: $2.n,
 			body: $4.n,
 			defaultCase: false,
@@ -662,6 +705,7 @@ labeled_statement
 	}
 	| DEFAULT ':' statement {
 		$$.n = &ASTSwitchCase{
+			located: span($1, $3),
 			caseVal: nil,
 			body: $3.n,
 			defaultCase: true,
@@ -671,15 +715,30 @@ labeled_statement
 
 // TODO: create a new scope for these
 compound_statement
-	: '{' '}' { $$.n = &ASTScope{} }
-	| '{' statement_list '}' { 
-		$$.n = &ASTScope{body: $2.n} 
+	: '{' '}' { $$.n = &ASTScope{located: span($1, $2)} }
+	| '{' statement_list '}' {
+		$$.n = &ASTScope{located: span($1, $3), body: $2.n}
 	}
-	| '{' declaration_list '}' { 
-		$$.n = &ASTScope{body: $2.n} 
+	| '{' declaration_list '}' {
+		$$.n = &ASTScope{located: span($1, $3), body: $2.n}
 	}
 	| '{' declaration_list statement_list '}' {
 		$$.n = &ASTScope{
+			located: span($1, $4),
+			body: &ASTDeclarationStatementLists{
+				decls: $2.n.(ASTDeclaratorList),
+				stmts: $3.n.(ASTStatementList),
+			},
+		}
+	}
+	// A syntax error in the last statement of a block, such as a missing ';',
+	// recovers at the closing brace so the rest of the file is still parsed.
+	| '{' error '}' { $$.n = &ASTScope{located: span($1, $3)} }
+	| '{' statement_list error '}' { $$.n = &ASTScope{located: span($1, $4), body: $2.n} }
+	| '{' declaration_list error '}' { $$.n = &ASTScope{located: span($1, $4), body: $2.n} }
+	| '{' declaration_list statement_list error '}' {
+		$$.n = &ASTScope{
+			located: span($1, $5),
 			body: &ASTDeclarationStatementLists{
 				decls: $2.n.(ASTDeclaratorList),
 				stmts: $3.n.(ASTStatementList),
@@ -714,6 +773,7 @@ expression_statement
 selection_statement
 	: IF '(' expression ')' statement {
 		$$.n = &ASTIfStatement{
+			located: span($1, $5),
 			condition: $3.n,
 			body: $5.n,
 			elseBody: nil,
@@ -721,6 +781,7 @@ selection_statement
 	}
 	| IF '(' expression ')' statement ELSE statement {
 		$$.n = &ASTIfStatement{
+			located: span($1, $7),
 			condition: $3.n,
 			body: $5.n,
 			elseBody: $7.n,
@@ -728,6 +789,7 @@ selection_statement
 	}
 	| SWITCH '(' expression ')' statement {
 		$$.n = &ASTSwitchStatement{
+			located: span($1, $5),
 			switchOn: $3.n,
 			body: $5.n,
 		}
@@ -737,18 +799,21 @@ selection_statement
 iteration_statement
 	: WHILE '(' expression ')' statement {
 		$$.n = &ASTWhileLoop{
+			located: span($1, $5),
 			condition: $3.n,
 			body: $5.n,
 		}
 	}
 	| DO statement WHILE '(' expression ')' ';' {
 		$$.n = &ASTDoWhileLoop{
+			located: span($1, $7),
 			condition: $5.n,
 			body: $2.n,
 		}
 	}
 	| FOR '(' expression_statement expression_statement ')' statement {
 		$$.n = &ASTForLoop{
+			located: span($1, $6),
 			initialiser: $3.n,
 			condition: $4.n,
 			postIterationExpr: nil,
@@ -757,6 +822,7 @@ iteration_statement
 	}
 	| FOR '(' expression_statement expression_statement expression ')' statement {
 		$$.n = &ASTForLoop{
+			located: span($1, $7),
 			initialiser: $3.n,
 			condition: $4.n,
 			postIterationExpr: $5.n,
@@ -766,38 +832,60 @@ iteration_statement
 	;
 
 jump_statement
-	: GOTO IDENTIFIER ';' { 
+	: GOTO IDENTIFIER ';' {
 		$$.n = &ASTGoto{
-			label: &ASTIdentifier{ident: $2.str},
+			located: span($1, $3),
+			label: &ASTIdentifier{located: span($2, $2), ident: $2.str},
 		}
 	}
 	| CONTINUE ';' {
-		$$.n = &ASTContinue{}
+		$$.n = &ASTContinue{located: span($1, $2)}
 	}
 	| BREAK ';' {
-		$$.n = &ASTBreak{}
+		$$.n = &ASTBreak{located: span($1, $2)}
 	}
-	| RETURN ';' { $$.n = &ASTReturn{} }
-	| RETURN expression ';' { $$.n = &ASTReturn{returnVal: $2.n} }
+	| RETURN ';' { $$.n = &ASTReturn{located: span($1, $2)} }
+	| RETURN expression ';' { $$.n = &ASTReturn{located: span($1, $3), returnVal: $2.n} }
 	;
 
 translation_unit
-	: external_declaration { 
-		AST = ASTTranslationUnit{$1.n} 
+	: external_declaration {
+		if $1.n != nil {
+			yylex.(*lexer).unit = append(yylex.(*lexer).unit, $1.n)
+		}
 	}
 	| translation_unit external_declaration {
-		AST = append(AST, $2.n)
+		if $2.n != nil {
+			yylex.(*lexer).unit = append(yylex.(*lexer).unit, $2.n)
+		}
 	}
 	;
 
 external_declaration
 	: function_definition { $$.n = $1.n }
 	| declaration // TODO: global variables
+	| error ';' {
+		// Skip to the end of a declaration after a syntax error.
+		$$.n = nil
+	}
+	| error '}' {
+		// Skip to the end of a function after a syntax error.
+		$$.n = nil
+	}
 	;
 
 function_definition
-	: declaration_specifiers declarator declaration_list compound_statement { panic("Old K&R style function parsed (1)") }// Old K&R style C parameter declarations
-	| declaration_specifiers declarator compound_statement { $$.n = &ASTFunction{typ: $1.typ, decl: $2.n.(*ASTDirectDeclarator), body: $3.n} }
-	| declarator declaration_list compound_statement { panic("Old K&R style function parsed (2)") }
-	| declarator compound_statement { $$.n = &ASTFunction{typ: &ASTType{typ: VarTypeInteger}, decl: $1.n.(*ASTDirectDeclarator), body: $2.n} } // Function without a type
+	: declaration_specifiers declarator declaration_list compound_statement {
+		// Old K&R style C parameter declarations
+		parseErrorf(yylex, span($1, $3).rng, "old-style (K&R) function definitions are not supported")
+		$$.n = nil
+	}
+	| declaration_specifiers declarator compound_statement {
+		$$.n = &ASTFunction{located: span($1, $3), typ: specifiedType(yylex, $1), storage: $1.storage, decl: $2.n.(*ASTDirectDeclarator), body: $3.n}
+	}
+	| declarator declaration_list compound_statement {
+		parseErrorf(yylex, span($1, $2).rng, "old-style (K&R) function definitions are not supported")
+		$$.n = nil
+	}
+	| declarator compound_statement { $$.n = &ASTFunction{located: span($1, $2), typ: &ASTType{located: span($1, $1), specifiers: []string{"int"}, kind: TypeInt}, decl: $1.n.(*ASTDirectDeclarator), body: $2.n} } // Function without a type
 	;
@@ -0,0 +1,257 @@
+package c90
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jpnock/see90/pkg/cpp"
+)
+
+// PositionMapper maps a zero-based line and column within the lexer's input
+// back to the position in the original source files. *cpp.Reader implements
+// this interface.
+type PositionMapper interface {
+	Position(line, column int) cpp.Position
+}
+
+type lexedToken struct {
+	tok  int
+	text string
+	rng  Range
+}
+
+// lexer wraps the nex generated Lexer, recording the location of each token
+// and reporting syntax errors as diagnostics.
+type lexer struct {
+	nex       *Lexer
+	positions PositionMapper
+	sink      DiagnosticSink
+
+	// cur is the most recently read token, which is the one the parser was
+	// looking at when it reports an error, and prev is the one before it.
+	cur  lexedToken
+	prev lexedToken
+
+	// tracker has been fed every token before cur, and is used to describe
+	// what was expected when cur causes a syntax error.
+	tracker *lrTracker
+	fedCur  bool
+
+	errors int
+	eof    bool
+
+	// typedefs holds the names declared with typedef so far, which are lexed
+	// as TYPE_NAME rather than IDENTIFIER.
+	typedefs map[string]bool
+
+	// unit is built up by the parser as each external declaration is parsed.
+	unit ASTTranslationUnit
+}
+
+func newLexer(src io.Reader, positions PositionMapper, sink DiagnosticSink, typedefs map[string]bool) *lexer {
+	return &lexer{
+		nex:       NewLexer(src),
+		positions: positions,
+		sink:      sink,
+		tracker:   newLRTracker(),
+		fedCur:    true,
+		typedefs:  typedefs,
+	}
+}
+
+func (l *lexer) Lex(lval *yySymType) int {
+	*lval = yySymType{}
+	if !l.fedCur {
+		l.tracker.feed(yyInternalToken(l.cur.tok))
+	}
+	l.fedCur = false
+
+	tok := l.nex.Lex(lval)
+	l.prev = l.cur
+	if tok == 0 {
+		l.eof = true
+		// The nex lexer has no position at the end of the input, so use the
+		// end of the last token instead.
+		l.cur = lexedToken{rng: Range{Start: l.cur.rng.End, End: l.cur.rng.End}}
+		lval.rng = l.cur.rng
+		return 0
+	}
+
+	text := l.nex.Text()
+	if tok == IDENTIFIER {
+		if l.typedefs[text] {
+			tok = TYPE_NAME
+		} else if builtin, ok := builtinTokens[text]; ok {
+			tok = builtin
+		}
+	}
+
+	line, col := l.nex.Line(), l.nex.Column()
+	start := l.positions.Position(line, col)
+	end := l.positions.Position(line, col+len(text)-1)
+	end.Column++
+
+	l.cur = lexedToken{tok: tok, text: text, rng: Range{Start: start, End: end}}
+	lval.rng = l.cur.rng
+	return tok
+}
+
+// Error is called by the parser when it finds a syntax error. The message
+// from goyacc is only used if the tracker disagrees that cur is an error.
+func (l *lexer) Error(msg string) {
+	l.fedCur = true
+	if state, ok := l.tracker.feed(yyInternalToken(l.cur.tok)); ok {
+		msg = syntaxErrorMessage(state, l.prev, l.cur)
+	}
+	l.errorf(l.cur.rng, "%s", msg)
+}
+
+func (l *lexer) errorf(rng Range, format string, args ...interface{}) {
+	l.errors++
+	reportf(l.sink, SeverityError, rng, format, args...)
+}
+
+func (l *lexer) warningf(rng Range, format string, args ...interface{}) {
+	reportf(l.sink, SeverityWarning, rng, format, args...)
+}
+
+// builtinTokens maps the names of the <stdarg.h> macros to the tokens for
+// them. Both the standard names and the names used by gcc's stdarg.h are
+// accepted, so that variadic functions can be written with or without
+// including the header.
+var builtinTokens = map[string]int{
+	"va_start":           VA_START,
+	"va_arg":             VA_ARG,
+	"va_end":             VA_END,
+	"__builtin_va_start": VA_START,
+	"__builtin_va_arg":   VA_ARG,
+	"__builtin_va_end":   VA_END,
+}
+
+// span returns the location of the source between two grammar symbols.
+func span(from, to yySymType) located {
+	return located{spanRange(symbolRange(from), symbolRange(to))}
+}
+
+func symbolRange(s yySymType) Range {
+	if s.n != nil {
+		if rng := rangeOf(s.n); rng.IsValid() {
+			return rng
+		}
+	}
+	return s.rng
+}
+
+// typedefs returns the names declared with typedef in the translation unit
+// being parsed.
+func typedefs(yylex yyLexer) map[string]bool {
+	return yylex.(*lexer).typedefs
+}
+
+// basicTypes maps the type specifier keywords which can be combined, written
+// in canonical order, to the type they name.
+var basicTypes = map[string]TypeKind{
+	"void":               TypeVoid,
+	"char":               TypeChar,
+	"signed char":        TypeSignedChar,
+	"unsigned char":      TypeUnsignedChar,
+	"short":              TypeShort,
+	"short int":          TypeShort,
+	"signed short":       TypeShort,
+	"signed short int":   TypeShort,
+	"unsigned short":     TypeUnsignedShort,
+	"unsigned short int": TypeUnsignedShort,
+	"int":                TypeInt,
+	"signed":             TypeInt,
+	"signed int":         TypeInt,
+	"unsigned":           TypeUnsignedInt,
+	"unsigned int":       TypeUnsignedInt,
+	"long":               TypeLong,
+	"long int":           TypeLong,
+	"signed long":        TypeLong,
+	"signed long int":    TypeLong,
+	"unsigned long":      TypeUnsignedLong,
+	"unsigned long int":  TypeUnsignedLong,
+	"float":              TypeFloat,
+	"double":             TypeDouble,
+	"long double":        TypeLongDouble,
+}
+
+// specifierOrder gives the position of each keyword when the type specifiers
+// are put into canonical order.
+var specifierOrder = map[string]int{
+	"signed":   0,
+	"unsigned": 0,
+	"short":    1,
+	"long":     1,
+}
+
+// combineTypes merges the type specifiers a and b of a declaration, such as
+// the `unsigned` and `long` of `unsigned long`. Only keywords naming basic
+// types can be combined.
+func combineTypes(yylex yyLexer, loc located, a, b *ASTType) *ASTType {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.specifiers == nil || b.specifiers == nil:
+		parseErrorf(yylex, loc.rng, "two or more data types in declaration specifiers")
+		return b
+	}
+	specifiers := append(append([]string(nil), a.specifiers...), b.specifiers...)
+	return &ASTType{located: loc, specifiers: specifiers}
+}
+
+// specifiedType returns the type named by some declaration specifiers, which
+// is int if they only contain storage classes and qualifiers. The keywords of
+// a basic type are folded into its TypeKind.
+func specifiedType(yylex yyLexer, s yySymType) *ASTType {
+	typ := s.typ
+	if typ == nil {
+		typ = &ASTType{located: located{s.rng}, specifiers: []string{"int"}, kind: TypeInt}
+	}
+	if typ.specifiers != nil && typ.kind == TypeInvalid {
+		specifiers := append([]string(nil), typ.specifiers...)
+		sort.SliceStable(specifiers, func(i, j int) bool {
+			return orderOfSpecifier(specifiers[i]) < orderOfSpecifier(specifiers[j])
+		})
+		kind, ok := basicTypes[strings.Join(specifiers, " ")]
+		if !ok {
+			parseErrorf(yylex, typ.rng, "invalid combination of type specifiers '%s'", strings.Join(typ.specifiers, " "))
+			kind = TypeInt
+		}
+		typ.kind = kind
+	}
+	typ.qualifiers |= s.qualifiers
+	return typ
+}
+
+func orderOfSpecifier(keyword string) int {
+	if order, ok := specifierOrder[keyword]; ok {
+		return order
+	}
+	return 2
+}
+
+// parseErrorf reports an error found while running the actions of the
+// grammar.
+func parseErrorf(yylex yyLexer, rng Range, format string, args ...interface{}) {
+	yylex.(*lexer).errorf(rng, format, args...)
+}
+
+func parseWarningf(yylex yyLexer, rng Range, format string, args ...interface{}) {
+	yylex.(*lexer).warningf(rng, format, args...)
+}
+
+// close stops the nex lexer if the parser gave up before reaching the end of
+// the input, as otherwise its goroutine would never exit.
+func (l *lexer) close() {
+	if l.eof {
+		return
+	}
+	for (<-l.nex.ch).i != -1 {
+	}
+	l.eof = true
+}
@@ -0,0 +1,161 @@
+package c90
+
+import (
+	"errors"
+	"io"
+
+	"github.com/jpnock/see90/pkg/cpp"
+	"github.com/jpnock/see90/pkg/ir"
+	"github.com/jpnock/see90/pkg/mips"
+	"github.com/jpnock/see90/pkg/opt"
+)
+
+// ErrCompile is returned by the Session methods when compilation failed
+// because of errors which have been reported to the session's DiagnosticSink.
+var ErrCompile = errors.New("c90: compilation failed")
+
+// Session compiles a single translation unit. It owns all of the state used
+// while compiling, so separate sessions can be used from different goroutines
+// at the same time.
+//
+// The methods are called in order: ParseFile (or Parse), Check, Optimize if
+// the code is to be optimised, and then Emit or EmitIR.
+type Session struct {
+	pp          *cpp.Preprocessor
+	diagnostics DiagnosticSink
+
+	typedefs map[string]bool
+	unit     ASTTranslationUnit
+	parsed   bool
+
+	// module is lowered by Check once the checker has annotated the AST, as
+	// lowering reports the constructs it doesn't support.
+	module  *ir.Module
+	checked bool
+	errors  int
+
+	// peephole is set if Emit runs the peephole optimiser over the
+	// assembly.
+	peephole bool
+}
+
+// NewSession creates a session which preprocesses its input with pp and
+// reports diagnostics to sink. The preprocessor must not be shared with any
+// other session.
+func NewSession(pp *cpp.Preprocessor, sink DiagnosticSink) *Session {
+	typedefs := map[string]bool{}
+	for name := range builtinTypedefs {
+		typedefs[name] = true
+	}
+	return &Session{
+		pp:          pp,
+		diagnostics: sink,
+		typedefs:    typedefs,
+	}
+}
+
+// ParseFile preprocesses and parses the C source file at path.
+func (s *Session) ParseFile(path string) error {
+	src, err := s.pp.PreprocessFile(path)
+	if err != nil {
+		s.reportPreprocessorError(err)
+		return ErrCompile
+	}
+	return s.Parse(src)
+}
+
+// Parse parses source which has already been preprocessed.
+func (s *Session) Parse(src *cpp.Reader) error {
+	lexer := newLexer(src, src, s.diagnostics, s.typedefs)
+	defer lexer.close()
+
+	if yyParse(lexer) != 0 && lexer.errors == 0 {
+		lexer.errorf(lexer.cur.rng, "unable to parse the translation unit")
+	}
+	s.unit = append(s.unit, lexer.unit...)
+	s.parsed = true
+
+	s.errors += lexer.errors
+	if lexer.errors > 0 {
+		return ErrCompile
+	}
+	return nil
+}
+
+func (s *Session) reportPreprocessorError(err error) {
+	var list cpp.ErrorList
+	if !errors.As(err, &list) {
+		reportf(s.diagnostics, SeverityError, Range{}, "%v", err)
+		s.errors++
+		return
+	}
+	for _, e := range list {
+		reportf(s.diagnostics, SeverityError, Range{Start: e.Pos, End: e.Pos}, "%s", e.Msg)
+		s.errors++
+	}
+}
+
+// TranslationUnit returns the AST parsed so far.
+func (s *Session) TranslationUnit() ASTTranslationUnit {
+	return s.unit
+}
+
+// Check finds the semantic errors in the parsed translation unit.
+func (s *Session) Check() error {
+	if !s.parsed || s.errors > 0 {
+		return ErrCompile
+	}
+	if !s.checked {
+		s.checked = true
+
+		c := newChecker(s.diagnostics)
+		c.checkTranslationUnit(s.unit)
+		s.errors += c.errors
+		if s.errors > 0 {
+			return ErrCompile
+		}
+
+		l := NewLowerer(s.diagnostics)
+		s.unit.Lower(l)
+		s.module = l.Module()
+		s.errors += l.Errors()
+	}
+	if s.errors > 0 {
+		return ErrCompile
+	}
+	return nil
+}
+
+// Optimize optimises the IR of the translation unit at the given level, from
+// 0 (no optimisation) to 2. It must be called at most once.
+func (s *Session) Optimize(level int) error {
+	if err := s.Check(); err != nil {
+		return err
+	}
+	opt.Optimize(s.module, level)
+	return nil
+}
+
+// SetPeephole sets whether Emit runs the peephole optimiser over the
+// assembly it writes, which it doesn't by default.
+func (s *Session) SetPeephole(enabled bool) {
+	s.peephole = enabled
+}
+
+// Emit writes the MIPS assembly for the translation unit to w. Nothing is
+// written if any errors have been found.
+func (s *Session) Emit(w io.Writer) error {
+	if err := s.Check(); err != nil {
+		return err
+	}
+	return mips.Generate(w, s.module, s.peephole)
+}
+
+// EmitIR writes the IR the translation unit is lowered to to w, in the same
+// way as Emit.
+func (s *Session) EmitIR(w io.Writer) error {
+	if err := s.Check(); err != nil {
+		return err
+	}
+	return ir.Fprint(w, s.module)
+}
@@ -0,0 +1,114 @@
+package c90
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jpnock/see90/pkg/cpp"
+)
+
+func TestPeepholeKeepsVolatileLoads(t *testing.T) {
+	const src = `int f(void) { volatile int x; int a; x = 1; a = x + x; return a; }`
+	var diagnostics DiagnosticList
+	pp := cpp.New(nil)
+	s := NewSession(pp, &diagnostics)
+	r, err := pp.Preprocess("test.c", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Parse(r); err != nil {
+		t.Fatalf("parsing: %v %v", err, diagnostics)
+	}
+	if err := s.Optimize(1); err != nil {
+		t.Fatalf("optimising: %v %v", err, diagnostics)
+	}
+	s.SetPeephole(true)
+	var asm bytes.Buffer
+	if err := s.Emit(&asm); err != nil {
+		t.Fatal(err)
+	}
+	loads := regexp.MustCompile(`(?m)^lw \$\w+, -?\d+\(\$fp\)$`).FindAllString(asm.String(), -1)
+	// Both reads of x, and the load of $ra in the epilogue.
+	if len(loads) != 3 {
+		t.Errorf("got loads %q, want two of x and one of $ra\n%s", loads, asm.String())
+	}
+}
+
+// compileFile compiles the file at path with a new session, returning the
+// assembly and the messages of the diagnostics reported.
+func compileFile(path string) (string, []string) {
+	var diagnostics DiagnosticList
+	s := NewSession(cpp.New(nil), &diagnostics)
+	var asm bytes.Buffer
+	if err := s.ParseFile(path); err == nil {
+		if err := s.Check(); err == nil {
+			s.Emit(&asm)
+		}
+	}
+	var messages []string
+	for _, d := range diagnostics {
+		messages = append(messages, d.Message)
+	}
+	return asm.String(), messages
+}
+
+// TestConcurrentSessions compiles several files at once, each many times.
+// Run it with -race to check that sessions don't share any state.
+func TestConcurrentSessions(t *testing.T) {
+	// Each file gives T a different meaning, so a typedef leaking from one
+	// session into another makes it fail to parse or changes its code.
+	sources := []string{
+		"typedef int T;\nT f(T x) { return x + 1; }\n",
+		"typedef double T;\nT f(T x) { return x * 2; }\n",
+		"typedef struct { int a, b; } T;\nint f(T *t) { return t->a - t->b; }\n",
+		"int T;\nint f(int x) { return T + x; }\n",
+		"int f(void) { return sizeof(T); }\n",
+	}
+	dir := t.TempDir()
+	var paths []string
+	for i, src := range sources {
+		path := filepath.Join(dir, string(rune('a'+i))+".c")
+		if err := ioutil.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	want := make([]string, len(paths))
+	for i, path := range paths {
+		asm, messages := compileFile(path)
+		want[i] = asm + strings.Join(messages, "\n")
+		undeclared := i == len(paths)-1
+		if undeclared && want[i] != "'T' undeclared" || !undeclared && (asm == "" || len(messages) != 0) {
+			t.Errorf("%s: got %q", sources[i], want[i])
+		}
+	}
+
+	const rounds = 20
+	got := make([][]string, rounds)
+	var wg sync.WaitGroup
+	for r := 0; r < rounds; r++ {
+		got[r] = make([]string, len(paths))
+		for i, path := range paths {
+			wg.Add(1)
+			go func(r, i int, path string) {
+				defer wg.Done()
+				asm, messages := compileFile(path)
+				got[r][i] = asm + strings.Join(messages, "\n")
+			}(r, i, path)
+		}
+	}
+	wg.Wait()
+	for r := range got {
+		for i := range paths {
+			if got[r][i] != want[i] {
+				t.Errorf("%s compiled concurrently: got\n%s\nwant\n%s", sources[i], got[r][i], want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package c90
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lrTracker follows the goyacc parser through its tables one token at a time.
+// The generated parser only reports the state it failed in when there are at
+// most four expected tokens, so the lexer feeds every token through a tracker
+// as well to find out everything which would have been accepted instead.
+type lrTracker struct {
+	states  []int
+	errFlag int
+}
+
+func newLRTracker() *lrTracker {
+	return &lrTracker{states: []int{0}}
+}
+
+// feed advances the tracker past tok, a goyacc internal token number, in the
+// same way as yyParse including its error recovery. It returns the state the
+// token was rejected in if it causes a new syntax error to be reported.
+func (p *lrTracker) feed(tok int) (errState int, isError bool) {
+	errState = -1
+	for len(p.states) > 0 {
+		state := p.states[len(p.states)-1]
+
+		if n := yyPact[state]; n > yyFlag {
+			if n += tok; n >= 0 && n < yyLast && yyChk[yyAct[n]] == tok {
+				p.states = append(p.states, yyAct[n])
+				if p.errFlag > 0 {
+					p.errFlag--
+				}
+				return errState, errState >= 0
+			}
+		}
+
+		n := yyDef[state]
+		if n == -2 {
+			n = yyExcaAction(state, tok)
+			if n < 0 {
+				// Accepted the translation unit.
+				return errState, errState >= 0
+			}
+		}
+
+		if n != 0 {
+			p.states = p.states[:len(p.states)-yyR2[n]]
+			p.states = append(p.states, yyGoto(p.states[len(p.states)-1], yyR1[n]))
+			continue
+		}
+
+		if p.errFlag == 3 {
+			// The parser discards the token and tries again with the next.
+			return errState, errState >= 0
+		}
+		if p.errFlag == 0 {
+			errState = state
+		}
+		p.errFlag = 3
+		for len(p.states) > 0 {
+			top := p.states[len(p.states)-1]
+			if n := yyPact[top] + yyErrCode; n >= 0 && n < yyLast && yyChk[yyAct[n]] == yyErrCode {
+				p.states = append(p.states, yyAct[n])
+				break
+			}
+			p.states = p.states[:len(p.states)-1]
+		}
+	}
+	return errState, errState >= 0
+}
+
+func yyExcaAction(state, tok int) int {
+	i := 0
+	for yyExca[i] != -1 || yyExca[i+1] != state {
+		i += 2
+	}
+	for i += 2; yyExca[i] >= 0 && yyExca[i] != tok; i += 2 {
+	}
+	return yyExca[i+1]
+}
+
+func yyGoto(state, nonTerminal int) int {
+	g := yyPgo[nonTerminal]
+	if j := g + state + 1; j < yyLast {
+		if next := yyAct[j]; yyChk[next] == -nonTerminal {
+			return next
+		}
+	}
+	return yyAct[g]
+}
+
+// yyInternalToken converts a token returned by the lexer into the numbering
+// used by the goyacc tables.
+func yyInternalToken(char int) int {
+	token := 0
+	switch {
+	case char <= 0:
+		token = yyTok1[0]
+	case char < len(yyTok1):
+		token = yyTok1[char]
+	case char >= yyPrivate && char < yyPrivate+len(yyTok2):
+		token = yyTok2[char-yyPrivate]
+	default:
+		for i := 0; i < len(yyTok3); i += 2 {
+			if yyTok3[i] == char {
+				token = yyTok3[i+1]
+				break
+			}
+		}
+	}
+	if token == 0 {
+		token = yyTok2[1]
+	}
+	return token
+}
+
+// yyExpected returns the tokens which are valid in a parser state, in the same
+// way as yyErrorMessage but without limiting how many there are.
+func yyExpected(state int) []int {
+	const firstToken = 4
+
+	var expected []int
+	base := yyPact[state]
+	for tok := firstToken; tok-1 < len(yyToknames); tok++ {
+		if n := base + tok; n >= 0 && n < yyLast && yyChk[yyAct[n]] == tok {
+			expected = append(expected, tok)
+		}
+	}
+
+	if yyDef[state] == -2 {
+		i := 0
+		for yyExca[i] != -1 || yyExca[i+1] != state {
+			i += 2
+		}
+		for i += 2; yyExca[i] >= 0; i += 2 {
+			if tok := yyExca[i]; tok >= firstToken && yyExca[i+1] != 0 {
+				expected = append(expected, tok)
+			}
+		}
+	}
+	return expected
+}
+
+var tokenDescriptions = map[string]string{
+	"$end":           "end of input",
+	"IDENTIFIER":     "identifier",
+	"CONSTANT":       "constant",
+	"STRING_LITERAL": "string literal",
+	"TYPE_NAME":      "type name",
+	"PTR_OP":         "'->'",
+	"INC_OP":         "'++'",
+	"DEC_OP":         "'--'",
+	"LEFT_OP":        "'<<'",
+	"RIGHT_OP":       "'>>'",
+	"LE_OP":          "'<='",
+	"GE_OP":          "'>='",
+	"EQ_OP":          "'=='",
+	"NE_OP":          "'!='",
+	"AND_OP":         "'&&'",
+	"OR_OP":          "'||'",
+	"MUL_ASSIGN":     "'*='",
+	"DIV_ASSIGN":     "'/='",
+	"MOD_ASSIGN":     "'%='",
+	"ADD_ASSIGN":     "'+='",
+	"SUB_ASSIGN":     "'-='",
+	"LEFT_ASSIGN":    "'<<='",
+	"RIGHT_ASSIGN":   "'>>='",
+	"AND_ASSIGN":     "'&='",
+	"XOR_ASSIGN":     "'^='",
+	"OR_ASSIGN":      "'|='",
+	"ELLIPSIS":       "'...'",
+}
+
+// describeToken returns how a token is written in a diagnostic, e.g.
+// "identifier", "'while'" or "';'".
+func describeToken(tok int) string {
+	name := yyTokname(tok)
+	if desc, ok := tokenDescriptions[name]; ok {
+		return desc
+	}
+	if strings.HasPrefix(name, "'") {
+		return name
+	}
+	// The remaining tokens are all keywords.
+	return "'" + strings.ToLower(name) + "'"
+}
+
+// tokenGroups are sets of tokens which are summarised by a single word when
+// they are all expected, so that errors don't list every keyword.
+var tokenGroups = []struct {
+	name   string
+	tokens []int
+}{
+	{"statement", []int{IF, SWITCH, WHILE, DO, FOR, GOTO, CONTINUE, BREAK, RETURN, CASE, DEFAULT, '{', ';', IDENTIFIER, CONSTANT, STRING_LITERAL, SIZEOF, VA_START, VA_ARG, VA_END, INC_OP, DEC_OP, '(', '&', '*', '+', '-', '~', '!'}},
+	{"declaration specifiers", []int{TYPEDEF, EXTERN, STATIC, AUTO, REGISTER, CHAR, SHORT, INT, LONG, SIGNED, UNSIGNED, FLOAT, DOUBLE, CONST, VOLATILE, VOID, STRUCT, UNION, ENUM, TYPE_NAME}},
+	{"type specifier", []int{CHAR, SHORT, INT, LONG, SIGNED, UNSIGNED, FLOAT, DOUBLE, VOID, STRUCT, UNION, ENUM, TYPE_NAME}},
+	{"expression", []int{IDENTIFIER, CONSTANT, STRING_LITERAL, SIZEOF, VA_START, VA_ARG, VA_END, INC_OP, DEC_OP, '(', '&', '*', '+', '-', '~', '!'}},
+}
+
+// describeExpected summarises the tokens valid in a parser state, e.g.
+// "';' or ','" or "expression".
+func describeExpected(state int) string {
+	expected := yyExpected(state)
+	remaining := map[int]bool{}
+	for _, tok := range expected {
+		remaining[tok] = true
+	}
+
+	var names []string
+	for _, group := range tokenGroups {
+		all := true
+		for _, char := range group.tokens {
+			if !remaining[yyInternalToken(char)] {
+				all = false
+				break
+			}
+		}
+		if !all {
+			continue
+		}
+		for _, char := range group.tokens {
+			delete(remaining, yyInternalToken(char))
+		}
+		names = append(names, group.name)
+	}
+	for _, tok := range expected {
+		if remaining[tok] {
+			names = append(names, describeToken(tok))
+		}
+	}
+
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+	}
+}
+
+// syntaxErrorMessage describes the token which couldn't be parsed in the given
+// state, e.g. "expected ';' or ',', found identifier near `a b`". The source
+// it's near is the token before it, prev, followed by the token itself.
+func syntaxErrorMessage(state int, prev, found lexedToken) string {
+	tok := yyInternalToken(found.tok)
+
+	foundDesc := describeToken(tok)
+	if tok == yyTok2[1] {
+		foundDesc = fmt.Sprintf("stray '%s'", found.text)
+	}
+	if near := strings.TrimSpace(prev.text + " " + found.text); near != "" {
+		foundDesc += fmt.Sprintf(" near `%s`", near)
+	}
+
+	expected := describeExpected(state)
+	if expected == "" {
+		return "unexpected " + foundDesc
+	}
+	return fmt.Sprintf("expected %s, found %s", expected, foundDesc)
+}
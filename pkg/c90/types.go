@@ -0,0 +1,583 @@
+package c90
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Qualifiers are the type qualifiers applied to a type.
+type Qualifiers uint8
+
+const (
+	QualifierConst Qualifiers = 1 << iota
+	QualifierVolatile
+)
+
+func (q Qualifiers) String() string {
+	var names []string
+	if q&QualifierConst != 0 {
+		names = append(names, "const")
+	}
+	if q&QualifierVolatile != 0 {
+		names = append(names, "volatile")
+	}
+	return strings.Join(names, " ")
+}
+
+// TypeKind is the kind of a Type. The basic types each have their own kind,
+// after the type specifiers naming them have been folded together, so `long`,
+// `signed long` and `long int` are all TypeLong.
+type TypeKind int
+
+const (
+	TypeInvalid TypeKind = iota
+	TypeVoid
+	TypeChar
+	TypeSignedChar
+	TypeUnsignedChar
+	TypeShort
+	TypeUnsignedShort
+	TypeInt
+	TypeUnsignedInt
+	TypeLong
+	TypeUnsignedLong
+	TypeFloat
+	TypeDouble
+	TypeLongDouble
+	TypeEnum
+	TypePointer
+	TypeArray
+	TypeFunction
+	TypeStruct
+	TypeUnion
+)
+
+// typeKindNames are the names gcc uses for the basic types in diagnostics.
+var typeKindNames = map[TypeKind]string{
+	TypeInvalid:       "<invalid>",
+	TypeVoid:          "void",
+	TypeChar:          "char",
+	TypeSignedChar:    "signed char",
+	TypeUnsignedChar:  "unsigned char",
+	TypeShort:         "short int",
+	TypeUnsignedShort: "short unsigned int",
+	TypeInt:           "int",
+	TypeUnsignedInt:   "unsigned int",
+	TypeLong:          "long int",
+	TypeUnsignedLong:  "long unsigned int",
+	TypeFloat:         "float",
+	TypeDouble:        "double",
+	TypeLongDouble:    "long double",
+}
+
+// Type is the C type of a declaration or an expression, as worked out by the
+// checker. Derived types refer to the type they are derived from, so
+// `int *x[3]` is an array of 3 pointers to int, and `int (*x)[3]` is a pointer
+// to an array of 3 ints.
+type Type struct {
+	Kind       TypeKind
+	Qualifiers Qualifiers
+
+	// elem is the type pointed to by a pointer, or the type of the elements of
+	// an array.
+	elem *Type
+	// Len is the number of elements in an array, or 0 if it isn't known.
+	Len int
+
+	// Struct is the layout of a structure or union.
+	Struct *StructLayout
+	// Tag is the name of an enumeration, or empty for an anonymous one.
+	Tag string
+
+	Func *Signature
+}
+
+// Signature describes the parameters and return type of a function.
+type Signature struct {
+	Return *Type
+	Params []*Type
+
+	// Prototype is false for functions declared with an empty parameter
+	// list, whose arguments are not checked.
+	Prototype bool
+	Variadic  bool
+}
+
+// StructLayout is the layout of a structure or union in memory.
+type StructLayout struct {
+	// Name is the tag of the structure, or empty for an anonymous struct.
+	Name   string
+	Union  bool
+	Fields []*StructField
+
+	Size  int
+	Align int
+
+	// Complete is false until the members of the structure are known.
+	Complete bool
+}
+
+type StructField struct {
+	Name   string
+	Type   *Type
+	Offset int
+}
+
+// Field returns the member called name, or nil if there isn't one.
+func (s *StructLayout) Field(name string) *StructField {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// AddField adds a member to the end of the structure, placing it at the next
+// offset which satisfies its alignment. Every member of a union is placed at
+// offset 0.
+func (s *StructLayout) AddField(name string, typ *Type) {
+	align := typ.Align()
+	offset := alignTo(s.Size, align)
+	if s.Union {
+		offset = 0
+	}
+	s.Fields = append(s.Fields, &StructField{Name: name, Type: typ, Offset: offset})
+	if end := offset + typ.Size(); end > s.Size {
+		s.Size = end
+	}
+	if align > s.Align {
+		s.Align = align
+	}
+}
+
+// complete pads the end of the structure to its alignment, so that the
+// members of each element of an array of the structures are aligned.
+func (s *StructLayout) complete() {
+	if s.Align == 0 {
+		s.Align = 1
+	}
+	s.Size = alignTo(s.Size, s.Align)
+	s.Complete = true
+}
+
+// InitializedFields returns the members set by an initializer list, in
+// order. Only the first member of a union can be initialized.
+func (s *StructLayout) InitializedFields() []*StructField {
+	if s.Union && len(s.Fields) > 1 {
+		return s.Fields[:1]
+	}
+	return s.Fields
+}
+
+func (s *StructLayout) keyword() string {
+	if s.Union {
+		return "union"
+	}
+	return "struct"
+}
+
+func alignTo(offset, align int) int {
+	if align <= 1 {
+		return offset
+	}
+	return (offset + align - 1) / align * align
+}
+
+var (
+	typeInvalid      = &Type{Kind: TypeInvalid}
+	typeVoid         = &Type{Kind: TypeVoid}
+	typeChar         = &Type{Kind: TypeChar}
+	typeInt          = &Type{Kind: TypeInt}
+	typeUnsignedInt  = &Type{Kind: TypeUnsignedInt}
+	typeLong         = &Type{Kind: TypeLong}
+	typeUnsignedLong = &Type{Kind: TypeUnsignedLong}
+	typeFloat        = &Type{Kind: TypeFloat}
+	typeDouble       = &Type{Kind: TypeDouble}
+	typeLongDouble   = &Type{Kind: TypeLongDouble}
+
+	// typeSize is the type of sizeof expressions.
+	typeSize = typeUnsignedInt
+)
+
+func (t *Type) clone() *Type {
+	c := *t
+	return &c
+}
+
+// IsValid is false for the type given to expressions containing errors.
+func (t *Type) IsValid() bool {
+	return t.Kind != TypeInvalid
+}
+
+func (t *Type) IsFunction() bool {
+	return t.Kind == TypeFunction
+}
+
+func (t *Type) IsArray() bool {
+	return t.Kind == TypeArray
+}
+
+func (t *Type) IsPointer() bool {
+	return t.Kind == TypePointer
+}
+
+func (t *Type) IsVoid() bool {
+	return t.Kind == TypeVoid
+}
+
+// IsStruct reports whether t is a structure or a union, which are both laid
+// out by a StructLayout.
+func (t *Type) IsStruct() bool {
+	return t.Kind == TypeStruct || t.Kind == TypeUnion
+}
+
+func (t *Type) IsInteger() bool {
+	switch t.Kind {
+	case TypeChar, TypeSignedChar, TypeUnsignedChar, TypeShort, TypeUnsignedShort,
+		TypeInt, TypeUnsignedInt, TypeLong, TypeUnsignedLong, TypeEnum:
+		return true
+	}
+	return false
+}
+
+func (t *Type) IsFloating() bool {
+	switch t.Kind {
+	case TypeFloat, TypeDouble, TypeLongDouble:
+		return true
+	}
+	return false
+}
+
+// IsReadOnly reports whether objects of the type are const, or are arrays of
+// const elements, and so can be placed in read-only memory.
+func (t *Type) IsReadOnly() bool {
+	for t.Kind == TypeArray {
+		t = t.elem
+	}
+	return t.Qualifiers&QualifierConst != 0
+}
+
+// IsConst reports whether t is const qualified, or is a structure or union
+// with a const member, so an lvalue of the type can't be assigned to.
+func (t *Type) IsConst() bool {
+	if t.Qualifiers&QualifierConst != 0 {
+		return true
+	}
+	if (t.Kind == TypeStruct || t.Kind == TypeUnion) && t.Struct.Complete {
+		for _, field := range t.Struct.Fields {
+			if field.Type.IsConst() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsVolatile reports whether t is volatile qualified. Every access to a
+// volatile object has to be made to memory, in order, so its value is never
+// cached in a register and the access is never removed or merged.
+func (t *Type) IsVolatile() bool {
+	return t.Qualifiers&QualifierVolatile != 0
+}
+
+// IsUnsigned reports whether t is an unsigned integer type. Plain char is
+// signed, as it is in gcc for MIPS.
+func (t *Type) IsUnsigned() bool {
+	switch t.Kind {
+	case TypeUnsignedChar, TypeUnsignedShort, TypeUnsignedInt, TypeUnsignedLong:
+		return true
+	}
+	return false
+}
+
+func (t *Type) IsArithmetic() bool {
+	return t.IsInteger() || t.IsFloating()
+}
+
+func (t *Type) IsScalar() bool {
+	return t.IsArithmetic() || t.IsPointer()
+}
+
+// IsComplete is false for types whose size isn't known.
+func (t *Type) IsComplete() bool {
+	switch t.Kind {
+	case TypeFunction, TypeVoid, TypeInvalid:
+		return false
+	case TypeArray:
+		return t.Len != 0 && t.elem.IsComplete()
+	case TypeStruct, TypeUnion:
+		return t.Struct.Complete
+	}
+	return true
+}
+
+// Elem returns the type of the elements of an array, or the type pointed to by
+// a pointer.
+func (t *Type) Elem() *Type {
+	if t.elem == nil {
+		return typeInvalid
+	}
+	return t.elem
+}
+
+// PointerTo returns a pointer to t.
+func (t *Type) PointerTo() *Type {
+	return &Type{Kind: TypePointer, elem: t}
+}
+
+// ArrayOf returns an array of n elements of type t. n is 0 if the number of
+// elements isn't known.
+func (t *Type) ArrayOf(n int) *Type {
+	return &Type{Kind: TypeArray, elem: t, Len: n}
+}
+
+// FunctionReturning returns a function with signature sig.
+func FunctionReturning(sig *Signature) *Type {
+	return &Type{Kind: TypeFunction, Func: sig}
+}
+
+// Decay returns the type an expression of type t has when its value is used,
+// which turns arrays into pointers to their first element and functions into
+// pointers to the function.
+func (t *Type) Decay() *Type {
+	switch t.Kind {
+	case TypeArray:
+		return t.elem.PointerTo()
+	case TypeFunction:
+		return t.PointerTo()
+	}
+	return t
+}
+
+// Unqualified returns t without its qualifiers.
+func (t *Type) Unqualified() *Type {
+	if t.Qualifiers == 0 {
+		return t
+	}
+	u := t.clone()
+	u.Qualifiers = 0
+	return u
+}
+
+// Qualified returns t with the qualifiers q added. Qualifying an array type
+// qualifies its elements instead.
+func (t *Type) Qualified(q Qualifiers) *Type {
+	if q == 0 || t.Qualifiers&q == q {
+		return t
+	}
+	if t.Kind == TypeArray {
+		return t.elem.Qualified(q).ArrayOf(t.Len)
+	}
+	u := t.clone()
+	u.Qualifiers |= q
+	return u
+}
+
+// Size returns the number of bytes used to store a value of the type, which
+// follows the o32 ABI.
+func (t *Type) Size() int {
+	switch t.Kind {
+	case TypeChar, TypeSignedChar, TypeUnsignedChar:
+		return 1
+	case TypeShort, TypeUnsignedShort:
+		return 2
+	case TypeInt, TypeUnsignedInt, TypeLong, TypeUnsignedLong, TypeEnum, TypeFloat, TypePointer:
+		return 4
+	case TypeDouble, TypeLongDouble:
+		return 8
+	case TypeArray:
+		return t.Len * t.elem.Size()
+	case TypeStruct, TypeUnion:
+		return t.Struct.Size
+	}
+	return 0
+}
+
+// Align returns the alignment in bytes needed for a value of the type.
+func (t *Type) Align() int {
+	switch t.Kind {
+	case TypeArray:
+		return t.elem.Align()
+	case TypeStruct, TypeUnion:
+		if t.Struct.Align == 0 {
+			return 1
+		}
+		return t.Struct.Align
+	}
+	if size := t.Size(); size > 0 {
+		return size
+	}
+	return 1
+}
+
+// Equal reports whether t and u are the same type. The qualifiers of t and u
+// themselves are ignored, but not those of the types they are derived from.
+func (t *Type) Equal(u *Type) bool {
+	if t.Kind != u.Kind {
+		return false
+	}
+	switch t.Kind {
+	case TypePointer:
+		return t.elem.Qualifiers == u.elem.Qualifiers && t.elem.Equal(u.elem)
+	case TypeArray:
+		return t.Len == u.Len && t.elem.Qualifiers == u.elem.Qualifiers && t.elem.Equal(u.elem)
+	case TypeStruct, TypeUnion:
+		return t.Struct == u.Struct
+	case TypeEnum:
+		return t.Tag == u.Tag
+	case TypeFunction:
+		if !t.Func.Return.Equal(u.Func.Return) || len(t.Func.Params) != len(u.Func.Params) || t.Func.Variadic != u.Func.Variadic {
+			return false
+		}
+		for i := range t.Func.Params {
+			if !t.Func.Params[i].Equal(u.Func.Params[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String formats the type in the same way as gcc, e.g. `int *[3]` or
+// `int (*)(int)`.
+func (t *Type) String() string {
+	return t.format("")
+}
+
+// format writes the type around decl, which is the part of an abstract
+// declarator built up by the types derived from t.
+func (t *Type) format(decl string) string {
+	switch t.Kind {
+	case TypePointer:
+		ptr := "*"
+		if t.Qualifiers != 0 {
+			ptr += " " + t.Qualifiers.String()
+			if decl != "" {
+				ptr += " "
+			}
+		}
+		ptr += decl
+		if t.elem.IsArray() || t.elem.IsFunction() {
+			ptr = "(" + ptr + ")"
+		}
+		return t.elem.format(ptr)
+
+	case TypeArray:
+		if t.Len == 0 {
+			return t.elem.format(decl + "[]")
+		}
+		return t.elem.format(fmt.Sprintf("%s[%d]", decl, t.Len))
+
+	case TypeFunction:
+		params := make([]string, len(t.Func.Params))
+		for i, param := range t.Func.Params {
+			params[i] = param.String()
+		}
+		switch {
+		case t.Func.Variadic:
+			params = append(params, "...")
+		case t.Func.Prototype && len(params) == 0:
+			params = append(params, "void")
+		}
+		return t.Func.Return.format(fmt.Sprintf("%s(%s)", decl, strings.Join(params, ", ")))
+	}
+
+	var sb strings.Builder
+	if t.Qualifiers != 0 {
+		sb.WriteString(t.Qualifiers.String())
+		sb.WriteString(" ")
+	}
+	switch t.Kind {
+	case TypeStruct, TypeUnion:
+		sb.WriteString(t.Struct.keyword())
+		if t.Struct.Name == "" {
+			sb.WriteString(" <anonymous>")
+		} else {
+			sb.WriteString(" " + t.Struct.Name)
+		}
+	case TypeEnum:
+		if t.Tag == "" {
+			sb.WriteString("enum <anonymous>")
+		} else {
+			sb.WriteString("enum " + t.Tag)
+		}
+	default:
+		sb.WriteString(typeKindNames[t.Kind])
+	}
+
+	if strings.HasPrefix(decl, "*") || strings.HasPrefix(decl, "(*") {
+		sb.WriteString(" ")
+	}
+	sb.WriteString(decl)
+	return sb.String()
+}
+
+// integerRank orders the integer types for the usual arithmetic conversions.
+var integerRank = map[TypeKind]int{
+	TypeChar:          1,
+	TypeSignedChar:    1,
+	TypeUnsignedChar:  1,
+	TypeShort:         2,
+	TypeUnsignedShort: 2,
+	TypeInt:           3,
+	TypeUnsignedInt:   3,
+	TypeEnum:          3,
+	TypeLong:          4,
+	TypeUnsignedLong:  4,
+}
+
+// unsignedKinds maps each signed integer type to the unsigned type of the
+// same size.
+var unsignedKinds = map[TypeKind]TypeKind{
+	TypeInt:  TypeUnsignedInt,
+	TypeLong: TypeUnsignedLong,
+}
+
+// promote applies the integer promotions to t, which turn the integer types
+// smaller than int (and enumerations) into int. Every value of these types
+// can be represented by an int.
+func promote(t *Type) *Type {
+	if t.IsInteger() && (integerRank[t.Kind] < integerRank[TypeInt] || t.Kind == TypeEnum) {
+		return typeInt
+	}
+	return t.Unqualified()
+}
+
+// usualArithmeticConversion returns the type both operands of a binary
+// operator are converted to before the operation is carried out.
+func usualArithmeticConversion(a, b *Type) *Type {
+	switch {
+	case a.Kind == TypeLongDouble || b.Kind == TypeLongDouble:
+		return typeLongDouble
+	case a.Kind == TypeDouble || b.Kind == TypeDouble:
+		return typeDouble
+	case a.Kind == TypeFloat || b.Kind == TypeFloat:
+		return typeFloat
+	}
+
+	a, b = promote(a), promote(b)
+	switch {
+	case a.Kind == b.Kind:
+		return a
+	case a.IsUnsigned() == b.IsUnsigned():
+		if integerRank[a.Kind] > integerRank[b.Kind] {
+			return a
+		}
+		return b
+	}
+
+	unsigned, signed := a, b
+	if !unsigned.IsUnsigned() {
+		unsigned, signed = b, a
+	}
+	switch {
+	case integerRank[unsigned.Kind] >= integerRank[signed.Kind]:
+		return unsigned
+	case signed.Size() > unsigned.Size():
+		return signed
+	}
+	// long is the same size as unsigned int, so it can't represent every
+	// unsigned int and both are converted to unsigned long.
+	return &Type{Kind: unsignedKinds[signed.Kind]}
+}
@@ -1,30 +1,17 @@
-// Code generated by goyacc -o pkg/c90/y.go -v pkg/c90/y.output pkg/c90/grammar.y. DO NOT EDIT.
+// Code generated by goyacc -o y.go -v /tmp/y.output grammar.y. DO NOT EDIT.
 
-//line pkg/c90/grammar.y:2
+//line grammar.y:2
 package c90
 
 import __yyfmt__ "fmt"
 
-//line pkg/c90/grammar.y:2
-
-import (
-	"fmt"
-	"os"
-)
-
-var AST ASTTranslationUnit
-var typmap map[string]*ASTTypeDef = map[string]*ASTTypeDef{}
+//line grammar.y:2
 
 func init() {
-	yyDebug = 1
 	yyErrorVerbose = true
 }
 
-func Parse(yylex yyLexer) int {
-	return yyParse(yylex)
-}
-
-//line pkg/c90/grammar.y:22
+//line grammar.y:9
 type yySymType struct {
 	yys                int
 	n                  Node
@@ -33,6 +20,10 @@ type yySymType struct {
 	assignmentOperator ASTAssignmentOperator
 	unaryOperator      ASTExprPrefixUnaryType
 	pointerDepth       int
+	pointerQualifiers  []Qualifiers
+	storage            StorageClass
+	qualifiers         Qualifiers
+	rng                Range
 }
 
 const IDENTIFIER = 57346
@@ -81,18 +72,21 @@ const STRUCT = 57388
 const UNION = 57389
 const ENUM = 57390
 const ELLIPSIS = 57391
-const CASE = 57392
-const DEFAULT = 57393
-const IF = 57394
-const ELSE = 57395
-const SWITCH = 57396
-const WHILE = 57397
-const DO = 57398
-const FOR = 57399
-const GOTO = 57400
-const CONTINUE = 57401
-const BREAK = 57402
-const RETURN = 57403
+const VA_START = 57392
+const VA_ARG = 57393
+const VA_END = 57394
+const CASE = 57395
+const DEFAULT = 57396
+const IF = 57397
+const ELSE = 57398
+const SWITCH = 57399
+const WHILE = 57400
+const DO = 57401
+const FOR = 57402
+const GOTO = 57403
+const CONTINUE = 57404
+const BREAK = 57405
+const RETURN = 57406
 
 var yyToknames = [...]string{
 	"$end",
@@ -144,6 +138,9 @@ var yyToknames = [...]string{
 	"UNION",
 	"ENUM",
 	"ELLIPSIS",
+	"VA_START",
+	"VA_ARG",
+	"VA_END",
 	"CASE",
 	"DEFAULT",
 	"IF",
@@ -158,10 +155,10 @@ var yyToknames = [...]string{
 	"RETURN",
 	"'('",
 	"')'",
+	"','",
 	"'['",
 	"']'",
 	"'.'",
-	"','",
 	"'&'",
 	"'*'",
 	"'+'",
@@ -197,327 +194,353 @@ var yyExca = [...]int{
 
 const yyPrivate = 57344
 
-const yyLast = 1280
+const yyLast = 1421
 
 var yyAct = [...]int{
-	72, 95, 94, 296, 145, 6, 233, 93, 121, 75,
-	149, 41, 47, 5, 5, 226, 133, 123, 116, 11,
-	48, 49, 50, 115, 201, 140, 10, 144, 139, 308,
-	51, 294, 32, 301, 83, 120, 160, 147, 239, 57,
-	138, 239, 347, 254, 119, 117, 169, 307, 168, 300,
-	8, 118, 255, 122, 126, 125, 306, 128, 55, 238,
-	160, 61, 58, 63, 114, 240, 134, 110, 64, 122,
-	160, 154, 150, 302, 199, 246, 161, 184, 159, 62,
-	102, 135, 126, 125, 315, 157, 209, 210, 165, 203,
-	33, 213, 214, 204, 316, 341, 160, 31, 158, 215,
-	192, 194, 126, 196, 216, 217, 70, 28, 222, 195,
-	142, 39, 32, 18, 19, 20, 21, 24, 25, 22,
-	23, 29, 30, 17, 36, 37, 35, 171, 174, 175,
-	176, 177, 178, 179, 180, 181, 182, 183, 32, 185,
-	223, 60, 59, 313, 272, 340, 228, 234, 200, 207,
-	208, 31, 327, 218, 326, 29, 30, 154, 245, 150,
-	247, 225, 243, 299, 142, 231, 224, 229, 248, 345,
-	227, 252, 228, 160, 325, 241, 253, 31, 156, 141,
-	256, 31, 319, 343, 317, 269, 126, 160, 318, 142,
-	142, 173, 219, 142, 142, 262, 33, 249, 250, 251,
-	126, 126, 293, 31, 292, 126, 126, 126, 126, 126,
-	126, 126, 126, 126, 126, 126, 126, 126, 126, 126,
-	258, 259, 265, 274, 285, 286, 287, 273, 200, 271,
-	126, 125, 200, 57, 270, 134, 9, 126, 125, 290,
-	134, 291, 126, 125, 304, 51, 298, 309, 142, 283,
-	284, 275, 276, 303, 225, 231, 281, 282, 305, 277,
-	278, 279, 280, 314, 267, 257, 266, 32, 56, 42,
-	32, 126, 312, 189, 190, 191, 160, 311, 320, 310,
-	142, 160, 268, 160, 220, 134, 160, 291, 221, 137,
-	272, 136, 228, 202, 126, 125, 143, 324, 53, 271,
-	52, 45, 4, 4, 126, 125, 134, 234, 328, 166,
-	322, 332, 333, 334, 32, 164, 150, 126, 338, 331,
-	329, 163, 236, 237, 162, 33, 339, 187, 227, 186,
-	228, 188, 31, 148, 342, 205, 206, 344, 198, 211,
-	212, 146, 288, 346, 235, 67, 348, 264, 335, 337,
-	143, 263, 167, 2, 3, 38, 79, 111, 112, 101,
-	78, 98, 99, 77, 76, 73, 242, 67, 131, 54,
-	129, 232, 33, 67, 34, 143, 143, 202, 27, 143,
-	143, 28, 12, 13, 14, 15, 16, 18, 19, 20,
-	21, 24, 25, 22, 23, 29, 30, 17, 36, 37,
-	35, 26, 80, 81, 84, 7, 85, 86, 87, 88,
-	89, 90, 91, 92, 113, 40, 172, 96, 100, 261,
-	104, 105, 106, 107, 108, 109, 97, 103, 1, 0,
-	0, 0, 0, 0, 143, 82, 46, 155, 0, 0,
-	0, 0, 79, 111, 112, 101, 28, 98, 99, 0,
-	0, 0, 18, 19, 20, 21, 24, 25, 22, 23,
-	29, 30, 17, 36, 37, 35, 143, 28, 12, 13,
-	14, 15, 16, 18, 19, 20, 21, 24, 25, 22,
-	23, 29, 30, 17, 36, 37, 35, 0, 80, 81,
-	84, 0, 85, 86, 87, 88, 89, 90, 91, 92,
-	113, 0, 230, 0, 0, 43, 104, 105, 106, 107,
-	108, 109, 79, 111, 112, 101, 0, 98, 99, 0,
-	0, 82, 46, 69, 0, 0, 0, 28, 12, 13,
-	14, 15, 16, 18, 19, 20, 21, 24, 25, 22,
-	23, 29, 30, 17, 36, 37, 35, 65, 79, 111,
-	112, 101, 71, 98, 99, 0, 0, 0, 80, 81,
-	84, 0, 85, 86, 87, 88, 89, 90, 91, 92,
-	113, 0, 0, 0, 0, 0, 104, 105, 106, 107,
-	108, 109, 46, 0, 79, 111, 112, 101, 0, 98,
-	99, 82, 46, 244, 80, 81, 84, 0, 85, 86,
-	87, 88, 89, 90, 91, 92, 113, 0, 0, 0,
-	0, 0, 104, 105, 106, 107, 108, 109, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 82, 46, 153,
-	80, 81, 84, 0, 85, 86, 87, 88, 89, 90,
-	91, 92, 113, 0, 0, 0, 0, 0, 104, 105,
-	106, 107, 108, 109, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 82, 46, 28, 12, 13, 14, 15,
-	16, 18, 19, 20, 21, 24, 25, 22, 23, 29,
-	30, 17, 36, 37, 35, 0, 0, 0, 0, 0,
-	127, 111, 112, 101, 0, 98, 99, 0, 0, 0,
-	0, 0, 127, 111, 112, 101, 0, 98, 99, 0,
-	0, 0, 0, 0, 0, 28, 0, 0, 64, 0,
-	46, 18, 19, 20, 21, 24, 25, 22, 23, 29,
-	30, 17, 36, 37, 35, 127, 111, 112, 101, 0,
-	98, 99, 74, 0, 0, 0, 0, 0, 113, 44,
-	0, 0, 0, 0, 104, 105, 106, 107, 108, 109,
-	113, 0, 0, 0, 0, 0, 104, 105, 106, 107,
-	108, 109, 127, 111, 112, 101, 0, 98, 99, 0,
-	0, 0, 151, 330, 66, 0, 68, 0, 0, 127,
-	111, 112, 101, 113, 98, 99, 0, 0, 0, 104,
-	105, 106, 107, 108, 109, 0, 0, 0, 152, 0,
-	28, 0, 0, 0, 0, 151, 18, 19, 20, 21,
-	24, 25, 22, 23, 29, 30, 17, 36, 37, 35,
-	113, 0, 0, 0, 0, 0, 104, 105, 106, 107,
-	108, 109, 0, 0, 32, 0, 0, 113, 0, 0,
-	0, 82, 0, 104, 105, 106, 107, 108, 109, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 170, 28,
-	12, 13, 14, 15, 16, 18, 19, 20, 21, 24,
-	25, 22, 23, 29, 30, 17, 36, 37, 35, 0,
+	78, 101, 100, 132, 10, 89, 9, 215, 150, 319,
+	130, 81, 154, 158, 250, 243, 124, 51, 6, 6,
+	125, 11, 108, 142, 317, 7, 52, 53, 54, 149,
+	12, 153, 45, 116, 33, 65, 123, 60, 148, 168,
+	129, 332, 55, 331, 256, 128, 168, 168, 264, 261,
+	256, 168, 378, 169, 59, 126, 127, 62, 135, 134,
+	61, 156, 147, 145, 330, 329, 324, 137, 152, 257,
+	151, 255, 40, 173, 41, 143, 164, 131, 99, 44,
+	144, 33, 173, 274, 67, 323, 168, 183, 135, 134,
+	171, 182, 275, 131, 178, 34, 68, 69, 325, 173,
+	266, 174, 32, 66, 185, 198, 206, 208, 135, 210,
+	172, 170, 75, 221, 252, 213, 339, 209, 64, 220,
+	230, 231, 346, 33, 152, 214, 151, 369, 216, 188,
+	189, 190, 191, 192, 193, 194, 195, 196, 197, 354,
+	63, 232, 34, 226, 227, 235, 233, 234, 159, 32,
+	374, 173, 152, 152, 151, 151, 152, 152, 151, 151,
+	253, 254, 173, 42, 340, 242, 345, 164, 241, 240,
+	199, 265, 292, 267, 260, 245, 251, 316, 248, 32,
+	315, 269, 270, 271, 244, 239, 246, 245, 258, 166,
+	292, 32, 273, 245, 57, 187, 46, 56, 372, 173,
+	135, 341, 342, 336, 173, 278, 279, 335, 173, 224,
+	225, 334, 173, 214, 135, 135, 152, 214, 151, 285,
+	216, 277, 135, 135, 135, 135, 135, 135, 135, 135,
+	135, 135, 135, 135, 135, 135, 135, 296, 291, 159,
+	33, 290, 297, 308, 309, 310, 286, 135, 134, 321,
+	287, 152, 268, 151, 135, 134, 326, 143, 314, 135,
+	134, 328, 143, 313, 157, 276, 242, 333, 376, 327,
+	61, 306, 307, 55, 304, 305, 248, 375, 298, 299,
+	282, 300, 301, 302, 303, 338, 30, 31, 33, 288,
+	173, 135, 49, 4, 4, 370, 293, 294, 295, 353,
+	344, 34, 272, 237, 238, 352, 347, 314, 32, 33,
+	143, 203, 204, 205, 291, 32, 343, 135, 134, 349,
+	289, 236, 146, 337, 219, 218, 351, 135, 134, 356,
+	80, 217, 180, 177, 143, 359, 360, 361, 48, 355,
+	72, 135, 365, 362, 364, 358, 176, 175, 371, 244,
+	251, 152, 245, 151, 368, 216, 222, 223, 47, 212,
+	228, 229, 367, 72, 373, 155, 311, 284, 201, 72,
+	34, 200, 377, 202, 29, 379, 71, 283, 73, 181,
+	19, 20, 21, 22, 25, 26, 23, 24, 30, 31,
+	18, 37, 38, 36, 2, 167, 39, 85, 117, 118,
+	107, 161, 104, 105, 70, 3, 84, 83, 82, 76,
+	159, 79, 259, 140, 58, 138, 249, 35, 28, 27,
+	8, 366, 29, 13, 14, 15, 16, 17, 19, 20,
+	21, 22, 25, 26, 23, 24, 30, 31, 18, 37,
+	38, 36, 43, 120, 121, 122, 86, 87, 90, 186,
+	91, 92, 93, 94, 95, 96, 97, 98, 119, 102,
+	106, 281, 103, 109, 110, 111, 112, 113, 114, 115,
+	77, 1, 85, 117, 118, 107, 29, 104, 105, 88,
+	50, 165, 19, 20, 21, 22, 25, 26, 23, 24,
+	30, 31, 18, 37, 38, 36, 0, 29, 13, 14,
+	15, 16, 17, 19, 20, 21, 22, 25, 26, 23,
+	24, 30, 31, 18, 37, 38, 36, 0, 120, 121,
+	122, 86, 87, 90, 0, 91, 92, 93, 94, 95,
+	96, 97, 98, 119, 0, 322, 0, 0, 0, 110,
+	111, 112, 113, 114, 115, 263, 0, 85, 117, 118,
+	107, 0, 104, 105, 88, 50, 74, 0, 0, 0,
+	0, 0, 0, 0, 29, 13, 14, 15, 16, 17,
+	19, 20, 21, 22, 25, 26, 23, 24, 30, 31,
+	18, 37, 38, 36, 163, 0, 85, 117, 118, 107,
+	0, 104, 105, 120, 121, 122, 86, 87, 90, 0,
+	91, 92, 93, 94, 95, 96, 97, 98, 119, 0,
+	0, 0, 0, 0, 110, 111, 112, 113, 114, 115,
+	69, 0, 50, 0, 0, 0, 0, 0, 0, 88,
+	50, 262, 120, 121, 122, 86, 87, 90, 0, 91,
+	92, 93, 94, 95, 96, 97, 98, 119, 0, 0,
+	0, 0, 0, 110, 111, 112, 113, 114, 115, 179,
+	0, 85, 117, 118, 107, 0, 104, 105, 88, 50,
+	162, 0, 0, 0, 0, 0, 29, 13, 14, 15,
+	16, 17, 19, 20, 21, 22, 25, 26, 23, 24,
+	30, 31, 18, 37, 38, 36, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 120, 121, 122,
+	86, 87, 90, 0, 91, 92, 93, 94, 95, 96,
+	97, 98, 119, 0, 0, 0, 0, 0, 110, 111,
+	112, 113, 114, 115, 50, 136, 117, 118, 107, 0,
+	104, 105, 0, 88, 50, 0, 0, 136, 117, 118,
+	107, 0, 104, 105, 0, 0, 0, 0, 0, 0,
+	29, 0, 0, 0, 0, 0, 19, 20, 21, 22,
+	25, 26, 23, 24, 30, 31, 18, 37, 38, 36,
+	0, 120, 121, 122, 0, 0, 136, 117, 118, 107,
+	0, 104, 105, 120, 121, 122, 119, 0, 0, 0,
+	0, 0, 110, 111, 112, 113, 114, 115, 119, 0,
+	0, 0, 0, 0, 110, 111, 112, 113, 114, 115,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	160, 357, 120, 121, 122, 0, 0, 0, 136, 117,
+	118, 107, 0, 104, 105, 0, 0, 119, 0, 0,
+	0, 0, 0, 110, 111, 112, 113, 114, 115, 0,
+	0, 0, 0, 0, 29, 0, 0, 0, 0, 160,
+	19, 20, 21, 22, 25, 26, 23, 24, 30, 31,
+	18, 37, 38, 36, 120, 121, 122, 136, 117, 118,
+	107, 0, 104, 105, 0, 0, 0, 0, 0, 119,
+	0, 0, 0, 0, 0, 110, 111, 112, 113, 114,
+	115, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	88, 0, 0, 247, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 120, 121, 122, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 33, 0, 0, 119, 0,
+	0, 0, 0, 0, 110, 111, 112, 113, 114, 115,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 184,
+	29, 13, 14, 15, 16, 17, 19, 20, 21, 22,
+	25, 26, 23, 24, 30, 31, 18, 37, 38, 36,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 227, 295, 228, 0, 0, 0, 0, 31,
-	28, 12, 13, 14, 15, 16, 18, 19, 20, 21,
-	24, 25, 22, 23, 29, 30, 17, 36, 37, 35,
-	32, 0, 127, 111, 112, 101, 0, 98, 99, 0,
-	0, 0, 0, 272, 295, 228, 127, 111, 112, 101,
-	31, 98, 99, 0, 0, 28, 12, 13, 14, 15,
-	16, 18, 19, 20, 21, 24, 25, 22, 23, 29,
-	30, 17, 36, 37, 35, 127, 111, 112, 101, 0,
-	98, 99, 0, 0, 0, 0, 0, 0, 33, 0,
-	113, 336, 0, 0, 0, 31, 104, 105, 106, 107,
-	108, 109, 0, 0, 113, 0, 0, 321, 0, 0,
-	104, 105, 106, 107, 108, 109, 127, 111, 112, 101,
-	0, 98, 99, 0, 0, 0, 0, 0, 0, 127,
-	111, 112, 101, 113, 98, 99, 297, 0, 0, 104,
-	105, 106, 107, 108, 109, 127, 111, 112, 101, 0,
-	98, 99, 0, 0, 0, 0, 0, 127, 111, 112,
-	101, 0, 98, 99, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 113, 260, 0, 0, 0, 0,
-	104, 105, 106, 107, 108, 109, 0, 113, 0, 0,
-	124, 0, 0, 104, 105, 106, 107, 108, 109, 127,
-	111, 112, 101, 113, 98, 99, 0, 0, 0, 104,
-	105, 106, 107, 108, 109, 197, 0, 0, 0, 0,
-	0, 104, 105, 106, 107, 108, 109, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 244, 318, 0, 245,
+	0, 0, 0, 32, 29, 13, 14, 15, 16, 17,
+	19, 20, 21, 22, 25, 26, 23, 24, 30, 31,
+	18, 37, 38, 36, 5, 0, 33, 0, 136, 117,
+	118, 107, 0, 104, 105, 0, 0, 0, 0, 0,
+	292, 318, 0, 245, 0, 0, 0, 32, 0, 0,
+	0, 29, 13, 14, 15, 16, 17, 19, 20, 21,
+	22, 25, 26, 23, 24, 30, 31, 18, 37, 38,
+	36, 0, 0, 0, 120, 121, 122, 136, 117, 118,
+	107, 0, 104, 105, 0, 0, 0, 34, 0, 119,
+	363, 0, 0, 0, 32, 110, 111, 112, 113, 114,
+	115, 0, 0, 0, 136, 117, 118, 107, 0, 104,
+	105, 0, 0, 0, 0, 0, 136, 117, 118, 107,
+	0, 104, 105, 120, 121, 122, 0, 0, 0, 0,
+	0, 0, 0, 0, 136, 117, 118, 107, 119, 104,
+	105, 0, 348, 0, 110, 111, 112, 113, 114, 115,
+	120, 121, 122, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 120, 121, 122, 119, 0, 0, 0, 320,
+	0, 110, 111, 112, 113, 114, 115, 119, 280, 0,
+	120, 121, 122, 110, 111, 112, 113, 114, 115, 0,
+	0, 136, 117, 118, 107, 119, 104, 105, 0, 133,
+	0, 110, 111, 112, 113, 114, 115, 136, 117, 118,
+	107, 0, 104, 105, 0, 0, 0, 0, 0, 136,
+	117, 118, 107, 0, 104, 105, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 120, 121, 122,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 132, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 193, 0, 0,
-	0, 0, 0, 104, 105, 106, 107, 108, 109, 28,
-	12, 13, 14, 15, 16, 18, 19, 20, 21, 24,
-	25, 22, 23, 29, 30, 17, 36, 37, 35, 0,
+	0, 0, 119, 120, 121, 122, 0, 0, 110, 111,
+	112, 113, 114, 115, 0, 120, 121, 122, 211, 141,
+	0, 0, 0, 0, 110, 111, 112, 113, 114, 115,
+	207, 0, 0, 0, 0, 0, 110, 111, 112, 113,
+	114, 115, 0, 0, 29, 13, 14, 15, 16, 17,
+	19, 20, 21, 22, 25, 26, 23, 24, 30, 31,
+	18, 37, 38, 36, 0, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 130, 28, 12, 13, 14, 15, 16,
-	18, 19, 20, 21, 24, 25, 22, 23, 29, 30,
-	17, 36, 37, 35, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 323, 28,
-	12, 13, 14, 15, 16, 18, 19, 20, 21, 24,
-	25, 22, 23, 29, 30, 17, 36, 37, 35, 289,
-	28, 12, 13, 14, 15, 16, 18, 19, 20, 21,
-	24, 25, 22, 23, 29, 30, 17, 36, 37, 35,
+	0, 139, 29, 13, 14, 15, 16, 17, 19, 20,
+	21, 22, 25, 26, 23, 24, 30, 31, 18, 37,
+	38, 36, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 350,
+	29, 13, 14, 15, 16, 17, 19, 20, 21, 22,
+	25, 26, 23, 24, 30, 31, 18, 37, 38, 36,
+	312, 29, 13, 14, 15, 16, 17, 19, 20, 21,
+	22, 25, 26, 23, 24, 30, 31, 18, 37, 38,
+	36,
 }
 
 var yyPact = [...]int{
-	926, 926, -1000, -1000, -1000, 28, 498, 1231, 1231, 1231,
-	310, 236, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	1032, 1032, -1000, -1000, -1000, -14, 77, 647, 1372, 1372,
+	1372, 305, 129, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, 112, -1000, 134, 58, 57, -1000, -1000, -1000, -1000,
-	-4, 636, -1000, 498, -1000, -1000, 438, 28, -1000, -1000,
-	-1000, 236, 1025, 1140, 112, -1000, -1000, 226, -44, 781,
-	337, -47, -1000, 134, 731, 498, -1000, -1000, -1000, -1000,
-	544, 352, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 4,
-	1041, -3, -1000, -7, 262, 259, 253, 580, 247, 348,
-	-35, -37, 785, -1000, -1000, 109, 59, 265, 1095, 1095,
-	1041, 1053, 321, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-5, -1000, -1000, 686, 11, 25, 320, 73, 328, 21,
-	30, -1000, -14, 88, -1000, -1000, -1000, -1000, 129, 221,
-	-1000, 41, -1000, -1000, 108, -1000, -1000, -1000, 781, 417,
-	-1000, 263, 781, 781, -26, -1000, -17, 337, -1000, -1000,
-	-1000, 731, -1000, -1000, -1000, -1000, 508, 580, -6, 580,
-	1041, -1000, 1041, 1041, 1041, 116, 768, -40, -1000, -1000,
-	-1000, -31, 1041, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, 1041, 1041, 1041, 1012, 347, 343,
-	-1000, -1000, -1000, 1041, -1000, -1000, -1000, 686, 1041, 1041,
-	219, 122, 82, 1041, 1041, 1041, 1041, 1041, 1041, 1041,
-	1041, 1041, 1041, 1041, 1041, 1041, 1041, 1041, -1000, -1000,
-	-1000, 338, 1210, -1000, -1000, 266, 140, 840, 971, 78,
-	-1000, -1000, -34, -1000, -8, 1041, -1000, -1000, -1000, 337,
-	1041, -29, -38, -1000, -1000, -1000, 580, -1000, -1000, 216,
-	214, 209, 81, 768, -1000, -1000, -1000, 321, 3, 29,
-	-1000, 121, -1000, -1000, -1000, 119, -5, 11, -1000, 1041,
-	-1000, 228, 881, 25, 320, 73, 73, 328, 328, 328,
-	328, 21, 21, 30, 30, -1000, -1000, -1000, -1000, -1000,
-	-1000, 140, 942, 1175, 111, -1000, 91, -1000, 87, -1000,
-	-1000, 263, 1041, -1000, -1000, -1000, -1000, -1000, 698, -1000,
-	580, 580, 580, 1041, 928, 1041, -1000, -1000, 1041, -1000,
-	-1000, -1000, 80, -1000, 32, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, 281, -1000, -1000, 120, 580, 106, -1000, -1000,
-	-1000, -1000, 580, -41, -1000, 580, -1000, -1000, -1000,
+	-1000, -1000, 243, -1000, 236, 53, 31, -1000, -1000, -1000,
+	-1000, -1000, -1000, 17, 10, 535, -1000, 647, -1000, -1000,
+	468, 77, -1000, -1000, -1000, 129, 1140, 1275, 243, -1000,
+	-1000, 256, -25, 345, 361, -26, -1000, 236, -1000, 782,
+	647, -1000, -1000, -1000, -1000, 582, 393, -35, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, 27, 1197, 26, -1000, 15,
+	282, 281, 268, 657, 267, 375, 5, 1, 883, -1000,
+	-1000, 110, 87, 303, 1225, 1225, 1197, 1213, 342, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, 33, -1000, -1000, 731,
+	266, 260, 259, 38, 42, 341, 130, 349, 47, 69,
+	-1000, 12, 76, -1000, -1000, -1000, -1000, 255, 237, -1000,
+	118, -1000, -1000, 119, -1000, -1000, -1000, 345, 835, -1000,
+	30, 345, 345, -17, -1000, -16, 361, -1000, -1000, -1000,
+	782, -1000, -1000, -39, -1000, -1000, 543, -40, -1000, -1000,
+	657, 16, 657, 1197, -1000, 1197, 1197, 1197, 244, 0,
+	834, -3, -1000, -1000, -1000, 6, 1197, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 1197, 1197,
+	1197, 1122, 373, 363, -1000, -1000, -1000, 1197, -1000, -1000,
+	-1000, 731, 1197, 1197, 223, 254, 107, 1197, 1197, 1197,
+	1197, 1197, 1197, 1197, 1197, 1197, 1197, 1197, 1197, 1197,
+	1197, 1197, 1197, 1197, 1197, -1000, -1000, -1000, 362, 1351,
+	-1000, -1000, 284, 112, 941, 1110, 447, -1000, -1000, -1,
+	-1000, 14, 1197, -1000, -1000, -1000, 361, 1197, -23, -24,
+	-1000, -1000, -1000, -47, -1000, -1000, 657, -1000, -1000, 145,
+	141, 137, 258, 834, -1000, -1000, -1000, 342, 32, 95,
+	-1000, 135, -1000, -1000, -1000, 250, 33, 38, -1000, 1197,
+	-1000, 125, 985, 99, 55, 240, 42, 341, 130, 130,
+	349, 349, 349, 349, 47, 47, 69, 69, -1000, -1000,
+	-1000, -1000, -1000, -1000, 112, 1083, 1313, 239, -1000, 233,
+	-1000, 70, -1000, -1000, 30, 1197, -1000, -1000, -1000, -1000,
+	-1000, 743, -1000, -1000, 657, 657, 657, 1197, 1034, 1197,
+	-1000, -1000, 1197, -1000, -1000, 358, 345, -1000, -1000, 58,
+	-1000, 229, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 292,
+	-1000, -1000, 132, 657, 84, -1000, -1000, 211, 202, -1000,
+	-1000, 657, -34, -1000, 657, -1000, -1000, -1000, -1000, -1000,
 }
 
 var yyPgo = [...]int{
-	0, 428, 427, 34, 426, 419, 7, 1, 418, 8,
-	24, 35, 44, 51, 45, 18, 23, 64, 67, 80,
-	417, 2, 416, 17, 301, 12, 415, 405, 50, 236,
-	269, 5, 10, 401, 378, 374, 28, 25, 179, 371,
-	6, 27, 4, 26, 19, 3, 370, 369, 368, 16,
-	31, 15, 366, 0, 365, 742, 9, 364, 363, 360,
-	106, 505, 353, 354,
+	0, 471, 463, 5, 78, 7, 462, 461, 1, 460,
+	10, 40, 45, 56, 55, 20, 16, 36, 33, 22,
+	459, 2, 449, 3, 292, 17, 442, 420, 6, 4,
+	196, 25, 13, 419, 418, 417, 38, 29, 8, 416,
+	14, 31, 12, 21, 30, 9, 415, 414, 413, 23,
+	24, 15, 412, 0, 411, 330, 11, 408, 407, 406,
+	112, 358, 394, 405,
 }
 
 var yyR1 = [...]int{
-	0, 2, 2, 2, 2, 4, 4, 4, 4, 4,
-	4, 4, 4, 5, 5, 7, 7, 7, 7, 7,
-	7, 8, 8, 8, 8, 8, 8, 9, 9, 11,
-	11, 11, 11, 12, 12, 12, 13, 13, 13, 14,
-	14, 14, 14, 14, 15, 15, 15, 16, 16, 17,
-	17, 18, 18, 19, 19, 20, 20, 21, 21, 6,
-	6, 22, 22, 22, 22, 22, 22, 22, 22, 22,
-	22, 22, 3, 3, 23, 24, 24, 25, 25, 25,
-	25, 25, 25, 26, 26, 30, 30, 27, 27, 27,
-	27, 27, 28, 28, 28, 28, 28, 28, 28, 28,
-	28, 28, 28, 28, 33, 33, 33, 35, 35, 36,
-	36, 37, 38, 38, 38, 38, 39, 39, 40, 40,
-	40, 34, 34, 34, 41, 41, 42, 42, 29, 29,
-	31, 31, 44, 44, 44, 44, 44, 44, 44, 43,
-	43, 43, 43, 47, 47, 45, 45, 48, 48, 49,
-	49, 49, 46, 46, 10, 10, 50, 50, 50, 51,
-	51, 51, 51, 51, 51, 51, 51, 51, 32, 32,
-	32, 52, 52, 53, 53, 53, 53, 53, 53, 54,
-	54, 54, 55, 55, 55, 55, 61, 61, 60, 60,
-	56, 56, 57, 57, 57, 58, 58, 58, 58, 59,
-	59, 59, 59, 59, 1, 1, 62, 62, 63, 63,
-	63, 63,
+	0, 2, 2, 2, 2, 2, 2, 2, 6, 6,
+	6, 6, 6, 6, 6, 6, 7, 7, 8, 8,
+	8, 8, 8, 8, 9, 9, 9, 9, 9, 9,
+	10, 10, 11, 11, 11, 11, 12, 12, 12, 13,
+	13, 13, 14, 14, 14, 14, 14, 15, 15, 15,
+	16, 16, 17, 17, 18, 18, 19, 19, 20, 20,
+	21, 21, 4, 4, 22, 22, 22, 22, 22, 22,
+	22, 22, 22, 22, 22, 3, 3, 23, 24, 24,
+	24, 25, 25, 25, 25, 25, 25, 26, 26, 30,
+	30, 27, 27, 27, 27, 27, 28, 28, 28, 28,
+	28, 28, 28, 28, 28, 28, 28, 28, 33, 33,
+	33, 35, 35, 36, 36, 37, 38, 38, 38, 38,
+	39, 39, 40, 40, 40, 34, 34, 34, 41, 41,
+	42, 42, 29, 29, 31, 31, 44, 44, 44, 44,
+	44, 44, 44, 43, 43, 43, 43, 47, 47, 45,
+	45, 48, 48, 49, 49, 49, 46, 46, 5, 5,
+	50, 50, 50, 51, 51, 51, 51, 51, 51, 51,
+	51, 51, 32, 32, 32, 52, 52, 53, 53, 53,
+	53, 53, 53, 53, 54, 54, 54, 55, 55, 55,
+	55, 55, 55, 55, 55, 61, 61, 60, 60, 56,
+	56, 57, 57, 57, 58, 58, 58, 58, 59, 59,
+	59, 59, 59, 1, 1, 62, 62, 62, 62, 63,
+	63, 63, 63,
 }
 
 var yyR2 = [...]int{
-	0, 1, 1, 1, 3, 1, 4, 3, 4, 3,
-	3, 2, 2, 1, 3, 1, 2, 2, 2, 2,
-	4, 1, 1, 1, 1, 1, 1, 1, 4, 1,
-	3, 3, 3, 1, 3, 3, 1, 3, 3, 1,
-	3, 3, 3, 3, 1, 3, 3, 1, 3, 1,
-	3, 1, 3, 1, 3, 1, 3, 1, 5, 1,
+	0, 1, 1, 1, 3, 6, 6, 4, 1, 4,
+	3, 4, 3, 3, 2, 2, 1, 3, 1, 2,
+	2, 2, 2, 4, 1, 1, 1, 1, 1, 1,
+	1, 4, 1, 3, 3, 3, 1, 3, 3, 1,
+	3, 3, 1, 3, 3, 3, 3, 1, 3, 3,
+	1, 3, 1, 3, 1, 3, 1, 3, 1, 3,
+	1, 5, 1, 3, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 3, 1, 2, 3,
+	3, 1, 2, 1, 2, 1, 2, 1, 3, 1,
 	3, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 3, 1, 2, 3, 1, 2, 1,
-	2, 1, 2, 1, 3, 1, 3, 1, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 5, 4, 2, 1, 1, 1,
-	2, 3, 2, 1, 2, 1, 1, 3, 1, 2,
-	3, 4, 5, 2, 1, 3, 1, 3, 1, 1,
-	2, 1, 1, 3, 4, 3, 4, 4, 3, 1,
-	2, 2, 3, 1, 2, 1, 3, 1, 3, 2,
-	2, 1, 1, 3, 1, 2, 1, 1, 2, 3,
-	2, 3, 3, 4, 2, 3, 3, 4, 1, 3,
-	4, 1, 3, 1, 1, 1, 1, 1, 1, 3,
-	4, 3, 2, 3, 3, 4, 1, 2, 1, 2,
-	1, 2, 5, 7, 5, 5, 7, 6, 7, 3,
-	2, 2, 2, 3, 1, 2, 1, 1, 4, 3,
-	3, 2,
+	1, 1, 1, 1, 1, 1, 1, 1, 5, 4,
+	2, 1, 1, 1, 2, 3, 2, 1, 2, 1,
+	1, 3, 1, 2, 3, 4, 5, 2, 1, 3,
+	1, 3, 1, 1, 2, 1, 1, 3, 4, 3,
+	4, 4, 3, 1, 2, 2, 3, 1, 2, 1,
+	3, 1, 3, 2, 2, 1, 1, 3, 1, 2,
+	1, 1, 2, 3, 2, 3, 3, 4, 2, 3,
+	3, 4, 1, 3, 4, 1, 3, 1, 1, 1,
+	1, 1, 1, 2, 3, 4, 3, 2, 3, 3,
+	4, 3, 4, 4, 5, 1, 2, 1, 2, 1,
+	2, 5, 7, 5, 5, 7, 6, 7, 3, 2,
+	2, 2, 3, 1, 2, 1, 1, 2, 2, 4,
+	3, 3, 2,
 }
 
 var yyChk = [...]int{
-	-1000, -1, -62, -63, -24, -25, -31, -27, -28, -29,
-	-43, -44, 30, 31, 32, 33, 34, 45, 35, 36,
-	37, 38, 41, 42, 39, 40, -33, -34, 29, 43,
-	44, 69, 4, 62, -35, 48, 46, 47, -62, 83,
-	-26, -31, -30, -61, -55, -24, 84, -25, -25, -25,
-	-25, -44, 64, 62, -47, -43, -29, -31, 4, 84,
-	84, 4, 83, 67, 82, -61, -55, -24, -55, 85,
-	-60, -61, -53, -54, -55, -56, -57, -58, -59, 4,
-	50, 51, 83, -3, 52, 54, 55, 56, 57, 58,
-	59, 60, 61, -6, -21, -7, -20, -4, 9, 10,
-	-8, 7, -19, -2, 68, 69, 70, 71, 72, 73,
-	-18, 5, 6, 62, -17, -16, -15, -14, -13, -12,
-	-11, -9, -31, -23, 65, -21, -7, 4, -45, -46,
-	63, -48, 4, -49, -25, -43, -29, 63, 84, -36,
-	-37, -38, -28, -29, -41, -42, 4, 84, -30, -32,
-	-6, 84, -55, 85, -53, 85, -60, 81, -23, 81,
-	67, 83, 62, 62, 62, -53, 62, 4, 83, 83,
-	83, -3, -22, 82, 19, 20, 21, 22, 23, 24,
-	25, 26, 27, 28, 18, 80, 64, 62, 66, 8,
-	9, 10, -7, 62, -7, -9, -7, 62, 17, 79,
-	-3, -10, -38, 78, 68, 15, 16, 76, 77, 13,
-	14, 11, 12, 70, 71, 69, 74, 75, 65, 63,
-	63, 67, 67, -31, -50, -43, -51, 62, 64, -36,
-	85, -37, -39, -40, -31, 81, -38, -38, 85, 67,
-	82, -41, -52, -32, 85, -53, 81, -53, -6, -3,
-	-3, -3, 55, -56, 83, 83, -6, -19, -3, -3,
-	63, -5, -6, 4, 4, -10, -18, -17, 63, 63,
-	-50, -43, 62, -16, -15, -14, -14, -13, -13, -13,
-	-13, -12, -12, -11, -11, -9, -9, -9, 4, 49,
-	-49, -51, 64, 62, -50, 63, -45, 65, -23, 85,
-	83, 67, 81, -23, -42, -23, 85, 85, 67, -53,
-	63, 63, 63, 62, -56, 81, 65, 63, 67, 63,
-	-9, 65, -23, 63, -45, 63, 63, 65, -40, -23,
-	85, -32, -53, -53, -53, -3, 63, -3, -21, -6,
-	65, 63, 53, 63, -53, 63, -53, 83, -53,
+	-1000, -1, -62, -63, -24, 2, -25, -31, -27, -28,
+	-29, -43, -44, 30, 31, 32, 33, 34, 45, 35,
+	36, 37, 38, 41, 42, 39, 40, -33, -34, 29,
+	43, 44, 72, 4, 65, -35, 48, 46, 47, -62,
+	86, 88, 86, -26, 2, -31, -30, -61, -55, -24,
+	87, -25, -25, -25, -25, -44, 68, 65, -47, -43,
+	-29, -31, 4, 87, 87, 4, 86, 67, 86, 85,
+	-61, -55, -24, -55, 88, -60, -61, 2, -53, -54,
+	-55, -56, -57, -58, -59, 4, 53, 54, 86, -3,
+	55, 57, 58, 59, 60, 61, 62, 63, 64, -4,
+	-21, -8, -20, -6, 9, 10, -9, 7, -19, -2,
+	71, 72, 73, 74, 75, 76, -18, 5, 6, 65,
+	50, 51, 52, -17, -16, -15, -14, -13, -12, -11,
+	-10, -31, -23, 69, -21, -8, 4, -45, -46, 66,
+	-48, 4, -49, -25, -43, -29, 66, 87, -36, -37,
+	-38, -28, -29, -41, -42, 4, 87, -30, -32, -4,
+	87, -55, 88, 2, -53, 88, -60, 2, 86, 88,
+	84, -23, 84, 67, 86, 65, 65, 65, -53, 2,
+	65, 4, 86, 86, 86, -3, -22, 85, 19, 20,
+	21, 22, 23, 24, 25, 26, 27, 28, 18, 83,
+	68, 65, 70, 8, 9, 10, -8, 65, -8, -10,
+	-8, 65, 17, 82, -3, -5, -38, 65, 65, 65,
+	81, 71, 15, 16, 79, 80, 13, 14, 11, 12,
+	73, 74, 72, 77, 78, 69, 66, 66, 67, 67,
+	-31, -50, -43, -51, 65, 68, -36, 88, -37, -39,
+	-40, -31, 84, -38, -38, 88, 67, 85, -41, -52,
+	-32, 88, 88, 2, 88, -53, 84, -53, -4, -3,
+	-3, -3, 58, -56, 86, 86, -4, -19, -3, -3,
+	66, -7, -4, 4, 4, -5, -18, -17, 66, 66,
+	-50, -43, 65, -4, -4, -4, -16, -15, -14, -14,
+	-13, -13, -13, -13, -12, -12, -11, -11, -10, -10,
+	-10, 4, 49, -49, -51, 68, 65, -50, 66, -45,
+	69, -23, 88, 86, 67, 84, -23, -42, -23, 88,
+	88, 67, 88, -53, 66, 66, 66, 65, -56, 84,
+	69, 66, 67, 66, -10, 67, 67, 66, 69, -23,
+	66, -45, 66, 66, 69, -40, -23, 88, -32, -53,
+	-53, -53, -3, 66, -3, -21, -4, 4, -5, 69,
+	66, 56, 66, -53, 66, 66, 66, -53, 86, -53,
 }
 
 var yyDef = [...]int{
-	0, -2, 204, 206, 207, 0, 0, 77, 79, 81,
-	0, 131, 87, 88, 89, 90, 91, 92, 93, 94,
-	95, 96, 97, 98, 99, 100, 101, 102, 103, 128,
-	129, 139, 132, 0, 0, 0, 107, 108, 205, 75,
-	0, 85, 83, 0, 211, 186, 0, 0, 78, 80,
-	82, 130, 0, 0, 140, 141, 143, 0, 106, 0,
-	0, 123, 76, 0, 0, 0, 209, 187, 210, 182,
-	0, 0, 188, 173, 174, 175, 176, 177, 178, 1,
-	0, 0, 190, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 72, 59, 27, 57, 15, 0, 0,
-	0, 0, 55, 5, 21, 22, 23, 24, 25, 26,
-	53, 2, 3, 0, 51, 49, 47, 44, 39, 36,
-	33, 29, 85, 0, 135, 74, 27, 1, 0, 0,
-	138, 145, 152, 147, 151, 142, 144, 133, 0, 0,
-	109, 0, 113, 115, 0, 124, 126, 0, 84, 86,
-	168, 0, 208, 183, 189, 184, 0, 0, 0, 0,
-	0, 191, 0, 0, 0, 0, 0, 0, 200, 201,
-	202, 0, 0, 61, 62, 63, 64, 65, 66, 67,
-	68, 69, 70, 71, 0, 0, 0, 0, 0, 0,
-	11, 12, 16, 0, 17, 18, 19, 0, 0, 0,
-	0, 0, 154, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 134, 136,
-	137, 0, 0, 149, 150, 156, 157, 0, 0, 0,
-	105, 110, 0, 116, 118, 0, 112, 114, 121, 0,
-	0, 0, 0, 171, 185, 179, 0, 181, 73, 0,
-	0, 0, 0, 0, 199, 203, 60, 56, 0, 0,
-	7, 0, 13, 9, 10, 0, 54, 52, 4, 0,
-	155, 156, 0, 50, 48, 45, 46, 40, 41, 42,
-	43, 37, 38, 34, 35, 30, 31, 32, 153, 146,
-	148, 158, 0, 0, 0, 164, 0, 160, 0, 104,
-	111, 0, 0, 119, 125, 127, 122, 169, 0, 180,
-	0, 0, 0, 0, 0, 0, 6, 8, 0, 20,
-	28, 162, 0, 166, 0, 159, 165, 161, 117, 120,
-	170, 172, 192, 194, 195, 0, 0, 0, 58, 14,
-	163, 167, 0, 0, 197, 0, 193, 196, 198,
+	0, -2, 213, 215, 216, 0, 0, 0, 81, 83,
+	85, 0, 135, 91, 92, 93, 94, 95, 96, 97,
+	98, 99, 100, 101, 102, 103, 104, 105, 106, 107,
+	132, 133, 143, 136, 0, 0, 0, 111, 112, 214,
+	217, 218, 78, 0, 0, 89, 87, 0, 222, 195,
+	0, 0, 82, 84, 86, 134, 0, 0, 144, 145,
+	147, 0, 110, 0, 0, 127, 79, 0, 80, 0,
+	0, 220, 196, 221, 187, 0, 0, 0, 197, 177,
+	178, 179, 180, 181, 182, 1, 0, 0, 199, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 75,
+	62, 30, 60, 18, 0, 0, 0, 0, 58, 8,
+	24, 25, 26, 27, 28, 29, 56, 2, 3, 0,
+	0, 0, 0, 54, 52, 50, 47, 42, 39, 36,
+	32, 89, 0, 139, 77, 30, 1, 0, 0, 142,
+	149, 156, 151, 155, 146, 148, 137, 0, 0, 113,
+	0, 117, 119, 0, 128, 130, 0, 88, 90, 172,
+	0, 219, 188, 0, 198, 189, 0, 0, 183, 191,
+	0, 0, 0, 0, 200, 0, 0, 0, 0, 0,
+	0, 0, 209, 210, 211, 0, 0, 64, 65, 66,
+	67, 68, 69, 70, 71, 72, 73, 74, 0, 0,
+	0, 0, 0, 0, 14, 15, 19, 0, 20, 21,
+	22, 0, 0, 0, 0, 0, 158, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 138, 140, 141, 0, 0,
+	153, 154, 160, 161, 0, 0, 0, 109, 114, 0,
+	120, 122, 0, 116, 118, 125, 0, 0, 0, 0,
+	175, 192, 190, 0, 193, 184, 0, 186, 76, 0,
+	0, 0, 0, 0, 208, 212, 63, 59, 0, 0,
+	10, 0, 16, 12, 13, 0, 57, 55, 4, 0,
+	159, 160, 0, 0, 0, 0, 53, 51, 48, 49,
+	43, 44, 45, 46, 40, 41, 37, 38, 33, 34,
+	35, 157, 150, 152, 162, 0, 0, 0, 168, 0,
+	164, 0, 108, 115, 0, 0, 123, 129, 131, 126,
+	173, 0, 194, 185, 0, 0, 0, 0, 0, 0,
+	9, 11, 0, 23, 31, 0, 0, 7, 166, 0,
+	170, 0, 163, 169, 165, 121, 124, 174, 176, 201,
+	203, 204, 0, 0, 0, 61, 17, 0, 0, 167,
+	171, 0, 0, 206, 0, 5, 6, 202, 205, 207,
 }
 
 var yyTok1 = [...]int{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 73, 3, 3, 3, 75, 68, 3,
-	62, 63, 69, 70, 67, 71, 66, 74, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 81, 83,
-	76, 82, 77, 80, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 76, 3, 3, 3, 78, 71, 3,
+	65, 66, 72, 73, 67, 74, 70, 77, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 84, 86,
+	79, 85, 80, 83, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 64, 3, 65, 78, 3, 3, 3, 3, 3,
+	3, 68, 3, 69, 81, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 84, 79, 85, 72,
+	3, 3, 3, 87, 82, 88, 75,
 }
 
 var yyTok2 = [...]int{
@@ -527,6 +550,7 @@ var yyTok2 = [...]int{
 	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
 	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
 	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
+	62, 63, 64,
 }
 
 var yyTok3 = [...]int{
@@ -872,875 +896,1015 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:47
+//line grammar.y:39
 		{
-			yyVAL.n = &ASTIdentifier{ident: yyDollar[1].str}
+			yyVAL.n = &ASTIdentifier{located: span(yyDollar[1], yyDollar[1]), ident: yyDollar[1].str}
 		}
 	case 2:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:48
+//line grammar.y:40
 		{
-			yyVAL.n = &ASTConstant{value: yyDollar[1].str}
+			yyVAL.n = &ASTConstant{located: span(yyDollar[1], yyDollar[1]), value: yyDollar[1].str}
 		}
 	case 3:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:49
+//line grammar.y:41
 		{
-			yyVAL.n = &ASTStringLiteral{value: yyDollar[1].str}
+			yyVAL.n = &ASTStringLiteral{located: span(yyDollar[1], yyDollar[1]), value: yyDollar[1].str}
 		}
 	case 4:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:50
+//line grammar.y:42
 		{
-			yyVAL.n = &ASTBrackets{yyDollar[2].n}
+			yyVAL.n = &ASTBrackets{located: span(yyDollar[1], yyDollar[3]), Node: yyDollar[2].n}
 		}
 	case 5:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line grammar.y:43
+		{
+			yyVAL.n = &ASTVaStart{located: span(yyDollar[1], yyDollar[6]), ap: yyDollar[3].n, last: &ASTIdentifier{located: span(yyDollar[5], yyDollar[5]), ident: yyDollar[5].str}}
+		}
+	case 6:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line grammar.y:46
+		{
+			yyVAL.n = &ASTVaArg{located: span(yyDollar[1], yyDollar[6]), ap: yyDollar[3].n, typeName: yyDollar[5].n.(*ASTTypeName)}
+		}
+	case 7:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line grammar.y:49
+		{
+			yyVAL.n = &ASTVaEnd{located: span(yyDollar[1], yyDollar[4]), ap: yyDollar[3].n}
+		}
+	case 8:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:54
+//line grammar.y:55
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 6:
+	case 9:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:55
+//line grammar.y:56
 		{
 			// Array indexing
 			yyVAL.n = &ASTIndexedExpression{
-				lvalue: yyDollar[1].n,
-				index:  yyDollar[3].n,
+				located: span(yyDollar[1], yyDollar[4]),
+				lvalue:  yyDollar[1].n,
+				index:   yyDollar[3].n,
 			}
 		}
-	case 7:
+	case 10:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:62
+//line grammar.y:64
 		{
-			yyVAL.n = &ASTFunctionCall{function: yyDollar[1].n}
+			yyVAL.n = &ASTFunctionCall{located: span(yyDollar[1], yyDollar[3]), function: yyDollar[1].n}
 		}
-	case 8:
+	case 11:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:65
+//line grammar.y:67
 		{
 			yyVAL.n = &ASTFunctionCall{
+				located:   span(yyDollar[1], yyDollar[4]),
 				function:  yyDollar[1].n,
 				arguments: yyDollar[3].n.(ASTArgumentExpressionList),
 			}
 		}
-	case 9:
+	case 12:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:71
+//line grammar.y:74
 		{
-			yyVAL.n = &ASTStructElement{structImp: yyDollar[1].n, ident: yyDollar[3].str}
+			yyVAL.n = &ASTStructElement{located: span(yyDollar[1], yyDollar[3]), structImp: yyDollar[1].n, ident: yyDollar[3].str}
 		}
-	case 10:
+	case 13:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:72
+//line grammar.y:75
 		{
-			yyVAL.n = &ASTStructElement{structImp: yyDollar[1].n, ident: yyDollar[3].str, pointer: true}
+			yyVAL.n = &ASTStructElement{located: span(yyDollar[1], yyDollar[3]), structImp: yyDollar[1].n, ident: yyDollar[3].str, pointer: true}
 		}
-	case 11:
+	case 14:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:73
+//line grammar.y:76
 		{
-			yyVAL.n = &ASTExprSuffixUnary{typ: ASTExprSuffixUnaryTypeIncrement, lvalue: yyDollar[1].n}
+			yyVAL.n = &ASTExprSuffixUnary{located: span(yyDollar[1], yyDollar[2]), typ: ASTExprSuffixUnaryTypeIncrement, lvalue: yyDollar[1].n}
 		}
-	case 12:
+	case 15:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:76
+//line grammar.y:79
 		{
-			yyVAL.n = &ASTExprSuffixUnary{typ: ASTExprSuffixUnaryTypeDecrement, lvalue: yyDollar[1].n}
+			yyVAL.n = &ASTExprSuffixUnary{located: span(yyDollar[1], yyDollar[2]), typ: ASTExprSuffixUnaryTypeDecrement, lvalue: yyDollar[1].n}
 		}
-	case 13:
+	case 16:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:82
+//line grammar.y:85
 		{
 			yyVAL.n = ASTArgumentExpressionList{yyDollar[1].n.(*ASTAssignment)}
 		}
-	case 14:
+	case 17:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:83
+//line grammar.y:86
 		{
 			li := yyDollar[1].n.(ASTArgumentExpressionList)
 			li = append(li, yyDollar[3].n.(*ASTAssignment))
 			yyVAL.n = li
 		}
-	case 15:
+	case 18:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:91
+//line grammar.y:94
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 16:
+	case 19:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:92
+//line grammar.y:95
 		{
-			yyVAL.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeIncrement, lvalue: yyDollar[2].n}
+			yyVAL.n = &ASTExprPrefixUnary{located: span(yyDollar[1], yyDollar[2]), typ: ASTExprPrefixUnaryTypeIncrement, lvalue: yyDollar[2].n}
 		}
-	case 17:
+	case 20:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:95
+//line grammar.y:98
 		{
-			yyVAL.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeDecrement, lvalue: yyDollar[2].n}
+			yyVAL.n = &ASTExprPrefixUnary{located: span(yyDollar[1], yyDollar[2]), typ: ASTExprPrefixUnaryTypeDecrement, lvalue: yyDollar[2].n}
 		}
-	case 18:
+	case 21:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:98
+//line grammar.y:101
 		{
-			yyVAL.n = &ASTExprPrefixUnary{typ: yyDollar[1].unaryOperator, lvalue: yyDollar[2].n}
+			yyVAL.n = &ASTExprPrefixUnary{located: span(yyDollar[1], yyDollar[2]), typ: yyDollar[1].unaryOperator, lvalue: yyDollar[2].n}
 		}
-	case 19:
+	case 22:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:101
+//line grammar.y:104
 		{
-			yyVAL.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: yyDollar[2].n}
+			yyVAL.n = &ASTExprPrefixUnary{located: span(yyDollar[1], yyDollar[2]), typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: yyDollar[2].n}
 		}
-	case 20:
+	case 23:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:104
+//line grammar.y:107
 		{
-			yyVAL.n = &ASTExprPrefixUnary{typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: yyDollar[3].typ}
+			yyVAL.n = &ASTExprPrefixUnary{located: span(yyDollar[1], yyDollar[4]), typ: ASTExprPrefixUnaryTypeSizeOf, lvalue: yyDollar[3].n}
 		}
-	case 21:
+	case 24:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:110
+//line grammar.y:113
 		{
 			yyVAL.unaryOperator = ASTExprPrefixUnaryTypeAddressOf
 		}
-	case 22:
+	case 25:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:111
+//line grammar.y:114
 		{
 			yyVAL.unaryOperator = ASTExprPrefixUnaryTypeDereference
 		}
-	case 23:
+	case 26:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:112
+//line grammar.y:115
 		{
 			yyVAL.unaryOperator = ASTExprPrefixUnaryTypePositive
 		}
-	case 24:
+	case 27:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:113
+//line grammar.y:116
 		{
 			yyVAL.unaryOperator = ASTExprPrefixUnaryTypeNegative
 		}
-	case 25:
+	case 28:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:114
+//line grammar.y:117
 		{
 			yyVAL.unaryOperator = ASTExprPrefixUnaryTypeNot
 		}
-	case 26:
+	case 29:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:115
+//line grammar.y:118
 		{
 			yyVAL.unaryOperator = ASTExprPrefixUnaryTypeInvert
 		}
-	case 27:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:119
-		{
-			yyVAL.n = yyDollar[1].n
-		}
-	case 29:
+	case 30:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:124
+//line grammar.y:122
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 30:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:125
-		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeMul}
-		}
 	case 31:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:126
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line grammar.y:123
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeDiv}
+			yyVAL.n = &ASTCast{located: span(yyDollar[1], yyDollar[4]), typeName: yyDollar[2].n.(*ASTTypeName), expr: yyDollar[4].n}
 		}
 	case 32:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:127
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:129
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeMod}
+			yyVAL.n = yyDollar[1].n
 		}
 	case 33:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:131
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:130
 		{
-			yyVAL.n = yyDollar[1].n
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeMul}
 		}
 	case 34:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:132
+//line grammar.y:131
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeAdd}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeDiv}
 		}
 	case 35:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:133
+//line grammar.y:132
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeSub}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeMod}
 		}
 	case 36:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:137
+//line grammar.y:136
 		{
 			yyVAL.n = yyDollar[1].n
 		}
 	case 37:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:138
+//line grammar.y:137
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLeftShift}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeAdd}
 		}
 	case 38:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:139
+//line grammar.y:138
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeRightShift}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeSub}
 		}
 	case 39:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:143
+//line grammar.y:142
 		{
 			yyVAL.n = yyDollar[1].n
 		}
 	case 40:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:144
+//line grammar.y:143
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLessThan}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLeftShift}
 		}
 	case 41:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:145
+//line grammar.y:144
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeGreaterThan}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeRightShift}
 		}
 	case 42:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:146
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:148
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLessOrEqual}
+			yyVAL.n = yyDollar[1].n
 		}
 	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:147
+//line grammar.y:149
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeGreaterOrEqual}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLessThan}
 		}
 	case 44:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:151
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:150
 		{
-			yyVAL.n = yyDollar[1].n
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeGreaterThan}
 		}
 	case 45:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:152
+//line grammar.y:151
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeEquality}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLessOrEqual}
 		}
 	case 46:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:153
+//line grammar.y:152
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeNotEquality}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeGreaterOrEqual}
 		}
 	case 47:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:157
+//line grammar.y:156
 		{
 			yyVAL.n = yyDollar[1].n
 		}
 	case 48:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:158
+//line grammar.y:157
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeBitwiseAnd}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeEquality}
 		}
 	case 49:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:158
+		{
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeNotEquality}
+		}
+	case 50:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:162
+//line grammar.y:162
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 50:
+	case 51:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:163
+//line grammar.y:163
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeXor}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeBitwiseAnd}
 		}
-	case 51:
+	case 52:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:167
+//line grammar.y:167
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 52:
+	case 53:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:168
+//line grammar.y:168
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeBitwiseOr}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeXor}
 		}
-	case 53:
+	case 54:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:172
+//line grammar.y:172
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 54:
+	case 55:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:173
+//line grammar.y:173
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLogicalAnd}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeBitwiseOr}
 		}
-	case 55:
+	case 56:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:177
+//line grammar.y:177
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 56:
+	case 57:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:178
+//line grammar.y:178
 		{
-			yyVAL.n = &ASTExprBinary{lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLogicalOr}
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLogicalAnd}
 		}
-	case 57:
+	case 58:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:182
+//line grammar.y:182
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 58:
+	case 59:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:183
+		{
+			yyVAL.n = &ASTExprBinary{located: span(yyDollar[1], yyDollar[3]), lhs: yyDollar[1].n, rhs: yyDollar[3].n, typ: ASTExprBinaryTypeLogicalOr}
+		}
+	case 60:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:187
+		{
+			yyVAL.n = yyDollar[1].n
+		}
+	case 61:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line pkg/c90/grammar.y:183
+//line grammar.y:188
 		{
 			yyVAL.n = &ASTIfStatement{
+				located:   span(yyDollar[1], yyDollar[5]),
 				condition: yyDollar[1].n,
 				body:      yyDollar[3].n,
 				elseBody:  yyDollar[5].n,
 				ternary:   true,
 			}
 		}
-	case 59:
+	case 62:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:194
+//line grammar.y:200
 		{
-			yyVAL.n = &ASTAssignment{value: yyDollar[1].n, tmpAssign: true}
+			yyVAL.n = &ASTAssignment{located: span(yyDollar[1], yyDollar[1]), value: yyDollar[1].n, tmpAssign: true}
 		}
-	case 60:
+	case 63:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:197
+//line grammar.y:203
 		{
-			yyVAL.n = &ASTAssignment{lval: yyDollar[1].n, operator: yyDollar[2].assignmentOperator, value: yyDollar[3].n}
+			yyVAL.n = &ASTAssignment{located: span(yyDollar[1], yyDollar[3]), lval: yyDollar[1].n, operator: yyDollar[2].assignmentOperator, value: yyDollar[3].n}
 		}
-	case 61:
+	case 64:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:203
+//line grammar.y:209
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorEquals
 		}
-	case 62:
+	case 65:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:204
+//line grammar.y:210
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorMulEquals
 		}
-	case 63:
+	case 66:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:205
+//line grammar.y:211
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorDivEquals
 		}
-	case 64:
+	case 67:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:206
+//line grammar.y:212
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorModEquals
 		}
-	case 65:
+	case 68:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:207
+//line grammar.y:213
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorAddEquals
 		}
-	case 66:
+	case 69:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:208
+//line grammar.y:214
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorSubEquals
 		}
-	case 67:
+	case 70:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:209
+//line grammar.y:215
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorLeftEquals
 		}
-	case 68:
+	case 71:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:210
+//line grammar.y:216
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorRightEquals
 		}
-	case 69:
+	case 72:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:211
+//line grammar.y:217
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorAndEquals
 		}
-	case 70:
+	case 73:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:212
+//line grammar.y:218
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorXorEquals
 		}
-	case 71:
+	case 74:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:213
+//line grammar.y:219
 		{
 			yyVAL.assignmentOperator = ASTAssignmentOperatorOrEquals
 		}
-	case 72:
+	case 75:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:217
+//line grammar.y:223
 		{
 			yyVAL.n = ASTExpression{yyDollar[1].n.(*ASTAssignment)}
 		}
-	case 73:
+	case 76:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:220
+//line grammar.y:226
 		{
 			li := yyDollar[1].n.(ASTExpression)
 			li = append(li, yyDollar[3].n.(*ASTAssignment))
 			yyVAL.n = li
 		}
-	case 74:
+	case 77:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:228
+//line grammar.y:234
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 75:
+	case 78:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:232
+//line grammar.y:238
 		{
-			if yyDollar[1].typ != nil && yyDollar[1].typ.typ == VarTypeEnum || yyDollar[1].typ.typ == VarTypeStruct {
+			if yyDollar[1].typ != nil && (yyDollar[1].typ.enum != nil || yyDollar[1].typ.structure != nil) {
 				yyVAL.n = ASTDeclaratorList{
 					&ASTDecl{
-						typ: yyDollar[1].typ,
+						located: span(yyDollar[1], yyDollar[2]),
+						typ:     yyDollar[1].typ,
 					},
 				}
 			} else {
-				fmt.Fprintf(os.Stderr, "Ignoring declaration specifier without init declaration list\n")
+				parseWarningf(yylex, span(yyDollar[1], yyDollar[2]).rng, "declaration does not declare anything")
 				yyVAL.n = ASTDeclaratorList{}
 			}
 		}
-	case 76:
+	case 79:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:244
-		{
-			if yyDollar[1].typ != nil {
-				vartype := yyDollar[1].typ
-				addPointerDepth := 0
-				var typeDefDecl *ASTDirectDeclarator
-				if yyDollar[1].typ.typ == VarTypeTypeName {
-					mapEntry := typmap[yyDollar[1].typ.typName]
-					vartype = mapEntry.typ
-					addPointerDepth = mapEntry.decl.pointerDepth
-					typeDefDecl = mapEntry.decl
-				}
-				for _, entry := range yyDollar[2].n.(ASTDeclaratorList) {
-					entry.typ = vartype
-
-					if typeDefDecl != nil {
-						if entry.decl.array != nil {
-							decl := &entry.decl
-							for *decl != nil {
-								decl = &(*decl).decl
-							}
-							*decl = typeDefDecl
-						} else {
-							entry.decl.array = typeDefDecl.array
-							decl := &entry.decl
-							for *decl != nil {
-								decl = &(*decl).decl
-							}
-							*decl = typeDefDecl
-
-						}
-					}
-
-					entry.decl.pointerDepth += addPointerDepth
-				}
-				yyVAL.n = yyDollar[2].n
-			} else {
-				// Parse typedef line
-				for _, entry := range yyDollar[2].n.(ASTDeclaratorList) {
-					typeDef := yyDollar[1].n.(*ASTTypeDef)
-					ident := entry.decl.Identifier()
-
-					typeDef.typeName = ident.ident
-					typeDef.decl = entry.decl
-
-					if typeDef.typ.typName != "" {
-						typeDef.decl.pointerDepth += typmap[typeDef.typ.typName].decl.pointerDepth
-						decl := &typeDef.decl
-						for *decl != nil {
-							decl = &(*decl).decl
-						}
-						*decl = typmap[typeDef.typ.typName].decl
-					}
-
-					typmap[ident.ident] = typeDef
+//line grammar.y:251
+		{
+			typ := specifiedType(yylex, yyDollar[1])
+			for _, entry := range yyDollar[2].n.(ASTDeclaratorList) {
+				entry.typ = typ
+				entry.storage = yyDollar[1].storage
+				if yyDollar[1].storage == StorageClassTypedef {
+					// The name is lexed as a TYPE_NAME from now on.
+					typedefs(yylex)[entry.decl.Identifier().ident] = true
 				}
-				yyVAL.n = yyDollar[1].n
 			}
-
+			yyVAL.n = yyDollar[2].n
 		}
-	case 78:
+	case 80:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:263
+		{
+			// Skip to the end of a declaration after a syntax error.
+			yyVAL.n = ASTDeclaratorList{}
+		}
+	case 81:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:270
+		{
+			yyVAL.storage = yyDollar[1].storage
+		}
+	case 82:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:308
+//line grammar.y:271
 		{
-			if yyDollar[2].typ.typ == VarTypeTypeName {
-				typName := yyDollar[2].typ.typName
-				typ := &ASTTypeDef{typ: typmap[yyDollar[2].typ.typName].typ}
-				typ.typ.typName = typName
-				yyVAL.n = typ
-			} else {
-				yyVAL.n = &ASTTypeDef{typ: yyDollar[2].typ}
-			}
+			yyVAL = yyDollar[2]
+			yyVAL.storage = yyDollar[1].storage
 		}
-	case 79:
+	case 83:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:318
+//line grammar.y:275
 		{
 			yyVAL.typ = yyDollar[1].typ
 		}
-	case 83:
+	case 84:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:278
+		{
+			yyVAL = yyDollar[2]
+			yyVAL.typ = combineTypes(yylex, span(yyDollar[1], yyDollar[2]), yyDollar[1].typ, yyDollar[2].typ)
+		}
+	case 85:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:327
+//line grammar.y:282
+		{
+			yyVAL.qualifiers = yyDollar[1].qualifiers
+		}
+	case 86:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:283
+		{
+			yyVAL = yyDollar[2]
+			yyVAL.qualifiers |= yyDollar[1].qualifiers
+		}
+	case 87:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:290
 		{
 			yyVAL.n = ASTDeclaratorList{yyDollar[1].n.(*ASTDecl)}
 		}
-	case 84:
+	case 88:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:328
+//line grammar.y:291
 		{
 			li := yyDollar[1].n.(ASTDeclaratorList)
 			li = append(li, yyDollar[3].n.(*ASTDecl))
 			yyVAL.n = li
 		}
-	case 85:
+	case 89:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:336
+//line grammar.y:299
 		{
 			yyVAL.n = &ASTDecl{
-				decl: yyDollar[1].n.(*ASTDirectDeclarator),
+				located: span(yyDollar[1], yyDollar[1]),
+				decl:    yyDollar[1].n.(*ASTDirectDeclarator),
 			}
 		}
-	case 86:
+	case 90:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:341
+//line grammar.y:305
 		{
 			yyVAL.n = &ASTDecl{
+				located: span(yyDollar[1], yyDollar[3]),
 				decl:    yyDollar[1].n.(*ASTDirectDeclarator),
 				initVal: yyDollar[3].n,
 			}
 		}
+	case 91:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:315
+		{
+			yyVAL.storage = StorageClassTypedef
+		}
 	case 92:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:358
+//line grammar.y:316
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeVoid}
+			yyVAL.storage = StorageClassExtern
 		}
 	case 93:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:359
+//line grammar.y:317
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeChar}
+			yyVAL.storage = StorageClassStatic
 		}
 	case 94:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:360
+//line grammar.y:318
 		{
-			// https://stackoverflow.com/a/697531
-			yyVAL.typ = &ASTType{typ: VarTypeShort}
+			yyVAL.storage = StorageClassAuto
 		}
 	case 95:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:364
+//line grammar.y:319
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeInteger}
+			yyVAL.storage = StorageClassRegister
 		}
 	case 96:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:365
+//line grammar.y:323
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeLong}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"void"}}
 		}
 	case 97:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:366
+//line grammar.y:324
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeFloat}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"char"}}
 		}
 	case 98:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:367
+//line grammar.y:325
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeDouble}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"short"}}
 		}
 	case 99:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:368
+//line grammar.y:326
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeSigned}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"int"}}
 		}
 	case 100:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:369
+//line grammar.y:327
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeUnsigned}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"long"}}
 		}
 	case 101:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:370
+//line grammar.y:328
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeStruct, typName: yyDollar[1].n.(*ASTStruct).ident.ident, structure: yyDollar[1].n.(*ASTStruct)}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"float"}}
 		}
 	case 102:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:373
+//line grammar.y:329
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeEnum, enum: yyDollar[1].n.(*ASTEnum)}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"double"}}
 		}
 	case 103:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:376
+//line grammar.y:330
 		{
-			yyVAL.typ = &ASTType{typ: VarTypeTypeName, typName: yyDollar[1].str}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"signed"}}
 		}
 	case 104:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line pkg/c90/grammar.y:380
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:331
+		{
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"unsigned"}}
+		}
+	case 105:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:332
 		{
-			yyVAL.n = &ASTStruct{ident: &ASTIdentifier{ident: yyDollar[2].str}, elements: yyDollar[4].n.(ASTStructDeclarationList)}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), structure: yyDollar[1].n.(*ASTStruct)}
 		}
 	case 106:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:384
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:335
+		{
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), enum: yyDollar[1].n.(*ASTEnum)}
+		}
+	case 107:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:338
 		{
-			yyVAL.n = &ASTStruct{ident: &ASTIdentifier{ident: yyDollar[2].str}, init: true}
+			yyVAL.typ = &ASTType{located: span(yyDollar[1], yyDollar[1]), typName: yyDollar[1].str}
+		}
+	case 108:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammar.y:342
+		{
+			yyVAL.n = &ASTStruct{located: span(yyDollar[1], yyDollar[5]), union: yyDollar[1].str == "union", ident: &ASTIdentifier{located: span(yyDollar[2], yyDollar[2]), ident: yyDollar[2].str}, elements: yyDollar[4].n.(ASTStructDeclarationList)}
 		}
 	case 109:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line grammar.y:345
+		{
+			yyVAL.n = &ASTStruct{located: span(yyDollar[1], yyDollar[4]), union: yyDollar[1].str == "union", elements: yyDollar[3].n.(ASTStructDeclarationList)}
+		}
+	case 110:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:348
+		{
+			yyVAL.n = &ASTStruct{located: span(yyDollar[1], yyDollar[2]), union: yyDollar[1].str == "union", ident: &ASTIdentifier{located: span(yyDollar[2], yyDollar[2]), ident: yyDollar[2].str}}
+		}
+	case 111:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:354
+		{
+			yyVAL.str = "struct"
+		}
+	case 112:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:395
+//line grammar.y:355
+		{
+			yyVAL.str = "union"
+		}
+	case 113:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:359
 		{
 			yyVAL.n = ASTStructDeclarationList{yyDollar[1].n.(ASTStructDeclaratorList)}
 		}
-	case 110:
+	case 114:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:396
+//line grammar.y:360
 		{
 			li := yyDollar[1].n.(ASTStructDeclarationList)
 			li = append(li, yyDollar[2].n.(ASTStructDeclaratorList))
 			yyVAL.n = li
 		}
-	case 111:
+	case 115:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:404
+//line grammar.y:368
 		{
+			typ := specifiedType(yylex, yyDollar[1])
 			for _, entry := range yyDollar[2].n.(ASTStructDeclaratorList) {
-				entry.decl.typ = yyDollar[1].typ
+				entry.decl.typ = typ
 			}
 			yyVAL.n = yyDollar[2].n
 		}
-	case 113:
+	case 116:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:378
+		{
+			yyVAL = yyDollar[2]
+			yyVAL.typ = combineTypes(yylex, span(yyDollar[1], yyDollar[2]), yyDollar[1].typ, yyDollar[2].typ)
+		}
+	case 117:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:414
+//line grammar.y:382
 		{
-			yyVAL.n = yyDollar[1].typ
+			yyVAL.typ = yyDollar[1].typ
 		}
-	case 116:
+	case 118:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:383
+		{
+			yyVAL = yyDollar[2]
+			yyVAL.qualifiers |= yyDollar[1].qualifiers
+		}
+	case 119:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:420
+//line grammar.y:387
+		{
+			yyVAL.qualifiers = yyDollar[1].qualifiers
+		}
+	case 120:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:391
 		{
 			yyVAL.n = ASTStructDeclaratorList{yyDollar[1].n.(ASTStructDeclarator)}
 		}
-	case 117:
+	case 121:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:421
+//line grammar.y:392
 		{
 			li := yyDollar[1].n.(ASTStructDeclaratorList)
 			li = append(li, yyDollar[3].n.(ASTStructDeclarator))
 			yyVAL.n = li
 		}
-	case 118:
+	case 122:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:429
+//line grammar.y:400
 		{
-			yyVAL.n = ASTStructDeclarator{decl: &ASTDecl{decl: yyDollar[1].n.(*ASTDirectDeclarator)}}
+			yyVAL.n = ASTStructDeclarator{located: span(yyDollar[1], yyDollar[1]), decl: &ASTDecl{located: span(yyDollar[1], yyDollar[1]), decl: yyDollar[1].n.(*ASTDirectDeclarator)}}
 		}
-	case 121:
+	case 125:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:435
+//line grammar.y:406
 		{
 			yyVAL.n = NewASTEnum(
+				span(yyDollar[1], yyDollar[4]),
 				nil,
 				yyDollar[3].n.(ASTEnumEntryList),
 			)
 		}
-	case 122:
+	case 126:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line pkg/c90/grammar.y:441
+//line grammar.y:413
 		{
 			yyVAL.n = NewASTEnum(
-				&ASTIdentifier{ident: yyDollar[2].str},
+				span(yyDollar[1], yyDollar[5]),
+				&ASTIdentifier{located: span(yyDollar[2], yyDollar[2]), ident: yyDollar[2].str},
 				yyDollar[4].n.(ASTEnumEntryList),
 			)
 		}
-	case 123:
+	case 127:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:447
+//line grammar.y:420
 		{
 			// TODO: still need to parse for typedef
 			yyVAL.n = NewASTEnum(
-				&ASTIdentifier{ident: yyDollar[2].str},
+				span(yyDollar[1], yyDollar[2]),
+				&ASTIdentifier{located: span(yyDollar[2], yyDollar[2]), ident: yyDollar[2].str},
 				nil,
 			)
 		}
-	case 124:
+	case 128:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:457
+//line grammar.y:431
 		{
 			yyVAL.n = ASTEnumEntryList{yyDollar[1].n.(*ASTEnumEntry)}
 		}
-	case 125:
+	case 129:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:460
+//line grammar.y:434
 		{
 			li := yyDollar[1].n.(ASTEnumEntryList)
 			li = append(li, yyDollar[3].n.(*ASTEnumEntry))
 			yyVAL.n = li
 		}
-	case 126:
+	case 130:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:468
+//line grammar.y:442
 		{
 			yyVAL.n = &ASTEnumEntry{
-				ident: &ASTIdentifier{ident: yyDollar[1].str},
-				value: nil,
+				located: span(yyDollar[1], yyDollar[1]),
+				ident:   &ASTIdentifier{located: span(yyDollar[1], yyDollar[1]), ident: yyDollar[1].str},
+				value:   nil,
 			}
 		}
-	case 127:
+	case 131:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:474
+//line grammar.y:449
 		{
 			yyVAL.n = &ASTEnumEntry{
-				ident: &ASTIdentifier{ident: yyDollar[1].str},
-				value: yyDollar[3].n,
+				located: span(yyDollar[1], yyDollar[3]),
+				ident:   &ASTIdentifier{located: span(yyDollar[1], yyDollar[1]), ident: yyDollar[1].str},
+				value:   yyDollar[3].n,
 			}
 		}
-	case 130:
+	case 132:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:459
+		{
+			yyVAL.qualifiers = QualifierConst
+		}
+	case 133:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:460
+		{
+			yyVAL.qualifiers = QualifierVolatile
+		}
+	case 134:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:488
+//line grammar.y:464
 		{
-			yyDollar[2].n.(*ASTDirectDeclarator).pointerDepth = yyDollar[1].pointerDepth
-			yyVAL.n = yyDollar[2].n
+			yyVAL.n = pointerDeclarator(span(yyDollar[1], yyDollar[2]), yyDollar[1].pointerDepth, yyDollar[1].pointerQualifiers, yyDollar[2].n.(*ASTDirectDeclarator))
 		}
-	case 131:
+	case 135:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:492
+//line grammar.y:467
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 132:
+	case 136:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:496
+//line grammar.y:471
 		{
 			yyVAL.n = &ASTDirectDeclarator{
+				located: span(yyDollar[1], yyDollar[1]),
 				identifier: &ASTIdentifier{
-					ident: yyDollar[1].str,
+					located: span(yyDollar[1], yyDollar[1]),
+					ident:   yyDollar[1].str,
 				},
 			}
 		}
-	case 134:
+	case 137:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:480
+		{
+			yyVAL.n = yyDollar[2].n
+		}
+	case 138:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:504
+//line grammar.y:481
 		{
 			yyVAL.n = &ASTDirectDeclarator{
-				decl:  yyDollar[1].n.(*ASTDirectDeclarator),
-				array: NewASTArray(yyDollar[3].n),
+				located: span(yyDollar[1], yyDollar[4]),
+				decl:    yyDollar[1].n.(*ASTDirectDeclarator),
+				array:   NewASTArray(yyDollar[3].n),
 			}
 		}
-	case 135:
+	case 139:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:510
+//line grammar.y:488
 		{
 			yyVAL.n = &ASTDirectDeclarator{
-				decl:  yyDollar[1].n.(*ASTDirectDeclarator),
-				array: NewASTArray(nil),
+				located: span(yyDollar[1], yyDollar[3]),
+				decl:    yyDollar[1].n.(*ASTDirectDeclarator),
+				array:   NewASTArray(nil),
 			}
 		}
-	case 136:
+	case 140:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:516
+//line grammar.y:495
 		{
 			// Function declaration with arguments
 			yyVAL.n = &ASTDirectDeclarator{
+				located:    span(yyDollar[1], yyDollar[4]),
 				decl:       yyDollar[1].n.(*ASTDirectDeclarator),
 				parameters: yyDollar[3].n.(*ASTParameterList),
 			}
 		}
-	case 137:
+	case 141:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:523
+//line grammar.y:503
 		{
 			// Function declaration for old K&R style funcs
+			parseErrorf(yylex, span(yyDollar[1], yyDollar[4]).rng, "old-style (K&R) function declarations are not supported")
+			yyVAL.n = &ASTDirectDeclarator{
+				located:    span(yyDollar[1], yyDollar[4]),
+				decl:       yyDollar[1].n.(*ASTDirectDeclarator),
+				parameters: &ASTParameterList{},
+			}
 		}
-	case 138:
+	case 142:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:526
+//line grammar.y:512
 		{
 			// Function declaration with no arguments
 			yyVAL.n = &ASTDirectDeclarator{
+				located:    span(yyDollar[1], yyDollar[3]),
 				decl:       yyDollar[1].n.(*ASTDirectDeclarator),
 				parameters: &ASTParameterList{},
 			}
 		}
-	case 139:
+	case 143:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:536
+//line grammar.y:523
 		{
 			yyVAL.pointerDepth = 1
+			yyVAL.pointerQualifiers = []Qualifiers{0}
 		}
-	case 141:
+	case 144:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:538
+//line grammar.y:527
 		{
-			yyVAL.pointerDepth = 1 + yyDollar[2].pointerDepth
+			yyVAL.pointerDepth = 1
+			yyVAL.pointerQualifiers = []Qualifiers{yyDollar[2].qualifiers}
 		}
 	case 145:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:531
+		{
+			yyVAL.pointerDepth = 1 + yyDollar[2].pointerDepth
+			yyVAL.pointerQualifiers = append([]Qualifiers{0}, yyDollar[2].pointerQualifiers...)
+		}
+	case 146:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:535
+		{
+			yyVAL.pointerDepth = 1 + yyDollar[3].pointerDepth
+			yyVAL.pointerQualifiers = append([]Qualifiers{yyDollar[2].qualifiers}, yyDollar[3].pointerQualifiers...)
+		}
+	case 147:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:548
+//line grammar.y:542
+		{
+			yyVAL.qualifiers = yyDollar[1].qualifiers
+		}
+	case 148:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:543
+		{
+			yyVAL.qualifiers = yyDollar[1].qualifiers | yyDollar[2].qualifiers
+		}
+	case 149:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:547
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 146:
+	case 150:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:551
+//line grammar.y:550
 		{
 			paramList := yyDollar[1].n.(*ASTParameterList)
 			paramList.elipsis = true
 			yyVAL.n = paramList
 		}
-	case 147:
+	case 151:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:559
+//line grammar.y:558
 		{
 			yyVAL.n = &ASTParameterList{
 				li: []*ASTParameterDeclaration{
@@ -1748,347 +1912,515 @@ yydefault:
 				},
 			}
 		}
-	case 148:
+	case 152:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:566
+//line grammar.y:565
 		{
 			li := yyDollar[1].n.(*ASTParameterList)
 			li.li = append(li.li, yyDollar[3].n.(*ASTParameterDeclaration))
 			yyVAL.n = li
 		}
-	case 149:
+	case 153:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:574
+//line grammar.y:573
 		{
-			vartype := yyDollar[1].typ
-			if yyDollar[1].typ.typ == VarTypeTypeName {
-				vartype = typmap[yyDollar[1].typ.typName].typ
+			yyVAL.n = &ASTParameterDeclaration{
+				located:    span(yyDollar[1], yyDollar[2]),
+				specifier:  specifiedType(yylex, yyDollar[1]),
+				declarator: yyDollar[2].n,
 			}
+		}
+	case 154:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:580
+		{
 			yyVAL.n = &ASTParameterDeclaration{
-				specifier:  vartype,
+				located:    span(yyDollar[1], yyDollar[2]),
+				specifier:  specifiedType(yylex, yyDollar[1]),
 				declarator: yyDollar[2].n,
 			}
 		}
-	case 151:
+	case 155:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:585
+//line grammar.y:587
 		{
-			vartype := yyDollar[1].typ
-			if yyDollar[1].typ.typ == VarTypeTypeName {
-				vartype = typmap[yyDollar[1].typ.typName].typ
-			}
 			yyVAL.n = &ASTParameterDeclaration{
-				specifier: vartype,
+				located:   span(yyDollar[1], yyDollar[1]),
+				specifier: specifiedType(yylex, yyDollar[1]),
 			}
 		}
-	case 154:
+	case 158:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:602
+		{
+			yyVAL.n = &ASTTypeName{located: span(yyDollar[1], yyDollar[1]), typ: specifiedType(yylex, yyDollar[1])}
+		}
+	case 159:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:605
+		{
+			yyVAL.n = &ASTTypeName{located: span(yyDollar[1], yyDollar[2]), typ: specifiedType(yylex, yyDollar[1]), decl: yyDollar[2].n.(*ASTDirectDeclarator)}
+		}
+	case 160:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:603
+//line grammar.y:611
+		{
+			yyVAL.n = &ASTDirectDeclarator{located: span(yyDollar[1], yyDollar[1]), pointerDepth: yyDollar[1].pointerDepth, pointerQualifiers: yyDollar[1].pointerQualifiers}
+		}
+	case 161:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammar.y:612
 		{
 			yyVAL.n = yyDollar[1].n
 		}
+	case 162:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:613
+		{
+			yyVAL.n = pointerDeclarator(span(yyDollar[1], yyDollar[2]), yyDollar[1].pointerDepth, yyDollar[1].pointerQualifiers, yyDollar[2].n.(*ASTDirectDeclarator))
+		}
+	case 163:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:619
+		{
+			yyVAL.n = yyDollar[2].n
+		}
+	case 164:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:620
+		{
+			yyVAL.n = &ASTDirectDeclarator{located: span(yyDollar[1], yyDollar[2]), array: NewASTArray(nil)}
+		}
+	case 165:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:623
+		{
+			yyVAL.n = &ASTDirectDeclarator{located: span(yyDollar[1], yyDollar[3]), array: NewASTArray(yyDollar[2].n)}
+		}
+	case 166:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:626
+		{
+			yyVAL.n = &ASTDirectDeclarator{
+				located: span(yyDollar[1], yyDollar[3]),
+				decl:    yyDollar[1].n.(*ASTDirectDeclarator),
+				array:   NewASTArray(nil),
+			}
+		}
+	case 167:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line grammar.y:633
+		{
+			yyVAL.n = &ASTDirectDeclarator{
+				located: span(yyDollar[1], yyDollar[4]),
+				decl:    yyDollar[1].n.(*ASTDirectDeclarator),
+				array:   NewASTArray(yyDollar[3].n),
+			}
+		}
 	case 168:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:640
+		{
+			yyVAL.n = &ASTDirectDeclarator{located: span(yyDollar[1], yyDollar[2]), parameters: &ASTParameterList{}}
+		}
+	case 169:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:643
+		{
+			yyVAL.n = &ASTDirectDeclarator{located: span(yyDollar[1], yyDollar[3]), parameters: yyDollar[2].n.(*ASTParameterList)}
+		}
+	case 170:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:646
+		{
+			yyVAL.n = &ASTDirectDeclarator{
+				located:    span(yyDollar[1], yyDollar[3]),
+				decl:       yyDollar[1].n.(*ASTDirectDeclarator),
+				parameters: &ASTParameterList{},
+			}
+		}
+	case 171:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line grammar.y:653
+		{
+			yyVAL.n = &ASTDirectDeclarator{
+				located:    span(yyDollar[1], yyDollar[4]),
+				decl:       yyDollar[1].n.(*ASTDirectDeclarator),
+				parameters: yyDollar[3].n.(*ASTParameterList),
+			}
+		}
+	case 172:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:626
+//line grammar.y:663
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 169:
+	case 173:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:627
+//line grammar.y:664
 		{
 			yyVAL.n = yyDollar[2].n
 		}
-	case 170:
+	case 174:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:628
+//line grammar.y:665
 		{
 			yyVAL.n = yyDollar[2].n
 		}
-	case 171:
+	case 175:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:632
+//line grammar.y:669
 		{
 			yyVAL.n = ASTInitializerList{yyDollar[1].n}
 		}
-	case 172:
+	case 176:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:633
+//line grammar.y:670
 		{
 			li := yyDollar[1].n.(ASTInitializerList)
 			li = append(li, yyDollar[3].n)
 			yyVAL.n = li
 		}
-	case 173:
+	case 177:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:641
+//line grammar.y:678
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 174:
+	case 178:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:642
+//line grammar.y:679
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 175:
+	case 179:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:643
+//line grammar.y:680
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 176:
+	case 180:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:644
+//line grammar.y:681
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 177:
+	case 181:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:645
+//line grammar.y:682
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 178:
+	case 182:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:646
+//line grammar.y:683
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 179:
+	case 183:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:684
+		{
+			// Skip to the end of a statement after a syntax error.
+			yyVAL.n = nil
+		}
+	case 184:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:650
+//line grammar.y:691
 		{
 			yyVAL.n = &ASTLabeledStatement{
-				ident: &ASTIdentifier{ident: yyDollar[1].str},
-				stmt:  yyDollar[3].n,
+				located: span(yyDollar[1], yyDollar[3]),
+				ident:   &ASTIdentifier{located: span(yyDollar[1], yyDollar[1]), ident: yyDollar[1].str},
+				stmt:    yyDollar[3].n,
 			}
 		}
-	case 180:
+	case 185:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:656
+//line grammar.y:698
 		{
 			yyVAL.n = &ASTSwitchCase{
+				located:     span(yyDollar[1], yyDollar[4]),
 				caseVal:     yyDollar[2].n,
 				body:        yyDollar[4].n,
 				defaultCase: false,
 			}
 		}
-	case 181:
+	case 186:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:663
+//line grammar.y:706
 		{
 			yyVAL.n = &ASTSwitchCase{
+				located:     span(yyDollar[1], yyDollar[3]),
 				caseVal:     nil,
 				body:        yyDollar[3].n,
 				defaultCase: true,
 			}
 		}
-	case 182:
+	case 187:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:674
+//line grammar.y:718
 		{
-			yyVAL.n = &ASTScope{}
+			yyVAL.n = &ASTScope{located: span(yyDollar[1], yyDollar[2])}
 		}
-	case 183:
+	case 188:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:675
+//line grammar.y:719
 		{
-			yyVAL.n = &ASTScope{body: yyDollar[2].n}
+			yyVAL.n = &ASTScope{located: span(yyDollar[1], yyDollar[3]), body: yyDollar[2].n}
 		}
-	case 184:
+	case 189:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:678
+//line grammar.y:722
 		{
-			yyVAL.n = &ASTScope{body: yyDollar[2].n}
+			yyVAL.n = &ASTScope{located: span(yyDollar[1], yyDollar[3]), body: yyDollar[2].n}
 		}
-	case 185:
+	case 190:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:681
+//line grammar.y:725
 		{
 			yyVAL.n = &ASTScope{
+				located: span(yyDollar[1], yyDollar[4]),
 				body: &ASTDeclarationStatementLists{
 					decls: yyDollar[2].n.(ASTDeclaratorList),
 					stmts: yyDollar[3].n.(ASTStatementList),
 				},
 			}
 		}
-	case 186:
+	case 191:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammar.y:736
+		{
+			yyVAL.n = &ASTScope{located: span(yyDollar[1], yyDollar[3])}
+		}
+	case 192:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line grammar.y:737
+		{
+			yyVAL.n = &ASTScope{located: span(yyDollar[1], yyDollar[4]), body: yyDollar[2].n}
+		}
+	case 193:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line grammar.y:738
+		{
+			yyVAL.n = &ASTScope{located: span(yyDollar[1], yyDollar[4]), body: yyDollar[2].n}
+		}
+	case 194:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammar.y:739
+		{
+			yyVAL.n = &ASTScope{
+				located: span(yyDollar[1], yyDollar[5]),
+				body: &ASTDeclarationStatementLists{
+					decls: yyDollar[2].n.(ASTDeclaratorList),
+					stmts: yyDollar[3].n.(ASTStatementList),
+				},
+			}
+		}
+	case 195:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:692
+//line grammar.y:751
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 187:
+	case 196:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:693
+//line grammar.y:752
 		{
 			li := yyDollar[1].n.(ASTDeclaratorList)
 			li = append(li, yyDollar[2].n.(ASTDeclaratorList)...)
 			yyVAL.n = li
 		}
-	case 188:
+	case 197:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:701
+//line grammar.y:760
 		{
 			yyVAL.n = ASTStatementList{yyDollar[1].n}
 		}
-	case 189:
+	case 198:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:702
+//line grammar.y:761
 		{
 			li := yyDollar[1].n.(ASTStatementList)
 			li = append(li, yyDollar[2].n)
 			yyVAL.n = li
 		}
-	case 191:
+	case 200:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:711
+//line grammar.y:770
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 192:
+	case 201:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line pkg/c90/grammar.y:715
+//line grammar.y:774
 		{
 			yyVAL.n = &ASTIfStatement{
+				located:   span(yyDollar[1], yyDollar[5]),
 				condition: yyDollar[3].n,
 				body:      yyDollar[5].n,
 				elseBody:  nil,
 			}
 		}
-	case 193:
+	case 202:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line pkg/c90/grammar.y:722
+//line grammar.y:782
 		{
 			yyVAL.n = &ASTIfStatement{
+				located:   span(yyDollar[1], yyDollar[7]),
 				condition: yyDollar[3].n,
 				body:      yyDollar[5].n,
 				elseBody:  yyDollar[7].n,
 			}
 		}
-	case 194:
+	case 203:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line pkg/c90/grammar.y:729
+//line grammar.y:790
 		{
 			yyVAL.n = &ASTSwitchStatement{
+				located:  span(yyDollar[1], yyDollar[5]),
 				switchOn: yyDollar[3].n,
 				body:     yyDollar[5].n,
 			}
 		}
-	case 195:
+	case 204:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line pkg/c90/grammar.y:738
+//line grammar.y:800
 		{
 			yyVAL.n = &ASTWhileLoop{
+				located:   span(yyDollar[1], yyDollar[5]),
 				condition: yyDollar[3].n,
 				body:      yyDollar[5].n,
 			}
 		}
-	case 196:
+	case 205:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line pkg/c90/grammar.y:744
+//line grammar.y:807
 		{
 			yyVAL.n = &ASTDoWhileLoop{
+				located:   span(yyDollar[1], yyDollar[7]),
 				condition: yyDollar[5].n,
 				body:      yyDollar[2].n,
 			}
 		}
-	case 197:
+	case 206:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line pkg/c90/grammar.y:750
+//line grammar.y:814
 		{
 			yyVAL.n = &ASTForLoop{
+				located:           span(yyDollar[1], yyDollar[6]),
 				initialiser:       yyDollar[3].n,
 				condition:         yyDollar[4].n,
 				postIterationExpr: nil,
 				body:              yyDollar[6].n,
 			}
 		}
-	case 198:
+	case 207:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line pkg/c90/grammar.y:758
+//line grammar.y:823
 		{
 			yyVAL.n = &ASTForLoop{
+				located:           span(yyDollar[1], yyDollar[7]),
 				initialiser:       yyDollar[3].n,
 				condition:         yyDollar[4].n,
 				postIterationExpr: yyDollar[5].n,
 				body:              yyDollar[7].n,
 			}
 		}
-	case 199:
+	case 208:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:769
+//line grammar.y:835
 		{
 			yyVAL.n = &ASTGoto{
-				label: &ASTIdentifier{ident: yyDollar[2].str},
+				located: span(yyDollar[1], yyDollar[3]),
+				label:   &ASTIdentifier{located: span(yyDollar[2], yyDollar[2]), ident: yyDollar[2].str},
 			}
 		}
-	case 200:
+	case 209:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:774
+//line grammar.y:841
 		{
-			yyVAL.n = &ASTContinue{}
+			yyVAL.n = &ASTContinue{located: span(yyDollar[1], yyDollar[2])}
 		}
-	case 201:
+	case 210:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:777
+//line grammar.y:844
 		{
-			yyVAL.n = &ASTBreak{}
+			yyVAL.n = &ASTBreak{located: span(yyDollar[1], yyDollar[2])}
 		}
-	case 202:
+	case 211:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:780
+//line grammar.y:847
 		{
-			yyVAL.n = &ASTReturn{}
+			yyVAL.n = &ASTReturn{located: span(yyDollar[1], yyDollar[2])}
 		}
-	case 203:
+	case 212:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:781
+//line grammar.y:848
 		{
-			yyVAL.n = &ASTReturn{returnVal: yyDollar[2].n}
+			yyVAL.n = &ASTReturn{located: span(yyDollar[1], yyDollar[3]), returnVal: yyDollar[2].n}
 		}
-	case 204:
+	case 213:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:785
+//line grammar.y:852
 		{
-			AST = ASTTranslationUnit{yyDollar[1].n}
+			if yyDollar[1].n != nil {
+				yylex.(*lexer).unit = append(yylex.(*lexer).unit, yyDollar[1].n)
+			}
 		}
-	case 205:
+	case 214:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:788
+//line grammar.y:857
 		{
-			AST = append(AST, yyDollar[2].n)
+			if yyDollar[2].n != nil {
+				yylex.(*lexer).unit = append(yylex.(*lexer).unit, yyDollar[2].n)
+			}
 		}
-	case 206:
+	case 215:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/c90/grammar.y:794
+//line grammar.y:865
 		{
 			yyVAL.n = yyDollar[1].n
 		}
-	case 208:
+	case 217:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:867
+		{
+			// Skip to the end of a declaration after a syntax error.
+			yyVAL.n = nil
+		}
+	case 218:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammar.y:871
+		{
+			// Skip to the end of a function after a syntax error.
+			yyVAL.n = nil
+		}
+	case 219:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/c90/grammar.y:799
+//line grammar.y:878
 		{
-			panic("Old K&R style function parsed (1)")
+			// Old K&R style C parameter declarations
+			parseErrorf(yylex, span(yyDollar[1], yyDollar[3]).rng, "old-style (K&R) function definitions are not supported")
+			yyVAL.n = nil
 		}
-	case 209:
+	case 220:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:800
+//line grammar.y:883
 		{
-			yyVAL.n = &ASTFunction{typ: yyDollar[1].typ, decl: yyDollar[2].n.(*ASTDirectDeclarator), body: yyDollar[3].n}
+			yyVAL.n = &ASTFunction{located: span(yyDollar[1], yyDollar[3]), typ: specifiedType(yylex, yyDollar[1]), storage: yyDollar[1].storage, decl: yyDollar[2].n.(*ASTDirectDeclarator), body: yyDollar[3].n}
 		}
-	case 210:
+	case 221:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/c90/grammar.y:801
+//line grammar.y:886
 		{
-			panic("Old K&R style function parsed (2)")
+			parseErrorf(yylex, span(yyDollar[1], yyDollar[2]).rng, "old-style (K&R) function definitions are not supported")
+			yyVAL.n = nil
 		}
-	case 211:
+	case 222:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/c90/grammar.y:802
+//line grammar.y:890
 		{
-			yyVAL.n = &ASTFunction{typ: &ASTType{typ: VarTypeInteger}, decl: yyDollar[1].n.(*ASTDirectDeclarator), body: yyDollar[2].n}
+			yyVAL.n = &ASTFunction{located: span(yyDollar[1], yyDollar[2]), typ: &ASTType{located: span(yyDollar[1], yyDollar[1]), specifiers: []string{"int"}, kind: TypeInt}, decl: yyDollar[1].n.(*ASTDirectDeclarator), body: yyDollar[2].n}
 		}
 	}
 	goto yystack /* stack new state and value */
@@ -0,0 +1,531 @@
+// Package cpp implements a C90 preprocessor. Its output is fed to the c90
+// lexer, and it keeps track of where each output token came from so that
+// later stages can report positions in the original source files.
+package cpp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxIncludeDepth mirrors the limit used by gcc.
+const maxIncludeDepth = 200
+
+// builtinHeaders are supplied by the preprocessor when they aren't found on
+// the include path. The compiler understands the names they define without
+// them, so they only need to exist.
+var builtinHeaders = map[string]string{
+	"stdarg.h": `#ifndef __STDARG_H
+#define __STDARG_H
+#define va_start(ap, last) __builtin_va_start(ap, last)
+#define va_arg(ap, type) __builtin_va_arg(ap, type)
+#define va_end(ap) __builtin_va_end(ap)
+#endif
+`,
+}
+
+type conditional struct {
+	pos Position
+	// active is set if the current group is being output.
+	active bool
+	// taken is set once any group of the #if chain has been output.
+	taken bool
+	// skipAll is set if the whole chain is within a group which is skipped.
+	skipAll bool
+	sawElse bool
+}
+
+type sourceFile struct {
+	s   *scanner
+	dir string
+
+	conds []*conditional
+}
+
+// Preprocessor expands macros and processes directives. A Preprocessor should
+// only be used for a single translation unit, as macros defined while
+// preprocessing a file are remembered.
+type Preprocessor struct {
+	includePaths []string
+	macros       map[string]*macro
+
+	files []*sourceFile
+
+	// pending holds tokens waiting to be rescanned, with the next token to be
+	// read at the end of the slice.
+	pending []*token
+
+	errs ErrorList
+}
+
+// New creates a preprocessor which searches includePaths (in order) for
+// #include files.
+func New(includePaths []string) *Preprocessor {
+	p := &Preprocessor{
+		includePaths: includePaths,
+		macros:       map[string]*macro{},
+	}
+	p.defineBuiltins(time.Now())
+	return p
+}
+
+// Define defines a macro in the same form as the -D command line flag, i.e.
+// `NAME` (defined as 1) or `NAME=VALUE`.
+func (p *Preprocessor) Define(def string) {
+	name, value := def, "1"
+	if i := strings.IndexByte(def, '='); i >= 0 {
+		name, value = def[:i], def[i+1:]
+	}
+	s := newScanner("<command-line>", []byte(name+" "+value), &p.errs)
+	p.define(lineTokens(s))
+}
+
+// Undefine removes a macro definition, as with the -U command line flag.
+func (p *Preprocessor) Undefine(name string) {
+	delete(p.macros, name)
+}
+
+// PreprocessFile preprocesses the file at path.
+func (p *Preprocessor) PreprocessFile(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return p.Preprocess(path, f)
+}
+
+// Preprocess preprocesses the source read from r. The name is used for
+// diagnostics, __FILE__ and to find files included with quotes.
+//
+// If any errors are found, the returned error is an ErrorList. The Reader is
+// always returned so that the caller may inspect the output.
+func (p *Preprocessor) Preprocess(name string, r io.Reader) (*Reader, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p.pushFile(name, src)
+
+	out := newReader()
+	for {
+		tok := p.next()
+		if tok.kind == tokenEOF {
+			break
+		}
+		if tok.kind == tokenNewline {
+			out.newline()
+			continue
+		}
+		out.write(tok)
+	}
+	out.finish()
+
+	return out, p.errs.Err()
+}
+
+func (p *Preprocessor) errorf(pos Position, format string, args ...interface{}) {
+	p.errs.add(pos, format, args...)
+}
+
+func (p *Preprocessor) pushFile(path string, src []byte) {
+	p.files = append(p.files, &sourceFile{
+		s:   newScanner(path, src, &p.errs),
+		dir: filepath.Dir(path),
+	})
+}
+
+func (p *Preprocessor) topFile() *sourceFile {
+	return p.files[len(p.files)-1]
+}
+
+// skipping returns true if the current conditional group is not being output.
+func (p *Preprocessor) skipping() bool {
+	if len(p.files) == 0 {
+		return false
+	}
+	conds := p.topFile().conds
+	return len(conds) > 0 && !conds[len(conds)-1].active
+}
+
+// pushBack queues toks to be read again before anything else.
+func (p *Preprocessor) pushBack(toks []*token) {
+	for i := len(toks) - 1; i >= 0; i-- {
+		p.pending = append(p.pending, toks[i])
+	}
+}
+
+// lexFile returns the next token from the include stack, moving back to the
+// including file once the end of a file is reached.
+func (p *Preprocessor) lexFile() *token {
+	for len(p.files) > 0 {
+		f := p.topFile()
+		tok := f.s.lex()
+		if tok.kind != tokenEOF {
+			return tok
+		}
+
+		for _, cond := range f.conds {
+			p.errorf(cond.pos, "unterminated conditional directive")
+		}
+		p.files = p.files[:len(p.files)-1]
+		if len(p.files) > 0 {
+			// Make sure that the last line of an included file is not joined
+			// with the next line of the file which included it.
+			return &token{kind: tokenNewline, text: "\n", pos: tok.pos}
+		}
+		return tok
+	}
+	return &token{kind: tokenEOF}
+}
+
+// readToken returns the next token before macro expansion. Directives are
+// processed and skipped groups are removed here.
+func (p *Preprocessor) readToken() *token {
+	for {
+		if n := len(p.pending); n > 0 {
+			tok := p.pending[n-1]
+			p.pending = p.pending[:n-1]
+			return tok
+		}
+
+		tok := p.lexFile()
+		if tok.kind == tokenEOF {
+			return tok
+		}
+		if tok.bol && tok.kind == tokenPunct && tok.text == "#" {
+			p.directive(tok)
+			continue
+		}
+		if p.skipping() && tok.kind != tokenNewline {
+			continue
+		}
+		return tok
+	}
+}
+
+// next returns the next fully macro expanded token.
+func (p *Preprocessor) next() *token {
+	for {
+		tok := p.readToken()
+		if tok.kind != tokenIdent || !p.expand(tok) {
+			return tok
+		}
+	}
+}
+
+// lineTokens reads the remaining tokens on the current line, leaving the
+// newline to be read later.
+func lineTokens(s *scanner) []*token {
+	var toks []*token
+	for {
+		saveOff, saveLine, saveCol, saveBOL := s.off, s.line, s.col, s.atBOL
+		tok := s.lex()
+		if tok.kind == tokenEOF || tok.kind == tokenNewline {
+			s.off, s.line, s.col, s.atBOL = saveOff, saveLine, saveCol, saveBOL
+			return toks
+		}
+		toks = append(toks, tok)
+	}
+}
+
+// directive handles the line starting with the hash token.
+func (p *Preprocessor) directive(hash *token) {
+	f := p.topFile()
+	s := f.s
+
+	nameTok := s.lex()
+	if nameTok.kind == tokenNewline || nameTok.kind == tokenEOF {
+		// Null directive. The newline is still output.
+		if nameTok.kind == tokenNewline {
+			p.pending = append(p.pending, nameTok)
+		}
+		return
+	}
+
+	name := nameTok.text
+	switch name {
+	case "if", "ifdef", "ifndef":
+		cond := &conditional{pos: hash.pos}
+		if p.skipping() {
+			cond.skipAll = true
+			lineTokens(s)
+		} else {
+			switch name {
+			case "if":
+				cond.active = p.evalCondition(hash, lineTokens(s))
+			case "ifdef", "ifndef":
+				ident := p.macroName(hash, name, lineTokens(s))
+				_, defined := p.macros[ident]
+				cond.active = defined == (name == "ifdef")
+			}
+			cond.taken = cond.active
+		}
+		f.conds = append(f.conds, cond)
+		return
+	case "elif", "else", "endif":
+		toks := lineTokens(s)
+		if len(f.conds) == 0 {
+			p.errorf(hash.pos, "#%s without #if", name)
+			return
+		}
+		cond := f.conds[len(f.conds)-1]
+		switch name {
+		case "endif":
+			f.conds = f.conds[:len(f.conds)-1]
+		case "else":
+			if cond.sawElse {
+				p.errorf(hash.pos, "#else after #else")
+			}
+			cond.sawElse = true
+			cond.active = !cond.skipAll && !cond.taken
+			cond.taken = true
+		case "elif":
+			if cond.sawElse {
+				p.errorf(hash.pos, "#elif after #else")
+			}
+			if cond.skipAll || cond.taken {
+				cond.active = false
+			} else {
+				cond.active = p.evalCondition(hash, toks)
+				cond.taken = cond.active
+			}
+		}
+		return
+	}
+
+	if p.skipping() {
+		// Other directives (even invalid ones) are ignored in skipped groups.
+		lineTokens(s)
+		return
+	}
+
+	if nameTok.kind == tokenNumber {
+		// GNU line marker (e.g. `# 1 "file.c" 2`), as produced by other
+		// preprocessors.
+		p.lineDirective(s, append([]*token{nameTok}, lineTokens(s)...), true)
+		return
+	}
+
+	switch name {
+	case "include":
+		p.include(hash, s)
+	case "define":
+		p.define(lineTokens(s))
+	case "undef":
+		ident := p.macroName(hash, name, lineTokens(s))
+		if ident == "defined" {
+			p.errorf(hash.pos, "\"defined\" cannot be used as a macro name")
+		}
+		delete(p.macros, ident)
+	case "line":
+		p.lineDirective(s, p.expandList(lineTokens(s)), false)
+	case "error":
+		p.errorf(hash.pos, "#error %s", joinTokens(lineTokens(s)))
+	case "pragma":
+		// No pragmas are supported, and unknown pragmas must be ignored.
+		lineTokens(s)
+	default:
+		lineTokens(s)
+		p.errorf(nameTok.pos, "invalid preprocessing directive #%s", name)
+	}
+}
+
+// macroName returns the identifier following #ifdef, #ifndef or #undef.
+func (p *Preprocessor) macroName(hash *token, directive string, toks []*token) string {
+	if len(toks) == 0 || toks[0].kind != tokenIdent {
+		p.errorf(hash.pos, "macro name missing or not an identifier in #%s", directive)
+		return ""
+	}
+	if len(toks) > 1 {
+		p.errorf(toks[1].pos, "extra tokens at end of #%s directive", directive)
+	}
+	return toks[0].text
+}
+
+func (p *Preprocessor) include(hash *token, s *scanner) {
+	s.skipSpace()
+
+	var path string
+	quoted := false
+	if s.peek() == '<' {
+		// Header names are not ordinary tokens, so read them directly.
+		s.next()
+		var sb strings.Builder
+		for !s.eof() && s.peek() != '>' && s.peek() != '\n' {
+			sb.WriteByte(s.next())
+		}
+		if s.peek() != '>' {
+			p.errorf(hash.pos, "missing terminating > character")
+			lineTokens(s)
+			return
+		}
+		s.next()
+		path = sb.String()
+		if extra := lineTokens(s); len(extra) > 0 {
+			p.errorf(extra[0].pos, "extra tokens at end of #include directive")
+		}
+	} else {
+		toks := p.expandList(lineTokens(s))
+		switch {
+		case len(toks) > 0 && toks[0].kind == tokenString && !strings.HasPrefix(toks[0].text, "L"):
+			path = toks[0].text[1 : len(toks[0].text)-1]
+			quoted = true
+			toks = toks[1:]
+		case len(toks) > 0 && toks[0].is("<"):
+			var sb strings.Builder
+			i := 1
+			for ; i < len(toks) && !toks[i].is(">"); i++ {
+				if toks[i].space && sb.Len() > 0 {
+					sb.WriteByte(' ')
+				}
+				sb.WriteString(toks[i].text)
+			}
+			if i == len(toks) {
+				p.errorf(hash.pos, "missing terminating > character")
+				return
+			}
+			path = sb.String()
+			toks = toks[i+1:]
+		default:
+			p.errorf(hash.pos, "#include expects \"FILENAME\" or <FILENAME>")
+			return
+		}
+		if len(toks) > 0 {
+			p.errorf(toks[0].pos, "extra tokens at end of #include directive")
+		}
+	}
+
+	if len(p.files) >= maxIncludeDepth {
+		p.errorf(hash.pos, "#include nested too deeply")
+		return
+	}
+
+	var dirs []string
+	if quoted {
+		dirs = append(dirs, p.topFile().dir)
+	}
+	dirs = append(dirs, p.includePaths...)
+
+	for _, dir := range dirs {
+		candidate := path
+		if !filepath.IsAbs(path) {
+			candidate = filepath.Join(dir, path)
+		}
+		src, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		p.pushFile(candidate, src)
+		return
+	}
+	if src, ok := builtinHeaders[path]; ok {
+		p.pushFile("<built-in>/"+path, []byte(src))
+		return
+	}
+	p.errorf(hash.pos, "%s: No such file or directory", path)
+}
+
+func (p *Preprocessor) lineDirective(s *scanner, toks []*token, lineMarker bool) {
+	if len(toks) == 0 || toks[0].kind != tokenNumber {
+		pos := s.pos()
+		if len(toks) > 0 {
+			pos = toks[0].pos
+		}
+		p.errorf(pos, "#line directive requires a simple digit sequence")
+		return
+	}
+	for _, c := range toks[0].text {
+		if c < '0' || c > '9' {
+			p.errorf(toks[0].pos, "\"%s\" after #line is not a positive integer", toks[0].text)
+			return
+		}
+	}
+	line, err := strconv.Atoi(toks[0].text)
+	if err != nil {
+		p.errorf(toks[0].pos, "line number out of range")
+		return
+	}
+
+	rest := toks[1:]
+	if len(rest) > 0 {
+		if rest[0].kind != tokenString {
+			p.errorf(rest[0].pos, "invalid filename \"%s\"", rest[0].text)
+			return
+		}
+		name, err := strconv.Unquote(rest[0].text)
+		if err != nil {
+			name = rest[0].text[1 : len(rest[0].text)-1]
+		}
+		s.presumedFile = name
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && !lineMarker {
+		p.errorf(rest[0].pos, "extra tokens at end of #line directive")
+	}
+
+	// The line following the directive has the given number.
+	s.lineDelta = line - (s.line + 1)
+}
+
+// joinTokens reconstructs the source text of toks.
+func joinTokens(toks []*token) string {
+	var sb strings.Builder
+	for i, tok := range toks {
+		if i != 0 && tok.space {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tok.text)
+	}
+	return sb.String()
+}
+
+func (p *Preprocessor) defineBuiltins(now time.Time) {
+	p.macros["__LINE__"] = &macro{
+		name: "__LINE__",
+		builtin: func(tok *token) *token {
+			return &token{kind: tokenNumber, text: strconv.Itoa(tok.pos.Line)}
+		},
+	}
+	p.macros["__FILE__"] = &macro{
+		name: "__FILE__",
+		builtin: func(tok *token) *token {
+			return &token{kind: tokenString, text: quote(tok.pos.File)}
+		},
+	}
+	date := fmt.Sprintf("%s %2d %d", now.Format("Jan"), now.Day(), now.Year())
+	p.macros["__DATE__"] = &macro{
+		name: "__DATE__",
+		builtin: func(tok *token) *token {
+			return &token{kind: tokenString, text: quote(date)}
+		},
+	}
+	clock := now.Format("15:04:05")
+	p.macros["__TIME__"] = &macro{
+		name: "__TIME__",
+		builtin: func(tok *token) *token {
+			return &token{kind: tokenString, text: quote(clock)}
+		},
+	}
+	p.Define("__STDC__=1")
+}
+
+// quote returns s as a C string literal.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
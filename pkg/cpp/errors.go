@@ -0,0 +1,45 @@
+package cpp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a problem found while preprocessing, such as a #error directive or
+// a missing include file.
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: error: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is the list of errors found while preprocessing a translation
+// unit. Preprocessing carries on after most errors so that all of them can be
+// reported at once.
+type ErrorList []*Error
+
+func (l *ErrorList) add(pos Position, format string, args ...interface{}) {
+	*l = append(*l, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l ErrorList) Error() string {
+	var sb strings.Builder
+	for i, err := range l {
+		if i != 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Err returns nil if the list is empty, otherwise it returns the list itself.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,381 @@
+package cpp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// value is the result of a #if expression. C90 evaluates these using long and
+// unsigned long, which are 32 bits wide on MIPS.
+type value struct {
+	v        int64
+	unsigned bool
+}
+
+func (v value) truncate() value {
+	if v.unsigned {
+		v.v = int64(uint32(v.v))
+	} else {
+		v.v = int64(int32(v.v))
+	}
+	return v
+}
+
+func boolValue(b bool) value {
+	if b {
+		return value{v: 1}
+	}
+	return value{v: 0}
+}
+
+// evalError is used to unwind the expression parser on the first error.
+type evalError struct{}
+
+type exprParser struct {
+	p    *Preprocessor
+	hash *token
+	toks []*token
+	i    int
+}
+
+// evalCondition evaluates the expression of a #if or #elif directive.
+func (p *Preprocessor) evalCondition(hash *token, toks []*token) (result bool) {
+	// `defined` must be handled before macro expansion.
+	var replaced []*token
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		if tok.kind != tokenIdent || tok.text != "defined" {
+			replaced = append(replaced, tok)
+			continue
+		}
+
+		var name *token
+		if i+1 < len(toks) && toks[i+1].is("(") {
+			if i+3 < len(toks) && toks[i+2].kind == tokenIdent && toks[i+3].is(")") {
+				name = toks[i+2]
+				i += 3
+			}
+		} else if i+1 < len(toks) && toks[i+1].kind == tokenIdent {
+			name = toks[i+1]
+			i++
+		}
+		if name == nil {
+			p.errorf(tok.pos, "operator \"defined\" requires an identifier")
+			return false
+		}
+		text := "0"
+		if _, ok := p.macros[name.text]; ok {
+			text = "1"
+		}
+		replaced = append(replaced, &token{kind: tokenNumber, text: text, pos: tok.pos})
+	}
+
+	expanded := p.expandList(replaced)
+	if len(expanded) == 0 {
+		p.errorf(hash.pos, "#if with no expression")
+		return false
+	}
+
+	e := &exprParser{p: p, hash: hash, toks: expanded}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(evalError); !ok {
+				panic(r)
+			}
+			result = false
+		}
+	}()
+
+	v := e.conditional()
+	if e.i < len(e.toks) {
+		e.errorf(e.toks[e.i], "missing binary operator before token \"%s\"", e.toks[e.i].text)
+	}
+	return v.v != 0
+}
+
+func (e *exprParser) errorf(tok *token, format string, args ...interface{}) {
+	pos := e.hash.pos
+	if tok != nil {
+		pos = tok.pos
+	}
+	e.p.errorf(pos, format, args...)
+	panic(evalError{})
+}
+
+func (e *exprParser) peek() *token {
+	if e.i < len(e.toks) {
+		return e.toks[e.i]
+	}
+	return nil
+}
+
+func (e *exprParser) accept(ops ...string) string {
+	tok := e.peek()
+	if tok == nil || tok.kind != tokenPunct {
+		return ""
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			e.i++
+			return op
+		}
+	}
+	return ""
+}
+
+func (e *exprParser) expect(op string) {
+	if e.accept(op) == "" {
+		tok := e.peek()
+		if tok == nil {
+			e.errorf(nil, "expected '%s' in preprocessor expression", op)
+		}
+		e.errorf(tok, "expected '%s' in preprocessor expression, found \"%s\"", op, tok.text)
+	}
+}
+
+func (e *exprParser) conditional() value {
+	cond := e.logicalOr()
+	if e.accept("?") == "" {
+		return cond
+	}
+	lhs := e.conditional()
+	e.expect(":")
+	rhs := e.conditional()
+	res := rhs
+	if cond.v != 0 {
+		res = lhs
+	}
+	res.unsigned = lhs.unsigned || rhs.unsigned
+	return res.truncate()
+}
+
+func (e *exprParser) logicalOr() value {
+	lhs := e.logicalAnd()
+	for e.accept("||") != "" {
+		rhs := e.logicalAnd()
+		lhs = boolValue(lhs.v != 0 || rhs.v != 0)
+	}
+	return lhs
+}
+
+func (e *exprParser) logicalAnd() value {
+	lhs := e.binary(0)
+	for e.accept("&&") != "" {
+		rhs := e.binary(0)
+		lhs = boolValue(lhs.v != 0 && rhs.v != 0)
+	}
+	return lhs
+}
+
+// binaryLevels lists the remaining binary operators from lowest to highest
+// precedence.
+var binaryLevels = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", ">", "<=", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (e *exprParser) binary(level int) value {
+	if level == len(binaryLevels) {
+		return e.unary()
+	}
+	lhs := e.binary(level + 1)
+	for {
+		opTok := e.peek()
+		op := e.accept(binaryLevels[level]...)
+		if op == "" {
+			return lhs
+		}
+		rhs := e.binary(level + 1)
+		lhs = e.apply(opTok, op, lhs, rhs)
+	}
+}
+
+func (e *exprParser) apply(opTok *token, op string, lhs, rhs value) value {
+	switch op {
+	case "<<", ">>":
+		// The result has the type of the promoted left operand.
+		shift := uint(rhs.v) & 31
+		if op == "<<" {
+			return value{v: lhs.v << shift, unsigned: lhs.unsigned}.truncate()
+		}
+		if lhs.unsigned {
+			return value{v: int64(uint32(lhs.v) >> shift), unsigned: true}
+		}
+		return value{v: int64(int32(lhs.v) >> shift)}
+	}
+
+	unsigned := lhs.unsigned || rhs.unsigned
+	l, r := lhs, rhs
+	if unsigned {
+		l.v, r.v = int64(uint32(l.v)), int64(uint32(r.v))
+	}
+
+	switch op {
+	case "==":
+		return boolValue(l.v == r.v)
+	case "!=":
+		return boolValue(l.v != r.v)
+	case "<":
+		return boolValue(l.v < r.v)
+	case ">":
+		return boolValue(l.v > r.v)
+	case "<=":
+		return boolValue(l.v <= r.v)
+	case ">=":
+		return boolValue(l.v >= r.v)
+	}
+
+	res := value{unsigned: unsigned}
+	switch op {
+	case "|":
+		res.v = l.v | r.v
+	case "^":
+		res.v = l.v ^ r.v
+	case "&":
+		res.v = l.v & r.v
+	case "+":
+		res.v = l.v + r.v
+	case "-":
+		res.v = l.v - r.v
+	case "*":
+		res.v = l.v * r.v
+	case "/", "%":
+		if r.v == 0 {
+			e.errorf(opTok, "division by zero in #if")
+		}
+		if op == "/" {
+			res.v = l.v / r.v
+		} else {
+			res.v = l.v % r.v
+		}
+	}
+	return res.truncate()
+}
+
+func (e *exprParser) unary() value {
+	switch e.accept("+", "-", "~", "!") {
+	case "+":
+		return e.unary()
+	case "-":
+		v := e.unary()
+		v.v = -v.v
+		return v.truncate()
+	case "~":
+		v := e.unary()
+		v.v = ^v.v
+		return v.truncate()
+	case "!":
+		return boolValue(e.unary().v == 0)
+	}
+	return e.primary()
+}
+
+func (e *exprParser) primary() value {
+	tok := e.peek()
+	if tok == nil {
+		e.errorf(nil, "#if with no expression")
+	}
+	e.i++
+
+	switch tok.kind {
+	case tokenPunct:
+		if tok.text == "(" {
+			v := e.conditional()
+			e.expect(")")
+			return v
+		}
+	case tokenIdent:
+		// Identifiers which are not macros evaluate to zero.
+		return value{}
+	case tokenNumber:
+		v, ok := parseInteger(tok.text)
+		if !ok {
+			e.errorf(tok, "invalid integer constant \"%s\" in #if", tok.text)
+		}
+		return v
+	case tokenChar:
+		v, ok := parseCharConstant(tok.text)
+		if !ok {
+			e.errorf(tok, "invalid character constant %s in #if", tok.text)
+		}
+		return value{v: v}
+	}
+	e.errorf(tok, "token \"%s\" is not valid in preprocessor expressions", tok.text)
+	return value{}
+}
+
+func parseInteger(text string) (value, bool) {
+	lower := strings.ToLower(text)
+	unsigned := false
+	for strings.HasSuffix(lower, "u") || strings.HasSuffix(lower, "l") {
+		if strings.HasSuffix(lower, "u") {
+			unsigned = true
+		}
+		lower = lower[:len(lower)-1]
+	}
+
+	var v uint64
+	var err error
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		v, err = strconv.ParseUint(lower[2:], 16, 64)
+	case strings.HasPrefix(lower, "0") && len(lower) > 1:
+		v, err = strconv.ParseUint(lower[1:], 8, 64)
+	default:
+		v, err = strconv.ParseUint(lower, 10, 64)
+	}
+	if err != nil || v > 0xFFFFFFFF {
+		return value{}, false
+	}
+	if v > 0x7FFFFFFF {
+		// Constants which don't fit in a long become unsigned long.
+		unsigned = true
+	}
+	return value{v: int64(v), unsigned: unsigned}.truncate(), true
+}
+
+// parseCharConstant returns the value of a character constant, as a (signed)
+// char on MIPS.
+func parseCharConstant(text string) (int64, bool) {
+	text = strings.TrimPrefix(text, "L")
+	if len(text) < 3 {
+		return 0, false
+	}
+	body := text[1 : len(text)-1]
+	if body[0] != '\\' {
+		return int64(int8(body[0])), len(body) == 1
+	}
+	if len(body) < 2 {
+		return 0, false
+	}
+	switch body[1] {
+	case 'n':
+		return '\n', len(body) == 2
+	case 't':
+		return '\t', len(body) == 2
+	case 'v':
+		return '\v', len(body) == 2
+	case 'b':
+		return '\b', len(body) == 2
+	case 'r':
+		return '\r', len(body) == 2
+	case 'f':
+		return '\f', len(body) == 2
+	case 'a':
+		return '\a', len(body) == 2
+	case '\\', '\'', '"', '?':
+		return int64(body[1]), len(body) == 2
+	case 'x':
+		v, err := strconv.ParseUint(body[2:], 16, 8)
+		return int64(int8(v)), err == nil
+	default:
+		v, err := strconv.ParseUint(body[1:], 8, 8)
+		return int64(int8(v)), err == nil && len(body) <= 4
+	}
+}
@@ -0,0 +1,351 @@
+package cpp
+
+import (
+	"strings"
+)
+
+type macro struct {
+	name     string
+	funcLike bool
+	params   []string
+	body     []*token
+
+	// builtin is set for predefined macros such as __LINE__, which expand to
+	// a single token depending on where they are used.
+	builtin func(tok *token) *token
+}
+
+func (m *macro) param(tok *token) int {
+	if !m.funcLike || tok.kind != tokenIdent {
+		return -1
+	}
+	for i, p := range m.params {
+		if p == tok.text {
+			return i
+		}
+	}
+	return -1
+}
+
+// define handles the tokens following #define.
+func (p *Preprocessor) define(toks []*token) {
+	if len(toks) == 0 || toks[0].kind != tokenIdent {
+		pos := Position{}
+		if len(toks) > 0 {
+			pos = toks[0].pos
+		}
+		p.errorf(pos, "macro names must be identifiers")
+		return
+	}
+
+	name := toks[0]
+	if name.text == "defined" {
+		p.errorf(name.pos, "\"defined\" cannot be used as a macro name")
+		return
+	}
+
+	m := &macro{name: name.text}
+	toks = toks[1:]
+
+	if len(toks) > 0 && toks[0].is("(") && !toks[0].space {
+		m.funcLike = true
+		i := 1
+		expectParam := true
+		for ; i < len(toks); i++ {
+			tok := toks[i]
+			if tok.is(")") && (!expectParam || len(m.params) == 0) {
+				break
+			}
+			if expectParam {
+				if tok.kind != tokenIdent {
+					p.errorf(tok.pos, "expected parameter name, found \"%s\"", tok.text)
+					return
+				}
+				if m.param(tok) >= 0 {
+					p.errorf(tok.pos, "duplicate macro parameter \"%s\"", tok.text)
+					return
+				}
+				m.params = append(m.params, tok.text)
+			} else if !tok.is(",") {
+				p.errorf(tok.pos, "expected ',' or ')', found \"%s\"", tok.text)
+				return
+			}
+			expectParam = !expectParam
+		}
+		if i == len(toks) {
+			p.errorf(name.pos, "missing ')' in macro parameter list")
+			return
+		}
+		toks = toks[i+1:]
+	} else if len(toks) > 0 && !toks[0].space {
+		p.errorf(toks[0].pos, "missing whitespace after the macro name")
+	}
+
+	for i, tok := range toks {
+		if tok.is("##") && (i == 0 || i == len(toks)-1) {
+			p.errorf(tok.pos, "'##' cannot appear at either end of a macro expansion")
+			return
+		}
+		if m.funcLike && tok.is("#") && (i == len(toks)-1 || m.param(toks[i+1]) < 0) {
+			p.errorf(tok.pos, "'#' is not followed by a macro parameter")
+			return
+		}
+	}
+	if len(toks) > 0 {
+		toks[0] = toks[0].copy()
+		toks[0].space = false
+	}
+	m.body = toks
+
+	p.macros[m.name] = m
+}
+
+// expand expands the macro invocation starting with tok, pushing the result
+// back onto the input so that it is rescanned. It returns false if tok does
+// not begin a macro invocation.
+func (p *Preprocessor) expand(tok *token) bool {
+	if tok.hide[tok.text] {
+		return false
+	}
+	m, ok := p.macros[tok.text]
+	if !ok {
+		return false
+	}
+
+	if m.builtin != nil {
+		res := m.builtin(tok)
+		res.pos, res.space, res.expanded = tok.pos, tok.space, true
+		p.pushBack([]*token{res})
+		return true
+	}
+
+	if !m.funcLike {
+		p.pushBack(p.finishExpansion(tok, p.substitute(m, nil), tok.hide.with(m.name)))
+		return true
+	}
+
+	// A function-like macro name is only an invocation if it is followed by
+	// an open bracket.
+	var skipped []*token
+	for {
+		next := p.readToken()
+		if next.kind == tokenNewline {
+			skipped = append(skipped, next)
+			continue
+		}
+		if !next.is("(") {
+			p.pushBack(append(skipped, next))
+			return false
+		}
+		break
+	}
+
+	args, rparen, ok := p.readArgs(tok, m)
+	if !ok {
+		return true
+	}
+
+	hide := tok.hide.intersect(rparen.hide).with(m.name)
+	p.pushBack(p.finishExpansion(tok, p.substitute(m, args), hide))
+	return true
+}
+
+// finishExpansion copies the replacement tokens, making them appear at the
+// location of the macro invocation.
+func (p *Preprocessor) finishExpansion(invocation *token, body []*token, hide hideset) []*token {
+	res := make([]*token, len(body))
+	for i, tok := range body {
+		c := tok.copy()
+		c.hide = c.hide.union(hide)
+		c.pos = invocation.pos
+		c.expanded = true
+		c.bol = false
+		if i == 0 {
+			c.space = invocation.space
+		}
+		res[i] = c
+	}
+	return res
+}
+
+// readArgs reads the arguments of a function-like macro invocation, after the
+// opening bracket.
+func (p *Preprocessor) readArgs(name *token, m *macro) (args [][]*token, rparen *token, ok bool) {
+	depth := 0
+	var cur []*token
+	newline := false
+	for {
+		tok := p.readToken()
+		switch {
+		case tok.kind == tokenEOF:
+			p.errorf(name.pos, "unterminated argument list invoking macro \"%s\"", m.name)
+			p.pushBack([]*token{tok})
+			return nil, nil, false
+		case tok.kind == tokenNewline:
+			newline = true
+			continue
+		case tok.is("(") && tok.kind == tokenPunct:
+			depth++
+		case tok.is(")") && tok.kind == tokenPunct:
+			if depth == 0 {
+				args = append(args, cur)
+				if len(m.params) == 0 && len(args) == 1 && len(args[0]) == 0 {
+					args = nil
+				}
+				if len(args) != len(m.params) {
+					p.errorf(name.pos, "macro \"%s\" requires %d arguments, but %d given", m.name, len(m.params), len(args))
+					return nil, nil, false
+				}
+				return args, tok, true
+			}
+			depth--
+		case tok.is(",") && tok.kind == tokenPunct && depth == 0:
+			args = append(args, cur)
+			cur = nil
+			continue
+		}
+		if newline {
+			tok = tok.copy()
+			tok.space = true
+			newline = false
+		}
+		cur = append(cur, tok)
+	}
+}
+
+// substitute replaces the parameters in the body of a macro with the given
+// arguments, handling the # and ## operators. Only ## applies to the body of an
+// object-like macro, which has no parameters for # to stringize.
+func (p *Preprocessor) substitute(m *macro, args [][]*token) []*token {
+	var res []*token
+	body := m.body
+	for i := 0; i < len(body); i++ {
+		tok := body[i]
+
+		// # param
+		if m.funcLike && tok.is("#") {
+			arg := args[m.param(body[i+1])]
+			str := &token{kind: tokenString, text: stringize(arg), pos: tok.pos, space: tok.space}
+			res = append(res, str)
+			i++
+			continue
+		}
+
+		// x ## y
+		if tok.is("##") {
+			rhs := body[i+1]
+			i++
+			if idx := m.param(rhs); idx >= 0 {
+				arg := args[idx]
+				if len(arg) == 0 {
+					continue
+				}
+				if len(res) == 0 {
+					res = append(res, copyTokens(arg)...)
+					continue
+				}
+				res[len(res)-1] = p.paste(res[len(res)-1], arg[0])
+				res = append(res, copyTokens(arg[1:])...)
+				continue
+			}
+			if len(res) == 0 {
+				res = append(res, rhs.copy())
+				continue
+			}
+			res[len(res)-1] = p.paste(res[len(res)-1], rhs)
+			continue
+		}
+
+		idx := m.param(tok)
+		if idx < 0 {
+			res = append(res, tok.copy())
+			continue
+		}
+
+		arg := args[idx]
+		if i+1 < len(body) && body[i+1].is("##") {
+			// Operands of ## are not macro expanded.
+			res = append(res, copyTokens(arg)...)
+			continue
+		}
+
+		expanded := p.expandList(copyTokens(arg))
+		if len(expanded) > 0 {
+			expanded[0] = expanded[0].copy()
+			expanded[0].space = tok.space
+		}
+		res = append(res, expanded...)
+	}
+	return res
+}
+
+func copyTokens(toks []*token) []*token {
+	res := make([]*token, len(toks))
+	for i, tok := range toks {
+		res[i] = tok.copy()
+	}
+	return res
+}
+
+// paste implements the ## operator by re-lexing the concatenated text.
+func (p *Preprocessor) paste(lhs, rhs *token) *token {
+	text := lhs.text + rhs.text
+	var scratch ErrorList
+	s := newScanner(lhs.pos.File, []byte(text), &scratch)
+	tok := s.lex()
+	if tok.kind == tokenEOF {
+		tok = &token{kind: tokenOther}
+	}
+	if !s.eof() || tok.kind == tokenOther || len(scratch) > 0 {
+		p.errorf(lhs.pos, "pasting \"%s\" and \"%s\" does not give a valid preprocessing token", lhs.text, rhs.text)
+	}
+	tok.text = text
+	tok.pos = lhs.pos
+	tok.space = lhs.space
+	tok.bol = false
+	tok.hide = lhs.hide
+	return tok
+}
+
+// stringize implements the # operator.
+func stringize(arg []*token) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i, tok := range arg {
+		if i != 0 && tok.space {
+			sb.WriteByte(' ')
+		}
+		if tok.kind == tokenString || tok.kind == tokenChar {
+			for j := 0; j < len(tok.text); j++ {
+				c := tok.text[j]
+				if c == '"' || c == '\\' {
+					sb.WriteByte('\\')
+				}
+				sb.WriteByte(c)
+			}
+			continue
+		}
+		sb.WriteString(tok.text)
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// expandList fully macro expands toks in isolation from the rest of the input.
+func (p *Preprocessor) expandList(toks []*token) []*token {
+	saved := p.pending
+	p.pending = nil
+	p.pushBack(append(toks, &token{kind: tokenEOF}))
+
+	var res []*token
+	for {
+		tok := p.next()
+		if tok.kind == tokenEOF {
+			break
+		}
+		res = append(res, tok)
+	}
+
+	p.pending = saved
+	return res
+}
@@ -0,0 +1,91 @@
+package cpp
+
+import (
+	"bytes"
+	"sort"
+)
+
+type segment struct {
+	column int
+	pos    Position
+
+	// expanded is set if the token came from a macro expansion, in which case
+	// pos is the location of the invocation.
+	expanded bool
+}
+
+// Reader holds the preprocessed output of a translation unit. As well as
+// implementing io.Reader, it remembers where every output token came from so
+// that the lexer can report positions in terms of the original source files.
+type Reader struct {
+	*bytes.Reader
+
+	buf   bytes.Buffer
+	lines [][]segment
+}
+
+func newReader() *Reader {
+	return &Reader{lines: [][]segment{nil}}
+}
+
+// write appends a token to the output.
+func (r *Reader) write(tok *token) {
+	line := len(r.lines) - 1
+	if len(r.lines[line]) > 0 {
+		r.buf.WriteByte(' ')
+	}
+	r.lines[line] = append(r.lines[line], segment{column: r.column(), pos: tok.pos, expanded: tok.expanded})
+	r.buf.WriteString(tok.text)
+}
+
+func (r *Reader) newline() {
+	r.buf.WriteByte('\n')
+	r.lines = append(r.lines, nil)
+}
+
+// column returns the zero-based column that the next byte will be written to.
+func (r *Reader) column() int {
+	b := r.buf.Bytes()
+	return len(b) - (bytes.LastIndexByte(b, '\n') + 1)
+}
+
+func (r *Reader) finish() {
+	r.Reader = bytes.NewReader(r.buf.Bytes())
+}
+
+// Bytes returns the complete preprocessed output.
+func (r *Reader) Bytes() []byte {
+	return r.buf.Bytes()
+}
+
+// Position maps a zero-based line and column within the preprocessed output
+// (as reported by the lexer) back to the source position it originated from.
+// Tokens produced by macro expansion map to the macro invocation.
+func (r *Reader) Position(line, column int) Position {
+	for line >= 0 {
+		if line < len(r.lines) && len(r.lines[line]) > 0 {
+			break
+		}
+		// Nothing was emitted on this line, so use the end of the closest
+		// preceding line instead.
+		line--
+		column = -1
+	}
+	if line < 0 {
+		return Position{Line: 1, Column: 1}
+	}
+
+	segs := r.lines[line]
+	if column < 0 {
+		return segs[len(segs)-1].pos
+	}
+	i := sort.Search(len(segs), func(i int) bool { return segs[i].column > column }) - 1
+	if i < 0 {
+		i = 0
+	}
+	pos := segs[i].pos
+	if delta := column - segs[i].column; delta > 0 && !segs[i].expanded {
+		pos.Column += delta
+	}
+	return pos
+}
@@ -0,0 +1,369 @@
+package cpp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position is a location within a source file. Lines and columns start at 1.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNewline
+	tokenIdent
+	tokenNumber
+	tokenChar
+	tokenString
+	tokenPunct
+	tokenOther
+)
+
+// hideset is the set of macro names which must not be expanded again for a
+// given token (see Prosser's algorithm).
+type hideset map[string]bool
+
+func (h hideset) with(name string) hideset {
+	res := make(hideset, len(h)+1)
+	for k := range h {
+		res[k] = true
+	}
+	res[name] = true
+	return res
+}
+
+func (h hideset) union(o hideset) hideset {
+	res := make(hideset, len(h)+len(o))
+	for k := range h {
+		res[k] = true
+	}
+	for k := range o {
+		res[k] = true
+	}
+	return res
+}
+
+func (h hideset) intersect(o hideset) hideset {
+	res := hideset{}
+	for k := range h {
+		if o[k] {
+			res[k] = true
+		}
+	}
+	return res
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Position
+
+	// space is set if the token was preceded by whitespace.
+	space bool
+	// bol is set if the token is the first on its line.
+	bol bool
+	// expanded is set if the token was produced by a macro expansion.
+	expanded bool
+
+	hide hideset
+}
+
+func (t *token) is(text string) bool {
+	return t.kind != tokenString && t.kind != tokenChar && t.text == text
+}
+
+func (t *token) copy() *token {
+	c := *t
+	return &c
+}
+
+// punctuators is ordered so that longer punctuators are matched first.
+var punctuators = []string{
+	"...", "<<=", ">>=",
+	"->", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"*=", "/=", "%=", "+=", "-=", "&=", "^=", "|=", "##",
+	"[", "]", "(", ")", "{", "}", ".", "&", "*", "+", "-", "~", "!",
+	"/", "%", "<", ">", "^", "|", "?", ":", ";", "=", ",", "#",
+}
+
+var trigraphs = map[byte]byte{
+	'=':  '#',
+	'(':  '[',
+	'/':  '\\',
+	')':  ']',
+	'\'': '^',
+	'<':  '{',
+	'!':  '|',
+	'>':  '}',
+	'-':  '~',
+}
+
+// scanner splits a single source file into preprocessing tokens. Trigraphs and
+// line splices (translation phases 1 and 2) are handled transparently, as are
+// comments, which become whitespace.
+type scanner struct {
+	src  []byte
+	file string
+	off  int
+
+	line, col int
+
+	// lineDelta and presumedFile are altered by #line directives.
+	lineDelta    int
+	presumedFile string
+
+	atBOL bool
+	errs  *ErrorList
+}
+
+func newScanner(file string, src []byte, errs *ErrorList) *scanner {
+	return &scanner{
+		src:          src,
+		file:         file,
+		presumedFile: file,
+		line:         1,
+		col:          1,
+		atBOL:        true,
+		errs:         errs,
+	}
+}
+
+// raw returns the byte at off after trigraph replacement and the number of
+// source bytes it occupies.
+func (s *scanner) raw(off int) (byte, int) {
+	if off >= len(s.src) {
+		return 0, 0
+	}
+	c := s.src[off]
+	if c == '?' && off+2 < len(s.src) && s.src[off+1] == '?' {
+		if r, ok := trigraphs[s.src[off+2]]; ok {
+			return r, 3
+		}
+	}
+	return c, 1
+}
+
+// skipSplices moves past any backslash-newline sequences at the current
+// offset.
+func (s *scanner) skipSplices() {
+	for {
+		c, n := s.raw(s.off)
+		if c != '\\' {
+			return
+		}
+		next := s.off + n
+		if next < len(s.src) && s.src[next] == '\r' {
+			next++
+		}
+		if next >= len(s.src) || s.src[next] != '\n' {
+			return
+		}
+		s.off = next + 1
+		s.line++
+		s.col = 1
+	}
+}
+
+func (s *scanner) peek() byte {
+	s.skipSplices()
+	c, _ := s.raw(s.off)
+	return c
+}
+
+// peekAt returns the n-th byte ahead of the current position, ignoring splices.
+func (s *scanner) peekAt(n int) byte {
+	saveOff, saveLine, saveCol := s.off, s.line, s.col
+	defer func() { s.off, s.line, s.col = saveOff, saveLine, saveCol }()
+	for i := 0; i < n; i++ {
+		s.next()
+	}
+	return s.peek()
+}
+
+func (s *scanner) next() byte {
+	s.skipSplices()
+	c, n := s.raw(s.off)
+	if n == 0 {
+		return 0
+	}
+	s.off += n
+	if c == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col += n
+	}
+	return c
+}
+
+func (s *scanner) eof() bool {
+	s.skipSplices()
+	return s.off >= len(s.src)
+}
+
+func (s *scanner) pos() Position {
+	return Position{File: s.presumedFile, Line: s.line + s.lineDelta, Column: s.col}
+}
+
+func (s *scanner) errorf(pos Position, format string, args ...interface{}) {
+	s.errs.add(pos, format, args...)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// skipSpace consumes whitespace and comments, but not newlines. It returns
+// true if anything was skipped.
+func (s *scanner) skipSpace() bool {
+	skipped := false
+	for !s.eof() {
+		c := s.peek()
+		switch {
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f' || c == '\r':
+			s.next()
+		case c == '/' && s.peekAt(1) == '*':
+			start := s.pos()
+			s.next()
+			s.next()
+			for {
+				if s.eof() {
+					s.errorf(start, "unterminated comment")
+					return true
+				}
+				if s.next() == '*' && s.peek() == '/' {
+					s.next()
+					break
+				}
+			}
+		case c == '/' && s.peekAt(1) == '/':
+			for !s.eof() && s.peek() != '\n' {
+				s.next()
+			}
+		default:
+			return skipped
+		}
+		skipped = true
+	}
+	return skipped
+}
+
+// lex returns the next preprocessing token from the file.
+func (s *scanner) lex() *token {
+	space := s.skipSpace()
+	tok := &token{pos: s.pos(), space: space, bol: s.atBOL}
+
+	if s.eof() {
+		tok.kind = tokenEOF
+		return tok
+	}
+
+	var sb strings.Builder
+	c := s.peek()
+	switch {
+	case c == '\n':
+		s.next()
+		tok.kind = tokenNewline
+		tok.text = "\n"
+		s.atBOL = true
+		return tok
+	case c == 'L' && (s.peekAt(1) == '\'' || s.peekAt(1) == '"'):
+		sb.WriteByte(s.next())
+		tok.kind = s.lexQuoted(&sb, s.peek())
+	case isIdentStart(c):
+		for !s.eof() && isIdentChar(s.peek()) {
+			sb.WriteByte(s.next())
+		}
+		tok.kind = tokenIdent
+	case isDigit(c) || (c == '.' && isDigit(s.peekAt(1))):
+		// pp-number
+		for !s.eof() {
+			c := s.peek()
+			if (c == '+' || c == '-') && sb.Len() > 0 {
+				last := sb.String()[sb.Len()-1]
+				if last != 'e' && last != 'E' {
+					break
+				}
+			} else if !isIdentChar(c) && c != '.' {
+				break
+			}
+			sb.WriteByte(s.next())
+		}
+		tok.kind = tokenNumber
+	case c == '\'' || c == '"':
+		tok.kind = s.lexQuoted(&sb, c)
+	default:
+		tok.kind = tokenOther
+		for _, p := range punctuators {
+			if s.matches(p) {
+				for range p {
+					sb.WriteByte(s.next())
+				}
+				tok.kind = tokenPunct
+				break
+			}
+		}
+		if tok.kind == tokenOther {
+			sb.WriteByte(s.next())
+		}
+	}
+
+	tok.text = sb.String()
+	s.atBOL = false
+	return tok
+}
+
+func (s *scanner) matches(p string) bool {
+	for i := 0; i < len(p); i++ {
+		if s.peekAt(i) != p[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lexQuoted reads a character constant or string literal. Unterminated
+// literals are returned as tokenOther so that they only cause an error if they
+// are not skipped.
+func (s *scanner) lexQuoted(sb *strings.Builder, quote byte) tokenKind {
+	sb.WriteByte(s.next())
+	for {
+		if s.eof() || s.peek() == '\n' {
+			return tokenOther
+		}
+		c := s.next()
+		sb.WriteByte(c)
+		if c == '\\' {
+			if s.eof() || s.peek() == '\n' {
+				return tokenOther
+			}
+			sb.WriteByte(s.next())
+			continue
+		}
+		if c == quote {
+			break
+		}
+	}
+	if quote == '"' {
+		return tokenString
+	}
+	return tokenChar
+}
@@ -0,0 +1,150 @@
+package ir
+
+// Builder appends instructions to the blocks of a function.
+type Builder struct {
+	Func  *Function
+	Block *Block
+}
+
+// NewBuilder returns a builder which appends to the first block of f,
+// creating it if there isn't one.
+func NewBuilder(f *Function) *Builder {
+	if len(f.Blocks) == 0 {
+		f.PlaceBlock(f.NewBlock("entry"))
+	}
+	return &Builder{Func: f, Block: f.Blocks[0]}
+}
+
+// SetBlock makes the builder append to block, placing it after the blocks
+// which have been placed so far.
+func (b *Builder) SetBlock(block *Block) {
+	b.Func.PlaceBlock(block)
+	b.Block = block
+}
+
+// StartBlock ends the current block by falling through to block, which the
+// builder then appends to.
+func (b *Builder) StartBlock(block *Block) {
+	b.Jump(block)
+	b.SetBlock(block)
+}
+
+// Emit appends in to the current block. Anything emitted after a terminator
+// can't be reached, so it's put in a new block of its own.
+func (b *Builder) Emit(in *Instr) *Instr {
+	if b.Block.Terminator() != nil {
+		b.SetBlock(b.Func.NewBlock("unreachable"))
+	}
+	b.Block.Instrs = append(b.Block.Instrs, in)
+	return in
+}
+
+// Copy returns a new register holding v.
+func (b *Builder) Copy(v Value) *Reg {
+	dst := b.Func.NewReg(v.Type())
+	b.Emit(&Instr{Op: OpCopy, Type: v.Type(), Dst: dst, Args: []Value{v}})
+	return dst
+}
+
+// CopyTo sets the existing register dst to v.
+func (b *Builder) CopyTo(dst *Reg, v Value) {
+	b.Emit(&Instr{Op: OpCopy, Type: dst.Typ, Dst: dst, Args: []Value{v}})
+}
+
+// Binary returns x op y, carried out in type t.
+func (b *Builder) Binary(op Op, t Type, unsigned bool, x, y Value) *Reg {
+	dstType := t
+	if op.IsCompare() {
+		dstType = I32
+	}
+	dst := b.Func.NewReg(dstType)
+	b.Emit(&Instr{Op: op, Type: t, Dst: dst, Args: []Value{x, y}, Unsigned: unsigned})
+	return dst
+}
+
+// Unary returns op x, which is OpNeg or OpNot.
+func (b *Builder) Unary(op Op, x Value) *Reg {
+	dst := b.Func.NewReg(x.Type())
+	b.Emit(&Instr{Op: op, Type: x.Type(), Dst: dst, Args: []Value{x}})
+	return dst
+}
+
+// Convert returns the result of the conversion op of x, whose type is t.
+func (b *Builder) Convert(op Op, t Type, unsigned bool, x Value) *Reg {
+	dstType := t
+	switch op {
+	case OpExt, OpFToI:
+		dstType = I32
+	}
+	dst := b.Func.NewReg(dstType)
+	b.Emit(&Instr{Op: op, Type: t, Dst: dst, Args: []Value{x}, Unsigned: unsigned})
+	return dst
+}
+
+// Load returns the value of type t at addr.
+func (b *Builder) Load(t Type, addr Value, unsigned, volatile bool) *Reg {
+	regType := t
+	if !t.IsFloat() {
+		regType = I32
+	}
+	dst := b.Func.NewReg(regType)
+	b.Emit(&Instr{Op: OpLoad, Type: t, Dst: dst, Args: []Value{addr}, Unsigned: unsigned, Volatile: volatile})
+	return dst
+}
+
+// Store stores v at addr as a value of type t.
+func (b *Builder) Store(t Type, addr, v Value, volatile bool) {
+	b.Emit(&Instr{Op: OpStore, Type: t, Args: []Value{addr, v}, Volatile: volatile})
+}
+
+// CopyBlock copies size bytes from src to dst.
+func (b *Builder) CopyBlock(dst, src Value, size, align int) {
+	b.Emit(&Instr{Op: OpCopyBlock, Args: []Value{dst, src}, Size: size, Align: align})
+}
+
+// Call calls fn with args, returning the result (of type ret) or nil if ret
+// is Void.
+func (b *Builder) Call(ret Type, fn Value, args []Value, call *Call) *Reg {
+	var dst *Reg
+	switch {
+	case ret.IsFloat():
+		dst = b.Func.NewReg(ret)
+	case ret != Void:
+		dst = b.Func.NewReg(I32)
+	}
+	b.Emit(&Instr{Op: OpCall, Type: ret, Dst: dst, Args: append([]Value{fn}, args...), Call: call})
+	return dst
+}
+
+// VaStart returns the address of the first unnamed argument.
+func (b *Builder) VaStart() *Reg {
+	dst := b.Func.NewReg(I32)
+	b.Emit(&Instr{Op: OpVaStart, Type: I32, Dst: dst})
+	return dst
+}
+
+// Jump ends the current block with a jump to target.
+func (b *Builder) Jump(target *Block) {
+	if b.Block.Terminator() != nil {
+		// Control never reaches the end of the block.
+		return
+	}
+	b.Emit(&Instr{Op: OpJump, Targets: []*Block{target}})
+}
+
+// Branch ends the current block with a branch to ifTrue if cond isn't zero,
+// and to ifFalse otherwise.
+func (b *Builder) Branch(cond Value, ifTrue, ifFalse *Block) {
+	b.Emit(&Instr{Op: OpBranch, Type: cond.Type(), Args: []Value{cond}, Targets: []*Block{ifTrue, ifFalse}})
+}
+
+// Ret ends the current block by returning v, which is nil for functions
+// without a result.
+func (b *Builder) Ret(v Value) {
+	in := &Instr{Op: OpRet, Type: Void}
+	if v != nil {
+		in.Type = v.Type()
+		in.Args = []Value{v}
+	}
+	b.Emit(in)
+}
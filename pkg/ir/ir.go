@@ -0,0 +1,541 @@
+// Package ir is the intermediate representation that C is compiled into
+// before MIPS assembly is generated from it.
+//
+// It's a three-address code: each instruction carries out a single operation
+// on at most a few operands, writing its result to a virtual register.
+// Registers may be assigned more than once, as the IR isn't in SSA form, which
+// lets values such as the result of a conditional expression be built up in
+// different blocks. The instructions of a function are grouped into basic
+// blocks, each of which ends with a jump, branch or return.
+//
+// Local variables live in stack slots, which are read and written with loads
+// and stores, until an optimisation moves them into registers.
+package ir
+
+import "fmt"
+
+// Type is the type of a value, or of the memory accessed by a load or store.
+// Registers only ever hold I32, F32 or F64 values: smaller integers are
+// extended to 32 bits when they're loaded, and truncated when stored.
+// Pointers are I32.
+type Type uint8
+
+const (
+	Void Type = iota
+	I8
+	I16
+	I32
+	F32
+	F64
+)
+
+func (t Type) String() string {
+	switch t {
+	case I8:
+		return "i8"
+	case I16:
+		return "i16"
+	case I32:
+		return "i32"
+	case F32:
+		return "f32"
+	case F64:
+		return "f64"
+	}
+	return "void"
+}
+
+// Size returns the number of bytes taken by a value of the type in memory.
+func (t Type) Size() int {
+	switch t {
+	case I8:
+		return 1
+	case I16:
+		return 2
+	case I32, F32:
+		return 4
+	case F64:
+		return 8
+	}
+	return 0
+}
+
+// IsFloat reports whether values of the type are held in floating point
+// registers.
+func (t Type) IsFloat() bool {
+	return t == F32 || t == F64
+}
+
+// Value is an operand of an instruction.
+type Value interface {
+	Type() Type
+	String() string
+}
+
+// Reg is a virtual register.
+type Reg struct {
+	ID  int
+	Typ Type
+}
+
+func (r *Reg) Type() Type {
+	return r.Typ
+}
+
+func (r *Reg) String() string {
+	return fmt.Sprintf("%%%d", r.ID)
+}
+
+// Const is an integer or floating point constant. Integer constants are I32.
+type Const struct {
+	Typ   Type
+	Int   int64
+	Float float64
+}
+
+// IntConst returns the I32 constant v.
+func IntConst(v int64) *Const {
+	return &Const{Typ: I32, Int: int64(int32(v))}
+}
+
+// FloatConst returns a floating point constant of type t.
+func FloatConst(t Type, v float64) *Const {
+	if t == F32 {
+		v = float64(float32(v))
+	}
+	return &Const{Typ: t, Float: v}
+}
+
+func (c *Const) Type() Type {
+	return c.Typ
+}
+
+func (c *Const) String() string {
+	if c.Typ.IsFloat() {
+		return fmt.Sprintf("%s %g", c.Typ, c.Float)
+	}
+	return fmt.Sprint(c.Int)
+}
+
+// Addr is a constant address: either that of a global symbol or that of a
+// stack slot, plus an offset in bytes.
+type Addr struct {
+	Symbol string
+	Slot   *Slot
+	Offset int
+}
+
+func (a *Addr) Type() Type {
+	return I32
+}
+
+func (a *Addr) String() string {
+	s := "@" + a.Symbol
+	if a.Slot != nil {
+		s = a.Slot.String()
+	}
+	if a.Offset != 0 {
+		s += fmt.Sprintf("%+d", a.Offset)
+	}
+	return s
+}
+
+// Slot is an object in the stack frame of a function, such as a local
+// variable, whose address is only known once the frame has been laid out.
+type Slot struct {
+	ID    int
+	Name  string
+	Size  int
+	Align int
+
+	// Param is set for the slots holding the parameters of the function,
+	// which are in the caller's frame where the calling convention puts them.
+	Param bool
+	// Type is the type of a scalar parameter. It's Void for structures and
+	// for the slots of local variables.
+	Type Type
+}
+
+func (s *Slot) String() string {
+	if s.Name != "" {
+		return fmt.Sprintf("&%s.%d", s.Name, s.ID)
+	}
+	return fmt.Sprintf("&slot.%d", s.ID)
+}
+
+// Op is the operation carried out by an instruction.
+type Op uint8
+
+const (
+	// OpCopy sets Dst to Args[0].
+	OpCopy Op = iota
+
+	// The arithmetic operations set Dst to Args[0] op Args[1], in Type.
+	// Division, remainder and right shift use Unsigned.
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpRem
+	OpAnd
+	OpOr
+	OpXor
+	OpShl
+	OpShr
+
+	// OpNeg and OpNot set Dst to -Args[0] and ^Args[0].
+	OpNeg
+	OpNot
+
+	// The comparisons set the I32 Dst to 1 if Args[0] op Args[1] holds, in
+	// Type, and 0 otherwise. OpLt and OpLe use Unsigned.
+	OpEq
+	OpNe
+	OpLt
+	OpLe
+
+	// OpExt truncates the integer Args[0] to Type, which is I8 or I16, and
+	// sign extends it back to I32, or zero extends it if Unsigned is set.
+	OpExt
+	// OpIToF converts the integer Args[0] to the floating point Type, treating
+	// it as unsigned if Unsigned is set.
+	OpIToF
+	// OpFToI truncates the floating point Args[0] to an integer, which is
+	// unsigned if Unsigned is set.
+	OpFToI
+	// OpFConv converts between F32 and F64, Type being the type of Dst.
+	OpFConv
+
+	// OpLoad sets Dst to the Type value at Args[0] + Offset. Values smaller
+	// than a word are zero extended if Unsigned is set, and sign extended
+	// otherwise.
+	OpLoad
+	// OpStore stores the Type value Args[1] at Args[0] + Offset.
+	OpStore
+	// OpCopyBlock copies Size bytes, aligned to Align, from Args[1] to
+	// Args[0].
+	OpCopyBlock
+
+	// OpCall calls the function at Args[0] with the arguments Args[1:], and
+	// sets Dst (if any) to its result. Call has the details of the arguments.
+	OpCall
+	// OpVaStart sets Dst to the address of the first unnamed argument of the
+	// current function.
+	OpVaStart
+
+	// OpJump jumps to Targets[0].
+	OpJump
+	// OpBranch jumps to Targets[0] if Args[0] isn't zero, and to Targets[1]
+	// otherwise.
+	OpBranch
+	// OpRet returns from the function, with the value Args[0] if there is
+	// one.
+	OpRet
+	// OpTailCall calls the function at Args[0] like OpCall, in place of the
+	// current function, and returns the result of the call. It's only used
+	// for calls whose arguments are all passed in registers.
+	OpTailCall
+)
+
+var opNames = [...]string{
+	OpCopy:      "copy",
+	OpAdd:       "add",
+	OpSub:       "sub",
+	OpMul:       "mul",
+	OpDiv:       "div",
+	OpRem:       "rem",
+	OpAnd:       "and",
+	OpOr:        "or",
+	OpXor:       "xor",
+	OpShl:       "shl",
+	OpShr:       "shr",
+	OpNeg:       "neg",
+	OpNot:       "not",
+	OpEq:        "eq",
+	OpNe:        "ne",
+	OpLt:        "lt",
+	OpLe:        "le",
+	OpExt:       "ext",
+	OpIToF:      "itof",
+	OpFToI:      "ftoi",
+	OpFConv:     "fconv",
+	OpLoad:      "load",
+	OpStore:     "store",
+	OpCopyBlock: "copyblock",
+	OpCall:      "call",
+	OpVaStart:   "vastart",
+	OpJump:      "jump",
+	OpBranch:    "branch",
+	OpRet:       "ret",
+	OpTailCall:  "tailcall",
+}
+
+func (op Op) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("op(%d)", op)
+}
+
+// IsTerminator reports whether op ends a basic block.
+func (op Op) IsTerminator() bool {
+	return op == OpJump || op == OpBranch || op == OpRet || op == OpTailCall
+}
+
+// IsCompare reports whether op is a comparison.
+func (op Op) IsCompare() bool {
+	return op >= OpEq && op <= OpLe
+}
+
+// Instr is a single instruction. Which of the fields are used depends on Op.
+type Instr struct {
+	Op   Op
+	Type Type
+	Dst  *Reg
+	Args []Value
+
+	// Unsigned is set if the integer operands are unsigned.
+	Unsigned bool
+	// Volatile is set on loads and stores of volatile objects, which must all
+	// be carried out in order and can't be removed, merged or moved.
+	Volatile bool
+
+	// Offset is added to the address of a load or store.
+	Offset int
+	// Size and Align describe the block copied by OpCopyBlock.
+	Size  int
+	Align int
+
+	Call    *Call
+	Targets []*Block
+}
+
+// Call holds the details of the arguments of an OpCall or OpTailCall.
+type Call struct {
+	// Aggregates has an entry for each argument, which is non-zero for the
+	// structures passed by value. These arguments are the address of the
+	// structure, which is copied into the argument area.
+	Aggregates []Aggregate
+	// Named is the number of arguments which match named parameters of the
+	// callee, or -1 if they all do. Only named arguments are passed in the
+	// floating point registers.
+	Named int
+}
+
+// Aggregate is the size and alignment of a structure.
+type Aggregate struct {
+	Size  int
+	Align int
+}
+
+// Uses returns the registers read by the instruction.
+func (in *Instr) Uses() []*Reg {
+	var regs []*Reg
+	for _, arg := range in.Args {
+		if r, ok := arg.(*Reg); ok {
+			regs = append(regs, r)
+		}
+	}
+	return regs
+}
+
+// HasSideEffects reports whether the instruction does more than set Dst, so
+// that it can't be removed even if Dst isn't used.
+func (in *Instr) HasSideEffects() bool {
+	switch in.Op {
+	case OpStore, OpCopyBlock, OpCall, OpJump, OpBranch, OpRet, OpTailCall:
+		return true
+	case OpLoad:
+		return in.Volatile
+	}
+	return false
+}
+
+// Block is a basic block: a sequence of instructions with a single entry
+// point, which ends with a terminator.
+type Block struct {
+	ID int
+	// Name describes where the block came from, such as "while.cond".
+	Name   string
+	Instrs []*Instr
+
+	// placed is set once the block has been added to the layout of its
+	// function.
+	placed bool
+}
+
+func (b *Block) String() string {
+	return fmt.Sprintf("b%d", b.ID)
+}
+
+// Terminator returns the last instruction of the block if it's a terminator,
+// or nil.
+func (b *Block) Terminator() *Instr {
+	if len(b.Instrs) == 0 {
+		return nil
+	}
+	last := b.Instrs[len(b.Instrs)-1]
+	if !last.Op.IsTerminator() {
+		return nil
+	}
+	return last
+}
+
+// Successors returns the blocks control can pass to from the end of b.
+func (b *Block) Successors() []*Block {
+	if term := b.Terminator(); term != nil {
+		return term.Targets
+	}
+	return nil
+}
+
+// Function is a function definition.
+type Function struct {
+	Name string
+	// Global is set if the function has external linkage.
+	Global bool
+	// Params holds the slots of the parameters, in order. The address a
+	// structure is returned to is passed as a hidden first parameter.
+	Params   []*Slot
+	Variadic bool
+	// Return is the type of the value returned, which is the address of the
+	// structure for functions returning a structure.
+	Return Type
+
+	// Blocks holds the blocks of the function in the order they're laid
+	// out. The first block is the entry point.
+	Blocks []*Block
+	// Slots holds the slots of the local variables and temporaries.
+	Slots []*Slot
+
+	regs   int
+	slots  int
+	blocks int
+}
+
+// NewFunction creates an empty function.
+func NewFunction(name string) *Function {
+	return &Function{Name: name}
+}
+
+// NewReg returns a new virtual register of type t.
+func (f *Function) NewReg(t Type) *Reg {
+	f.regs++
+	return &Reg{ID: f.regs, Typ: t}
+}
+
+// NumRegs returns the number of registers created in the function. Register
+// IDs are between 1 and NumRegs.
+func (f *Function) NumRegs() int {
+	return f.regs
+}
+
+// NewSlot adds a slot of size bytes to the frame of the function.
+func (f *Function) NewSlot(name string, size, align int) *Slot {
+	slot := &Slot{ID: f.slots, Name: name, Size: size, Align: align}
+	f.slots++
+	f.Slots = append(f.Slots, slot)
+	return slot
+}
+
+// NewParam adds a parameter of type t, or a structure if t is Void.
+func (f *Function) NewParam(name string, t Type, size, align int) *Slot {
+	slot := &Slot{ID: f.slots, Name: name, Size: size, Align: align, Param: true, Type: t}
+	f.slots++
+	f.Params = append(f.Params, slot)
+	return slot
+}
+
+// NewBlock creates an empty block. It isn't part of the layout of the
+// function until it's placed, so blocks can be created before the code which
+// comes ahead of them has been built.
+func (f *Function) NewBlock(name string) *Block {
+	block := &Block{ID: f.blocks, Name: name}
+	f.blocks++
+	return block
+}
+
+// PlaceBlock adds block to the end of the layout of the function, unless it
+// has already been placed.
+func (f *Function) PlaceBlock(block *Block) {
+	if block.placed {
+		return
+	}
+	block.placed = true
+	f.Blocks = append(f.Blocks, block)
+}
+
+// InsertBlock places block in the layout of the function before the block
+// at index i.
+func (f *Function) InsertBlock(i int, block *Block) {
+	block.placed = true
+	f.Blocks = append(f.Blocks, nil)
+	copy(f.Blocks[i+1:], f.Blocks[i:])
+	f.Blocks[i] = block
+}
+
+// Section is the section of the object file a global is placed in.
+type Section uint8
+
+const (
+	SectionData Section = iota
+	SectionRodata
+	SectionBSS
+	// SectionCommon is used for tentative definitions, which may be merged
+	// with a definition from another translation unit.
+	SectionCommon
+)
+
+func (s Section) String() string {
+	switch s {
+	case SectionRodata:
+		return ".rodata"
+	case SectionBSS:
+		return ".bss"
+	case SectionCommon:
+		return ".comm"
+	}
+	return ".data"
+}
+
+// Data is a global object, such as a global variable or a string literal.
+type Data struct {
+	Name    string
+	Section Section
+	// Global is set if the object has external linkage.
+	Global bool
+	Size   int
+	Align  int
+	// Items is the initial value of the object. Objects in .bss and .comm
+	// have no items.
+	Items []DataItem
+}
+
+// DataKind is the kind of a DataItem.
+type DataKind uint8
+
+const (
+	// DataBytes is the bytes in Bytes.
+	DataBytes DataKind = iota
+	// DataHalf and DataWord are the 16 and 32 bit integer Int.
+	DataHalf
+	DataWord
+	// DataAddress is the word holding the address of Symbol plus Int.
+	DataAddress
+	// DataSpace is Int bytes of zeroes.
+	DataSpace
+)
+
+// DataItem is part of the initial value of a Data.
+type DataItem struct {
+	Kind   DataKind
+	Bytes  []byte
+	Int    int64
+	Symbol string
+}
+
+// Module is a translation unit.
+type Module struct {
+	Data      []*Data
+	Functions []*Function
+}
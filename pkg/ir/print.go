@@ -0,0 +1,142 @@
+package ir
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Fprint writes a readable listing of the module to w.
+func Fprint(w io.Writer, m *Module) error {
+	var sb strings.Builder
+	for _, d := range m.Data {
+		writeData(&sb, d)
+		sb.WriteString("\n")
+	}
+	for i, f := range m.Functions {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(f.String())
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeData(sb *strings.Builder, d *Data) {
+	fmt.Fprintf(sb, "data @%s %s", d.Name, d.Section)
+	if d.Global {
+		sb.WriteString(" global")
+	}
+	fmt.Fprintf(sb, " size %d align %d\n", d.Size, d.Align)
+	for _, item := range d.Items {
+		switch item.Kind {
+		case DataBytes:
+			fmt.Fprintf(sb, "  bytes %s\n", strconv.Quote(string(item.Bytes)))
+		case DataHalf:
+			fmt.Fprintf(sb, "  half %d\n", item.Int)
+		case DataWord:
+			fmt.Fprintf(sb, "  word %d\n", item.Int)
+		case DataAddress:
+			addr := &Addr{Symbol: item.Symbol, Offset: int(item.Int)}
+			fmt.Fprintf(sb, "  address %s\n", addr)
+		case DataSpace:
+			fmt.Fprintf(sb, "  space %d\n", item.Int)
+		}
+	}
+}
+
+func (f *Function) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "func @%s(", f.Name)
+	for i, param := range f.Params {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if param.Type == Void {
+			fmt.Fprintf(&sb, "[%d] %s", param.Size, param)
+		} else {
+			fmt.Fprintf(&sb, "%s %s", param.Type, param)
+		}
+	}
+	if f.Variadic {
+		if len(f.Params) > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("...")
+	}
+	fmt.Fprintf(&sb, ") %s", f.Return)
+	if f.Global {
+		sb.WriteString(" global")
+	}
+	sb.WriteString("\n")
+
+	for _, slot := range f.Slots {
+		fmt.Fprintf(&sb, "  slot %s size %d align %d\n", slot, slot.Size, slot.Align)
+	}
+	for _, block := range f.Blocks {
+		fmt.Fprintf(&sb, "%s:", block)
+		if block.Name != "" {
+			fmt.Fprintf(&sb, " ; %s", block.Name)
+		}
+		sb.WriteString("\n")
+		for _, in := range block.Instrs {
+			fmt.Fprintf(&sb, "  %s\n", in)
+		}
+	}
+	return sb.String()
+}
+
+func (in *Instr) String() string {
+	var sb strings.Builder
+	if in.Dst != nil {
+		fmt.Fprintf(&sb, "%s = ", in.Dst)
+	}
+	sb.WriteString(in.Op.String())
+	if in.Unsigned {
+		sb.WriteString(".u")
+	}
+	if in.Volatile {
+		sb.WriteString(" volatile")
+	}
+
+	switch in.Op {
+	case OpJump:
+		fmt.Fprintf(&sb, " %s", in.Targets[0])
+		return sb.String()
+	case OpBranch:
+		fmt.Fprintf(&sb, " %s, %s, %s", in.Args[0], in.Targets[0], in.Targets[1])
+		return sb.String()
+	case OpCopyBlock:
+		fmt.Fprintf(&sb, " %s, %s, size %d align %d", in.Args[0], in.Args[1], in.Size, in.Align)
+		return sb.String()
+	case OpCall, OpTailCall:
+		fmt.Fprintf(&sb, " %s %s(", in.Type, in.Args[0])
+		for i, arg := range in.Args[1:] {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if i < len(in.Call.Aggregates) && in.Call.Aggregates[i].Size != 0 {
+				fmt.Fprintf(&sb, "[%d] ", in.Call.Aggregates[i].Size)
+			}
+			sb.WriteString(arg.String())
+		}
+		sb.WriteString(")")
+		return sb.String()
+	}
+
+	if in.Type != Void {
+		fmt.Fprintf(&sb, " %s", in.Type)
+	}
+	for i, arg := range in.Args {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, " %s", arg)
+	}
+	if in.Offset != 0 {
+		fmt.Fprintf(&sb, " %+d", in.Offset)
+	}
+	return sb.String()
+}
@@ -0,0 +1,141 @@
+package mips
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// argument is the size and alignment of an argument in the argument area.
+type argument struct {
+	size  int
+	align int
+	// float is set for floating point arguments, which may be passed in the
+	// floating point registers.
+	float bool
+}
+
+// valueArgument returns the argument for a value of type t.
+func valueArgument(t ir.Type) argument {
+	switch t {
+	case ir.F64:
+		return argument{size: 8, align: 8, float: true}
+	case ir.F32:
+		return argument{size: 4, align: 4, float: true}
+	}
+	return argument{size: 4, align: 4}
+}
+
+// paramArguments returns the arguments of the parameters of f.
+func paramArguments(f *ir.Function) []argument {
+	var args []argument
+	for _, param := range f.Params {
+		arg := argument{size: param.Size, align: param.Align, float: param.Type.IsFloat()}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// callArguments returns the arguments passed by a call.
+func callArguments(in *ir.Instr) []argument {
+	var args []argument
+	for i, arg := range in.Args[1:] {
+		if aggregate := in.Call.Aggregates[i]; aggregate.Size != 0 {
+			args = append(args, argument{size: aggregate.Size, align: aggregate.Align})
+			continue
+		}
+		args = append(args, valueArgument(arg.Type()))
+	}
+	return args
+}
+
+// argumentSlots returns the offset of each argument in the argument area, and
+// the number of bytes used by the arguments.
+func argumentSlots(args []argument) (offsets []int, size int) {
+	for _, arg := range args {
+		align := arg.align
+		if align < 4 {
+			align = 4
+		}
+		size = alignTo(size, align)
+		offsets = append(offsets, size)
+		size += alignTo(arg.size, 4)
+	}
+	return offsets, size
+}
+
+// slotOffset returns the offset of a parameter within its slot in the
+// argument area. Arguments smaller than a word are passed in the low bytes of
+// their word, which come last as MIPS is big-endian.
+func slotOffset(param *ir.Slot) int {
+	if param.Size < 4 && param.Type != ir.Void {
+		return 4 - param.Size
+	}
+	return 0
+}
+
+// argumentAreaSize returns the size of the argument area needed for
+// arguments taking size bytes. There is always room to store $a0-$a3, and the
+// stack pointer is kept 8 byte aligned.
+func argumentAreaSize(size int) int {
+	if size < 16 {
+		size = 16
+	}
+	return alignTo(size, 8)
+}
+
+// fpArgumentRegister returns the floating point register that argument i is
+// passed in, or 0 if it's passed in the integer registers or on the stack.
+func fpArgumentRegister(args []argument, i int) int {
+	if i > 1 || !args[0].float || !args[i].float {
+		return 0
+	}
+	return 12 + 2*i
+}
+
+func alignTo(n, align int) int {
+	return (n + align - 1) / align * align
+}
+
+// frame is the layout of the stack frame of a function.
+type frame struct {
+	// size is the number of bytes the stack pointer is moved down by.
+	size int
+	// used is the number of bytes below the frame pointer taken by the saved
+	// registers, slots and register homes.
+	used int
+
+	// slots holds the offset of each slot from the frame pointer.
+	slots map[*ir.Slot]int
+
+	// params holds the offsets of the parameters from the frame pointer,
+	// and paramSize the number of bytes they take.
+	params    []int
+	paramSize int
+}
+
+// newFrame lays out the parameters and slots of f, leaving room for the
+// saved $ra and $fp.
+func newFrame(f *ir.Function) *frame {
+	fr := &frame{used: 8, slots: map[*ir.Slot]int{}}
+	fr.params, fr.paramSize = argumentSlots(paramArguments(f))
+	for i, param := range f.Params {
+		fr.slots[param] = fr.params[i] + slotOffset(param)
+	}
+	for _, slot := range f.Slots {
+		fr.slots[slot] = fr.allocate(slot.Size, slot.Align)
+	}
+	return fr
+}
+
+// allocate returns the offset from the frame pointer of size bytes of space
+// in the frame.
+func (fr *frame) allocate(size, align int) int {
+	if align < 1 {
+		align = 1
+	}
+	fr.used = alignTo(fr.used+size, align)
+	return -fr.used
+}
+
+// finish sets the size of the frame, which ends with an argument area of
+// argumentArea bytes.
+func (fr *frame) finish(argumentArea int) {
+	fr.size = alignTo(fr.used+argumentArea, 8)
+}
@@ -0,0 +1,145 @@
+// Package mips generates MIPS I assembly from the IR.
+//
+// Functions use the MIPS o32 calling convention, so they can call and be
+// called by code from other compilers.
+//
+// The caller passes the arguments in an argument area at the bottom of its
+// frame. Each argument takes at least one word, doubles are aligned to 8
+// bytes, and the area is always at least 16 bytes long. The first 16 bytes are
+// also passed in $a0-$a3, except that if the first argument is floating point
+// it's passed in $f12, as is the second in $f14 if both are.
+//
+// The callee's frame looks like this, with $fp holding the value of $sp on
+// entry to the function:
+//
+//	argument n   [fp + ...]
+//	argument 1   [fp + 0]   <- fp
+//	saved $ra    [fp - 4]
+//	saved $fp    [fp - 8]
+//	slots
+//	registers
+//	argument area           <- sp
+//
+// Structures are passed by value, copied into as many words of the argument
+// area as they need. A function returning a structure is passed the address to
+// copy it to as a hidden first argument, and returns that address in $v0.
+//
+// The callee stores the argument registers into the caller's argument area, so
+// that every parameter has an address. A variadic function stores all four, so
+// va_arg can read every argument from the argument area. Unnamed arguments are
+// never passed in the floating point registers.
+package mips
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+// Generate writes the assembly for the module m to w. If peephole is set, the
+// assembly for each function is rewritten by Peephole.
+func Generate(w io.Writer, m *ir.Module, peephole bool) error {
+	bw := bufio.NewWriter(w)
+	section := ""
+	for _, d := range m.Data {
+		section = writeData(bw, d, section)
+	}
+	if len(m.Functions) > 0 {
+		switchSection(bw, section, ".text")
+	}
+	for _, f := range m.Functions {
+		g := newFunctionGen(bw, f)
+		g.peephole = peephole
+		g.generate()
+	}
+	return bw.Flush()
+}
+
+func write(w io.Writer, format string, args ...interface{}) {
+	fmt.Fprintf(w, format, args...)
+	io.WriteString(w, "\n")
+}
+
+// switchSection writes the directive to switch to section, unless it's
+// already current, the section the assembly is in. It returns the section
+// which is current afterwards.
+func switchSection(w io.Writer, current, section string) string {
+	switch {
+	case section == current:
+	case section == ".rodata":
+		// Unlike .text, .data and .bss, .rodata has no directive of its own.
+		write(w, ".section %s", section)
+	default:
+		write(w, "%s", section)
+	}
+	return section
+}
+
+// writeData writes out a global object, starting in the current section and
+// returning the section it ends in.
+func writeData(w io.Writer, d *ir.Data, current string) string {
+	if d.Section == ir.SectionCommon {
+		write(w, ".comm %s,%d,%d", d.Name, d.Size, d.Align)
+		return current
+	}
+
+	current = switchSection(w, current, d.Section.String())
+	if d.Global {
+		write(w, ".globl %s", d.Name)
+	}
+	write(w, ".align %d", alignmentPower(d.Align))
+	write(w, ".type %s, @object", d.Name)
+	write(w, ".size %s, %d", d.Name, d.Size)
+	write(w, "%s:", d.Name)
+	if d.Section == ir.SectionBSS {
+		write(w, "  .space %d", d.Size)
+	}
+	for _, item := range d.Items {
+		switch item.Kind {
+		case ir.DataBytes:
+			write(w, "  .ascii %s", asciiString(item.Bytes))
+		case ir.DataHalf:
+			write(w, "  .half %d", item.Int)
+		case ir.DataWord:
+			write(w, "  .word %d", item.Int)
+		case ir.DataAddress:
+			write(w, "  .word %s", symbolOffset(item.Symbol, int(item.Int)))
+		case ir.DataSpace:
+			write(w, "  .space %d", item.Int)
+		}
+	}
+	return current
+}
+
+// asciiString quotes value for .ascii, writing every byte as an escape.
+func asciiString(value []byte) string {
+	var sb strings.Builder
+	sb.WriteString("\"")
+	for _, b := range value {
+		fmt.Fprintf(&sb, "\\x%02x", b)
+	}
+	sb.WriteString("\"")
+	return sb.String()
+}
+
+// symbolOffset returns the assembler expression for the address offset bytes
+// past symbol.
+func symbolOffset(symbol string, offset int) string {
+	if offset == 0 {
+		return symbol
+	}
+	return fmt.Sprintf("%s%+d", symbol, offset)
+}
+
+// alignmentPower returns the power of two given to .align for an alignment in
+// bytes.
+func alignmentPower(align int) int {
+	power := 0
+	for 1<<power < align {
+		power++
+	}
+	return power
+}
@@ -0,0 +1,43 @@
+package mips
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+func TestGenerateSections(t *testing.T) {
+	m := &ir.Module{}
+	for _, d := range []struct {
+		name    string
+		section ir.Section
+	}{
+		{"a", ir.SectionData},
+		{"b", ir.SectionData},
+		{".L.string.0", ir.SectionRodata},
+		{".L.string.1", ir.SectionRodata},
+		{"c", ir.SectionCommon},
+		{"d", ir.SectionBSS},
+		{"e", ir.SectionData},
+	} {
+		m.Data = append(m.Data, &ir.Data{Name: d.name, Section: d.section, Size: 4, Align: 4})
+	}
+	var asm bytes.Buffer
+	if err := Generate(&asm, m, false); err != nil {
+		t.Fatal(err)
+	}
+	directives := regexp.MustCompile(`(?m)^(\.data|\.bss|\.text|\.section .*|\.comm .*|.*:)$`).FindAllString(asm.String(), -1)
+	want := []string{
+		".data", "a:", "b:",
+		".section .rodata", ".L.string.0:", ".L.string.1:",
+		".comm c,4,4",
+		".bss", "d:",
+		".data", "e:",
+	}
+	if strings.Join(directives, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", directives, want)
+	}
+}
@@ -0,0 +1,779 @@
+package mips
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Peephole rewrites the assembly written by Generate, removing the redundant
+// instructions left by selecting instructions for one IR instruction at a
+// time. It forwards values stored to the frame to the loads which read them
+// back, collapses moves, folds constants into immediate operands and removes
+// jumps and branches to the next instruction. Every rewrite which drops the
+// value of a register first checks that nothing can read it. Loads and
+// stores of the operands in volatile, the frame slots of volatile objects,
+// are never forwarded or removed.
+//
+// The result is in the assembler's noreorder mode: the delay slot of each
+// jump and branch is filled with the instruction before it where that's safe
+// and with a nop otherwise, and nops are added wherever MIPS I needs one
+// between two instructions.
+func Peephole(asm []byte, volatile map[string]bool) []byte {
+	p := &peephole{volatile: volatile}
+	for _, text := range strings.Split(strings.TrimSuffix(string(asm), "\n"), "\n") {
+		p.lines = append(p.lines, parseLine(text))
+	}
+	for changed := true; changed; {
+		changed = false
+		p.indexLabels()
+		for i := 0; i < len(p.lines); i++ {
+			if p.rewrite(i) {
+				changed = true
+			}
+		}
+		p.removeDeleted()
+	}
+	p.fillDelaySlots()
+	p.avoidHazards()
+
+	var out bytes.Buffer
+	out.WriteString(".set noreorder\n")
+	for _, l := range p.lines {
+		out.WriteString(l.String())
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// asmLine is one line of assembly: a label, a directive or an instruction.
+type asmLine struct {
+	// text is the whole of a label or directive.
+	text string
+	// op and args are the mnemonic and operands of an instruction, with the
+	// registers given by their names.
+	op   string
+	args []string
+
+	deleted bool
+}
+
+func parseLine(text string) *asmLine {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, ".") || strings.HasSuffix(trimmed, ":") || trimmed == "" {
+		return &asmLine{text: text}
+	}
+	parts := strings.SplitN(trimmed, " ", 2)
+	l := &asmLine{op: parts[0]}
+	if len(parts) == 2 {
+		for _, arg := range strings.Split(parts[1], ",") {
+			l.args = append(l.args, registerName(strings.TrimSpace(arg)))
+		}
+	}
+	return l
+}
+
+func (l *asmLine) String() string {
+	if l.op == "" {
+		return l.text
+	}
+	if len(l.args) == 0 {
+		return l.op
+	}
+	return l.op + " " + strings.Join(l.args, ", ")
+}
+
+func (l *asmLine) isInstr() bool {
+	return l.op != ""
+}
+
+// label returns the name defined by a label line.
+func (l *asmLine) label() (string, bool) {
+	text := strings.TrimSpace(l.text)
+	if l.op != "" || !strings.HasSuffix(text, ":") {
+		return "", false
+	}
+	return strings.TrimSuffix(text, ":"), true
+}
+
+var registerNames = [32]string{
+	"$zero", "$at", "$v0", "$v1", "$a0", "$a1", "$a2", "$a3",
+	"$t0", "$t1", "$t2", "$t3", "$t4", "$t5", "$t6", "$t7",
+	"$s0", "$s1", "$s2", "$s3", "$s4", "$s5", "$s6", "$s7",
+	"$t8", "$t9", "$k0", "$k1", "$gp", "$sp", "$fp", "$ra",
+}
+
+// registerName returns the name of a numbered integer register, such as $a0
+// for $4, so that each register is written one way. Other operands are
+// returned unchanged.
+func registerName(arg string) string {
+	if n, err := strconv.Atoi(strings.TrimPrefix(arg, "$")); err == nil && strings.HasPrefix(arg, "$") && n >= 0 && n < 32 {
+		return registerNames[n]
+	}
+	return arg
+}
+
+func isIntRegister(arg string) bool {
+	return strings.HasPrefix(arg, "$") && !strings.HasPrefix(arg, "$f")
+}
+
+// memoryBase returns the base register of a memory operand such as -8($fp).
+func memoryBase(arg string) (string, bool) {
+	open := strings.LastIndexByte(arg, '(')
+	if open < 0 || !strings.HasSuffix(arg, ")") {
+		return "", false
+	}
+	return registerName(arg[open+1 : len(arg)-1]), true
+}
+
+// setsFirst holds the instructions whose first operand is the register they
+// set, and which only read their other operands.
+var setsFirst = map[string]bool{
+	"addu": true, "addiu": true, "subu": true, "and": true, "andi": true,
+	"or": true, "ori": true, "xor": true, "xori": true, "nor": true,
+	"sll": true, "srl": true, "sra": true, "sllv": true, "srlv": true,
+	"srav": true, "slt": true, "slti": true, "sltu": true, "sltiu": true,
+	"lw": true, "lh": true, "lhu": true, "lb": true, "lbu": true, "lwc1": true,
+	"lui": true, "li": true, "move": true, "mflo": true, "mfhi": true,
+	"mfc1": true,
+}
+
+// pure holds the instructions in setsFirst which can be removed if the
+// register they set isn't used. Loads are left alone, as they may be from a
+// volatile object.
+var pure = map[string]bool{
+	"addu": true, "addiu": true, "subu": true, "and": true, "andi": true,
+	"or": true, "ori": true, "xor": true, "xori": true, "nor": true,
+	"sll": true, "srl": true, "sra": true, "sllv": true, "srlv": true,
+	"srav": true, "slt": true, "slti": true, "sltu": true, "sltiu": true,
+	"lui": true, "li": true, "move": true, "mflo": true, "mfhi": true,
+}
+
+var stores = map[string]bool{"sw": true, "sh": true, "sb": true, "swc1": true}
+
+var branches = map[string]bool{
+	"beq": true, "bne": true, "bgez": true, "bgtz": true, "blez": true,
+	"bltz": true, "bc1t": true, "bc1f": true,
+}
+
+// argumentRegisters are read by calls and tail calls.
+var argumentRegisters = []string{"$a0", "$a1", "$a2", "$a3", "$f12", "$f13", "$f14", "$f15"}
+
+// sets returns the register set by l, if it's an instruction in setsFirst
+// setting an integer register.
+func (l *asmLine) sets() (string, bool) {
+	if !setsFirst[l.op] || len(l.args) == 0 || !isIntRegister(l.args[0]) {
+		return "", false
+	}
+	return l.args[0], true
+}
+
+// reads returns the operands of l which are registers it reads, including
+// the base registers of memory operands. The integer multiply and divide
+// instructions read $hi and $lo through mflo and mfhi, which are treated as
+// registers too.
+func (l *asmLine) reads() []string {
+	var regs []string
+	args := l.args
+	if setsFirst[l.op] || l.op == "mtc1" {
+		// mtc1 sets its second operand.
+		if l.op == "mtc1" {
+			args = args[:1]
+		} else {
+			args = args[1:]
+		}
+	}
+	for _, arg := range args {
+		if base, ok := memoryBase(arg); ok {
+			regs = append(regs, base)
+		} else if strings.HasPrefix(arg, "$") {
+			regs = append(regs, arg)
+		}
+	}
+	switch l.op {
+	case "mflo":
+		regs = append(regs, "$lo")
+	case "mfhi":
+		regs = append(regs, "$hi")
+	case "jal", "jalr":
+		regs = append(regs, argumentRegisters...)
+	}
+	return regs
+}
+
+func (l *asmLine) readsRegister(reg string) bool {
+	for _, r := range l.reads() {
+		if r == reg {
+			return true
+		}
+	}
+	return false
+}
+
+// isExit reports whether l leaves the function, by returning or by a tail
+// call to another function.
+func (l *asmLine) isExit() bool {
+	switch l.op {
+	case "jr":
+		return true
+	case "j":
+		return !strings.HasPrefix(l.args[0], ".L")
+	}
+	return false
+}
+
+// isTemporary reports whether reg is one of the registers which aren't
+// preserved across calls and aren't used to return values, so that it's dead
+// when a function exits and after a call.
+func isTemporary(reg string) bool {
+	return strings.HasPrefix(reg, "$t")
+}
+
+type peephole struct {
+	lines    []*asmLine
+	labels   map[string]int
+	volatile map[string]bool
+}
+
+func (p *peephole) indexLabels() {
+	p.labels = map[string]int{}
+	for i, l := range p.lines {
+		if name, ok := l.label(); ok {
+			p.labels[name] = i
+		}
+	}
+}
+
+func (p *peephole) removeDeleted() {
+	lines := p.lines[:0]
+	for _, l := range p.lines {
+		if !l.deleted {
+			lines = append(lines, l)
+		}
+	}
+	p.lines = lines
+}
+
+// next returns the index of the first instruction after line i which hasn't
+// been deleted, if there are no labels or directives before it.
+func (p *peephole) next(i int) (int, bool) {
+	for j := i + 1; j < len(p.lines); j++ {
+		switch {
+		case p.lines[j].deleted:
+		case p.lines[j].isInstr():
+			return j, true
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// prev returns the index of the last instruction before line i in the same
+// way as next.
+func (p *peephole) prev(i int) (int, bool) {
+	for j := i - 1; j >= 0; j-- {
+		switch {
+		case p.lines[j].deleted:
+		case p.lines[j].isInstr():
+			return j, true
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// liveAfter reports whether the value of the integer register reg after line
+// i may be read, following every path from it until reg is set again.
+func (p *peephole) liveAfter(i int, reg string) bool {
+	visited := map[int]bool{}
+	work := []int{i + 1}
+	for len(work) > 0 {
+		j := work[len(work)-1]
+		work = work[:len(work)-1]
+	path:
+		for ; j < len(p.lines); j++ {
+			if visited[j] {
+				break
+			}
+			visited[j] = true
+			l := p.lines[j]
+			switch {
+			case l.deleted:
+				continue
+			case !l.isInstr():
+				if _, ok := l.label(); ok {
+					continue
+				}
+				// The end of the function, which always ends with a jump.
+				return true
+			case l.readsRegister(reg):
+				return true
+			case l.isExit():
+				if !isTemporary(reg) {
+					return true
+				}
+				break path
+			case l.op == "jal" || l.op == "jalr":
+				if isTemporary(reg) || reg == "$v0" || reg == "$v1" {
+					break path
+				}
+			case l.op == "j":
+				target, ok := p.labels[l.args[0]]
+				if !ok {
+					return true
+				}
+				work = append(work, target)
+				break path
+			case branches[l.op]:
+				target, ok := p.labels[l.args[len(l.args)-1]]
+				if !ok {
+					return true
+				}
+				work = append(work, target)
+			case !setsFirst[l.op] && !stores[l.op] && l.op != "mult" && l.op != "multu" &&
+				l.op != "div" && l.op != "divu" && l.op != "mtc1" && !strings.Contains(l.op, "."):
+				// An instruction which isn't understood might read reg in
+				// some other way.
+				return true
+			}
+			if r, ok := l.sets(); ok && r == reg {
+				break path
+			}
+		}
+	}
+	return false
+}
+
+// rewrite applies the first rule which matches the instruction at line i,
+// reporting whether it changed anything.
+func (p *peephole) rewrite(i int) bool {
+	l := p.lines[i]
+	if l.deleted || !l.isInstr() {
+		return false
+	}
+	return p.canonicalMove(l) ||
+		p.removeSelfMove(l) ||
+		p.removeJumpToNext(i) ||
+		p.forwardStore(i) ||
+		p.coalesceMove(i) ||
+		p.forwardMove(i) ||
+		p.foldImmediate(i) ||
+		p.removeDeadCode(i)
+}
+
+// canonicalMove turns additions of zero into moves, which the other rules
+// understand.
+func (p *peephole) canonicalMove(l *asmLine) bool {
+	if len(l.args) != 3 || !isIntRegister(l.args[0]) {
+		return false
+	}
+	src := ""
+	switch l.op {
+	case "addu", "or", "xor":
+		if l.args[1] == "$zero" {
+			src = l.args[2]
+		} else if l.args[2] == "$zero" {
+			src = l.args[1]
+		}
+	case "subu":
+		if l.args[2] == "$zero" {
+			src = l.args[1]
+		}
+	case "addiu", "ori", "xori", "sll", "srl", "sra":
+		if l.args[2] == "0" {
+			src = l.args[1]
+		}
+	}
+	if src == "" {
+		return false
+	}
+	l.op, l.args = "move", []string{l.args[0], src}
+	return true
+}
+
+func (p *peephole) removeSelfMove(l *asmLine) bool {
+	if l.op == "move" && l.args[0] == l.args[1] {
+		l.deleted = true
+		return true
+	}
+	return false
+}
+
+// removeJumpToNext removes a jump or branch to the label straight after it.
+func (p *peephole) removeJumpToNext(i int) bool {
+	l := p.lines[i]
+	if l.op != "j" && !branches[l.op] {
+		return false
+	}
+	target := l.args[len(l.args)-1]
+	for j := i + 1; j < len(p.lines); j++ {
+		next := p.lines[j]
+		if next.deleted {
+			continue
+		}
+		name, ok := next.label()
+		if !ok {
+			return false
+		}
+		if name == target {
+			l.deleted = true
+			return true
+		}
+	}
+	return false
+}
+
+// forwardStore replaces loads of a frame slot which has just been stored to,
+// or loaded, with a move from the register holding its value. The frame can
+// only be changed by a store, which ends the search.
+func (p *peephole) forwardStore(i int) bool {
+	l := p.lines[i]
+	if l.op != "sw" && l.op != "lw" {
+		return false
+	}
+	value, mem := l.args[0], l.args[1]
+	base, ok := memoryBase(mem)
+	if !ok || (base != "$fp" && base != "$sp") || value == base || p.volatile[mem] {
+		return false
+	}
+
+	changed := false
+	for j, ok := p.next(i); ok; j, ok = p.next(j) {
+		next := p.lines[j]
+		if p.accessesVolatile(next) {
+			break
+		}
+		if next.op == "lw" && next.args[1] == mem {
+			if next.args[0] == value {
+				next.deleted = true
+			} else {
+				next.op, next.args = "move", []string{next.args[0], value}
+			}
+			changed = true
+		}
+		if stores[next.op] || next.op == "j" || next.op == "jal" || next.op == "jalr" || next.isExit() || branches[next.op] {
+			break
+		}
+		if r, ok := next.sets(); ok && (r == value || r == base) {
+			break
+		}
+		if !setsFirst[next.op] && next.op != "mult" && next.op != "multu" && next.op != "div" && next.op != "divu" {
+			break
+		}
+	}
+	return changed
+}
+
+// accessesVolatile reports whether l loads or stores a volatile object in the
+// frame.
+func (p *peephole) accessesVolatile(l *asmLine) bool {
+	for _, arg := range l.args {
+		if p.volatile[arg] {
+			return true
+		}
+	}
+	return false
+}
+
+// coalesceMove has the instruction computing the value copied by a move set
+// the destination of the move instead, when nothing else reads the value.
+func (p *peephole) coalesceMove(i int) bool {
+	l := p.lines[i]
+	if l.op != "move" || !isIntRegister(l.args[1]) || l.args[1] == "$zero" {
+		return false
+	}
+	j, ok := p.prev(i)
+	if !ok {
+		return false
+	}
+	prev := p.lines[j]
+	if r, ok := prev.sets(); !ok || r != l.args[1] || p.liveAfter(i, r) {
+		return false
+	}
+	prev.args[0] = l.args[0]
+	l.deleted = true
+	return true
+}
+
+// forwardMove has the instruction after a move read its source instead of
+// its destination, when nothing else reads the destination.
+func (p *peephole) forwardMove(i int) bool {
+	l := p.lines[i]
+	if l.op != "move" {
+		return false
+	}
+	dst, src := l.args[0], l.args[1]
+	j, ok := p.next(i)
+	if !ok {
+		return false
+	}
+	next := p.lines[j]
+	if !next.readsRegister(dst) || next.op == "jal" || next.op == "jalr" {
+		return false
+	}
+	if r, ok := next.sets(); !(ok && r == dst) && p.liveAfter(j, dst) {
+		return false
+	}
+	if !setsFirst[next.op] && !stores[next.op] && !branches[next.op] && next.op != "mult" && next.op != "multu" && next.op != "div" && next.op != "divu" && next.op != "mtc1" {
+		return false
+	}
+	first := 0
+	if setsFirst[next.op] {
+		first = 1
+	}
+	for k := first; k < len(next.args); k++ {
+		arg := next.args[k]
+		if arg == dst {
+			next.args[k] = src
+		} else if base, ok := memoryBase(arg); ok && base == dst {
+			next.args[k] = arg[:strings.LastIndexByte(arg, '(')] + "(" + src + ")"
+		}
+	}
+	l.deleted = true
+	return true
+}
+
+// immediateForms are the instructions with an immediate operand used in
+// place of each instruction reading two registers, and whether the
+// immediate is zero extended rather than sign extended.
+var immediateForms = map[string]struct {
+	op          string
+	zeroExtends bool
+	commutes    bool
+}{
+	"addu": {"addiu", false, true},
+	"subu": {"addiu", false, false},
+	"and":  {"andi", true, true},
+	"or":   {"ori", true, true},
+	"xor":  {"xori", true, true},
+	"slt":  {"slti", false, false},
+	"sltu": {"sltiu", false, false},
+	"sllv": {"sll", false, false},
+	"srlv": {"srl", false, false},
+	"srav": {"sra", false, false},
+}
+
+// foldImmediate folds a constant loaded with li into the immediate operand
+// of the instruction after it, when nothing else reads the constant.
+func (p *peephole) foldImmediate(i int) bool {
+	l := p.lines[i]
+	if l.op != "li" {
+		return false
+	}
+	reg := l.args[0]
+	c, err := strconv.ParseInt(l.args[1], 0, 64)
+	if err != nil {
+		return false
+	}
+	j, ok := p.next(i)
+	if !ok {
+		return false
+	}
+	next := p.lines[j]
+	form, ok := immediateForms[next.op]
+	if !ok || len(next.args) != 3 {
+		return false
+	}
+
+	x, y := next.args[1], next.args[2]
+	if x == reg && form.commutes {
+		x, y = y, x
+	}
+	if x == reg || y != reg {
+		return false
+	}
+	if next.args[0] != reg && p.liveAfter(j, reg) {
+		return false
+	}
+	switch {
+	case next.op == "subu":
+		c = -c
+	case strings.HasSuffix(next.op, "v"):
+		c &= 31
+	}
+	if form.zeroExtends && (c < 0 || c > 0xFFFF) || !form.zeroExtends && (c < -0x8000 || c > 0x7FFF) {
+		return false
+	}
+	next.op, next.args = form.op, []string{next.args[0], x, strconv.FormatInt(c, 10)}
+	l.deleted = true
+	return true
+}
+
+// removeDeadCode removes instructions setting a register which nothing reads.
+func (p *peephole) removeDeadCode(i int) bool {
+	l := p.lines[i]
+	r, ok := l.sets()
+	if !ok || !pure[l.op] || r == "$sp" || r == "$fp" || p.liveAfter(i, r) {
+		return false
+	}
+	l.deleted = true
+	return true
+}
+
+// delayed holds the instructions other than those in branches which are
+// followed by a delay slot.
+var delayed = map[string]bool{"j": true, "jal": true, "jalr": true, "jr": true}
+
+// fillDelaySlots moves the instruction before each jump and branch into its
+// delay slot, or puts a nop there if that instruction can't be moved.
+func (p *peephole) fillDelaySlots() {
+	var lines []*asmLine
+	for _, l := range p.lines {
+		if !branches[l.op] && !delayed[l.op] {
+			lines = append(lines, l)
+			continue
+		}
+		slot := &asmLine{op: "nop"}
+		if n := len(lines); n > 0 && canFillDelaySlot(lines[n-1], l) &&
+			(n < 2 || !branches[lines[n-2].op] && !delayed[lines[n-2].op]) {
+			slot = lines[n-1]
+			lines = lines[:n-1]
+		}
+		lines = append(lines, l, slot)
+	}
+	p.lines = lines
+}
+
+// canFillDelaySlot reports whether l can be moved from before the jump or
+// branch to its delay slot. It must be a single machine instruction which
+// doesn't set anything the jump reads, and it mustn't be one which MIPS I
+// needs to be followed by an unrelated instruction, as the instruction after
+// a delay slot depends on the path taken.
+func canFillDelaySlot(l, jump *asmLine) bool {
+	if !l.isInstr() || !(setsFirst[l.op] || stores[l.op]) {
+		return false
+	}
+	switch l.op {
+	case "lw", "lh", "lhu", "lb", "lbu", "lwc1", "mflo", "mfhi", "mfc1":
+		return false
+	}
+	for _, arg := range l.args {
+		if !isSingleInstrOperand(l.op, arg) {
+			return false
+		}
+	}
+
+	reads := jump.args
+	if jump.op == "jal" {
+		reads = nil
+	}
+	if r, ok := l.sets(); ok {
+		for _, arg := range reads {
+			if arg == r {
+				return false
+			}
+		}
+	}
+	if jump.op == "jal" || jump.op == "jalr" {
+		// The return address is set by the jump itself.
+		for _, arg := range l.args {
+			if arg == "$ra" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isSingleInstrOperand reports whether arg can be encoded in a single
+// instruction op, rather than needing the assembler to expand op into
+// several.
+func isSingleInstrOperand(op, arg string) bool {
+	if strings.HasPrefix(arg, "$") || strings.HasPrefix(arg, "%") {
+		return true
+	}
+	if open := strings.LastIndexByte(arg, '('); open >= 0 {
+		arg = arg[:open]
+		if arg == "" || strings.HasPrefix(arg, "%") {
+			return true
+		}
+	} else if stores[op] {
+		// A store to a symbol, which is expanded to load its address.
+		return false
+	}
+	c, err := strconv.ParseInt(arg, 0, 64)
+	if err != nil {
+		return false
+	}
+	switch op {
+	case "andi", "ori", "xori", "lui":
+		return c >= 0 && c <= 0xFFFF
+	}
+	return c >= -0x8000 && c <= 0x7FFF
+}
+
+// avoidHazards adds nops where MIPS I needs them: between a load, mfc1 or
+// mtc1 and an instruction reading the register it sets, between a
+// comparison and a branch on its result, and between mflo or mfhi and a
+// multiply or divide less than two instructions later. None of these
+// instructions are in a delay slot, so the nops are never added between a
+// jump and its delay slot.
+func (p *peephole) avoidHazards() {
+	var lines []*asmLine
+	for i, l := range p.lines {
+		lines = append(lines, l)
+		if !l.isInstr() {
+			continue
+		}
+		next := p.followingInstrs(i, 2)
+		nops := 0
+		switch l.op {
+		case "lw", "lh", "lhu", "lb", "lbu", "lwc1", "mfc1", "mtc1":
+			dst := l.args[0]
+			if l.op == "mtc1" {
+				dst = l.args[1]
+			}
+			if len(next) > 0 && readsSameRegister(next[0], dst) {
+				nops = 1
+			}
+		case "mflo", "mfhi":
+			for k, n := range next {
+				switch n.op {
+				case "mult", "multu", "div", "divu":
+					if nops < 2-k {
+						nops = 2 - k
+					}
+				}
+			}
+		default:
+			if strings.HasPrefix(l.op, "c.") && len(next) > 0 && (next[0].op == "bc1t" || next[0].op == "bc1f") {
+				nops = 1
+			}
+		}
+		for ; nops > 0; nops-- {
+			lines = append(lines, &asmLine{op: "nop"})
+		}
+	}
+	p.lines = lines
+}
+
+// followingInstrs returns up to n of the instructions after line i, passing
+// over labels and directives.
+func (p *peephole) followingInstrs(i, n int) []*asmLine {
+	var instrs []*asmLine
+	for j := i + 1; j < len(p.lines) && len(instrs) < n; j++ {
+		if p.lines[j].isInstr() {
+			instrs = append(instrs, p.lines[j])
+		}
+	}
+	return instrs
+}
+
+// readsSameRegister reports whether l reads reg. A floating point register
+// is taken to be read by an instruction reading either register of the pair
+// holding a double.
+func readsSameRegister(l *asmLine, reg string) bool {
+	for _, r := range l.reads() {
+		if r == reg {
+			return true
+		}
+		x, errX := strconv.Atoi(strings.TrimPrefix(r, "$f"))
+		y, errY := strconv.Atoi(strings.TrimPrefix(reg, "$f"))
+		if strings.HasPrefix(r, "$f") && strings.HasPrefix(reg, "$f") && errX == nil && errY == nil && x/2 == y/2 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package mips
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPeephole(t *testing.T) {
+	tests := []struct {
+		name     string
+		asm      []string
+		volatile map[string]bool
+		want     []string
+	}{
+		{
+			name: "store forwarded to loads",
+			asm: []string{
+				"sw $t0, -12($fp)",
+				"lw $t1, -12($fp)",
+				"lw $t2, -12($fp)",
+				"addu $v0, $t1, $t2",
+				"jr $ra",
+			},
+			want: []string{
+				"sw $t0, -12($fp)",
+				"jr $ra",
+				"addu $v0, $t0, $t0",
+			},
+		},
+		{
+			name: "volatile slot",
+			asm: []string{
+				"sw $t0, -12($fp)",
+				"lw $t1, -12($fp)",
+				"lw $t2, -12($fp)",
+				"addu $v0, $t1, $t2",
+				"jr $ra",
+			},
+			volatile: map[string]bool{"-12($fp)": true},
+			want: []string{
+				"sw $t0, -12($fp)",
+				"lw $t1, -12($fp)",
+				"lw $t2, -12($fp)",
+				"jr $ra",
+				"addu $v0, $t1, $t2",
+			},
+		},
+		{
+			name: "not forwarded past a volatile access",
+			asm: []string{
+				"sw $t0, -12($fp)",
+				"lw $t1, -16($fp)",
+				"lw $t2, -12($fp)",
+				"addu $v0, $t1, $t2",
+				"jr $ra",
+			},
+			volatile: map[string]bool{"-16($fp)": true},
+			want: []string{
+				"sw $t0, -12($fp)",
+				"lw $t1, -16($fp)",
+				"lw $t2, -12($fp)",
+				"jr $ra",
+				"addu $v0, $t1, $t2",
+			},
+		},
+		{
+			name: "moves collapsed",
+			asm: []string{
+				"move $t7, $v0",
+				"addu $a0, $zero, $t7",
+				"jal f",
+				"jr $ra",
+			},
+			want: []string{
+				"jal f",
+				"move $a0, $v0",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "immediate folded",
+			asm: []string{
+				"li $t1, 3",
+				"addu $v0, $t0, $t1",
+				"jr $ra",
+			},
+			want: []string{
+				"jr $ra",
+				"addiu $v0, $t0, 3",
+			},
+		},
+		{
+			name: "immediate too large to fold",
+			asm: []string{
+				"li $v0, 70000",
+				"jr $ra",
+			},
+			want: []string{
+				"li $v0, 70000",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "jump to next label",
+			asm: []string{
+				"j .L1",
+				".L1:",
+				"jr $ra",
+			},
+			want: []string{
+				".L1:",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "delay slot filled",
+			asm: []string{
+				"addiu $v0, $v0, 1",
+				"beq $a0, $zero, .L1",
+				"addiu $v0, $v0, 2",
+				".L1:",
+				"jr $ra",
+			},
+			want: []string{
+				"beq $a0, $zero, .L1",
+				"addiu $v0, $v0, 1",
+				"addiu $v0, $v0, 2",
+				".L1:",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "branch reads the register set before it",
+			asm: []string{
+				"slt $t0, $a0, $a1",
+				"bne $t0, $zero, .L1",
+				"move $v0, $a0",
+				".L1:",
+				"jr $ra",
+			},
+			want: []string{
+				"slt $t0, $a0, $a1",
+				"bne $t0, $zero, .L1",
+				"nop",
+				"move $v0, $a0",
+				".L1:",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "return address read before a call",
+			asm: []string{
+				"move $s0, $ra",
+				"jal f",
+				"jr $ra",
+			},
+			want: []string{
+				"move $s0, $ra",
+				"jal f",
+				"nop",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "load delay",
+			asm: []string{
+				"lw $t0, 0($a0)",
+				"beq $t0, $zero, .L1",
+				"jr $ra",
+				".L1:",
+				"jr $ra",
+			},
+			want: []string{
+				"lw $t0, 0($a0)",
+				"nop",
+				"beq $t0, $zero, .L1",
+				"nop",
+				"jr $ra",
+				"nop",
+				".L1:",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "hi and lo read before a multiply",
+			asm: []string{
+				"mflo $v1",
+				"mult $a0, $a1",
+				"mflo $v0",
+				"jr $ra",
+			},
+			want: []string{
+				"mflo $v1",
+				"nop",
+				"nop",
+				"mult $a0, $a1",
+				"mflo $v0",
+				"jr $ra",
+				"nop",
+			},
+		},
+		{
+			name: "branch on a comparison",
+			asm: []string{
+				"c.lt.d $f0, $f2",
+				"bc1t .L1",
+				"jr $ra",
+				".L1:",
+				"jr $ra",
+			},
+			want: []string{
+				"c.lt.d $f0, $f2",
+				"nop",
+				"bc1t .L1",
+				"nop",
+				"jr $ra",
+				"nop",
+				".L1:",
+				"jr $ra",
+				"nop",
+			},
+		},
+	}
+	for _, test := range tests {
+		asm := strings.Join(test.asm, "\n") + "\n"
+		got := string(Peephole([]byte(asm), test.volatile))
+		want := ".set noreorder\n" + strings.Join(test.want, "\n") + "\n"
+		if got != want {
+			t.Errorf("%s: got\n%swant\n%s", test.name, got, want)
+		}
+	}
+}
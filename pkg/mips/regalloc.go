@@ -0,0 +1,313 @@
+package mips
+
+import (
+	"sort"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+// The registers virtual registers are allocated to. The rest are reserved:
+// $t0-$t2, $v1, $f4, $f6 and $f8 are scratch registers for the instruction
+// selector, and $v0, $f0, $a0-$a3, $f12 and $f14 pass arguments and results.
+//
+// Floating point values are always given an even register, and the odd
+// register above it holds the high half of doubles.
+var (
+	callerSavedRegs   = []string{"$t3", "$t4", "$t5", "$t6", "$t7", "$t8", "$t9"}
+	calleeSavedRegs   = []string{"$s0", "$s1", "$s2", "$s3", "$s4", "$s5", "$s6", "$s7"}
+	callerSavedFPRegs = []string{"$f10", "$f16", "$f18"}
+	calleeSavedFPRegs = []string{"$f20", "$f22", "$f24", "$f26", "$f28", "$f30"}
+)
+
+// interval is the range of instruction positions over which a virtual
+// register may be live.
+type interval struct {
+	reg        *ir.Reg
+	start, end int
+	// crossesCall is set if the register is live across a call, so it must
+	// be kept in a callee saved register or in memory.
+	crossesCall bool
+	loc         *location
+}
+
+// bitset is a set of virtual register IDs.
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, n/64+1)
+}
+
+func (s bitset) add(i int) {
+	s[i/64] |= 1 << uint(i%64)
+}
+
+func (s bitset) remove(i int) {
+	s[i/64] &^= 1 << uint(i%64)
+}
+
+func (s bitset) has(i int) bool {
+	return s[i/64]&(1<<uint(i%64)) != 0
+}
+
+// union adds the members of t to s, and reports whether s changed.
+func (s bitset) union(t bitset) bool {
+	changed := false
+	for i := range s {
+		if merged := s[i] | t[i]; merged != s[i] {
+			s[i] = merged
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (s bitset) each(f func(i int)) {
+	for i, word := range s {
+		for bit := 0; word != 0; bit++ {
+			if word&1 != 0 {
+				f(i*64 + bit)
+			}
+			word >>= 1
+		}
+	}
+}
+
+// liveness returns the registers live on entry to and exit from each block of
+// f.
+func liveness(f *ir.Function) (liveIn, liveOut map[*ir.Block]bitset) {
+	n := f.NumRegs() + 1
+	uses := map[*ir.Block]bitset{}
+	defs := map[*ir.Block]bitset{}
+	liveIn = map[*ir.Block]bitset{}
+	liveOut = map[*ir.Block]bitset{}
+	for _, block := range f.Blocks {
+		use, def := newBitset(n), newBitset(n)
+		for _, in := range block.Instrs {
+			for _, r := range in.Uses() {
+				if !def.has(r.ID) {
+					use.add(r.ID)
+				}
+			}
+			if in.Dst != nil {
+				def.add(in.Dst.ID)
+			}
+		}
+		uses[block], defs[block] = use, def
+		liveIn[block], liveOut[block] = newBitset(n), newBitset(n)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := len(f.Blocks) - 1; i >= 0; i-- {
+			block := f.Blocks[i]
+			out := liveOut[block]
+			for _, succ := range block.Successors() {
+				if in, ok := liveIn[succ]; ok {
+					out.union(in)
+				}
+			}
+			in := newBitset(n)
+			in.union(out)
+			defs[block].each(in.remove)
+			in.union(uses[block])
+			if liveIn[block].union(in) {
+				changed = true
+			}
+		}
+	}
+	return liveIn, liveOut
+}
+
+// liveIntervals returns the live interval of each register used in f, in
+// order of their starts. Instruction i of the layout is at position 2i, where
+// its operands are read, and writes its result at 2i+1.
+func liveIntervals(f *ir.Function) []*interval {
+	liveIn, liveOut := liveness(f)
+	intervals := make([]*interval, f.NumRegs()+1)
+	extend := func(r *ir.Reg, pos int) {
+		it := intervals[r.ID]
+		if it == nil {
+			intervals[r.ID] = &interval{reg: r, start: pos, end: pos}
+			return
+		}
+		if pos < it.start {
+			it.start = pos
+		}
+		if pos > it.end {
+			it.end = pos
+		}
+	}
+
+	regs := make([]*ir.Reg, f.NumRegs()+1)
+	var calls []int
+	pos := 0
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			for _, r := range in.Uses() {
+				regs[r.ID] = r
+				extend(r, pos)
+			}
+			if in.Dst != nil {
+				regs[in.Dst.ID] = in.Dst
+				extend(in.Dst, pos+1)
+			}
+			if in.Op == ir.OpCall {
+				calls = append(calls, pos)
+			}
+			pos += 2
+		}
+	}
+	// Registers live into or out of a block are live throughout it.
+	pos = 0
+	for _, block := range f.Blocks {
+		if len(block.Instrs) == 0 {
+			continue
+		}
+		start, end := pos, pos+2*len(block.Instrs)-1
+		liveIn[block].each(func(id int) {
+			if regs[id] != nil {
+				extend(regs[id], start)
+			}
+		})
+		liveOut[block].each(func(id int) {
+			if regs[id] != nil {
+				extend(regs[id], end)
+			}
+		})
+		pos += 2 * len(block.Instrs)
+	}
+
+	var res []*interval
+	for _, it := range intervals {
+		if it == nil {
+			continue
+		}
+		for _, call := range calls {
+			if it.start < call && it.end > call+1 {
+				it.crossesCall = true
+				break
+			}
+		}
+		res = append(res, it)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].start < res[j].start
+	})
+	return res
+}
+
+// allocator assigns machine registers to virtual registers by linear scan.
+type allocator struct {
+	frame *frame
+	// free holds the unused integer and floating point registers.
+	free map[bool][]string
+	// active holds the intervals which have been given registers and are
+	// still live, in order of their ends.
+	active []*interval
+	// saved holds the callee saved registers which have been used, which
+	// the function has to save and restore.
+	saved []string
+}
+
+// allocateRegisters gives each virtual register of f a location: a machine
+// register, or a home in the frame if it's spilled. It returns the
+// locations, indexed by register ID, and the callee saved registers used.
+func allocateRegisters(f *ir.Function, fr *frame) ([]*location, []string) {
+	a := &allocator{
+		frame: fr,
+		free: map[bool][]string{
+			false: append(append([]string{}, callerSavedRegs...), calleeSavedRegs...),
+			true:  append(append([]string{}, callerSavedFPRegs...), calleeSavedFPRegs...),
+		},
+	}
+	locations := make([]*location, f.NumRegs()+1)
+	for _, it := range liveIntervals(f) {
+		a.expire(it.start)
+		a.allocate(it)
+		locations[it.reg.ID] = it.loc
+	}
+	return locations, a.saved
+}
+
+// expire frees the registers of the intervals which end before pos.
+func (a *allocator) expire(pos int) {
+	for len(a.active) > 0 && a.active[0].end < pos {
+		it := a.active[0]
+		a.active = a.active[1:]
+		float := it.reg.Typ.IsFloat()
+		a.free[float] = append(a.free[float], it.loc.reg)
+	}
+}
+
+func (a *allocator) allocate(it *interval) {
+	float := it.reg.Typ.IsFloat()
+	// Caller saved registers are preferred, as they don't have to be saved,
+	// but they can't hold values which are live across a call.
+	for _, calleeSaved := range []bool{false, true} {
+		if it.crossesCall && !calleeSaved {
+			continue
+		}
+		for i, reg := range a.free[float] {
+			if isCalleeSaved(reg) != calleeSaved {
+				continue
+			}
+			a.free[float] = append(a.free[float][:i:i], a.free[float][i+1:]...)
+			a.assign(it, reg)
+			return
+		}
+	}
+
+	// Take the register of the interval which ends last, if it outlives
+	// this one.
+	for i := len(a.active) - 1; i >= 0; i-- {
+		victim := a.active[i]
+		if victim.reg.Typ.IsFloat() != float || (it.crossesCall && !isCalleeSaved(victim.loc.reg)) {
+			continue
+		}
+		if victim.end <= it.end {
+			break
+		}
+		a.active = append(a.active[:i:i], a.active[i+1:]...)
+		reg := victim.loc.reg
+		victim.loc.reg = ""
+		victim.loc.offset = a.spillSlot(victim.reg)
+		a.assign(it, reg)
+		return
+	}
+	it.loc = &location{offset: a.spillSlot(it.reg)}
+}
+
+func (a *allocator) assign(it *interval, reg string) {
+	if it.loc == nil {
+		it.loc = &location{}
+	}
+	it.loc.reg = reg
+	if isCalleeSaved(reg) && !contains(a.saved, reg) {
+		a.saved = append(a.saved, reg)
+	}
+	i := sort.Search(len(a.active), func(i int) bool {
+		return a.active[i].end > it.end
+	})
+	a.active = append(a.active, nil)
+	copy(a.active[i+1:], a.active[i:])
+	a.active[i] = it
+}
+
+// spillSlot returns the offset of the home of r in the frame.
+func (a *allocator) spillSlot(r *ir.Reg) int {
+	size := r.Typ.Size()
+	return a.frame.allocate(size, size)
+}
+
+func isCalleeSaved(reg string) bool {
+	return contains(calleeSavedRegs, reg) || contains(calleeSavedFPRegs, reg)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
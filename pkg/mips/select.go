@@ -0,0 +1,911 @@
+package mips
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+// Scratch registers, which are never allocated to virtual registers. $v1 is
+// used to build constants in the middle of a sequence of instructions.
+const (
+	scratch0 = "$t0"
+	scratch1 = "$t1"
+	scratch2 = "$t2"
+
+	fpScratch0 = "$f4"
+	fpScratch1 = "$f6"
+	fpScratch2 = "$f8"
+)
+
+// location is where a virtual register is kept: in a machine register, or in
+// its home in the frame.
+type location struct {
+	// reg is the machine register holding the value, or "" if it's kept in
+	// its home, offset bytes from the frame pointer.
+	reg    string
+	offset int
+}
+
+// functionGen generates the code for a function.
+type functionGen struct {
+	out io.Writer
+	// w holds the body of the function, which is generated before the
+	// prologue as the size of the frame isn't known until it's done.
+	w *bytes.Buffer
+
+	f     *ir.Function
+	frame *frame
+
+	// locations holds where each virtual register is kept, indexed by ID.
+	locations []*location
+	// saved holds the callee saved registers used by the function, and
+	// savedOffsets where they're saved in the frame.
+	saved        []string
+	savedOffsets []int
+	// argumentArea is the size of the largest argument area needed by a
+	// call.
+	argumentArea int
+	// next is the block laid out after the one being generated, which it
+	// can fall through to.
+	next *ir.Block
+	// labels is the number of local labels created so far.
+	labels int
+	// peephole is set if the function is rewritten by Peephole once it has
+	// been generated.
+	peephole bool
+}
+
+func newFunctionGen(w io.Writer, f *ir.Function) *functionGen {
+	g := &functionGen{
+		out:   w,
+		w:     new(bytes.Buffer),
+		f:     f,
+		frame: newFrame(f),
+	}
+	g.locations, g.saved = allocateRegisters(f, g.frame)
+	for _, reg := range g.saved {
+		size := 4
+		if reg[1] == 'f' {
+			size = 8
+		}
+		g.savedOffsets = append(g.savedOffsets, g.frame.allocate(size, size))
+	}
+	return g
+}
+
+func (g *functionGen) emit(format string, args ...interface{}) {
+	write(g.w, format, args...)
+}
+
+// generate writes out the function.
+func (g *functionGen) generate() {
+	for i, block := range g.f.Blocks {
+		g.next = nil
+		if i+1 < len(g.f.Blocks) {
+			g.next = g.f.Blocks[i+1]
+		}
+		g.emit("%s:", g.blockLabel(block))
+		for _, in := range block.Instrs {
+			g.instr(in)
+		}
+	}
+	g.frame.finish(g.argumentArea)
+
+	out := g.out
+	var asm bytes.Buffer
+	if g.peephole {
+		g.out = &asm
+	}
+	g.prologue()
+	io.Copy(g.out, g.w)
+	g.epilogue()
+	if g.peephole {
+		out.Write(Peephole(asm.Bytes(), g.volatileOperands()))
+	}
+}
+
+// volatileOperands returns the memory operands of the frame slots which are
+// accessed as volatile objects, with one for each byte of them so that any
+// access to part of a slot is found.
+func (g *functionGen) volatileOperands() map[string]bool {
+	operands := map[string]bool{}
+	for _, block := range g.f.Blocks {
+		for _, in := range block.Instrs {
+			if (in.Op != ir.OpLoad && in.Op != ir.OpStore) || !in.Volatile {
+				continue
+			}
+			addr, ok := in.Args[0].(*ir.Addr)
+			if !ok || addr.Slot == nil {
+				continue
+			}
+			m := memory{base: "$fp", offset: g.frame.slots[addr.Slot]}
+			for offset := 0; offset < addr.Slot.Size; offset++ {
+				operands[m.at(offset)] = true
+			}
+		}
+	}
+	return operands
+}
+
+func (g *functionGen) prologue() {
+	w, name, size := g.out, g.f.Name, g.frame.size
+	if g.f.Global {
+		write(w, ".globl %s", name)
+	}
+	write(w, ".type %s, @function", name)
+	write(w, "%s:", name)
+
+	write(w, "addiu $sp, $sp, %d", -size)
+	write(w, "sw $ra, %d($sp)", size-4)
+	write(w, "sw $fp, %d($sp)", size-8)
+	write(w, "addiu $fp, $sp, %d", size)
+	g.saveRegisters(w, "sw", "swc1")
+
+	// Store the arguments passed in registers to their slots.
+	spill := g.frame.paramSize
+	if g.f.Variadic {
+		spill = 16
+	}
+	for offset := 0; offset < spill && offset < 16; offset += 4 {
+		write(w, "sw $%d, %d($fp)", 4+offset/4, offset)
+	}
+	args := paramArguments(g.f)
+	for i, param := range g.f.Params {
+		if reg := fpArgumentRegister(args, i); reg != 0 {
+			storeFloat(w, param.Type, fmt.Sprintf("$f%d", reg), memory{base: "$fp", offset: g.frame.params[i]})
+		}
+	}
+}
+
+func (g *functionGen) epilogue() {
+	w := g.out
+	write(w, "%s:", g.returnLabel())
+	g.popFrame(w)
+	write(w, "jr $ra")
+	write(w, ".size %s, .-%s", g.f.Name, g.f.Name)
+}
+
+// popFrame restores the registers saved by the prologue and frees the frame.
+// Anything below $sp may be overwritten at any time, such as by a signal
+// handler, so the frame is freed down to the saved $fp before restoring it,
+// and only then freed completely. The size of the frame isn't known yet when
+// a tail call is generated, so the saved registers are found through $fp.
+func (g *functionGen) popFrame(w io.Writer) {
+	g.saveRegisters(w, "lw", "lwc1")
+	write(w, "lw $ra, -4($fp)")
+	write(w, "addiu $sp, $fp, -8")
+	write(w, "lw $fp, 0($sp)")
+	write(w, "addiu $sp, $sp, 8")
+}
+
+// saveRegisters saves or restores the callee saved registers used by the
+// function, with the given instructions for integer and floating point
+// registers.
+func (g *functionGen) saveRegisters(w io.Writer, instr, fpInstr string) {
+	for i, reg := range g.saved {
+		m := memory{base: "$fp", offset: g.savedOffsets[i]}
+		if reg[1] == 'f' {
+			write(w, "%s %s, %s", fpInstr, reg, m.at(4))
+			write(w, "%s %s, %s", fpInstr, oddHalf(reg), m.at(0))
+			continue
+		}
+		write(w, "%s %s, %s", instr, reg, m.at(0))
+	}
+}
+
+func (g *functionGen) blockLabel(block *ir.Block) string {
+	return fmt.Sprintf(".L%s_%d", g.f.Name, block.ID)
+}
+
+func (g *functionGen) returnLabel() string {
+	return fmt.Sprintf(".L%s_return", g.f.Name)
+}
+
+// newLabel returns a new local label for a branch within the code for an
+// instruction.
+func (g *functionGen) newLabel() string {
+	g.labels++
+	return fmt.Sprintf(".L%s_l%d", g.f.Name, g.labels)
+}
+
+// location returns where r is kept.
+func (g *functionGen) location(r *ir.Reg) *location {
+	return g.locations[r.ID]
+}
+
+// memory is a memory operand: offset bytes past the address in base, or past
+// the address of symbol if there is one, whose high half is in base.
+type memory struct {
+	base   string
+	symbol string
+	offset int
+}
+
+// at returns the operand for the address extra bytes past m.
+func (m memory) at(extra int) string {
+	if m.symbol != "" {
+		return fmt.Sprintf("%%lo(%s)(%s)", symbolOffset(m.symbol, m.offset+extra), m.base)
+	}
+	return fmt.Sprintf("%d(%s)", m.offset+extra, m.base)
+}
+
+// memory returns the memory operand for addr plus offset, using scratch for
+// the base address if it has to be built.
+func (g *functionGen) memory(addr ir.Value, offset int, scratch string) memory {
+	if a, ok := addr.(*ir.Addr); ok {
+		if a.Slot != nil {
+			return memory{base: "$fp", offset: g.frame.slots[a.Slot] + a.Offset + offset}
+		}
+		g.emit("lui %s, %%hi(%s)", scratch, symbolOffset(a.Symbol, a.Offset+offset))
+		return memory{base: scratch, symbol: a.Symbol, offset: a.Offset + offset}
+	}
+	return memory{base: g.intValue(addr, scratch), offset: offset}
+}
+
+// intValue returns the register holding the integer v, loading it into
+// scratch if it isn't in one.
+func (g *functionGen) intValue(v ir.Value, scratch string) string {
+	switch v := v.(type) {
+	case *ir.Reg:
+		loc := g.location(v)
+		if loc.reg != "" {
+			return loc.reg
+		}
+		g.emit("lw %s, %d($fp)", scratch, loc.offset)
+	case *ir.Const:
+		if v.Int == 0 {
+			return "$zero"
+		}
+		g.emit("li %s, %d", scratch, int32(v.Int))
+	case *ir.Addr:
+		if v.Slot != nil {
+			g.emit("addiu %s, $fp, %d", scratch, g.frame.slots[v.Slot]+v.Offset)
+			break
+		}
+		symbol := symbolOffset(v.Symbol, v.Offset)
+		g.emit("lui %s, %%hi(%s)", scratch, symbol)
+		g.emit("addiu %s, %s, %%lo(%s)", scratch, scratch, symbol)
+	}
+	return scratch
+}
+
+// floatValue returns the register holding the floating point v, loading it
+// into scratch if it isn't in one.
+func (g *functionGen) floatValue(v ir.Value, scratch string) string {
+	switch v := v.(type) {
+	case *ir.Reg:
+		loc := g.location(v)
+		if loc.reg != "" {
+			return loc.reg
+		}
+		loadFloat(g.w, v.Typ, scratch, memory{base: "$fp", offset: loc.offset})
+	case *ir.Const:
+		if v.Typ == ir.F32 {
+			g.moveWord(scratch, math.Float32bits(float32(v.Float)))
+			break
+		}
+		bits := math.Float64bits(v.Float)
+		g.moveWord(scratch, uint32(bits))
+		g.moveWord(oddHalf(scratch), uint32(bits>>32))
+	}
+	return scratch
+}
+
+// moveWord sets the floating point register reg to the bits of word.
+func (g *functionGen) moveWord(reg string, word uint32) {
+	if word == 0 {
+		g.emit("mtc1 $zero, %s", reg)
+		return
+	}
+	g.emit("li $v1, %d", int32(word))
+	g.emit("mtc1 $v1, %s", reg)
+}
+
+// result returns the register to compute r in, which is scratch if r isn't
+// kept in a register. save must be called once it has been set.
+func (g *functionGen) result(r *ir.Reg, scratch string) string {
+	if loc := g.location(r); loc.reg != "" {
+		return loc.reg
+	}
+	return scratch
+}
+
+// save stores the value of r, computed in reg, to its home if it has one.
+func (g *functionGen) save(r *ir.Reg, reg string) {
+	loc := g.location(r)
+	if loc.reg != "" {
+		return
+	}
+	if r.Typ.IsFloat() {
+		storeFloat(g.w, r.Typ, reg, memory{base: "$fp", offset: loc.offset})
+		return
+	}
+	g.emit("sw %s, %d($fp)", reg, loc.offset)
+}
+
+// oddHalf returns the odd register holding the high half of a double in the
+// even floating point register reg.
+func oddHalf(reg string) string {
+	var n int
+	fmt.Sscanf(reg, "$f%d", &n)
+	return fmt.Sprintf("$f%d", n+1)
+}
+
+// loadFloat loads the floating point value of type t at m into reg. Doubles
+// are held with the low word in the even register, and stored high word
+// first.
+func loadFloat(w io.Writer, t ir.Type, reg string, m memory) {
+	if t == ir.F64 {
+		write(w, "lwc1 %s, %s", reg, m.at(4))
+		write(w, "lwc1 %s, %s", oddHalf(reg), m.at(0))
+		return
+	}
+	write(w, "lwc1 %s, %s", reg, m.at(0))
+}
+
+// storeFloat stores the floating point value of type t in reg to m.
+func storeFloat(w io.Writer, t ir.Type, reg string, m memory) {
+	if t == ir.F64 {
+		write(w, "swc1 %s, %s", reg, m.at(4))
+		write(w, "swc1 %s, %s", oddHalf(reg), m.at(0))
+		return
+	}
+	write(w, "swc1 %s, %s", reg, m.at(0))
+}
+
+// floatSuffix returns the format suffix of floating point instructions on
+// values of type t.
+func floatSuffix(t ir.Type) string {
+	if t == ir.F64 {
+		return "d"
+	}
+	return "s"
+}
+
+// instr generates the code for in.
+func (g *functionGen) instr(in *ir.Instr) {
+	switch in.Op {
+	case ir.OpCopy:
+		g.copy(in)
+	case ir.OpAdd, ir.OpSub, ir.OpMul, ir.OpDiv, ir.OpRem, ir.OpAnd, ir.OpOr, ir.OpXor, ir.OpShl, ir.OpShr,
+		ir.OpEq, ir.OpNe, ir.OpLt, ir.OpLe:
+		if in.Type.IsFloat() {
+			g.floatBinary(in)
+		} else {
+			g.intBinary(in)
+		}
+	case ir.OpNeg:
+		if in.Type.IsFloat() {
+			x := g.floatValue(in.Args[0], fpScratch0)
+			d := g.result(in.Dst, fpScratch0)
+			g.emit("neg.%s %s, %s", floatSuffix(in.Type), d, x)
+			g.save(in.Dst, d)
+			break
+		}
+		x := g.intValue(in.Args[0], scratch0)
+		d := g.result(in.Dst, scratch0)
+		g.emit("subu %s, $zero, %s", d, x)
+		g.save(in.Dst, d)
+	case ir.OpNot:
+		x := g.intValue(in.Args[0], scratch0)
+		d := g.result(in.Dst, scratch0)
+		g.emit("nor %s, %s, $zero", d, x)
+		g.save(in.Dst, d)
+	case ir.OpExt:
+		g.extend(in)
+	case ir.OpIToF:
+		g.intToFloat(in)
+	case ir.OpFToI:
+		g.floatToInt(in)
+	case ir.OpFConv:
+		x := g.floatValue(in.Args[0], fpScratch0)
+		d := g.result(in.Dst, fpScratch0)
+		g.emit("cvt.%s.%s %s, %s", floatSuffix(in.Type), floatSuffix(in.Args[0].Type()), d, x)
+		g.save(in.Dst, d)
+	case ir.OpLoad:
+		g.load(in)
+	case ir.OpStore:
+		g.store(in)
+	case ir.OpCopyBlock:
+		dst := g.memory(in.Args[0], 0, scratch0)
+		src := g.memory(in.Args[1], 0, scratch1)
+		g.copyBlock(dst, src, in.Size, in.Align)
+	case ir.OpCall:
+		g.call(in)
+	case ir.OpVaStart:
+		d := g.result(in.Dst, scratch0)
+		g.emit("addiu %s, $fp, %d", d, g.frame.paramSize)
+		g.save(in.Dst, d)
+	case ir.OpJump:
+		g.jump(in.Targets[0])
+	case ir.OpBranch:
+		g.branch(in)
+	case ir.OpRet:
+		g.ret(in)
+	case ir.OpTailCall:
+		g.tailCall(in)
+	default:
+		panic(fmt.Sprintf("mips: can't generate %s", in.Op))
+	}
+}
+
+func (g *functionGen) copy(in *ir.Instr) {
+	if in.Type.IsFloat() {
+		d := g.result(in.Dst, fpScratch0)
+		if x := g.floatValue(in.Args[0], d); x != d {
+			g.emit("mov.%s %s, %s", floatSuffix(in.Type), d, x)
+		}
+		g.save(in.Dst, d)
+		return
+	}
+	d := g.result(in.Dst, scratch0)
+	if x := g.intValue(in.Args[0], d); x != d {
+		g.emit("move %s, %s", d, x)
+	}
+	g.save(in.Dst, d)
+}
+
+var intInstrs = map[ir.Op][2]string{
+	ir.OpAdd: {"addu", "addu"},
+	ir.OpSub: {"subu", "subu"},
+	ir.OpAnd: {"and", "and"},
+	ir.OpOr:  {"or", "or"},
+	ir.OpXor: {"xor", "xor"},
+	ir.OpShl: {"sllv", "sllv"},
+	ir.OpShr: {"srav", "srlv"},
+	ir.OpLt:  {"slt", "sltu"},
+}
+
+// commutative reports whether the operands of op can be swapped.
+func commutative(op ir.Op) bool {
+	switch op {
+	case ir.OpAdd, ir.OpMul, ir.OpAnd, ir.OpOr, ir.OpXor, ir.OpEq, ir.OpNe:
+		return true
+	}
+	return false
+}
+
+func (g *functionGen) intBinary(in *ir.Instr) {
+	x, y := in.Args[0], in.Args[1]
+	if _, ok := x.(*ir.Const); ok && commutative(in.Op) {
+		x, y = y, x
+	}
+
+	if c, ok := y.(*ir.Const); ok && g.intImmediate(in, x, c.Int) {
+		return
+	}
+
+	xr := g.intValue(x, scratch0)
+	yr := g.intValue(y, scratch1)
+	d := g.result(in.Dst, scratch0)
+	unsigned := 0
+	if in.Unsigned {
+		unsigned = 1
+	}
+	switch in.Op {
+	case ir.OpMul:
+		g.emit("mult %s, %s", xr, yr)
+		g.emit("mflo %s", d)
+	case ir.OpDiv, ir.OpRem:
+		if in.Unsigned {
+			g.emit("divu %s, %s", xr, yr)
+		} else {
+			g.emit("div %s, %s", xr, yr)
+		}
+		if in.Op == ir.OpDiv {
+			g.emit("mflo %s", d)
+		} else {
+			g.emit("mfhi %s", d)
+		}
+	case ir.OpEq:
+		g.emit("xor %s, %s, %s", d, xr, yr)
+		g.emit("sltiu %s, %s, 1", d, d)
+	case ir.OpNe:
+		g.emit("xor %s, %s, %s", d, xr, yr)
+		g.emit("sltu %s, $zero, %s", d, d)
+	case ir.OpLe:
+		// x <= y is !(y < x).
+		g.emit("%s %s, %s, %s", intInstrs[ir.OpLt][unsigned], d, yr, xr)
+		g.emit("xori %s, %s, 1", d, d)
+	case ir.OpShl, ir.OpShr:
+		g.emit("%s %s, %s, %s", intInstrs[in.Op][unsigned], d, xr, yr)
+	default:
+		g.emit("%s %s, %s, %s", intInstrs[in.Op][unsigned], d, xr, yr)
+	}
+	g.save(in.Dst, d)
+}
+
+// intImmediate generates x op c using an immediate operand, if there's an
+// instruction for it, and reports whether it did.
+func (g *functionGen) intImmediate(in *ir.Instr, x ir.Value, c int64) bool {
+	signed16 := c >= -32768 && c <= 32767
+	unsigned16 := c >= 0 && c <= 65535
+
+	var instr string
+	switch in.Op {
+	case ir.OpAdd:
+		if !signed16 {
+			return false
+		}
+		instr = "addiu %s, %s, %d"
+	case ir.OpSub:
+		if c == -32768 || !signed16 {
+			return false
+		}
+		c = -c
+		instr = "addiu %s, %s, %d"
+	case ir.OpAnd, ir.OpOr, ir.OpXor:
+		if !unsigned16 {
+			return false
+		}
+		instr = map[ir.Op]string{ir.OpAnd: "andi", ir.OpOr: "ori", ir.OpXor: "xori"}[in.Op] + " %s, %s, %d"
+	case ir.OpShl:
+		c &= 31
+		instr = "sll %s, %s, %d"
+	case ir.OpShr:
+		c &= 31
+		instr = "sra %s, %s, %d"
+		if in.Unsigned {
+			instr = "srl %s, %s, %d"
+		}
+	case ir.OpMul:
+		// Multiplying by a power of two is a shift.
+		shift := powerOfTwo(c)
+		if shift < 0 {
+			return false
+		}
+		c = int64(shift)
+		instr = "sll %s, %s, %d"
+	case ir.OpLt:
+		if !signed16 {
+			return false
+		}
+		instr = "slti %s, %s, %d"
+		if in.Unsigned {
+			instr = "sltiu %s, %s, %d"
+		}
+	case ir.OpEq, ir.OpNe:
+		if !unsigned16 {
+			return false
+		}
+		xr := g.intValue(x, scratch0)
+		d := g.result(in.Dst, scratch0)
+		if c != 0 {
+			g.emit("xori %s, %s, %d", d, xr, c)
+			xr = d
+		}
+		if in.Op == ir.OpEq {
+			g.emit("sltiu %s, %s, 1", d, xr)
+		} else {
+			g.emit("sltu %s, $zero, %s", d, xr)
+		}
+		g.save(in.Dst, d)
+		return true
+	default:
+		return false
+	}
+
+	xr := g.intValue(x, scratch0)
+	d := g.result(in.Dst, scratch0)
+	g.emit(instr, d, xr, c)
+	g.save(in.Dst, d)
+	return true
+}
+
+// powerOfTwo returns n if c is 2 to the power n, and -1 otherwise.
+func powerOfTwo(c int64) int {
+	if c <= 0 || c&(c-1) != 0 {
+		return -1
+	}
+	n := 0
+	for c > 1 {
+		c >>= 1
+		n++
+	}
+	return n
+}
+
+var floatInstrs = map[ir.Op]string{
+	ir.OpAdd: "add",
+	ir.OpSub: "sub",
+	ir.OpMul: "mul",
+	ir.OpDiv: "div",
+	ir.OpEq:  "c.eq",
+	ir.OpNe:  "c.eq",
+	ir.OpLt:  "c.lt",
+	ir.OpLe:  "c.le",
+}
+
+func (g *functionGen) floatBinary(in *ir.Instr) {
+	suffix := floatSuffix(in.Type)
+	x := g.floatValue(in.Args[0], fpScratch0)
+	y := g.floatValue(in.Args[1], fpScratch1)
+	if !in.Op.IsCompare() {
+		d := g.result(in.Dst, fpScratch0)
+		g.emit("%s.%s %s, %s, %s", floatInstrs[in.Op], suffix, d, x, y)
+		g.save(in.Dst, d)
+		return
+	}
+
+	d := g.result(in.Dst, scratch0)
+	branch := "bc1t"
+	if in.Op == ir.OpNe {
+		branch = "bc1f"
+	}
+	done := g.newLabel()
+	g.emit("%s.%s %s, %s", floatInstrs[in.Op], suffix, x, y)
+	g.emit("li %s, 1", d)
+	g.emit("%s %s", branch, done)
+	g.emit("move %s, $zero", d)
+	g.emit("%s:", done)
+	g.save(in.Dst, d)
+}
+
+func (g *functionGen) extend(in *ir.Instr) {
+	x := g.intValue(in.Args[0], scratch0)
+	d := g.result(in.Dst, scratch0)
+	bits := 8 * in.Type.Size()
+	if in.Unsigned {
+		g.emit("andi %s, %s, %d", d, x, 1<<bits-1)
+	} else {
+		g.emit("sll %s, %s, %d", d, x, 32-bits)
+		g.emit("sra %s, %s, %d", d, d, 32-bits)
+	}
+	g.save(in.Dst, d)
+}
+
+// loadDouble sets the even floating point register reg to the double with
+// the high word high and a zero low word.
+func (g *functionGen) loadDouble(reg string, high uint32) {
+	g.emit("mtc1 $zero, %s", reg)
+	g.emit("lui $v1, %d", high>>16)
+	g.emit("mtc1 $v1, %s", oddHalf(reg))
+}
+
+func (g *functionGen) intToFloat(in *ir.Instr) {
+	x := g.intValue(in.Args[0], scratch0)
+	d := g.result(in.Dst, fpScratch0)
+	g.emit("mtc1 %s, %s", x, fpScratch1)
+	if !in.Unsigned {
+		g.emit("cvt.%s.w %s, %s", floatSuffix(in.Type), d, fpScratch1)
+		g.save(in.Dst, d)
+		return
+	}
+
+	// Integers with the top bit set come out negative, and are corrected by
+	// adding 2^32.
+	g.emit("cvt.d.w %s, %s", fpScratch1, fpScratch1)
+	skip := g.newLabel()
+	g.emit("bgez %s, %s", x, skip)
+	g.loadDouble(fpScratch2, 0x41f00000)
+	g.emit("add.d %s, %s, %s", fpScratch1, fpScratch1, fpScratch2)
+	g.emit("%s:", skip)
+	if in.Type == ir.F64 {
+		g.emit("mov.d %s, %s", d, fpScratch1)
+	} else {
+		g.emit("cvt.s.d %s, %s", d, fpScratch1)
+	}
+	g.save(in.Dst, d)
+}
+
+func (g *functionGen) floatToInt(in *ir.Instr) {
+	x := g.floatValue(in.Args[0], fpScratch0)
+	if in.Args[0].Type() == ir.F32 {
+		g.emit("cvt.d.s %s, %s", fpScratch0, x)
+		x = fpScratch0
+	}
+	d := g.result(in.Dst, scratch0)
+	if !in.Unsigned {
+		g.emit("trunc.w.d %s, %s", fpScratch1, x)
+		g.emit("mfc1 %s, %s", d, fpScratch1)
+		g.save(in.Dst, d)
+		return
+	}
+
+	// Values of 2^31 and above don't fit in a signed integer, so 2^31 is
+	// taken off before they're converted and the top bit set afterwards.
+	small, done := g.newLabel(), g.newLabel()
+	g.loadDouble(fpScratch2, 0x41e00000)
+	g.emit("c.lt.d %s, %s", x, fpScratch2)
+	g.emit("bc1t %s", small)
+	g.emit("sub.d %s, %s, %s", fpScratch1, x, fpScratch2)
+	g.emit("trunc.w.d %s, %s", fpScratch1, fpScratch1)
+	g.emit("mfc1 %s, %s", d, fpScratch1)
+	g.emit("lui $v1, 0x8000")
+	g.emit("or %s, %s, $v1", d, d)
+	g.emit("j %s", done)
+	g.emit("%s:", small)
+	g.emit("trunc.w.d %s, %s", fpScratch1, x)
+	g.emit("mfc1 %s, %s", d, fpScratch1)
+	g.emit("%s:", done)
+	g.save(in.Dst, d)
+}
+
+var loadInstrs = map[ir.Type][2]string{
+	ir.I8:  {"lb", "lbu"},
+	ir.I16: {"lh", "lhu"},
+	ir.I32: {"lw", "lw"},
+}
+
+var storeInstrs = map[ir.Type]string{
+	ir.I8:  "sb",
+	ir.I16: "sh",
+	ir.I32: "sw",
+}
+
+func (g *functionGen) load(in *ir.Instr) {
+	m := g.memory(in.Args[0], in.Offset, scratch1)
+	if in.Type.IsFloat() {
+		d := g.result(in.Dst, fpScratch0)
+		loadFloat(g.w, in.Type, d, m)
+		g.save(in.Dst, d)
+		return
+	}
+	unsigned := 0
+	if in.Unsigned {
+		unsigned = 1
+	}
+	d := g.result(in.Dst, scratch0)
+	g.emit("%s %s, %s", loadInstrs[in.Type][unsigned], d, m.at(0))
+	g.save(in.Dst, d)
+}
+
+func (g *functionGen) store(in *ir.Instr) {
+	m := g.memory(in.Args[0], in.Offset, scratch1)
+	if in.Type.IsFloat() {
+		storeFloat(g.w, in.Type, g.floatValue(in.Args[1], fpScratch0), m)
+		return
+	}
+	g.emit("%s %s, %s", storeInstrs[in.Type], g.intValue(in.Args[1], scratch0), m.at(0))
+}
+
+// copyBlock copies size bytes from src to dst, a word at a time if they're
+// aligned to words.
+func (g *functionGen) copyBlock(dst, src memory, size, align int) {
+	unit := 4
+	if align < unit {
+		unit = align
+	}
+	if unit < 1 {
+		unit = 1
+	}
+	loadInstr := map[int]string{1: "lbu", 2: "lhu", 4: "lw"}[unit]
+	storeInstr := map[int]string{1: "sb", 2: "sh", 4: "sw"}[unit]
+	for offset := 0; offset < size; offset += unit {
+		g.emit("%s %s, %s", loadInstr, scratch2, src.at(offset))
+		g.emit("%s %s, %s", storeInstr, scratch2, dst.at(offset))
+	}
+}
+
+func (g *functionGen) call(in *ir.Instr) {
+	g.passArguments(in)
+	if symbol, ok := directCallee(in); ok {
+		g.emit("jal %s", symbol)
+	} else {
+		g.loadCallee(in)
+		g.emit("jalr $t9")
+	}
+
+	if in.Dst == nil {
+		return
+	}
+	if in.Dst.Typ.IsFloat() {
+		d := g.result(in.Dst, "$f0")
+		if d != "$f0" {
+			g.emit("mov.%s %s, $f0", floatSuffix(in.Dst.Typ), d)
+		}
+		g.save(in.Dst, d)
+		return
+	}
+	d := g.result(in.Dst, "$v0")
+	if d != "$v0" {
+		g.emit("move %s, $v0", d)
+	}
+	g.save(in.Dst, d)
+}
+
+// passArguments puts the arguments of the call in into the argument area,
+// and the argument registers.
+func (g *functionGen) passArguments(in *ir.Instr) {
+	args := callArguments(in)
+	offsets, size := argumentSlots(args)
+	if area := argumentAreaSize(size); area > g.argumentArea {
+		g.argumentArea = area
+	}
+
+	for i, arg := range in.Args[1:] {
+		slot := memory{base: "$sp", offset: offsets[i]}
+		switch {
+		case in.Call.Aggregates[i].Size != 0:
+			aggregate := in.Call.Aggregates[i]
+			g.copyBlock(slot, g.memory(arg, 0, scratch1), aggregate.Size, aggregate.Align)
+		case arg.Type().IsFloat():
+			storeFloat(g.w, arg.Type(), g.floatValue(arg, fpScratch0), slot)
+		default:
+			g.emit("sw %s, %s", g.intValue(arg, scratch0), slot.at(0))
+		}
+	}
+
+	for offset := 0; offset < size && offset < 16; offset += 4 {
+		g.emit("lw $%d, %d($sp)", 4+offset/4, offset)
+	}
+	named := args
+	if in.Call.Named >= 0 {
+		named = args[:in.Call.Named]
+	}
+	for i := range named {
+		if reg := fpArgumentRegister(named, i); reg != 0 {
+			loadFloat(g.w, in.Args[1+i].Type(), fmt.Sprintf("$f%d", reg), memory{base: "$sp", offset: offsets[i]})
+		}
+	}
+}
+
+// directCallee returns the name of the function called by in, if it's called
+// by name.
+func directCallee(in *ir.Instr) (string, bool) {
+	a, ok := in.Args[0].(*ir.Addr)
+	if !ok || a.Symbol == "" || a.Offset != 0 {
+		return "", false
+	}
+	return a.Symbol, true
+}
+
+// loadCallee puts the address of the function called by in into $t9.
+func (g *functionGen) loadCallee(in *ir.Instr) {
+	if fn := g.intValue(in.Args[0], "$t9"); fn != "$t9" {
+		g.emit("move $t9, %s", fn)
+	}
+}
+
+// tailCall calls the function in place of this one, which returns straight
+// to our caller. The arguments are all in registers, so the frame can be freed
+// before jumping to it.
+func (g *functionGen) tailCall(in *ir.Instr) {
+	g.passArguments(in)
+	symbol, direct := directCallee(in)
+	if !direct {
+		g.loadCallee(in)
+	}
+	g.popFrame(g.w)
+	if direct {
+		g.emit("j %s", symbol)
+	} else {
+		g.emit("jr $t9")
+	}
+}
+
+func (g *functionGen) jump(target *ir.Block) {
+	if target != g.next {
+		g.emit("j %s", g.blockLabel(target))
+	}
+}
+
+func (g *functionGen) branch(in *ir.Instr) {
+	c := g.intValue(in.Args[0], scratch0)
+	ifTrue, ifFalse := in.Targets[0], in.Targets[1]
+	if ifTrue == g.next {
+		g.emit("beq %s, $zero, %s", c, g.blockLabel(ifFalse))
+		return
+	}
+	g.emit("bne %s, $zero, %s", c, g.blockLabel(ifTrue))
+	g.jump(ifFalse)
+}
+
+func (g *functionGen) ret(in *ir.Instr) {
+	if len(in.Args) != 0 {
+		if in.Type.IsFloat() {
+			if x := g.floatValue(in.Args[0], "$f0"); x != "$f0" {
+				g.emit("mov.%s $f0, %s", floatSuffix(in.Type), x)
+			}
+		} else if x := g.intValue(in.Args[0], "$v0"); x != "$v0" {
+			g.emit("move $v0, %s", x)
+		}
+	}
+	if g.next != nil {
+		g.emit("j %s", g.returnLabel())
+	}
+}
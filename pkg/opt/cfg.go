@@ -0,0 +1,135 @@
+package opt
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// simplifyBranches turns branches on constants, or to the same block either
+// way, into jumps, skips over blocks which only jump somewhere else, removes
+// the blocks which can't be reached and merges blocks which can only be
+// entered from the end of the block before them.
+func simplifyBranches(f *ir.Function) bool {
+	changed := false
+	for _, block := range f.Blocks {
+		term := block.Terminator()
+		if term == nil {
+			continue
+		}
+		for i, target := range term.Targets {
+			if next := skipJumps(target); next != target {
+				term.Targets[i] = next
+				changed = true
+			}
+		}
+		if term.Op != ir.OpBranch {
+			continue
+		}
+		target := term.Targets[0]
+		switch c := term.Args[0].(type) {
+		case *ir.Const:
+			if c.Int == 0 {
+				target = term.Targets[1]
+			}
+		default:
+			if term.Targets[0] != term.Targets[1] {
+				continue
+			}
+		}
+		*term = ir.Instr{Op: ir.OpJump, Targets: []*ir.Block{target}}
+		changed = true
+	}
+
+	changed = removeUnreachableBlocks(f) || changed
+	return mergeBlocks(f) || changed
+}
+
+// skipJumps returns the block control ends up at after passing through any
+// blocks which only jump to another block.
+func skipJumps(block *ir.Block) *ir.Block {
+	seen := map[*ir.Block]bool{}
+	for len(block.Instrs) == 1 && block.Instrs[0].Op == ir.OpJump && !seen[block] {
+		seen[block] = true
+		block = block.Instrs[0].Targets[0]
+	}
+	return block
+}
+
+// removeUnreachableBlocks removes the blocks which can't be reached from the
+// entry block.
+func removeUnreachableBlocks(f *ir.Function) bool {
+	reachable := map[*ir.Block]bool{}
+	work := []*ir.Block{f.Blocks[0]}
+	reachable[f.Blocks[0]] = true
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, succ := range block.Successors() {
+			if !reachable[succ] {
+				reachable[succ] = true
+				work = append(work, succ)
+			}
+		}
+	}
+
+	blocks := f.Blocks[:0]
+	for _, block := range f.Blocks {
+		if reachable[block] {
+			blocks = append(blocks, block)
+		}
+	}
+	removed := len(blocks) != len(f.Blocks)
+	f.Blocks = blocks
+	return removed
+}
+
+// predecessors returns the blocks which can pass control to each block.
+func predecessors(f *ir.Function) map[*ir.Block][]*ir.Block {
+	preds := map[*ir.Block][]*ir.Block{}
+	for _, block := range f.Blocks {
+		for _, succ := range block.Successors() {
+			preds[succ] = append(preds[succ], block)
+		}
+	}
+	return preds
+}
+
+// mergeBlocks appends the blocks only entered by a jump from the end of one
+// other block to that block.
+func mergeBlocks(f *ir.Function) bool {
+	preds := predecessors(f)
+	merged := map[*ir.Block]bool{}
+	changed := false
+	for _, block := range f.Blocks {
+		if merged[block] {
+			continue
+		}
+		for {
+			term := block.Terminator()
+			if term == nil || term.Op != ir.OpJump {
+				break
+			}
+			succ := term.Targets[0]
+			if succ == block || succ == f.Blocks[0] || len(preds[succ]) != 1 {
+				break
+			}
+			block.Instrs = append(block.Instrs[:len(block.Instrs)-1], succ.Instrs...)
+			merged[succ] = true
+			// The successors of succ now have block as their predecessor.
+			for _, next := range succ.Successors() {
+				for i, pred := range preds[next] {
+					if pred == succ {
+						preds[next][i] = block
+					}
+				}
+			}
+			changed = true
+		}
+	}
+
+	blocks := f.Blocks[:0]
+	for _, block := range f.Blocks {
+		if !merged[block] {
+			blocks = append(blocks, block)
+		}
+	}
+	f.Blocks = blocks
+	return changed
+}
@@ -0,0 +1,139 @@
+package opt
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// propagateCopies replaces the uses of registers which hold a copy of another
+// value with that value. A register only set once, to a constant or an
+// address, always holds the same value, so it's replaced throughout the
+// function. Other copies are only followed within a block, until either
+// register is set again.
+func propagateCopies(f *ir.Function) bool {
+	defs := defCounts(f)
+	global := make([]ir.Value, f.NumRegs()+1)
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			if in.Op != ir.OpCopy || defs[in.Dst.ID] != 1 {
+				continue
+			}
+			switch v := in.Args[0].(type) {
+			case *ir.Const, *ir.Addr:
+				global[in.Dst.ID] = v
+			}
+		}
+	}
+	resolve := func(r *ir.Reg) ir.Value {
+		if v := global[r.ID]; v != nil {
+			return v
+		}
+		return r
+	}
+
+	changed := false
+	for _, block := range f.Blocks {
+		local := map[*ir.Reg]ir.Value{}
+		for _, in := range block.Instrs {
+			for i, arg := range in.Args {
+				r, ok := arg.(*ir.Reg)
+				if !ok {
+					continue
+				}
+				v := resolve(r)
+				if copied, ok := local[r]; ok {
+					v = copied
+				}
+				if v != arg {
+					in.Args[i] = v
+					changed = true
+				}
+			}
+
+			if in.Dst == nil {
+				continue
+			}
+			// Setting Dst ends the copies to and from it.
+			delete(local, in.Dst)
+			for r, v := range local {
+				if v == in.Dst {
+					delete(local, r)
+				}
+			}
+			if in.Op == ir.OpCopy && in.Args[0] != in.Dst {
+				local[in.Dst] = in.Args[0]
+			}
+		}
+	}
+	return changed
+}
+
+// coalesceCopies removes copies straight after the instruction computing the
+// value copied, by having the instruction set the destination of the copy
+// instead. The value mustn't be used anywhere else, except later in the same
+// block while the destination still holds it, where the destination is used
+// instead.
+func coalesceCopies(f *ir.Function) bool {
+	uses := useCounts(f)
+	defs := defCounts(f)
+	changed := false
+	for _, block := range f.Blocks {
+		for i := 1; i < len(block.Instrs); i++ {
+			in, prev := block.Instrs[i], block.Instrs[i-1]
+			if in.Op != ir.OpCopy || prev.Dst == nil {
+				continue
+			}
+			src, ok := in.Args[0].(*ir.Reg)
+			if !ok || src != prev.Dst || src.Typ != in.Dst.Typ || defs[src.ID] != 1 {
+				continue
+			}
+			if !renameLaterUses(block.Instrs[i+1:], src, in.Dst, uses[src.ID]-1) {
+				continue
+			}
+			uses[in.Dst.ID] += uses[src.ID] - 1
+			uses[src.ID] = 0
+			prev.Dst = in.Dst
+			removeInstr(block, i)
+			i--
+			changed = true
+		}
+	}
+	return changed
+}
+
+// renameLaterUses replaces the n uses of from in instrs with to, if they all
+// come before to is next set. It reports whether it did.
+func renameLaterUses(instrs []*ir.Instr, from, to *ir.Reg, n int) bool {
+	found := 0
+	last := -1
+	for i, in := range instrs {
+		if found == n {
+			break
+		}
+		if usesReg(in.Args, from) {
+			found += countUses(in.Args, from)
+			last = i
+		}
+		if in.Dst == to && found < n {
+			return false
+		}
+	}
+	if found != n {
+		return false
+	}
+	for _, in := range instrs[:last+1] {
+		for j, arg := range in.Args {
+			if arg == ir.Value(from) {
+				in.Args[j] = to
+			}
+		}
+	}
+	return true
+}
+
+func countUses(args []ir.Value, r *ir.Reg) int {
+	n := 0
+	for _, arg := range args {
+		if arg == ir.Value(r) {
+			n++
+		}
+	}
+	return n
+}
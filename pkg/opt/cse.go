@@ -0,0 +1,93 @@
+package opt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+// expression is an instruction computing a value which is available in reg.
+type expression struct {
+	reg  *ir.Reg
+	args []ir.Value
+	load bool
+}
+
+// eliminateCommonSubexpressions replaces instructions which compute a value
+// already computed earlier in the same block with a copy of it. Loads count
+// too, until memory may have been written to.
+func eliminateCommonSubexpressions(f *ir.Function) bool {
+	changed := false
+	for _, block := range f.Blocks {
+		available := map[string]*expression{}
+		for _, in := range block.Instrs {
+			switch in.Op {
+			case ir.OpStore, ir.OpCopyBlock, ir.OpCall:
+				for key, e := range available {
+					if e.load {
+						delete(available, key)
+					}
+				}
+			}
+
+			key, ok := expressionKey(in)
+			if ok {
+				if e, found := available[key]; found {
+					makeCopy(in, e.reg)
+					changed = true
+				}
+			}
+			if in.Dst == nil {
+				continue
+			}
+
+			// Setting Dst changes the value of the expressions using it.
+			for k, e := range available {
+				if e.reg == in.Dst || usesReg(e.args, in.Dst) {
+					delete(available, k)
+				}
+			}
+			if ok && in.Op != ir.OpCopy && !usesReg(in.Args, in.Dst) {
+				available[key] = &expression{reg: in.Dst, args: in.Args, load: in.Op == ir.OpLoad}
+			}
+		}
+	}
+	return changed
+}
+
+// expressionKey returns a string identifying the value computed by in, if
+// it's an instruction which can be replaced by an earlier one.
+func expressionKey(in *ir.Instr) (string, bool) {
+	if !isPure(in) && !(in.Op == ir.OpLoad && !in.Volatile) {
+		return "", false
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s %t %d", in.Op, in.Type, in.Dst.Typ, in.Unsigned, in.Offset)
+	args := in.Args
+	if commutative(in.Op) && args[0].String() > args[1].String() {
+		args = []ir.Value{args[1], args[0]}
+	}
+	for _, arg := range args {
+		fmt.Fprintf(&sb, " %s", arg)
+	}
+	return sb.String(), true
+}
+
+// commutative reports whether the operands of op can be swapped.
+func commutative(op ir.Op) bool {
+	switch op {
+	case ir.OpAdd, ir.OpMul, ir.OpAnd, ir.OpOr, ir.OpXor, ir.OpEq, ir.OpNe:
+		return true
+	}
+	return false
+}
+
+func usesReg(args []ir.Value, r *ir.Reg) bool {
+	for _, arg := range args {
+		if arg == ir.Value(r) {
+			return true
+		}
+	}
+	return false
+}
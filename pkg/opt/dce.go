@@ -0,0 +1,32 @@
+package opt
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// eliminateDeadCode removes the instructions whose results are never used and
+// which have no other effect, and copies of registers to themselves.
+func eliminateDeadCode(f *ir.Function) bool {
+	changed := false
+	for {
+		uses := useCounts(f)
+		removed := false
+		for _, block := range f.Blocks {
+			instrs := block.Instrs[:0]
+			for _, in := range block.Instrs {
+				dead := in.Dst != nil && !in.HasSideEffects() && uses[in.Dst.ID] == 0
+				if in.Op == ir.OpCopy && in.Args[0] == in.Dst {
+					dead = true
+				}
+				if dead {
+					removed = true
+					continue
+				}
+				instrs = append(instrs, in)
+			}
+			block.Instrs = instrs
+		}
+		if !removed {
+			return changed
+		}
+		changed = true
+	}
+}
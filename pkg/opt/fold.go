@@ -0,0 +1,238 @@
+package opt
+
+import (
+	"math"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+// foldConstants evaluates the instructions whose operands are all constants,
+// and simplifies arithmetic with an operand which leaves the other unchanged,
+// such as adding zero.
+func foldConstants(f *ir.Function) bool {
+	changed := false
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			if !isPure(in) || in.Op == ir.OpCopy {
+				continue
+			}
+			if v := fold(in); v != nil {
+				makeCopy(in, v)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// fold returns the value in computes, if it can be worked out at compile
+// time, or nil.
+func fold(in *ir.Instr) ir.Value {
+	if v := simplifyIdentity(in); v != nil {
+		return v
+	}
+	var consts []*ir.Const
+	for _, arg := range in.Args {
+		c, ok := arg.(*ir.Const)
+		if !ok {
+			return nil
+		}
+		consts = append(consts, c)
+	}
+
+	switch in.Op {
+	case ir.OpExt:
+		return ir.IntConst(extendConst(in.Type, in.Unsigned, consts[0].Int))
+	case ir.OpIToF:
+		v := float64(int32(consts[0].Int))
+		if in.Unsigned {
+			v = float64(uint32(consts[0].Int))
+		}
+		return ir.FloatConst(in.Type, v)
+	case ir.OpFToI:
+		v := math.Trunc(consts[0].Float)
+		if in.Unsigned {
+			if !(v >= 0 && v < 1<<32) {
+				return nil
+			}
+			return ir.IntConst(int64(uint32(v)))
+		}
+		if !(v >= math.MinInt32 && v <= math.MaxInt32) {
+			return nil
+		}
+		return ir.IntConst(int64(v))
+	case ir.OpFConv:
+		return ir.FloatConst(in.Type, consts[0].Float)
+	case ir.OpNeg:
+		if in.Type.IsFloat() {
+			return ir.FloatConst(in.Type, -consts[0].Float)
+		}
+		return ir.IntConst(-consts[0].Int)
+	case ir.OpNot:
+		return ir.IntConst(^consts[0].Int)
+	}
+
+	if in.Type.IsFloat() {
+		return foldFloat(in.Op, in.Type, consts[0].Float, consts[1].Float)
+	}
+	return foldInt(in.Op, in.Unsigned, int32(consts[0].Int), int32(consts[1].Int))
+}
+
+// foldInt returns x op y, or nil if it can't be worked out.
+func foldInt(op ir.Op, unsigned bool, x, y int32) ir.Value {
+	ux, uy := uint32(x), uint32(y)
+	var v int64
+	switch op {
+	case ir.OpAdd:
+		v = int64(x + y)
+	case ir.OpSub:
+		v = int64(x - y)
+	case ir.OpMul:
+		v = int64(x * y)
+	case ir.OpDiv, ir.OpRem:
+		if y == 0 || (!unsigned && x == math.MinInt32 && y == -1) {
+			return nil
+		}
+		switch {
+		case unsigned && op == ir.OpDiv:
+			v = int64(ux / uy)
+		case unsigned:
+			v = int64(ux % uy)
+		case op == ir.OpDiv:
+			v = int64(x / y)
+		default:
+			v = int64(x % y)
+		}
+	case ir.OpAnd:
+		v = int64(x & y)
+	case ir.OpOr:
+		v = int64(x | y)
+	case ir.OpXor:
+		v = int64(x ^ y)
+	case ir.OpShl:
+		v = int64(x << (uy & 31))
+	case ir.OpShr:
+		if unsigned {
+			v = int64(ux >> (uy & 31))
+		} else {
+			v = int64(x >> (uy & 31))
+		}
+	case ir.OpEq:
+		v = boolConst(x == y)
+	case ir.OpNe:
+		v = boolConst(x != y)
+	case ir.OpLt:
+		if unsigned {
+			v = boolConst(ux < uy)
+		} else {
+			v = boolConst(x < y)
+		}
+	case ir.OpLe:
+		if unsigned {
+			v = boolConst(ux <= uy)
+		} else {
+			v = boolConst(x <= y)
+		}
+	default:
+		return nil
+	}
+	return ir.IntConst(v)
+}
+
+// foldFloat returns x op y, computed in t, or nil if it can't be worked out.
+func foldFloat(op ir.Op, t ir.Type, x, y float64) ir.Value {
+	round := func(v float64) float64 {
+		if t == ir.F32 {
+			return float64(float32(v))
+		}
+		return v
+	}
+	switch op {
+	case ir.OpAdd:
+		return ir.FloatConst(t, round(x+y))
+	case ir.OpSub:
+		return ir.FloatConst(t, round(x-y))
+	case ir.OpMul:
+		return ir.FloatConst(t, round(x*y))
+	case ir.OpDiv:
+		return ir.FloatConst(t, round(x/y))
+	case ir.OpEq:
+		return ir.IntConst(boolConst(x == y))
+	case ir.OpNe:
+		return ir.IntConst(boolConst(x != y))
+	case ir.OpLt:
+		return ir.IntConst(boolConst(x < y))
+	case ir.OpLe:
+		return ir.IntConst(boolConst(x <= y))
+	}
+	return nil
+}
+
+// extendConst truncates v to t and extends it back to 32 bits.
+func extendConst(t ir.Type, unsigned bool, v int64) int64 {
+	switch {
+	case t == ir.I8 && unsigned:
+		return int64(uint8(v))
+	case t == ir.I8:
+		return int64(int8(v))
+	case t == ir.I16 && unsigned:
+		return int64(uint16(v))
+	case t == ir.I16:
+		return int64(int16(v))
+	}
+	return v
+}
+
+func boolConst(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// simplifyIdentity returns the value of integer arithmetic with an operand
+// that makes the result one of the operands or zero, or nil.
+func simplifyIdentity(in *ir.Instr) ir.Value {
+	if in.Type.IsFloat() || len(in.Args) != 2 {
+		return nil
+	}
+	x, y := in.Args[0], in.Args[1]
+	switch in.Op {
+	case ir.OpAdd, ir.OpOr, ir.OpXor:
+		if isConst(x, 0) {
+			return y
+		}
+		if isConst(y, 0) {
+			return x
+		}
+	case ir.OpSub, ir.OpShl, ir.OpShr:
+		if isConst(y, 0) {
+			return x
+		}
+	case ir.OpMul:
+		if isConst(x, 1) {
+			return y
+		}
+		if isConst(y, 1) {
+			return x
+		}
+		if isConst(x, 0) || isConst(y, 0) {
+			return ir.IntConst(0)
+		}
+	case ir.OpDiv:
+		if isConst(y, 1) {
+			return x
+		}
+	case ir.OpAnd:
+		if isConst(x, -1) {
+			return y
+		}
+		if isConst(y, -1) {
+			return x
+		}
+		if isConst(x, 0) || isConst(y, 0) {
+			return ir.IntConst(0)
+		}
+	}
+	return nil
+}
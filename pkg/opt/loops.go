@@ -0,0 +1,347 @@
+package opt
+
+import (
+	"sort"
+
+	"github.com/jpnock/see90/pkg/ir"
+)
+
+// loop is a natural loop: the blocks which can reach the end of a block
+// jumping back to the header without passing through the header.
+type loop struct {
+	header *ir.Block
+	blocks map[*ir.Block]bool
+	// preheader is the block before the loop which enters it, created the
+	// first time it's needed.
+	preheader *ir.Block
+	// enclosing holds the loops this one is nested in, which a new
+	// preheader becomes part of.
+	enclosing []*loop
+}
+
+// dominators returns the set of blocks dominating each block of f: those
+// which control must pass through to reach it from the entry block.
+func dominators(f *ir.Function) map[*ir.Block]map[*ir.Block]bool {
+	preds := predecessors(f)
+	dom := map[*ir.Block]map[*ir.Block]bool{}
+	all := map[*ir.Block]bool{}
+	for _, block := range f.Blocks {
+		all[block] = true
+	}
+	for _, block := range f.Blocks {
+		dom[block] = all
+	}
+	entry := f.Blocks[0]
+	dom[entry] = map[*ir.Block]bool{entry: true}
+
+	for changed := true; changed; {
+		changed = false
+		for _, block := range f.Blocks[1:] {
+			// A block is dominated by the blocks dominating all of its
+			// predecessors.
+			var set map[*ir.Block]bool
+			for _, pred := range preds[block] {
+				if set == nil {
+					set = map[*ir.Block]bool{}
+					for b := range dom[pred] {
+						set[b] = true
+					}
+					continue
+				}
+				for b := range set {
+					if !dom[pred][b] {
+						delete(set, b)
+					}
+				}
+			}
+			if set == nil {
+				set = map[*ir.Block]bool{}
+			}
+			set[block] = true
+			if len(set) != len(dom[block]) {
+				dom[block] = set
+				changed = true
+			}
+		}
+	}
+	return dom
+}
+
+// findLoops returns the natural loops of f, innermost first. Loops with the
+// same header are treated as one.
+func findLoops(f *ir.Function) []*loop {
+	dom := dominators(f)
+	preds := predecessors(f)
+	byHeader := map[*ir.Block]*loop{}
+	var loops []*loop
+	for _, block := range f.Blocks {
+		for _, succ := range block.Successors() {
+			if !dom[block][succ] {
+				continue
+			}
+			// block jumps back to succ, which dominates it.
+			l := byHeader[succ]
+			if l == nil {
+				l = &loop{header: succ, blocks: map[*ir.Block]bool{succ: true}}
+				byHeader[succ] = l
+				loops = append(loops, l)
+			}
+			work := []*ir.Block{block}
+			for len(work) > 0 {
+				b := work[len(work)-1]
+				work = work[:len(work)-1]
+				if l.blocks[b] {
+					continue
+				}
+				l.blocks[b] = true
+				work = append(work, preds[b]...)
+			}
+		}
+	}
+	sort.SliceStable(loops, func(i, j int) bool {
+		return len(loops[i].blocks) < len(loops[j].blocks)
+	})
+	for _, l := range loops {
+		for _, outer := range loops {
+			if outer != l && outer.blocks[l.header] {
+				l.enclosing = append(l.enclosing, outer)
+			}
+		}
+	}
+	return loops
+}
+
+// preheaderOf returns the block which all the edges entering the loop from
+// outside go through, creating one before the header if there isn't one.
+func preheaderOf(f *ir.Function, l *loop) *ir.Block {
+	if l.preheader != nil {
+		return l.preheader
+	}
+	var outside []*ir.Block
+	for _, pred := range predecessors(f)[l.header] {
+		if !l.blocks[pred] {
+			outside = append(outside, pred)
+		}
+	}
+	if len(outside) == 1 && len(outside[0].Successors()) == 1 {
+		l.preheader = outside[0]
+		return l.preheader
+	}
+
+	pre := f.NewBlock("preheader")
+	pre.Instrs = []*ir.Instr{{Op: ir.OpJump, Targets: []*ir.Block{l.header}}}
+	for _, pred := range outside {
+		term := pred.Terminator()
+		for i, target := range term.Targets {
+			if target == l.header {
+				term.Targets[i] = pre
+			}
+		}
+	}
+	for i, block := range f.Blocks {
+		if block == l.header {
+			f.InsertBlock(i, pre)
+			break
+		}
+	}
+	for _, outer := range l.enclosing {
+		outer.blocks[pre] = true
+	}
+	l.preheader = pre
+	return pre
+}
+
+// appendToPreheader adds in to the end of the preheader of l, before its
+// jump into the loop.
+func appendToPreheader(f *ir.Function, l *loop, in *ir.Instr) {
+	pre := preheaderOf(f, l)
+	insertInstr(pre, len(pre.Instrs)-1, in)
+}
+
+// loopDefs returns the number of instructions in l setting each register.
+func loopDefs(f *ir.Function, l *loop) []int {
+	counts := make([]int, f.NumRegs()+1)
+	for block := range l.blocks {
+		for _, in := range block.Instrs {
+			if in.Dst != nil {
+				counts[in.Dst.ID]++
+			}
+		}
+	}
+	return counts
+}
+
+// hoistLoopInvariants moves the computations in l whose operands don't
+// change in the loop to before it. Only instructions which are the one
+// definition of their register are moved, so that the value they leave
+// behind after the loop is the same.
+func hoistLoopInvariants(f *ir.Function, l *loop) {
+	defs := defCounts(f)
+	inLoop := loopDefs(f, l)
+	for moved := true; moved; {
+		moved = false
+		for _, block := range f.Blocks {
+			if !l.blocks[block] {
+				continue
+			}
+			for i := 0; i < len(block.Instrs); i++ {
+				in := block.Instrs[i]
+				if !isInvariant(in, defs, inLoop) {
+					continue
+				}
+				removeInstr(block, i)
+				i--
+				appendToPreheader(f, l, in)
+				inLoop[in.Dst.ID]--
+				moved = true
+			}
+		}
+	}
+}
+
+// isInvariant reports whether in computes the same value every time round a
+// loop, given the number of definitions of each register in the function and
+// in the loop.
+func isInvariant(in *ir.Instr, defs, inLoop []int) bool {
+	if !isPure(in) || defs[in.Dst.ID] != 1 {
+		return false
+	}
+	switch in.Op {
+	case ir.OpCopy:
+		// Copies are left where they are, as moving them only makes
+		// registers live for longer.
+		return false
+	case ir.OpDiv, ir.OpRem:
+		// These would be carried out even if the loop doesn't run, which
+		// mustn't divide by zero.
+		if !in.Type.IsFloat() {
+			return false
+		}
+	}
+	for _, r := range in.Uses() {
+		if inLoop[r.ID] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// inductionStep returns the amount in adds to its destination, if it's an
+// addition or subtraction of a constant from the register it sets.
+func inductionStep(in *ir.Instr) (int64, bool) {
+	if in.Dst == nil || in.Dst.Typ != ir.I32 || (in.Op != ir.OpAdd && in.Op != ir.OpSub) || in.Args[0] != ir.Value(in.Dst) {
+		return 0, false
+	}
+	c, ok := in.Args[1].(*ir.Const)
+	if !ok {
+		return 0, false
+	}
+	if in.Op == ir.OpSub {
+		return -c.Int, true
+	}
+	return c.Int, true
+}
+
+// reduceInductionVariables replaces multiplications of the basic induction
+// variables of l, those only changed in the loop by adding a constant, by a
+// constant with a register which is increased along with the variable. This
+// turns the multiplications by the element size in array indexing into
+// additions.
+func reduceInductionVariables(f *ir.Function, l *loop) {
+	defs := defCounts(f)
+	inLoop := loopDefs(f, l)
+	basic := map[*ir.Reg]bool{}
+	steps := map[*ir.Reg]int{}
+	for block := range l.blocks {
+		for _, in := range block.Instrs {
+			if _, ok := inductionStep(in); ok {
+				steps[in.Dst]++
+			}
+		}
+	}
+	for r, n := range steps {
+		if n == inLoop[r.ID] {
+			basic[r] = true
+		}
+	}
+	if len(basic) == 0 {
+		return
+	}
+
+	type derived struct {
+		iv     *ir.Reg
+		factor int64
+	}
+	reduced := map[derived]*ir.Reg{}
+	var order []derived
+	for _, block := range f.Blocks {
+		if !l.blocks[block] {
+			continue
+		}
+		for _, in := range block.Instrs {
+			if in.Dst == nil || defs[in.Dst.ID] != 1 {
+				continue
+			}
+			iv, factor, ok := scaledRegister(in)
+			if !ok || !basic[iv] {
+				continue
+			}
+			key := derived{iv, factor}
+			r := reduced[key]
+			if r == nil {
+				r = f.NewReg(ir.I32)
+				reduced[key] = r
+				order = append(order, key)
+			}
+			makeCopy(in, r)
+		}
+	}
+
+	// Each reduced register starts off as the product, and is stepped along
+	// with its induction variable.
+	for _, key := range order {
+		r := reduced[key]
+		appendToPreheader(f, l, &ir.Instr{Op: ir.OpMul, Type: ir.I32, Dst: r, Args: []ir.Value{key.iv, ir.IntConst(key.factor)}})
+	}
+	for _, block := range f.Blocks {
+		if !l.blocks[block] {
+			continue
+		}
+		for i := 0; i < len(block.Instrs); i++ {
+			step, ok := inductionStep(block.Instrs[i])
+			if !ok {
+				continue
+			}
+			iv := block.Instrs[i].Dst
+			for _, key := range order {
+				if key.iv != iv {
+					continue
+				}
+				r := reduced[key]
+				i++
+				insertInstr(block, i, &ir.Instr{Op: ir.OpAdd, Type: ir.I32, Dst: r, Args: []ir.Value{r, ir.IntConst(step * key.factor)}})
+			}
+		}
+	}
+}
+
+// scaledRegister returns the register in multiplies by a constant, and the
+// constant, if it's a multiplication or left shift by a constant.
+func scaledRegister(in *ir.Instr) (*ir.Reg, int64, bool) {
+	if in.Type != ir.I32 || (in.Op != ir.OpMul && in.Op != ir.OpShl) {
+		return nil, 0, false
+	}
+	x, y := in.Args[0], in.Args[1]
+	if _, ok := x.(*ir.Const); ok && in.Op == ir.OpMul {
+		x, y = y, x
+	}
+	r, ok := x.(*ir.Reg)
+	c, isConst := y.(*ir.Const)
+	if !ok || !isConst || r == in.Dst {
+		return nil, 0, false
+	}
+	if in.Op == ir.OpShl {
+		return r, 1 << uint(c.Int&31), true
+	}
+	return r, c.Int, true
+}
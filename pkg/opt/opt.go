@@ -0,0 +1,122 @@
+// Package opt optimises the IR.
+//
+// At level 1, local variables whose address isn't taken are kept in
+// registers, and constant folding, copy propagation, dead code elimination and
+// branch simplification are repeated until they stop finding anything to do.
+// Level 2 adds common subexpression elimination, loop invariant code motion,
+// strength reduction and tail calls. Level 0 leaves the IR as it's lowered.
+package opt
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// maxRounds limits the number of times the passes are repeated.
+const maxRounds = 10
+
+// Optimize optimises the functions of m at the given level.
+func Optimize(m *ir.Module, level int) {
+	if level <= 0 {
+		return
+	}
+	for _, f := range m.Functions {
+		optimizeFunction(f, level)
+	}
+}
+
+func optimizeFunction(f *ir.Function, level int) {
+	promoteSlots(f)
+	simplify(f, level)
+	if level < 2 {
+		return
+	}
+
+	loops := findLoops(f)
+	for _, loop := range loops {
+		hoistLoopInvariants(f, loop)
+	}
+	for _, loop := range loops {
+		reduceInductionVariables(f, loop)
+	}
+	reduceStrength(f)
+	simplify(f, level)
+	markTailCalls(f)
+}
+
+// simplify runs the scalar passes until they stop changing f.
+func simplify(f *ir.Function, level int) {
+	for round := 0; round < maxRounds; round++ {
+		changed := foldConstants(f)
+		changed = propagateCopies(f) || changed
+		if level >= 2 {
+			changed = eliminateCommonSubexpressions(f) || changed
+		}
+		changed = coalesceCopies(f) || changed
+		changed = simplifyBranches(f) || changed
+		changed = eliminateDeadCode(f) || changed
+		if !changed {
+			return
+		}
+	}
+}
+
+// useCounts returns the number of times each register of f is read, indexed
+// by ID.
+func useCounts(f *ir.Function) []int {
+	counts := make([]int, f.NumRegs()+1)
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			for _, r := range in.Uses() {
+				counts[r.ID]++
+			}
+		}
+	}
+	return counts
+}
+
+// defCounts returns the number of instructions setting each register of f,
+// indexed by ID.
+func defCounts(f *ir.Function) []int {
+	counts := make([]int, f.NumRegs()+1)
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			if in.Dst != nil {
+				counts[in.Dst.ID]++
+			}
+		}
+	}
+	return counts
+}
+
+// isPure reports whether in only computes Dst from its operands, so it can be
+// removed, repeated or moved without changing anything else.
+func isPure(in *ir.Instr) bool {
+	switch in.Op {
+	case ir.OpCopy, ir.OpAdd, ir.OpSub, ir.OpMul, ir.OpDiv, ir.OpRem, ir.OpAnd, ir.OpOr, ir.OpXor,
+		ir.OpShl, ir.OpShr, ir.OpNeg, ir.OpNot, ir.OpEq, ir.OpNe, ir.OpLt, ir.OpLe,
+		ir.OpExt, ir.OpIToF, ir.OpFToI, ir.OpFConv:
+		return in.Dst != nil
+	}
+	return false
+}
+
+// makeCopy turns in into a copy of v to its destination.
+func makeCopy(in *ir.Instr, v ir.Value) {
+	*in = ir.Instr{Op: ir.OpCopy, Type: in.Dst.Typ, Dst: in.Dst, Args: []ir.Value{v}}
+}
+
+// removeInstr removes the instruction at index i of block.
+func removeInstr(block *ir.Block, i int) {
+	block.Instrs = append(block.Instrs[:i], block.Instrs[i+1:]...)
+}
+
+// insertInstr inserts in into block before the instruction at index i.
+func insertInstr(block *ir.Block, i int, in *ir.Instr) {
+	block.Instrs = append(block.Instrs, nil)
+	copy(block.Instrs[i+1:], block.Instrs[i:])
+	block.Instrs[i] = in
+}
+
+// isConst reports whether v is an integer constant with the value c.
+func isConst(v ir.Value, c int64) bool {
+	k, ok := v.(*ir.Const)
+	return ok && !k.Typ.IsFloat() && k.Int == c
+}
@@ -0,0 +1,117 @@
+package opt
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// promotion is what's known about the accesses to a slot.
+type promotion struct {
+	// ok is cleared once the slot is found to be used in a way which needs
+	// it to be in memory.
+	ok bool
+	// typ is the type the slot is loaded and stored as, and unsigned whether
+	// its loads zero extend.
+	typ      ir.Type
+	unsigned bool
+	loaded   bool
+	reg      *ir.Reg
+}
+
+// promoteSlots keeps the local variables and parameters which are only ever
+// loaded and stored whole, as the same type, in registers instead of their
+// slots.
+func promoteSlots(f *ir.Function) bool {
+	promotions := map[*ir.Slot]*promotion{}
+	for _, slot := range f.Slots {
+		promotions[slot] = &promotion{ok: true}
+	}
+	for _, param := range f.Params {
+		promotions[param] = &promotion{ok: param.Type != ir.Void}
+	}
+
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			for i, arg := range in.Args {
+				a, ok := arg.(*ir.Addr)
+				if !ok || a.Slot == nil {
+					continue
+				}
+				p := promotions[a.Slot]
+				whole := i == 0 && (in.Op == ir.OpLoad || in.Op == ir.OpStore) &&
+					a.Offset == 0 && in.Offset == 0 && !in.Volatile && in.Type.Size() == a.Slot.Size
+				switch {
+				case !whole:
+					p.ok = false
+				case p.typ == ir.Void:
+					p.typ = in.Type
+				case p.typ != in.Type:
+					p.ok = false
+				}
+				if in.Op == ir.OpLoad {
+					if p.loaded && p.unsigned != in.Unsigned {
+						p.ok = false
+					}
+					p.loaded, p.unsigned = true, in.Unsigned
+				}
+			}
+		}
+	}
+
+	promoted := false
+	for _, slot := range append(append([]*ir.Slot{}, f.Params...), f.Slots...) {
+		if p := promotions[slot]; p.ok && p.typ != ir.Void {
+			regType := p.typ
+			if !regType.IsFloat() {
+				regType = ir.I32
+			}
+			p.reg = f.NewReg(regType)
+			promoted = true
+		}
+	}
+	if !promoted {
+		return false
+	}
+	slots := f.Slots[:0]
+	for _, slot := range f.Slots {
+		if promotions[slot].reg == nil {
+			slots = append(slots, slot)
+		}
+	}
+	f.Slots = slots
+
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			if in.Op != ir.OpLoad && in.Op != ir.OpStore {
+				continue
+			}
+			a, ok := in.Args[0].(*ir.Addr)
+			if !ok || a.Slot == nil || promotions[a.Slot].reg == nil {
+				continue
+			}
+			p := promotions[a.Slot]
+			if in.Op == ir.OpLoad {
+				makeCopy(in, p.reg)
+				continue
+			}
+			// Stores truncate values smaller than a word, which have to be
+			// extended again to be what a load would read.
+			v := in.Args[1]
+			if p.typ == ir.I8 || p.typ == ir.I16 {
+				*in = ir.Instr{Op: ir.OpExt, Type: p.typ, Dst: p.reg, Args: []ir.Value{v}, Unsigned: p.unsigned}
+				continue
+			}
+			*in = ir.Instr{Op: ir.OpCopy, Type: p.reg.Typ, Dst: p.reg, Args: []ir.Value{v}}
+		}
+	}
+
+	// Parameters start off with the value the caller passed.
+	entry := f.Blocks[0]
+	for i := len(f.Params) - 1; i >= 0; i-- {
+		param := f.Params[i]
+		p := promotions[param]
+		if p.reg == nil || !p.loaded {
+			continue
+		}
+		load := &ir.Instr{Op: ir.OpLoad, Type: p.typ, Dst: p.reg, Args: []ir.Value{&ir.Addr{Slot: param}}, Unsigned: p.unsigned}
+		insertInstr(entry, 0, load)
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package opt
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// reduceStrength replaces integer multiplication and division by constants
+// with shifts, additions and masks where they're equivalent.
+func reduceStrength(f *ir.Function) {
+	for _, block := range f.Blocks {
+		for i := 0; i < len(block.Instrs); i++ {
+			in := block.Instrs[i]
+			if in.Type != ir.I32 || in.Dst == nil {
+				continue
+			}
+			var seq []*ir.Instr
+			switch in.Op {
+			case ir.OpMul:
+				seq = reduceMul(f, in)
+			case ir.OpDiv, ir.OpRem:
+				seq = reduceDiv(f, in)
+			}
+			if seq == nil {
+				continue
+			}
+			block.Instrs = append(block.Instrs[:i], append(seq, block.Instrs[i+1:]...)...)
+			i += len(seq) - 1
+		}
+	}
+}
+
+// log2 returns n if c is 2 to the power n, and -1 otherwise.
+func log2(c int64) int {
+	if c <= 0 || c&(c-1) != 0 {
+		return -1
+	}
+	n := 0
+	for c > 1 {
+		c >>= 1
+		n++
+	}
+	return n
+}
+
+func binary(op ir.Op, dst *ir.Reg, x, y ir.Value, unsigned bool) *ir.Instr {
+	return &ir.Instr{Op: op, Type: ir.I32, Dst: dst, Args: []ir.Value{x, y}, Unsigned: unsigned}
+}
+
+// reduceMul returns the shifts and additions computing the multiplication in,
+// when it's by a constant with at most two bits set, or one less than a power
+// of two.
+func reduceMul(f *ir.Function, in *ir.Instr) []*ir.Instr {
+	x, y := in.Args[0], in.Args[1]
+	if _, ok := x.(*ir.Const); ok {
+		x, y = y, x
+	}
+	c, ok := y.(*ir.Const)
+	if !ok || c.Int <= 0 {
+		return nil
+	}
+	k := uint32(c.Int)
+	if n := log2(int64(k)); n >= 0 {
+		return []*ir.Instr{binary(ir.OpShl, in.Dst, x, ir.IntConst(int64(n)), false)}
+	}
+
+	low := k & -k
+	high := k - low
+	if n := log2(int64(k + 1)); n >= 0 {
+		// x * (2^n - 1) is (x << n) - x.
+		t := f.NewReg(ir.I32)
+		return []*ir.Instr{
+			binary(ir.OpShl, t, x, ir.IntConst(int64(n)), false),
+			binary(ir.OpSub, in.Dst, t, x, false),
+		}
+	}
+	if high&(high-1) != 0 {
+		return nil
+	}
+	// x * (2^a + 2^b) is (x << a) + (x << b).
+	t1, t2 := f.NewReg(ir.I32), f.NewReg(ir.I32)
+	return []*ir.Instr{
+		binary(ir.OpShl, t1, x, ir.IntConst(int64(log2(int64(high)))), false),
+		binary(ir.OpShl, t2, x, ir.IntConst(int64(log2(int64(low)))), false),
+		binary(ir.OpAdd, in.Dst, t1, t2, false),
+	}
+}
+
+// reduceDiv returns the shifts and masks computing the division or remainder
+// in, when it's by a power of two.
+func reduceDiv(f *ir.Function, in *ir.Instr) []*ir.Instr {
+	c, ok := in.Args[1].(*ir.Const)
+	if !ok {
+		return nil
+	}
+	n := log2(c.Int)
+	if n <= 0 {
+		return nil
+	}
+	x := in.Args[0]
+	if in.Unsigned {
+		if in.Op == ir.OpDiv {
+			return []*ir.Instr{binary(ir.OpShr, in.Dst, x, ir.IntConst(int64(n)), true)}
+		}
+		return []*ir.Instr{binary(ir.OpAnd, in.Dst, x, ir.IntConst(c.Int-1), false)}
+	}
+
+	// Signed division rounds towards zero, so 2^n - 1 is added to negative
+	// numbers before shifting.
+	sign, bias, biased := f.NewReg(ir.I32), f.NewReg(ir.I32), f.NewReg(ir.I32)
+	seq := []*ir.Instr{
+		binary(ir.OpShr, sign, x, ir.IntConst(31), false),
+		binary(ir.OpShr, bias, sign, ir.IntConst(int64(32-n)), true),
+		binary(ir.OpAdd, biased, x, bias, false),
+	}
+	if in.Op == ir.OpDiv {
+		return append(seq, binary(ir.OpShr, in.Dst, biased, ir.IntConst(int64(n)), false))
+	}
+	// The remainder is what's left after taking away the multiple of 2^n
+	// below the biased value.
+	multiple := f.NewReg(ir.I32)
+	return append(seq,
+		binary(ir.OpAnd, multiple, biased, ir.IntConst(-c.Int), false),
+		binary(ir.OpSub, in.Dst, x, multiple, false),
+	)
+}
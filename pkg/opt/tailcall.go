@@ -0,0 +1,76 @@
+package opt
+
+import "github.com/jpnock/see90/pkg/ir"
+
+// markTailCalls turns calls whose result is returned straight away into tail
+// calls, which reuse the frame of the caller. This is only done when the
+// arguments all fit in the argument registers, so that none have to be
+// written over the caller's own arguments, and when nothing can point into
+// the caller's frame.
+func markTailCalls(f *ir.Function) {
+	if frameEscapes(f) {
+		return
+	}
+	for _, block := range f.Blocks {
+		n := len(block.Instrs)
+		if n < 2 {
+			continue
+		}
+		call, ret := block.Instrs[n-2], block.Instrs[n-1]
+		if call.Op != ir.OpCall || ret.Op != ir.OpRet || !registerArguments(call) {
+			continue
+		}
+		switch {
+		case len(ret.Args) == 0 && f.Return == ir.Void:
+		case len(ret.Args) == 1 && call.Dst != nil && ret.Args[0] == ir.Value(call.Dst):
+		default:
+			continue
+		}
+		call.Op = ir.OpTailCall
+		call.Dst = nil
+		block.Instrs = block.Instrs[:n-1]
+	}
+}
+
+// frameEscapes reports whether the address of anything in the frame of f may
+// be used other than to load or store it directly, or f uses va_start.
+func frameEscapes(f *ir.Function) bool {
+	for _, block := range f.Blocks {
+		for _, in := range block.Instrs {
+			if in.Op == ir.OpVaStart {
+				return true
+			}
+			for i, arg := range in.Args {
+				a, ok := arg.(*ir.Addr)
+				if !ok || a.Slot == nil {
+					continue
+				}
+				switch {
+				case (in.Op == ir.OpLoad || in.Op == ir.OpStore) && i == 0:
+				case in.Op == ir.OpCopyBlock:
+				default:
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// registerArguments reports whether the arguments of call are all passed in
+// registers, rather than in the argument area.
+func registerArguments(call *ir.Instr) bool {
+	size := 0
+	for i, arg := range call.Args[1:] {
+		if call.Call.Aggregates[i].Size != 0 {
+			return false
+		}
+		if arg.Type() == ir.F64 {
+			size = (size + 7) &^ 7
+			size += 8
+		} else {
+			size += 4
+		}
+	}
+	return size <= 16
+}
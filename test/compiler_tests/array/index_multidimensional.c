@@ -0,0 +1,67 @@
+struct buffer {
+    int length;
+    int buf[4];
+};
+
+struct pair {
+    char tag;
+    double value;
+};
+
+int cube[2][3][4];
+int row[3] = {7, 8, 9};
+
+int *row_of(void)
+{
+    return row;
+}
+
+int sum_rows(int (*pa)[3], int rows)
+{
+    int i, j, total = 0;
+    for (i = 0; i < rows; i++) {
+        for (j = 0; j < 3; j++) {
+            total += pa[i][j];
+        }
+    }
+    return total;
+}
+
+int f()
+{
+    int i, j, k, fails = 0;
+    int grid[2][3] = {{1, 2, 3}, {4, 5, 6}};
+    int (*pgrid)[2][3] = &grid;
+    double values[3][2] = {{0.5, 1.5}, {2.5, 3.5}, {4.5, 5.5}};
+    struct pair pairs[3];
+    struct buffer b;
+    struct buffer *p = &b;
+
+    for (i = 0; i < 2; i++) {
+        for (j = 0; j < 3; j++) {
+            for (k = 0; k < 4; k++) {
+                cube[i][j][k] = i * 100 + j * 10 + k;
+            }
+        }
+    }
+    fails += cube[1][2][3] != 123 || cube[0][1][2] != 12;
+    fails += *(*(*(cube + 1) + 1) + 1) != 111;
+
+    for (i = 0; i < 4; i++) {
+        p->buf[i] = i * i;
+    }
+    fails += b.buf[3] != 9 || p->buf[2] != 4;
+
+    fails += row_of()[2] != 9;
+    fails += (*pgrid)[1][2] != 6;
+    fails += sum_rows(grid, 2) != 21;
+    fails += values[2][1] != 5.5 || values[1][0] != 2.5;
+
+    for (i = 0; i < 3; i++) {
+        pairs[i].tag = 'a' + i;
+        pairs[i].value = i * 0.25;
+    }
+    fails += pairs[2].tag != 'c' || pairs[2].value != 0.5 || pairs[1].value != 0.25;
+    fails += 2[row] != 9;
+    return fails;
+}
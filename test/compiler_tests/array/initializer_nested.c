@@ -0,0 +1,23 @@
+int global[2][3] = {{1, 2, 3}, {4, 5}};
+int global_flat[2][2] = {1, 2, 3};
+int global_sized[] = {10, 20, 30, 40};
+
+int f()
+{
+    int local[2][3] = {{1, 2, 3}, {4, 5}};
+    int local_flat[2][2] = {1, 2, 3};
+    int local_sized[] = {10, 20, 30, 40};
+    int i, j, fails = 0;
+
+    for (i = 0; i < 2; i++) {
+        for (j = 0; j < 3; j++) {
+            fails += local[i][j] != global[i][j];
+        }
+    }
+    fails += global[1][2] != 0 || global[1][1] != 5;
+    fails += global_flat[1][0] != 3 || global_flat[1][1] != 0;
+    fails += local_flat[0][1] != 2 || local_flat[1][1] != 0;
+    fails += sizeof(global_sized) != 4 * sizeof(int) || global_sized[3] != 40;
+    fails += sizeof(local_sized) != 4 * sizeof(int) || local_sized[2] != 30;
+    return fails;
+}
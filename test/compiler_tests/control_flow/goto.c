@@ -0,0 +1,23 @@
+int f(int n)
+{
+    int total;
+    int i;
+    total = 0;
+    i = 0;
+    if (n < 0)
+        goto fail;
+loop:
+    {
+        int j;
+        j = i * 2;
+        total = total + j;
+        i = i + 1;
+        if (i < n)
+            goto loop;
+    }
+    goto done;
+fail:
+    return -1;
+done:
+    return total;
+}
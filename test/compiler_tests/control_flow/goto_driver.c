@@ -0,0 +1,6 @@
+int f(int n);
+
+int main()
+{
+    return !(f(4)==12 && f(-1)==-1 && f(1)==0);
+}
@@ -0,0 +1,9 @@
+double f(int i)
+{
+    return i;
+}
+
+int g(double d)
+{
+    return d;
+}
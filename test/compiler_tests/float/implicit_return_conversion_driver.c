@@ -0,0 +1,7 @@
+double f(int i);
+int g(double d);
+
+int main()
+{
+    return !(f(7)==7.0 && g(5.9)==5 && g(f(2))==2);
+}
@@ -0,0 +1,4 @@
+double f(int i, float x)
+{
+    return i + x * 2 + 0.25;
+}
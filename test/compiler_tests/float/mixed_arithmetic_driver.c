@@ -0,0 +1,6 @@
+double f(int i, float x);
+
+int main()
+{
+    return !(f(3, 1.5f)==6.25);
+}
@@ -0,0 +1,14 @@
+float f(float a, float b)
+{
+    return a / b;
+}
+
+double g(float a, double b)
+{
+    return a - b;
+}
+
+int h(int a, float b)
+{
+    return a * 10 + b;
+}
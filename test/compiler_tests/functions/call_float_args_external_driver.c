@@ -0,0 +1,8 @@
+float f(float a, float b);
+double g(float a, double b);
+int h(int a, float b);
+
+int main()
+{
+    return !(f(9.0f, 2.0f)==4.5f && g(10.0f, 2.5)==7.5 && h(3, 4.0f)==34);
+}
@@ -0,0 +1,4 @@
+int apply(int (*f)(int, int), int a, int b)
+{
+    return f(a, b) + (*f)(a, b);
+}
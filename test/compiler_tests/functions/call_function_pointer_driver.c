@@ -0,0 +1,11 @@
+int apply(int (*f)(int, int), int a, int b);
+
+int sub(int a, int b)
+{
+    return a - b;
+}
+
+int main()
+{
+    return !(apply(sub, 10, 3)==14);
+}
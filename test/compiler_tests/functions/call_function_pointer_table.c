@@ -0,0 +1,33 @@
+int add(int a, int b)
+{
+    return a + b;
+}
+
+int mul(int a, int b)
+{
+    return a * b;
+}
+
+typedef int (*binop)(int, int);
+
+binop table[2] = { add, &mul };
+
+struct handler {
+    int id;
+    int (*fn)(int, int);
+};
+
+int f()
+{
+    struct handler h;
+    struct handler *p;
+    int total;
+    int i;
+    p = &h;
+    p->fn = table[0];
+    total = p->fn(1, 2);
+    for (i = 0; i < 2; i++) {
+        total = total + table[i](3, 4);
+    }
+    return total;
+}
@@ -0,0 +1,6 @@
+int f();
+
+int main()
+{
+    return !(f()==22);
+}
@@ -0,0 +1,9 @@
+int f(double a, int b, double c, float d)
+{
+    return a * 1000 + b * 100 + c * 10 + d;
+}
+
+int g(int a, int b, int c, int d, char e, short f, double h, unsigned char i)
+{
+    return a + b + c + d + e + f + h + i;
+}
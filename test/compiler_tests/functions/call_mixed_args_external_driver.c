@@ -0,0 +1,7 @@
+int f(double a, int b, double c, float d);
+int g(int a, int b, int c, int d, char e, short f, double h, unsigned char i);
+
+int main()
+{
+    return !(f(1.0, 2, 3.0, 4.0f)==1234 && g(1, 2, 3, 4, -5, -6, 7.0, 100)==106);
+}
@@ -0,0 +1,24 @@
+int id(int x)
+{
+    return x;
+}
+
+double half(double x)
+{
+    return x / 2;
+}
+
+int nested(int a, int b)
+{
+    return (a + 1) * ((b + 2) - ((a + 3) * ((b + 4) - ((a + 5) * ((b + 6) - ((a + 7) * ((b + 8) - ((a + 9) * ((b + 10) - ((a + 11) * ((b + 12) - ((a + 13) * ((b + 14) - (a + 15) * id(a + b))))))))))))));
+}
+
+double nested_float(double a, double b)
+{
+    return (a + 1) * ((b + 2) - ((a + 3) * ((b + 4) - ((a + 5) * ((b + 6) - ((a + 7) * ((b + 8) - (a + 9) * half(a + b))))))));
+}
+
+int mixed(int a, double b)
+{
+    return id(a) + (int)half(b) * id(a + 1) - id(a + 2) * (int)(b + half(b));
+}
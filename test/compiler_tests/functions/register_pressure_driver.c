@@ -0,0 +1,8 @@
+int nested(int a, int b);
+double nested_float(double a, double b);
+int mixed(int a, double b);
+
+int main()
+{
+    return !(nested(1, 2) == -21246376 && nested_float(0.5, 0.25) == -853.60546875 && mixed(3, 10.0) == -52);
+}
@@ -0,0 +1,28 @@
+int neg(int x)
+{
+    return -x;
+}
+
+double half(void)
+{
+    return 0.5;
+}
+
+int (*pick(int i))(int)
+{
+    if (i)
+        return neg;
+    return 0;
+}
+
+double (*choose(int i, double d))(void)
+{
+    if (i > d)
+        return half;
+    return 0;
+}
+
+int f()
+{
+    return pick(1)(7) + (pick(0) == 0) + (choose(2, 1.5) == half) + (choose(1, 1.5) == 0) + choose(2, 0.0)() * 4;
+}
@@ -0,0 +1,14 @@
+static int twice(int x)
+{
+    return x * 2;
+}
+
+extern int offset;
+
+int f(int x)
+{
+    extern int offset;
+    return twice(x) + offset;
+}
+
+int offset = 5;
@@ -0,0 +1,6 @@
+int f(int x);
+
+int main()
+{
+    return !(f(3)==11);
+}
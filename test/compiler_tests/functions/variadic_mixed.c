@@ -0,0 +1,16 @@
+double total(const char *format, ...)
+{
+    va_list ap;
+    double result;
+    result = 0;
+    va_start(ap, format);
+    for (; *format; format++) {
+        if (*format == 'd') {
+            result = result + va_arg(ap, double);
+        } else {
+            result = result + va_arg(ap, int);
+        }
+    }
+    va_end(ap);
+    return result;
+}
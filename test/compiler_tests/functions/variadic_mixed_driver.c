@@ -0,0 +1,8 @@
+double total(const char *format, ...);
+
+int main()
+{
+    float f;
+    f = 0.5f;
+    return !(total("idid", 1, 2.5, 'A', f)==69.0 && total("dd", 1.5, 2.0)==3.5);
+}
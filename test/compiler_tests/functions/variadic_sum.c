@@ -0,0 +1,15 @@
+#include <stdarg.h>
+
+int sum(int n, ...)
+{
+    va_list ap;
+    int total;
+    total = 0;
+    va_start(ap, n);
+    while (n > 0) {
+        total = total + va_arg(ap, int);
+        n = n - 1;
+    }
+    va_end(ap);
+    return total;
+}
@@ -0,0 +1,6 @@
+int sum(int n, ...);
+
+int main()
+{
+    return !(sum(3, 1, 2, 3)==6 && sum(0)==0 && sum(6, 1, 2, 3, 4, 5, 6)==21);
+}
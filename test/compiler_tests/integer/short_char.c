@@ -0,0 +1,32 @@
+short s;
+unsigned short us;
+unsigned char uc;
+signed char sc;
+
+int f(short a, unsigned short b, unsigned char c, signed char d)
+{
+    short local_s = a;
+    unsigned short local_us = b;
+    unsigned char local_uc = c;
+    int fails = 0;
+
+    fails += a != -2 || b != 65534 || c != 254 || d != -2;
+    s = 32767;
+    s++;
+    fails += s != -32768;
+    us = 65535;
+    us++;
+    fails += us != 0;
+    uc = 255;
+    uc += 2;
+    fails += uc != 1;
+    sc = 127;
+    sc++;
+    fails += sc != -128;
+    fails += local_s / 2 != -1 || local_us / 2 != 32767 || local_uc >> 1 != 127;
+    fails += (local_uc > 200) != 1 || (d < 0) != 1;
+    fails += sizeof(short) != 2 || sizeof(s + 1) != 4;
+    local_us = (unsigned short)(local_us + 3);
+    fails += local_us != 1;
+    return fails;
+}
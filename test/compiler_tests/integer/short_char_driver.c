@@ -0,0 +1,6 @@
+int f(short a, unsigned short b, unsigned char c, signed char d);
+
+int main()
+{
+    return f(-2, 65534, 254, -2);
+}
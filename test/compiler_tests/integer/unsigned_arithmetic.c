@@ -0,0 +1,28 @@
+int f(unsigned big, int negative)
+{
+    unsigned u = big;
+    unsigned long ul = big;
+    int fails = 0;
+
+    fails += big / 2 != 2147483647u;
+    fails += big % 10 != 5;
+    fails += (big >> 28) != 15;
+    fails += !(big > 1);
+    fails += negative < 1u ? 1 : 0;
+    fails += (unsigned)negative / 2 != 2147483647u;
+    fails += (negative >> 1) != -1;
+    fails += ul / 3 != 1431655765;
+
+    u /= 16;
+    fails += u != 268435455;
+    u = big;
+    u %= 7;
+    fails += u != 3;
+    u = big;
+    u >>= 4;
+    fails += u != 0x0FFFFFFF;
+    u = 3;
+    u -= 4;
+    fails += u != big;
+    return fails;
+}
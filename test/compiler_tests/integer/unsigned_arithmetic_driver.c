@@ -0,0 +1,6 @@
+int f(unsigned big, int negative);
+
+int main()
+{
+    return f(4294967295u, -1);
+}
@@ -0,0 +1,7 @@
+int next()
+{
+    static int n = 10;
+    static int calls;
+    calls = calls + 1;
+    return n++ + calls * 100;
+}
@@ -0,0 +1,10 @@
+int next();
+
+int main()
+{
+    int a;
+    int b;
+    a = next();
+    b = next();
+    return !(a==110 && b==211);
+}
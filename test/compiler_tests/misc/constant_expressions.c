@@ -0,0 +1,49 @@
+enum size {
+    WIDTH = 0x10,
+    HEIGHT = WIDTH / 3,
+    AREA = WIDTH * HEIGHT,
+    NEXT
+};
+
+int hex = 0x7fffffff + 0;
+unsigned wrapped = 0u - 1 + (1 << 4);
+int negative = -7 / 2 + -7 % 2 * 100;
+unsigned shifted = 0x80000000u >> 4;
+int arithmetic = -16 >> 2;
+char letter = 'a' + 2;
+short truncated = (short)0x12345;
+unsigned char byte = (unsigned char)-1;
+int sized = sizeof(int[4]) + sizeof(double);
+double fraction = 7 / 2 + 1.0 / 4;
+float single = (float)(1 / 3.0);
+int chosen = sizeof(char) == 1 ? 'z' : 0;
+int truth = !0 + (3 < 4) + (1 && 2) + (0 || 0);
+
+int table[AREA + 1];
+char buffer[sizeof(table) / sizeof(table[0]) - 1];
+
+int classify(int x)
+{
+    switch (x) {
+    case 'A':
+        return 1;
+    case WIDTH * 2:
+        return 2;
+    case -(int)sizeof(double):
+        return 3;
+    case NEXT:
+        return 4;
+    }
+    return 0;
+}
+
+int check()
+{
+    return hex == 2147483647 && wrapped == 15 && negative == -103
+        && shifted == 0x08000000 && arithmetic == -4 && letter == 'c'
+        && truncated == 0x2345 && byte == 255 && sized == 24
+        && fraction == 3.25 && single == (float)(1 / 3.0) && chosen == 'z'
+        && truth == 3 && sizeof(table) == 81 * sizeof(int) && sizeof(buffer) == 80
+        && classify(65) == 1 && classify(32) == 2 && classify(-8) == 3
+        && classify(81) == 4 && classify(5) == 0;
+}
@@ -0,0 +1,6 @@
+int check();
+
+int main()
+{
+    return !check();
+}
@@ -0,0 +1,10 @@
+int counter = 3;
+int zeroed;
+const int limits[3] = { 10, 20, 30 };
+static int hidden = 4;
+
+int bump()
+{
+    counter = counter + hidden;
+    return counter + zeroed;
+}
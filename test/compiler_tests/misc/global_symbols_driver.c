@@ -0,0 +1,11 @@
+extern int counter;
+extern int zeroed;
+extern const int limits[3];
+
+int bump();
+
+int main()
+{
+    zeroed = 1;
+    return !(bump()==8 && counter==7 && limits[2]==30);
+}
@@ -0,0 +1,41 @@
+struct pair {
+    int first;
+    int second[3];
+};
+
+int arr[4] = {10, 20, 30, 40};
+struct pair s = {1, {2, 3, 4}};
+int grid[2][3] = {{1, 2, 3}, {4, 5, 6}};
+
+int *plus_one = arr + 1;
+int *third = &arr[2];
+int *last = arr + 4 - 1;
+int *member = &s.second[1];
+int *first = &s.first;
+char *str = "abc" + 1;
+int *row = grid[1];
+int *pointers[] = {arr, &arr[1], 2 + arr};
+
+int twice(int x)
+{
+    return x * 2;
+}
+
+int (*function)(int) = &twice;
+
+int f()
+{
+    static int *before_last = &arr[4] - 1;
+    int fails = 0;
+    fails += *plus_one != 20;
+    fails += *third != 30;
+    fails += *last != 40;
+    fails += *member != 3;
+    fails += *first != 1;
+    fails += str[0] != 'b' || str[1] != 'c' || str[2] != 0;
+    fails += row[2] != 6;
+    fails += *pointers[0] != 10 || *pointers[1] != 20 || *pointers[2] != 30;
+    fails += function(4) != 8;
+    fails += *before_last != 40;
+    return fails;
+}
@@ -0,0 +1,7 @@
+#include "include.h"
+#include "include.h"
+
+int add_offset(int x)
+{
+    return x + OFFSET;
+}
@@ -0,0 +1,8 @@
+#ifndef INCLUDE_H
+#define INCLUDE_H
+
+#define OFFSET 3
+
+int add_offset(int x);
+
+#endif
@@ -0,0 +1,6 @@
+int add_offset(int x);
+
+int main()
+{
+    return !(add_offset(4) == 7);
+}
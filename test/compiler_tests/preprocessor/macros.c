@@ -0,0 +1,15 @@
+#define SQUARE(x) ((x) * (x))
+#define CAT(a, b) a ## b
+#define LIMIT 10
+
+#if defined(LIMIT) && LIMIT > 5
+int CAT(f, oo)(int a)
+{
+    return SQUARE(a + 1) + LIMIT;
+}
+#else
+int foo(int a)
+{
+    return 0;
+}
+#endif
@@ -0,0 +1,6 @@
+int foo(int a);
+
+int main()
+{
+    return !(foo(2) == 19);
+}
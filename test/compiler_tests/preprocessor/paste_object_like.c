@@ -0,0 +1,23 @@
+#define H a ## b
+#define hash_hash # ## #
+#define mkstr(a) # a
+#define in_between(a) mkstr(a)
+#define join(c, d) in_between(c hash_hash d)
+
+int ab = 5;
+
+int f()
+{
+    char *s = join(x, y);
+    char *want = "x ## y";
+    int i;
+    for (i = 0; want[i] != 0; i++) {
+        if (s[i] != want[i]) {
+            return 0;
+        }
+    }
+    if (s[i] != 0) {
+        return 0;
+    }
+    return H;
+}
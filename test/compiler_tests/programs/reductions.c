@@ -0,0 +1,46 @@
+int grid[6][7];
+
+int fill(int scale)
+{
+    int i;
+    int j;
+    int total;
+    total = 0;
+    for (i = 0; i < 6; i++) {
+        for (j = 0; j < 7; j++) {
+            grid[i][j] = i * 7 + j * scale;
+        }
+    }
+    for (i = 0; i < 6; i++) {
+        for (j = 0; j < 7; j++) {
+            total = total + grid[i][j] * 15 + grid[i][j] * 10;
+        }
+    }
+    return total;
+}
+
+int divisions(int x)
+{
+    return x / 8 + x % 8 * 100 + x / 2 * 10000;
+}
+
+unsigned udivisions(unsigned x)
+{
+    return x / 16 + x % 16;
+}
+
+int gcd(int a, int b)
+{
+    if (b == 0) {
+        return a;
+    }
+    return gcd(b, a % b);
+}
+
+int count_down(int n, int acc)
+{
+    if (n == 0) {
+        return acc;
+    }
+    return count_down(n - 1, acc + n);
+}
@@ -0,0 +1,10 @@
+int fill(int scale);
+int divisions(int x);
+unsigned udivisions(unsigned x);
+int gcd(int a, int b);
+int count_down(int n, int acc);
+
+int main()
+{
+    return !(fill(3) == 27825 && divisions(-27) == -130303 && divisions(27) == 130303 && udivisions(4294967295u) == 268435470u && gcd(1071, 462) == 21 && count_down(1000, 0) == 500500);
+}
@@ -0,0 +1,15 @@
+char global[] = "abc";
+char global_braced[8] = {"xy"};
+
+int f()
+{
+    char local[] = "hello";
+    char local_padded[6] = "hi";
+    int fails = 0;
+
+    fails += sizeof(global) != 4 || global[2] != 'c' || global[3] != 0;
+    fails += global_braced[1] != 'y' || global_braced[7] != 0;
+    fails += sizeof(local) != 6 || local[4] != 'o' || local[5] != 0;
+    fails += local_padded[1] != 'i' || local_padded[2] != 0 || local_padded[5] != 0;
+    return fails;
+}
@@ -0,0 +1,42 @@
+struct point {
+    int x;
+    int y;
+};
+
+struct shape {
+    char name[4];
+    struct point points[2];
+    double scale;
+};
+
+struct point global_points[3] = {{1, 2}, {3}};
+struct shape global_shape = {"ab", {{1, 2}, {3, 4}}, 2.5};
+struct shape global_elided = {{'x'}, 5, 6, 7};
+
+int check(struct shape *s, struct point *points)
+{
+    int fails = 0;
+    fails += s->name[0] != 'x' || s->name[1] != 0;
+    fails += s->points[0].x != 5 || s->points[0].y != 6;
+    fails += s->points[1].x != 7 || s->points[1].y != 0;
+    fails += s->scale != 0.0;
+    fails += points[0].y != 2 || points[1].x != 3 || points[1].y != 0;
+    fails += points[2].x != 0 || points[2].y != 0;
+    return fails;
+}
+
+int f()
+{
+    struct point local_points[3] = {{1, 2}, {3}};
+    struct shape local_shape = {"ab", {{1, 2}, {3, 4}}, 2.5};
+    struct shape local_elided = {{'x'}, 5, 6, 7};
+    int fails = 0;
+
+    fails += local_shape.name[1] != 'b' || local_shape.points[1].y != 4;
+    fails += local_shape.scale != 2.5;
+    fails += global_shape.name[1] != 'b' || global_shape.points[1].y != 4;
+    fails += global_shape.scale != 2.5;
+    fails += check(&local_elided, local_points);
+    fails += check(&global_elided, global_points);
+    return fails;
+}
@@ -0,0 +1,16 @@
+struct pair {
+    char tag;
+    double value;
+};
+
+int f()
+{
+    struct pair a;
+    struct pair b;
+    struct pair c;
+    a.tag = 2;
+    a.value = 1.5;
+    c = b = a;
+    a.tag = 7;
+    return b.tag * 10 + c.tag + (c.value == 1.5);
+}
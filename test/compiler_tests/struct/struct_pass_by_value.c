@@ -0,0 +1,21 @@
+struct rgb {
+    unsigned char r;
+    unsigned char g;
+    unsigned char b;
+};
+
+struct point {
+    int x;
+    int y;
+};
+
+int f(int k, struct rgb c, double scale)
+{
+    c.r = 0;
+    return k + c.g + c.b + scale;
+}
+
+int g(struct point a, struct point b)
+{
+    return a.x * b.x + a.y * b.y;
+}
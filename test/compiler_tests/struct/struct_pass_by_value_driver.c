@@ -0,0 +1,28 @@
+struct rgb {
+    unsigned char r;
+    unsigned char g;
+    unsigned char b;
+};
+
+struct point {
+    int x;
+    int y;
+};
+
+int f(int k, struct rgb c, double scale);
+int g(struct point a, struct point b);
+
+int main()
+{
+    struct rgb c;
+    struct point a;
+    struct point b;
+    c.r = 1;
+    c.g = 2;
+    c.b = 3;
+    a.x = 1;
+    a.y = 2;
+    b.x = 3;
+    b.y = 4;
+    return !(f(100, c, 2.5)==107 && c.r==1 && g(a, b)==11);
+}
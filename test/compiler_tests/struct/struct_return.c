@@ -0,0 +1,19 @@
+struct point {
+    int x;
+    int y;
+};
+
+struct point make(int x, int y)
+{
+    struct point p;
+    p.x = x;
+    p.y = y;
+    return p;
+}
+
+struct point add(struct point a, struct point b)
+{
+    a.x += b.x;
+    a.y += b.y;
+    return a;
+}
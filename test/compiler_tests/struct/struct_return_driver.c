@@ -0,0 +1,14 @@
+struct point {
+    int x;
+    int y;
+};
+
+struct point make(int x, int y);
+struct point add(struct point a, struct point b);
+
+int main()
+{
+    struct point p = make(3, 4);
+    struct point q = add(p, make(10, 20));
+    return !(p.x==3 && p.y==4 && q.x==13 && q.y==24 && make(5, 6).y==6);
+}
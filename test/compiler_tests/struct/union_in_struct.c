@@ -0,0 +1,22 @@
+typedef union {
+    short s[3];
+    int i;
+} small;
+
+struct tagged {
+    int kind;
+    union {
+        char c;
+        int i;
+    } v;
+    small sm;
+};
+
+int f()
+{
+    struct tagged t;
+    t.kind = 3;
+    t.v.i = 7;
+    t.sm.i = 65538;
+    return sizeof(struct tagged) * 100 + t.kind * 10 + t.sm.s[0] + t.sm.s[1] + t.v.c;
+}
@@ -0,0 +1,14 @@
+union num {
+    char c;
+    int i;
+    double d;
+};
+
+int f(union num *p)
+{
+    p->i = 1094861636;
+    if (p->c != 'A')
+        return 1;
+    p->d = 2.5;
+    return sizeof(union num) + (p->d == 2.5);
+}
@@ -0,0 +1,13 @@
+union num {
+    char c;
+    int i;
+    double d;
+};
+
+int f(union num *p);
+
+int main()
+{
+    union num n;
+    return !(f(&n)==9 && n.d==2.5);
+}
@@ -0,0 +1,13 @@
+int f(double d, unsigned u)
+{
+    int fails = 0;
+    fails += (int)d != 3;
+    fails += (char)300 != 44;
+    fails += (unsigned char)300 != 44;
+    fails += (short)70000 != 4464;
+    fails += (unsigned short)-1 != 65535;
+    fails += (double)u != 4294967295.0;
+    fails += (unsigned)4000000000.0 != 4000000000u;
+    fails += (float)d != 3.75f;
+    return fails;
+}
@@ -0,0 +1,6 @@
+int f(double d, unsigned u);
+
+int main()
+{
+    return f(3.75, 4294967295u);
+}
@@ -0,0 +1,31 @@
+const int limit = 40;
+const char greeting[3] = "hi";
+const int table[3] = {1, 2, 3};
+
+struct point {
+    const int x;
+    int y;
+};
+
+static int sum(const int *values, int n)
+{
+    int total = 0;
+    const int *end = values + n;
+    while (values != end) {
+        total += *values++;
+    }
+    return total;
+}
+
+int f(int *out)
+{
+    int *const p = out;
+    const int *const *pp;
+    const int *q = p;
+    struct point pt = {1, 2};
+
+    pp = &q;
+    *p = limit + sum(table, 3);
+    pt.y = pt.x + **pp;
+    return pt.y + greeting[1];
+}
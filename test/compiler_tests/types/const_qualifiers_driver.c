@@ -0,0 +1,8 @@
+int f(int *out);
+
+int main()
+{
+    int out = 0;
+    int r = f(&out);
+    return !(out == 46 && r == 47 + 'i');
+}
@@ -0,0 +1,4 @@
+int f()
+{
+    return sizeof(unsigned long int) + sizeof(short int) + sizeof(long double) + sizeof(unsigned char);
+}
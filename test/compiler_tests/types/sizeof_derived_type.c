@@ -0,0 +1,6 @@
+int f()
+{
+    int (*pa)[3];
+    char m[2][5];
+    return sizeof(int *[3]) + sizeof(pa) + sizeof(*pa) + sizeof(m[1]);
+}
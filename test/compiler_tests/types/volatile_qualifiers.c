@@ -0,0 +1,17 @@
+volatile int ticks;
+
+void tick(void)
+{
+    ticks++;
+}
+
+int f(volatile int *flag)
+{
+    int seen = 0;
+    ticks = 0;
+    while (ticks < 3) {
+        tick();
+        seen += *flag;
+    }
+    return seen;
+}
@@ -0,0 +1,7 @@
+int f(volatile int *flag);
+
+int main()
+{
+    volatile int flag = 2;
+    return f(&flag) != 6;
+}